@@ -0,0 +1,48 @@
+// Command healthcheck asks a gRPC server for its grpc.health.v1 status and exits 0 only if it is
+// SERVING, for docker compose healthchecks.
+//
+//	healthcheck [-addr localhost:50051] [-service name] [-timeout 2s]
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func main() {
+	addr := flag.String("addr", "localhost:50051", "address of the gRPC server")
+	service := flag.String("service", "", "service to check, empty for the whole server")
+	timeout := flag.Duration("timeout", 2*time.Second, "how long to wait for the answer")
+	flag.Parse()
+
+	if err := check(*addr, *service, *timeout); err != nil {
+		fmt.Fprintf(os.Stderr, "healthcheck %s: %v\n", *addr, err)
+		os.Exit(1)
+	}
+}
+
+func check(addr, service string, timeout time.Duration) error {
+	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	resp, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{Service: service})
+	if err != nil {
+		return err
+	}
+	if resp.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+		return fmt.Errorf("status %s", resp.GetStatus())
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+// Command migrate applies the schema migrations in db/migrations.
+//
+//	migrate [-env file] up [N]     apply all (or the next N) pending migrations
+//	migrate [-env file] down [N]   roll back the last (or the last N) migrations
+//	migrate [-env file] status     list migrations and when they were applied
+//	migrate [-env file] redo       roll back and re-apply the last migration
+//
+// The connection is configured like the services, through bootstrap.DBConfig and the same
+// db/.env lookup, unless -env names another file.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/Costin2000/GoChat---Schwarz-Internship---2025/db/migrations"
+	"github.com/Costin2000/GoChat---Schwarz-Internship---2025/pkg/bootstrap"
+	"github.com/Costin2000/GoChat---Schwarz-Internship---2025/pkg/migrate"
+)
+
+type config struct {
+	DB bootstrap.DBConfig
+}
+
+func usage() {
+	fmt.Fprintf(flag.CommandLine.Output(), "usage: migrate [-env file] up [N] | down [N] | status | redo\n")
+	flag.PrintDefaults()
+}
+
+func main() {
+	envPath := flag.String("env", "", "env file with the database settings (default: the db/.env the services use)")
+	timeout := flag.Duration("timeout", 5*time.Minute, "give up after this long, waiting for the migration lock included")
+	flag.Usage = usage
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		usage()
+		os.Exit(2)
+	}
+	cmd, args := flag.Arg(0), flag.Args()[1:]
+
+	loadEnv := bootstrap.LoadEnv
+	if *envPath != "" {
+		loadEnv = func() error { return bootstrap.LoadEnvFile(*envPath) }
+	}
+	if err := loadEnv(); err != nil {
+		log.Fatalf("load env: %v", err)
+	}
+	var cfg config
+	if err := bootstrap.LoadConfig(&cfg); err != nil {
+		log.Fatalf("load config: %v", err)
+	}
+	// DB_REQUIRE_MIGRATED is meant for the services, migrate is what brings the schema up to date
+	cfg.DB.RequireMigrated = false
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	db, err := bootstrap.OpenDB(ctx, cfg.DB)
+	if err != nil {
+		log.Fatalf("open db: %v", err)
+	}
+	defer db.Close()
+
+	m, err := migrate.New(db.DB, migrations.FS)
+	if err != nil {
+		log.Fatalf("load migrations: %v", err)
+	}
+
+	if err := run(ctx, m, cmd, args); err != nil {
+		log.Fatalf("%s: %v", cmd, err)
+	}
+}
+
+func run(ctx context.Context, m *migrate.Migrator, cmd string, args []string) error {
+	switch cmd {
+	case "up":
+		n, err := count(args, 0)
+		if err != nil {
+			return err
+		}
+		applied, err := m.Up(ctx, n)
+		for _, mig := range applied {
+			log.Printf("applied %04d_%s", mig.Version, mig.Name)
+		}
+		if err == nil && len(applied) == 0 {
+			log.Println("schema is up to date")
+		}
+		return err
+
+	case "down":
+		n, err := count(args, 1)
+		if err != nil {
+			return err
+		}
+		reverted, err := m.Down(ctx, n)
+		for _, mig := range reverted {
+			log.Printf("rolled back %04d_%s", mig.Version, mig.Name)
+		}
+		return err
+
+	case "redo":
+		mig, err := m.Redo(ctx)
+		if err != nil {
+			return err
+		}
+		log.Printf("redone %04d_%s", mig.Version, mig.Name)
+		return nil
+
+	case "status":
+		statuses, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, st := range statuses {
+			applied := "pending"
+			if st.AppliedAt != nil {
+				applied = st.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%04d  %-40s %s\n", st.Version, st.Name, applied)
+		}
+		return nil
+	}
+
+	usage()
+	os.Exit(2)
+	return nil
+}
+
+// count parses the optional N argument of up and down
+func count(args []string, fallback int) (int, error) {
+	if len(args) == 0 {
+		return fallback, nil
+	}
+	n, err := strconv.Atoi(args[0])
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid number of migrations %q", args[0])
+	}
+	return n, nil
+}
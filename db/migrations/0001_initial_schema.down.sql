@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS "Message";
+DROP TABLE IF EXISTS "Conversation";
+DROP TABLE IF EXISTS "Friend Requests";
+DROP TABLE IF EXISTS "User";
+
+DROP TYPE IF EXISTS FRIEND_REQUEST_STATUS;
@@ -1,3 +1,5 @@
+-- IF NOT EXISTS keeps this migration safe on databases created from the old db/init.sql
+
 CREATE TABLE IF NOT EXISTS "User" (
     id BIGSERIAL PRIMARY KEY, -- BIGSERIAL instead of TEXT to ensure automatic id increment without race conditions
     first_name TEXT NOT NULL,
@@ -48,4 +50,4 @@ CREATE TABLE IF NOT EXISTS "Message" (
     created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
 );
 
-CREATE INDEX IF NOT EXISTS message_conv_created_idx ON "Message"(conversation_id, created_at);
\ No newline at end of file
+CREATE INDEX IF NOT EXISTS message_conv_created_idx ON "Message"(conversation_id, created_at);
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS "Notification Preferences" (
+    user_id BIGINT NOT NULL REFERENCES "User"(id) ON DELETE CASCADE,
+    event_type TEXT NOT NULL,
+    email_enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    digest BOOLEAN NOT NULL DEFAULT FALSE,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    PRIMARY KEY (user_id, event_type)
+);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS "Unread Digests";
+DROP TABLE IF EXISTS "Conversation Reads";
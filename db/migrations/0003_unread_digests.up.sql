@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS "Conversation Reads" (
+    conversation_id BIGINT NOT NULL REFERENCES "Conversation"(id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL REFERENCES "User"(id) ON DELETE CASCADE,
+    last_read_message_id BIGINT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    PRIMARY KEY (conversation_id, user_id)
+);
+
+-- highest message id already included in an unread digest email, per recipient
+CREATE TABLE IF NOT EXISTS "Unread Digests" (
+    user_id BIGINT PRIMARY KEY REFERENCES "User"(id) ON DELETE CASCADE,
+    last_message_id BIGINT NOT NULL,
+    sent_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS email_deliveries;
+
+DROP TYPE IF EXISTS EMAIL_DELIVERY_STATUS;
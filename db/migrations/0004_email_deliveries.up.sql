@@ -0,0 +1,22 @@
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'email_delivery_status') THEN
+        CREATE TYPE EMAIL_DELIVERY_STATUS AS ENUM ('sent', 'failed', 'bounced', 'dropped', 'digested');
+    END IF;
+END$$;
+
+-- one row per attempt of the email service; no foreign key on user_id so the audit outlives deleted users
+CREATE TABLE IF NOT EXISTS email_deliveries (
+    id BIGSERIAL PRIMARY KEY,
+    recipient TEXT NOT NULL,
+    user_id BIGINT,
+    template TEXT NOT NULL,
+    status EMAIL_DELIVERY_STATUS NOT NULL,
+    error TEXT NOT NULL DEFAULT '',
+    attempt INT NOT NULL DEFAULT 1,
+    received_at TIMESTAMPTZ NOT NULL,
+    finished_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS email_deliveries_recipient_idx ON email_deliveries(recipient, id);
+CREATE INDEX IF NOT EXISTS email_deliveries_user_idx ON email_deliveries(user_id, id);
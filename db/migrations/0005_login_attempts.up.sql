@@ -0,0 +1,10 @@
+-- failed logins counted by auth, keyed by "account:<email>" or "ip:<address>". Accounts are keyed by
+-- the email that was typed in, registered or not, so a lockout does not reveal which emails exist.
+CREATE TABLE IF NOT EXISTS login_attempts (
+    key TEXT PRIMARY KEY,
+    failures INT NOT NULL DEFAULT 0,
+    last_failure_at TIMESTAMPTZ NOT NULL,
+    locked_until TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS login_attempts_last_failure_idx ON login_attempts(last_failure_at);
@@ -0,0 +1,20 @@
+-- TOTP two-factor authentication of auth. The secret is encrypted with AUTH_TOTP_KEY; confirmed_at stays
+-- NULL until the first code was verified, only then the login asks for codes. last_used_step keeps a
+-- code from being used twice.
+CREATE TABLE IF NOT EXISTS user_totp (
+    user_id BIGINT PRIMARY KEY REFERENCES "User"(id) ON DELETE CASCADE,
+    secret TEXT NOT NULL,
+    confirmed_at TIMESTAMPTZ,
+    last_used_step BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- SHA-256 of the recovery codes, each can be used once
+CREATE TABLE IF NOT EXISTS totp_recovery_codes (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES "User"(id) ON DELETE CASCADE,
+    code_hash TEXT NOT NULL,
+    used_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS totp_recovery_codes_user_idx ON totp_recovery_codes(user_id);
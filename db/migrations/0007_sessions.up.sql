@@ -0,0 +1,18 @@
+-- Sessions of auth, one per issued JWT (its sid claim). The gateway checks them on every request,
+-- so revoked_at signs a device out before the token expires. Expired rows are kept for a while so
+-- that a login from a device seen before does not count as new; device_key is the device id sent
+-- by the client, or else its user agent.
+CREATE TABLE IF NOT EXISTS sessions (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES "User"(id) ON DELETE CASCADE,
+    device_key TEXT NOT NULL,
+    device_label TEXT NOT NULL DEFAULT '',
+    user_agent TEXT NOT NULL DEFAULT '',
+    ip TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_seen_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMPTZ NOT NULL,
+    revoked_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS sessions_user_idx ON sessions(user_id, device_key);
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS personal_access_tokens;
+
+ALTER TABLE "User" DROP COLUMN IF EXISTS owner_id;
+ALTER TABLE "User" DROP COLUMN IF EXISTS is_bot;
@@ -0,0 +1,23 @@
+-- Bot accounts belong to the user who created them and have no password, they can only
+-- authenticate with personal access tokens.
+ALTER TABLE "User" ADD COLUMN IF NOT EXISTS is_bot BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE "User" ADD COLUMN IF NOT EXISTS owner_id BIGINT REFERENCES "User"(id) ON DELETE CASCADE;
+
+-- Personal access tokens of auth, for scripts and bots. Only the SHA-256 of a token is stored;
+-- user_id is who the token acts as, owner_id who manages it (the user, or the owner of the bot).
+CREATE TABLE IF NOT EXISTS personal_access_tokens (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES "User"(id) ON DELETE CASCADE,
+    owner_id BIGINT NOT NULL REFERENCES "User"(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    token_hash TEXT NOT NULL UNIQUE,
+    -- the start of the token, so users can tell their tokens apart
+    prefix TEXT NOT NULL,
+    scopes TEXT[] NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMPTZ NOT NULL,
+    last_used_at TIMESTAMPTZ,
+    revoked_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS personal_access_tokens_owner_idx ON personal_access_tokens(owner_id);
@@ -0,0 +1,3 @@
+-- user names stay lower case, the old names are not kept
+DROP INDEX IF EXISTS user_email_lower_idx;
+ALTER TABLE "User" DROP CONSTRAINT IF EXISTS user_name_lower_case;
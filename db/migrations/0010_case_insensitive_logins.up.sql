@@ -0,0 +1,19 @@
+-- Users log in with their user name or email, both matched case-insensitively.
+
+-- User names are stored lower case. Names that only differed in case keep the oldest account's
+-- name; the others get '@' and their id appended. Valid user names cannot contain '@', so this
+-- cannot collide with an existing name ("alice-7" could); those users log in with their email.
+UPDATE "User" u
+SET user_name = LOWER(u.user_name) || '@' || u.id
+WHERE EXISTS (
+    SELECT 1 FROM "User" o
+    WHERE LOWER(o.user_name) = LOWER(u.user_name) AND o.id < u.id
+);
+UPDATE "User" SET user_name = LOWER(user_name) WHERE user_name <> LOWER(user_name);
+
+ALTER TABLE "User" DROP CONSTRAINT IF EXISTS user_name_lower_case;
+ALTER TABLE "User" ADD CONSTRAINT user_name_lower_case CHECK (user_name = LOWER(user_name));
+
+-- Emails keep the case they were typed in. This fails if two accounts have emails that only
+-- differ in case; merge or rename them by hand first.
+CREATE UNIQUE INDEX IF NOT EXISTS user_email_lower_idx ON "User"(LOWER(email));
@@ -0,0 +1 @@
+ALTER TABLE "User" DROP COLUMN IF EXISTS share_email_with_friends;
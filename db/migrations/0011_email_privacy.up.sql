@@ -0,0 +1,2 @@
+-- Users choose whether their friends see their email; nobody else but services ever does.
+ALTER TABLE "User" ADD COLUMN IF NOT EXISTS share_email_with_friends BOOLEAN NOT NULL DEFAULT FALSE;
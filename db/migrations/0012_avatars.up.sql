@@ -0,0 +1,2 @@
+-- The key of the avatar thumbnails in the blob store, NULL without an avatar.
+ALTER TABLE "User" ADD COLUMN IF NOT EXISTS avatar_key TEXT;
@@ -0,0 +1,7 @@
+ALTER TABLE "User"
+    DROP COLUMN IF EXISTS discoverable,
+    DROP COLUMN IF EXISTS friend_request_policy,
+    DROP COLUMN IF EXISTS locale,
+    DROP COLUMN IF EXISTS timezone,
+    DROP COLUMN IF EXISTS status_text,
+    DROP COLUMN IF EXISTS bio;
@@ -0,0 +1,9 @@
+-- Profile fields shown with the user, and who may reach them.
+ALTER TABLE "User"
+    ADD COLUMN IF NOT EXISTS bio TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS status_text TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS timezone TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS locale TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS friend_request_policy TEXT NOT NULL DEFAULT 'everyone'
+        CONSTRAINT friend_request_policy_valid CHECK (friend_request_policy IN ('everyone', 'friends_of_friends', 'nobody')),
+    ADD COLUMN IF NOT EXISTS discoverable BOOLEAN NOT NULL DEFAULT TRUE;
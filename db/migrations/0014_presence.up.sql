@@ -0,0 +1,5 @@
+-- When users were last online, written by presence when they come online and go offline.
+CREATE TABLE IF NOT EXISTS "Presence" (
+    user_id BIGINT PRIMARY KEY REFERENCES "User"(id) ON DELETE CASCADE,
+    last_seen_at TIMESTAMPTZ NOT NULL
+);
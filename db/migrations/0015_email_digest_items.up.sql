@@ -0,0 +1,9 @@
+-- notifications waiting for the next digest of their recipient; rows are deleted once the digest went out
+CREATE TABLE IF NOT EXISTS email_digest_items (
+    id BIGSERIAL PRIMARY KEY,
+    recipient TEXT NOT NULL,
+    message JSONB NOT NULL,
+    queued_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS email_digest_items_recipient_idx ON email_digest_items(recipient, id);
@@ -0,0 +1,9 @@
+// Package migrations embeds the versioned schema migrations, applied with cmd/migrate.
+// Files are named NNNN_description.up.sql / NNNN_description.down.sql; never edit a migration
+// that was already applied somewhere, add a new one instead.
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS
@@ -0,0 +1,154 @@
+// Package avatar turns uploaded pictures into square thumbnails and signs the URLs they are served
+// from. The gateway stores the thumbnails and serves them, user-base puts the signed URL of a
+// user's avatar in avatar_url.
+package avatar
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/gif" // registers the GIF decoder
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// Sizes are the widths, in pixels, of the square thumbnails of every avatar
+var Sizes = []int{64, 256}
+
+// DefaultSize is served when the URL asks for no size
+const DefaultSize = 256
+
+// maxPixels keeps small files that decode to huge images out; decoding the largest allowed
+// picture still allocates 64 MiB of RGBA
+const maxPixels = 4096 * 4096
+
+var (
+	ErrUnsupportedType = errors.New("avatar must be a PNG, JPEG or GIF image")
+	ErrTooManyPixels   = errors.New("avatar image is too large")
+)
+
+// Thumbnails are the resized pictures of one upload, by size
+type Thumbnails struct {
+	// Key names the avatar, e.g. "42-9f86d081884c7d65.png"; it changes with the picture so the
+	// thumbnails can be cached forever
+	Key         string
+	ContentType string
+	Images      map[int][]byte
+}
+
+// BlobKey is where the thumbnail of size is stored
+func BlobKey(key string, size int) string {
+	return fmt.Sprintf("avatars/%d/%s", size, key)
+}
+
+// ValidKey tells keys made by Process from anything else, e.g. a path in a URL
+func ValidKey(key string) bool {
+	name, ext, ok := strings.Cut(key, ".")
+	if !ok || (ext != "png" && ext != "jpg") || name == "" {
+		return false
+	}
+	for _, c := range name {
+		if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'f' || c == '-') {
+			return false
+		}
+	}
+	return true
+}
+
+// Process checks that data is a picture and crops it to a square in the middle, at every size.
+// JPEGs stay JPEGs, the other formats become PNGs to keep their transparency.
+func Process(userID int64, data []byte) (*Thumbnails, error) {
+	contentType := http.DetectContentType(data)
+	if !slices.Contains([]string{"image/png", "image/jpeg", "image/gif"}, contentType) {
+		return nil, ErrUnsupportedType
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedType
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > maxPixels {
+		return nil, ErrTooManyPixels
+	}
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedType
+	}
+
+	sum := sha256.Sum256(data)
+	thumbs := &Thumbnails{Images: make(map[int][]byte, len(Sizes))}
+	ext := "png"
+	if contentType == "image/jpeg" {
+		ext = "jpg"
+	}
+	thumbs.Key = fmt.Sprintf("%d-%s.%s", userID, hex.EncodeToString(sum[:8]), ext)
+	thumbs.ContentType = ContentType(thumbs.Key)
+
+	square := centerSquare(src.Bounds())
+	for _, size := range Sizes {
+		img := resize(src, square, size)
+		var buf bytes.Buffer
+		if ext == "jpg" {
+			err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: 85})
+		} else {
+			err = png.Encode(&buf, img)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("encode %dpx thumbnail: %w", size, err)
+		}
+		thumbs.Images[size] = buf.Bytes()
+	}
+	return thumbs, nil
+}
+
+// ContentType is the type of the thumbnails of key
+func ContentType(key string) string {
+	if strings.HasSuffix(key, ".jpg") {
+		return "image/jpeg"
+	}
+	return "image/png"
+}
+
+func centerSquare(b image.Rectangle) image.Rectangle {
+	side := min(b.Dx(), b.Dy())
+	x := b.Min.X + (b.Dx()-side)/2
+	y := b.Min.Y + (b.Dy()-side)/2
+	return image.Rect(x, y, x+side, y+side)
+}
+
+// resize scales the part r of src to a size×size image. Every pixel is the average of the source
+// pixels it covers, or the nearest one when enlarging.
+func resize(src image.Image, r image.Rectangle, size int) *image.RGBA {
+	rgba := image.NewRGBA(image.Rect(0, 0, r.Dx(), r.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), src, r.Min, draw.Src)
+
+	dst := image.NewRGBA(image.Rect(0, 0, size, size))
+	n := r.Dx()
+	for y := 0; y < size; y++ {
+		y0, y1 := y*n/size, max((y+1)*n/size, y*n/size+1)
+		for x := 0; x < size; x++ {
+			x0, x1 := x*n/size, max((x+1)*n/size, x*n/size+1)
+			var sr, sg, sb, sa, count uint64
+			for sy := y0; sy < y1; sy++ {
+				row := rgba.Pix[sy*rgba.Stride:]
+				for sx := x0; sx < x1; sx++ {
+					p := row[sx*4 : sx*4+4]
+					sr += uint64(p[0])
+					sg += uint64(p[1])
+					sb += uint64(p[2])
+					sa += uint64(p[3])
+					count++
+				}
+			}
+			d := dst.Pix[y*dst.Stride+x*4:]
+			d[0], d[1], d[2], d[3] = uint8(sr/count), uint8(sg/count), uint8(sb/count), uint8(sa/count)
+		}
+	}
+	return dst
+}
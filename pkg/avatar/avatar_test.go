@@ -0,0 +1,132 @@
+package avatar
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	errchecks "github.com/Costin2000/GoChat---Schwarz-Internship---2025/pkg"
+)
+
+func encodePNG(t *testing.T, w, h int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			// the left half is red, the right half blue
+			if x < w/2 {
+				img.Set(x, y, color.RGBA{R: 255, A: 255})
+			} else {
+				img.Set(x, y, color.RGBA{B: 255, A: 255})
+			}
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestProcess(t *testing.T) {
+	var jpg bytes.Buffer
+	if err := jpeg.Encode(&jpg, image.NewRGBA(image.Rect(0, 0, 300, 200)), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// a tiny GIF whose header claims 4097x4096 pixels
+	var huge bytes.Buffer
+	if err := gif.Encode(&huge, image.NewPaletted(image.Rect(0, 0, 1, 1), color.Palette{color.White}), nil); err != nil {
+		t.Fatal(err)
+	}
+	hugeGIF := huge.Bytes()
+	hugeGIF[6], hugeGIF[7], hugeGIF[8], hugeGIF[9] = 0x01, 0x10, 0x00, 0x10
+
+	tests := []struct {
+		name        string
+		data        []byte
+		wantExt     string
+		expectedErr errchecks.Check
+	}{
+		{name: "wide png is cropped to a square", data: encodePNG(t, 600, 300), wantExt: ".png"},
+		{name: "small png is enlarged", data: encodePNG(t, 20, 20), wantExt: ".png"},
+		{name: "jpeg stays a jpeg", data: jpg.Bytes(), wantExt: ".jpg"},
+		{name: "not an image", data: []byte("<svg xmlns='http://www.w3.org/2000/svg'/>"), expectedErr: errchecks.Is(ErrUnsupportedType)},
+		{name: "picture that decodes too large", data: hugeGIF, expectedErr: errchecks.Is(ErrTooManyPixels)},
+		{name: "truncated png", data: encodePNG(t, 10, 10)[:40], expectedErr: errchecks.Is(ErrUnsupportedType)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			thumbs, err := Process(42, tt.data)
+
+			errchecks.Assert(t, err, tt.expectedErr)
+			if err != nil {
+				return
+			}
+			if !strings.HasPrefix(thumbs.Key, "42-") || !strings.HasSuffix(thumbs.Key, tt.wantExt) || !ValidKey(thumbs.Key) {
+				t.Errorf("unexpected key %q", thumbs.Key)
+			}
+			for _, size := range Sizes {
+				cfg, _, err := image.DecodeConfig(bytes.NewReader(thumbs.Images[size]))
+				if err != nil {
+					t.Fatalf("%dpx thumbnail: %v", size, err)
+				}
+				if cfg.Width != size || cfg.Height != size {
+					t.Errorf("%dpx thumbnail is %dx%d", size, cfg.Width, cfg.Height)
+				}
+			}
+		})
+	}
+}
+
+func TestProcess_KeepsTheMiddle(t *testing.T) {
+	thumbs, err := Process(42, encodePNG(t, 400, 100))
+	errchecks.Assert(t, err, errchecks.IsNil)
+
+	img, err := png.Decode(bytes.NewReader(thumbs.Images[64]))
+	errchecks.Assert(t, err, errchecks.IsNil)
+	left, right := img.At(0, 32).(color.RGBA), img.At(63, 32).(color.RGBA)
+	if left.R != 255 || right.B != 255 {
+		t.Errorf("got %v on the left and %v on the right, want red and blue", left, right)
+	}
+}
+
+func TestURLConfig(t *testing.T) {
+	now := time.Date(2025, 8, 1, 12, 30, 0, 0, time.UTC)
+	c := URLConfig{Secret: "test-secret", BaseURL: "https://chat.example.com/v1/avatars/", TTL: time.Hour}
+
+	signed := c.URL("42-ab.png", now)
+	if signed != c.URL("42-ab.png", now.Add(29*time.Minute)) {
+		t.Error("the URL changed within the same period, caches would miss")
+	}
+	u, err := url.Parse(signed)
+	errchecks.Assert(t, err, errchecks.IsNil)
+	if u.Path != "/v1/avatars/42-ab.png" {
+		t.Errorf("unexpected path %q", u.Path)
+	}
+	exp, sig := u.Query().Get("exp"), u.Query().Get("sig")
+
+	if until, ok := c.Verify("42-ab.png", exp, sig, now); !ok || !until.Equal(time.Date(2025, 8, 1, 14, 0, 0, 0, time.UTC)) {
+		t.Errorf("Verify = %v, %v; want valid until 14:00", until, ok)
+	}
+	if _, ok := c.Verify("43-ab.png", exp, sig, now); ok {
+		t.Error("signature of another key accepted")
+	}
+	if _, ok := c.Verify("42-ab.png", exp, sig, now.Add(2*time.Hour)); ok {
+		t.Error("expired URL accepted")
+	}
+	if _, ok := (URLConfig{Secret: "other", TTL: time.Hour}).Verify("42-ab.png", exp, sig, now); ok {
+		t.Error("URL signed with another secret accepted")
+	}
+	if got := (URLConfig{TTL: time.Hour}).URL("42-ab.png", now); got != "" {
+		t.Errorf("URL without a secret = %q, want none", got)
+	}
+}
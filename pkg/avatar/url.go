@@ -0,0 +1,65 @@
+package avatar
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// URLConfig signs avatar URLs in user-base and checks them in the gateway, both need the same secret.
+// Avatars are disabled without one.
+type URLConfig struct {
+	Secret string `env:"AVATAR_URL_SECRET"`
+	// where the gateway serves GET /v1/avatars/{key}, as the browser sees it
+	BaseURL string `env:"AVATAR_BASE_URL" default:"http://localhost:8080/v1/avatars"`
+	// a URL stays the same for TTL, so browsers and proxies can cache it, and works for up to 2*TTL
+	TTL time.Duration `env:"AVATAR_URL_TTL" default:"24h"`
+}
+
+func (c *URLConfig) Validate() error {
+	if c.TTL < time.Minute {
+		return fmt.Errorf("AVATAR_URL_TTL must be at least a minute, got %s", c.TTL)
+	}
+	return nil
+}
+
+func (c URLConfig) Enabled() bool {
+	return c.Secret != ""
+}
+
+// URL returns the signed URL of the avatar key, "" if there is none
+func (c URLConfig) URL(key string, now time.Time) string {
+	if key == "" || !c.Enabled() {
+		return ""
+	}
+	exp := strconv.FormatInt(now.Truncate(c.TTL).Add(2*c.TTL).Unix(), 10)
+	q := url.Values{"exp": {exp}, "sig": {c.sign(key, exp)}}
+	return strings.TrimSuffix(c.BaseURL, "/") + "/" + key + "?" + q.Encode()
+}
+
+// Verify checks the signature of an avatar URL and returns until when it may be cached
+func (c URLConfig) Verify(key, exp, sig string, now time.Time) (time.Time, bool) {
+	if !c.Enabled() {
+		return time.Time{}, false
+	}
+	unix, err := strconv.ParseInt(exp, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	expires := time.Unix(unix, 0)
+	if !expires.After(now) || !hmac.Equal([]byte(sig), []byte(c.sign(key, exp))) {
+		return time.Time{}, false
+	}
+	return expires, true
+}
+
+func (c URLConfig) sign(key, exp string) string {
+	h := hmac.New(sha256.New, []byte(c.Secret))
+	h.Write([]byte(key + "\n" + exp))
+	return base64.RawURLEncoding.EncodeToString(h.Sum(nil))
+}
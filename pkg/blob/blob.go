@@ -0,0 +1,123 @@
+// Package blob stores files, e.g. avatars, by key. Store is shaped after S3 so another backend can
+// be added next to the local filesystem one without changing the callers.
+package blob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var ErrNotFound = errors.New("blob not found")
+
+// Store keeps blobs under slash separated keys like "avatars/256/42-ab12.png"
+type Store interface {
+	Put(ctx context.Context, key string, body io.Reader, contentType string) error
+	// Get returns ErrNotFound if there is no blob with the key
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete does nothing if there is no blob with the key
+	Delete(ctx context.Context, key string) error
+}
+
+type Config struct {
+	Driver   string `env:"BLOB_DRIVER" default:"local"`
+	LocalDir string `env:"BLOB_LOCAL_DIR" default:"./data/blobs"`
+}
+
+func (c *Config) Validate() error {
+	switch c.Driver {
+	case "local":
+		if c.LocalDir == "" {
+			return errors.New("BLOB_LOCAL_DIR is required by the local blob store")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported blob store %q", c.Driver)
+	}
+}
+
+// Open returns the store the config selects
+func Open(c Config) (Store, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return NewLocal(c.LocalDir)
+}
+
+// Local keeps every blob in a file under its directory. The content type is not stored, callers
+// put the extension in the key.
+type Local struct {
+	dir string
+}
+
+func NewLocal(dir string) (*Local, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create blob directory: %w", err)
+	}
+	return &Local{dir: dir}, nil
+}
+
+func (l *Local) Put(ctx context.Context, key string, body io.Reader, contentType string) error {
+	path, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	// readers never see a partly written blob
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := io.Copy(tmp, body); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (l *Local) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	path, err := l.path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return f, err
+}
+
+func (l *Local) Delete(ctx context.Context, key string) error {
+	path, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// path keeps keys inside the directory
+func (l *Local) path(key string) (string, error) {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return "", fmt.Errorf("invalid blob key %q", key)
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." || strings.HasPrefix(part, ".upload-") {
+			return "", fmt.Errorf("invalid blob key %q", key)
+		}
+	}
+	return filepath.Join(l.dir, filepath.FromSlash(key)), nil
+}
@@ -0,0 +1,46 @@
+package blob
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	errchecks "github.com/Costin2000/GoChat---Schwarz-Internship---2025/pkg"
+)
+
+func TestLocal(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewLocal(t.TempDir())
+	errchecks.Assert(t, err, errchecks.IsNil)
+
+	errchecks.Assert(t, store.Put(ctx, "avatars/64/42-ab.png", strings.NewReader("first"), "image/png"), errchecks.IsNil)
+	errchecks.Assert(t, store.Put(ctx, "avatars/64/42-ab.png", strings.NewReader("second"), "image/png"), errchecks.IsNil)
+
+	r, err := store.Get(ctx, "avatars/64/42-ab.png")
+	errchecks.Assert(t, err, errchecks.IsNil)
+	got, _ := io.ReadAll(r)
+	r.Close()
+	if string(got) != "second" {
+		t.Errorf("got %q, want the last put", got)
+	}
+
+	errchecks.Assert(t, store.Delete(ctx, "avatars/64/42-ab.png"), errchecks.IsNil)
+	errchecks.Assert(t, store.Delete(ctx, "avatars/64/42-ab.png"), errchecks.IsNil)
+	_, err = store.Get(ctx, "avatars/64/42-ab.png")
+	errchecks.Assert(t, err, errchecks.Is(ErrNotFound))
+}
+
+func TestLocal_RejectsKeysOutsideTheDirectory(t *testing.T) {
+	store, err := NewLocal(t.TempDir())
+	errchecks.Assert(t, err, errchecks.IsNil)
+
+	for _, key := range []string{"", "/etc/passwd", "../secret", "avatars/../../secret", "avatars//x", `avatars\x`} {
+		if err := store.Put(context.Background(), key, strings.NewReader("x"), ""); err == nil {
+			t.Errorf("put %q: want an error", key)
+		}
+		if _, err := store.Get(context.Background(), key); err == nil {
+			t.Errorf("get %q: want an error", key)
+		}
+	}
+}
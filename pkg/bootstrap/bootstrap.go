@@ -0,0 +1,95 @@
+// Package bootstrap holds the startup code every service shares: loading the .env file and typed
+// config, JSON logging, opening the database and building the gRPC server. Startup errors go
+// through Fail, so every service reports them the same way and exits with status 1.
+package bootstrap
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// envFile is the settings file shared by the services, relative to the repository root
+const envFile = "db/.env"
+
+var serviceName = filepath.Base(os.Args[0])
+
+// Init loads the .env file (see LoadEnv) and makes the redacting JSON logger the default of slog
+// and of the log package, tagged with the service name.
+func Init(service string) {
+	serviceName = service
+	err := LoadEnv()
+	slog.SetDefault(newLogger(os.Stdout, service))
+	if err != nil {
+		Fail("load env", err)
+	}
+}
+
+// Fail reports a startup error and exits.
+func Fail(step string, err error) {
+	slog.Error("startup failed", "step", step, "error", err)
+	os.Exit(1)
+}
+
+// LoadEnv loads ENV_FILE if set, otherwise the first db/.env found from the working directory
+// upwards, so a service starts the same from the repository root, its own directory or a container.
+// Finding no file is fine, the environment may already hold everything (e.g. docker compose).
+func LoadEnv() error {
+	if path := os.Getenv("ENV_FILE"); path != "" {
+		return LoadEnvFile(path)
+	}
+
+	dir, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	for {
+		path := filepath.Join(dir, envFile)
+		if _, err := os.Stat(path); err == nil {
+			return LoadEnvFile(path)
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil
+		}
+		dir = parent
+	}
+}
+
+// LoadEnvFile sets the KEY=value pairs of the file. Variables already set in the environment win,
+// so docker compose and the shell can override the file.
+func LoadEnvFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var errs []error
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		key = strings.TrimSpace(key)
+		if _, set := os.LookupEnv(key); set {
+			continue
+		}
+		if err := os.Setenv(key, strings.TrimSpace(value)); err != nil {
+			errs = append(errs, fmt.Errorf("set %s: %w", key, err))
+		}
+	}
+	if err := sc.Err(); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,55 @@
+package bootstrap
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_LoadEnvFile_EnvironmentWins(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".env")
+	content := "# database\nBOOT_TEST_USER=file-user\n\nBOOT_TEST_HOST = file-host \nnot a pair\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("BOOT_TEST_USER", "shell-user")
+	t.Setenv("BOOT_TEST_HOST", "")
+	os.Unsetenv("BOOT_TEST_HOST")
+
+	if err := LoadEnvFile(path); err != nil {
+		t.Fatalf("LoadEnvFile() error = %v", err)
+	}
+
+	if got := os.Getenv("BOOT_TEST_USER"); got != "shell-user" {
+		t.Errorf("BOOT_TEST_USER = %q, want the environment value", got)
+	}
+	if got := os.Getenv("BOOT_TEST_HOST"); got != "file-host" {
+		t.Errorf("BOOT_TEST_HOST = %q, want %q", got, "file-host")
+	}
+}
+
+func Test_LoadEnv_FindsFileUpwards(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "db"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "db", ".env"), []byte("BOOT_TEST_FOUND=yes\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	serviceDir := filepath.Join(root, "services", "user-base")
+	if err := os.MkdirAll(serviceDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(serviceDir)
+	t.Setenv("ENV_FILE", "")
+	t.Setenv("BOOT_TEST_FOUND", "")
+	os.Unsetenv("BOOT_TEST_FOUND")
+
+	if err := LoadEnv(); err != nil {
+		t.Fatalf("LoadEnv() error = %v", err)
+	}
+
+	if got := os.Getenv("BOOT_TEST_FOUND"); got != "yes" {
+		t.Errorf("BOOT_TEST_FOUND = %q, want %q", got, "yes")
+	}
+}
@@ -0,0 +1,119 @@
+package bootstrap
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Validator is implemented by config structs with rules beyond required fields.
+type Validator interface {
+	Validate() error
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// LoadConfig fills the struct cfg points to from the environment. Fields are read from the
+// variable in their `env` tag, fall back to the `default` tag and fail if `required:"true"`
+// and still empty. Supported types are string, bool, int, int64, float64 and time.Duration;
+// nested structs are loaded recursively. Every problem is reported, not only the first one.
+//
+//	type Config struct {
+//		Addr    string        `env:"GRPC_ADDR" default:":50051"`
+//		Secret  string        `env:"AUTH_JWT_SECRET" required:"true"`
+//		Timeout time.Duration `env:"UPSTREAM_REQUEST_TIMEOUT" default:"5s"`
+//		DB      bootstrap.DBConfig
+//	}
+func LoadConfig(cfg any) error {
+	v := reflect.ValueOf(cfg)
+	if v.Kind() != reflect.Pointer || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("config must be a pointer to a struct, got %T", cfg)
+	}
+	return loadStruct(v.Elem())
+}
+
+func loadStruct(v reflect.Value) error {
+	var errs []error
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field, fv := t.Field(i), v.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		key, tagged := field.Tag.Lookup("env")
+		if !tagged {
+			if fv.Kind() == reflect.Struct && field.Type != durationType {
+				if err := loadStruct(fv); err != nil {
+					errs = append(errs, err)
+				}
+			}
+			continue
+		}
+
+		raw, ok := os.LookupEnv(key)
+		raw = strings.TrimSpace(raw)
+		if !ok || raw == "" {
+			raw = field.Tag.Get("default")
+		}
+		if raw == "" {
+			if field.Tag.Get("required") == "true" {
+				errs = append(errs, fmt.Errorf("%s is required", key))
+			}
+			continue
+		}
+		if err := setField(fv, raw); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", key, err))
+		}
+	}
+
+	if len(errs) == 0 && v.CanAddr() {
+		if val, ok := v.Addr().Interface().(Validator); ok {
+			if err := val.Validate(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func setField(fv reflect.Value, raw string) error {
+	if fv.Type() == durationType {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", raw)
+		}
+		fv.SetInt(int64(d))
+		return nil
+	}
+
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("invalid bool %q", raw)
+		}
+		fv.SetBool(b)
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, fv.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", raw)
+		}
+		fv.SetInt(n)
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", raw)
+		}
+		fv.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported config type %s", fv.Type())
+	}
+	return nil
+}
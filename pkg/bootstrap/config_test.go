@@ -0,0 +1,117 @@
+package bootstrap
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+type testConfig struct {
+	Addr    string        `env:"TEST_ADDR" default:":50051"`
+	Secret  string        `env:"TEST_SECRET" required:"true"`
+	Workers int           `env:"TEST_WORKERS" default:"4"`
+	Debug   bool          `env:"TEST_DEBUG"`
+	Timeout time.Duration `env:"TEST_TIMEOUT" default:"5s"`
+	Nested  struct {
+		Ratio float64 `env:"TEST_RATIO" default:"0.5"`
+	}
+	ignored string
+}
+
+func Test_LoadConfig(t *testing.T) {
+	tests := []struct {
+		name       string
+		env        map[string]string
+		want       testConfig
+		wantErrors []string
+	}{
+		{
+			name: "defaults",
+			env:  map[string]string{"TEST_SECRET": "s3cret"},
+			want: func() testConfig {
+				c := testConfig{Addr: ":50051", Secret: "s3cret", Workers: 4, Timeout: 5 * time.Second}
+				c.Nested.Ratio = 0.5
+				return c
+			}(),
+		},
+		{
+			name: "environment overrides defaults",
+			env: map[string]string{
+				"TEST_ADDR": ":9000", "TEST_SECRET": "s3cret", "TEST_WORKERS": "8",
+				"TEST_DEBUG": "true", "TEST_TIMEOUT": "1m", "TEST_RATIO": "0.25",
+			},
+			want: func() testConfig {
+				c := testConfig{Addr: ":9000", Secret: "s3cret", Workers: 8, Debug: true, Timeout: time.Minute}
+				c.Nested.Ratio = 0.25
+				return c
+			}(),
+		},
+		{
+			name:       "every problem is reported",
+			env:        map[string]string{"TEST_WORKERS": "many", "TEST_TIMEOUT": "soon"},
+			wantErrors: []string{"TEST_SECRET is required", `TEST_WORKERS: invalid integer "many"`, `TEST_TIMEOUT: invalid duration "soon"`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, key := range []string{"TEST_ADDR", "TEST_SECRET", "TEST_WORKERS", "TEST_DEBUG", "TEST_TIMEOUT", "TEST_RATIO"} {
+				t.Setenv(key, tt.env[key])
+			}
+
+			var got testConfig
+			err := LoadConfig(&got)
+
+			if len(tt.wantErrors) > 0 {
+				if err == nil {
+					t.Fatal("LoadConfig() expected an error")
+				}
+				for _, want := range tt.wantErrors {
+					if !strings.Contains(err.Error(), want) {
+						t.Errorf("LoadConfig() error %q does not mention %q", err, want)
+					}
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadConfig() error = %v", err)
+			}
+			if diff := cmp.Diff(tt.want, got, cmp.AllowUnexported(testConfig{})); diff != "" {
+				t.Errorf("LoadConfig() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func Test_LoadConfig_DBConfigValidation(t *testing.T) {
+	t.Setenv("POSTGRES_USER", "gochat")
+	t.Setenv("POSTGRES_PASSWORD", "secret")
+	t.Setenv("POSTGRES_DB", "gochat")
+	t.Setenv("DB_SSLMODE", "sometimes")
+
+	var cfg struct{ DB DBConfig }
+	err := LoadConfig(&cfg)
+
+	if err == nil || !strings.Contains(err.Error(), `unknown mode "sometimes"`) {
+		t.Errorf("LoadConfig() error = %v, want invalid sslmode", err)
+	}
+}
+
+func Test_DBConfig_DSN(t *testing.T) {
+	cfg := DBConfig{
+		User:        "gochat",
+		Password:    `it's a \secret`,
+		Name:        "gochat",
+		Host:        "db.internal",
+		Port:        5432,
+		SSLMode:     "verify-full",
+		SSLRootCert: "/certs/root ca.pem",
+	}
+
+	want := `user=gochat password='it\'s a \\secret' host=db.internal port=5432 dbname=gochat sslmode=verify-full sslrootcert='/certs/root ca.pem'`
+	if got := cfg.DSN(); got != want {
+		t.Errorf("DSN() = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,200 @@
+package bootstrap
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Costin2000/GoChat---Schwarz-Internship---2025/db/migrations"
+	"github.com/Costin2000/GoChat---Schwarz-Internship---2025/pkg/migrate"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5/stdlib"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// DBConfig holds the Postgres settings shared by the services, read from the same variables as db/.env.
+type DBConfig struct {
+	User     string `env:"POSTGRES_USER" required:"true"`
+	Password string `env:"POSTGRES_PASSWORD" required:"true"`
+	Name     string `env:"POSTGRES_DB" required:"true"`
+	Host     string `env:"DB_HOST"` // empty means postgres-db when ENV=docker, localhost otherwise
+	Port     int    `env:"DB_PORT" default:"5432"`
+
+	// TLS, see https://www.postgresql.org/docs/current/libpq-ssl.html
+	SSLMode     string `env:"DB_SSLMODE" default:"disable"`
+	SSLRootCert string `env:"DB_SSLROOTCERT"`
+	SSLCert     string `env:"DB_SSLCERT"`
+	SSLKey      string `env:"DB_SSLKEY"`
+
+	MaxConns        int           `env:"DB_MAX_CONNS" default:"10"`
+	MinConns        int           `env:"DB_MIN_CONNS" default:"0"`
+	MaxConnLifetime time.Duration `env:"DB_MAX_CONN_LIFETIME" default:"1h"`
+	MaxConnIdleTime time.Duration `env:"DB_MAX_CONN_IDLE_TIME" default:"10m"`
+
+	// how long to keep retrying while the database is not reachable yet
+	ConnectTimeout time.Duration `env:"DB_CONNECT_TIMEOUT" default:"30s"`
+	// refuse to start while db/migrations has changes the database does not have, see cmd/migrate
+	RequireMigrated bool `env:"DB_REQUIRE_MIGRATED" default:"false"`
+}
+
+func (c *DBConfig) Validate() error {
+	switch c.SSLMode {
+	case "disable", "allow", "prefer", "require", "verify-ca", "verify-full":
+	default:
+		return fmt.Errorf("DB_SSLMODE: unknown mode %q", c.SSLMode)
+	}
+	if c.MaxConns <= 0 {
+		return fmt.Errorf("DB_MAX_CONNS must be positive")
+	}
+	if c.MinConns < 0 || c.MinConns > c.MaxConns {
+		return fmt.Errorf("DB_MIN_CONNS must be between 0 and DB_MAX_CONNS")
+	}
+	return nil
+}
+
+// DSN returns the keyword/value connection string of the config.
+func (c *DBConfig) DSN() string {
+	host := c.Host
+	if host == "" {
+		host = "localhost"
+		if os.Getenv("ENV") == "docker" {
+			host = "postgres-db"
+		}
+	}
+
+	params := []string{
+		"user=" + quote(c.User),
+		"password=" + quote(c.Password),
+		"host=" + quote(host),
+		fmt.Sprintf("port=%d", c.Port),
+		"dbname=" + quote(c.Name),
+		"sslmode=" + quote(c.SSLMode),
+	}
+	for _, kv := range [][2]string{{"sslrootcert", c.SSLRootCert}, {"sslcert", c.SSLCert}, {"sslkey", c.SSLKey}} {
+		if kv[1] != "" {
+			params = append(params, kv[0]+"="+quote(kv[1]))
+		}
+	}
+	return strings.Join(params, " ")
+}
+
+// quote escapes a value of a keyword/value connection string
+func quote(v string) string {
+	if v != "" && !strings.ContainsAny(v, ` '\`) {
+		return v
+	}
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(v) + "'"
+}
+
+// DB is a database/sql handle backed by a pgx connection pool.
+type DB struct {
+	*sql.DB
+	pool *pgxpool.Pool
+}
+
+// Close closes the handle and the pool behind it.
+func (db *DB) Close() error {
+	err := db.DB.Close()
+	db.pool.Close()
+	return err
+}
+
+// Check reports the database as the "postgres" dependency of the service.
+func (db *DB) Check() Check {
+	return Check{Name: "postgres", Probe: db.PingContext}
+}
+
+// OpenDB connects to Postgres, retrying until cfg.ConnectTimeout, and checks the schema
+// version when cfg.RequireMigrated is set.
+func OpenDB(ctx context.Context, cfg DBConfig) (*DB, error) {
+	poolCfg, err := pgxpool.ParseConfig(cfg.DSN())
+	if err != nil {
+		return nil, fmt.Errorf("parse database config: %w", err)
+	}
+	poolCfg.MaxConns = int32(cfg.MaxConns)
+	poolCfg.MinConns = int32(cfg.MinConns)
+	poolCfg.MaxConnLifetime = cfg.MaxConnLifetime
+	poolCfg.MaxConnIdleTime = cfg.MaxConnIdleTime
+	poolCfg.ConnConfig.Tracer = queryTracer{tracer: otel.Tracer("github.com/Costin2000/GoChat---Schwarz-Internship---2025/pkg/bootstrap")}
+
+	pool, err := pgxpool.NewWithConfig(ctx, poolCfg)
+	if err != nil {
+		return nil, fmt.Errorf("create pool: %w", err)
+	}
+	db := &DB{DB: stdlib.OpenDBFromPool(pool), pool: pool}
+
+	if err := ping(ctx, db, cfg.ConnectTimeout); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	registerDBStats(db.DB, cfg.Name)
+	slog.Info("connected to PostgreSQL", "host", poolCfg.ConnConfig.Host, "port", cfg.Port, "database", cfg.Name, "sslmode", cfg.SSLMode)
+
+	if cfg.RequireMigrated {
+		if err := migrate.RequireLatest(ctx, db.DB, migrations.FS); err != nil {
+			_ = db.Close()
+			return nil, err
+		}
+	}
+	return db, nil
+}
+
+func ping(ctx context.Context, db *DB, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	for {
+		err := db.PingContext(ctx)
+		if err == nil {
+			return nil
+		}
+		slog.Warn("database not reachable yet", "error", err)
+		select {
+		case <-time.After(time.Second):
+		case <-ctx.Done():
+			return fmt.Errorf("ping database: %w", err)
+		}
+	}
+}
+
+// queryTracer records a span per query. The statement is recorded with its placeholders, the
+// arguments are not, they may hold user data.
+type queryTracer struct {
+	tracer trace.Tracer
+}
+
+func (t queryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	ctx, _ = t.tracer.Start(ctx, "postgres "+operation(data.SQL),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", "postgresql"),
+			attribute.String("db.statement", data.SQL),
+		))
+	return ctx
+}
+
+func (t queryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	span := trace.SpanFromContext(ctx)
+	if data.Err != nil {
+		span.RecordError(data.Err)
+		span.SetStatus(otelcodes.Error, data.Err.Error())
+	}
+	span.End()
+}
+
+// operation is the SQL verb of a statement, e.g. SELECT
+func operation(sql string) string {
+	fields := strings.Fields(sql)
+	if len(fields) == 0 {
+		return "query"
+	}
+	return strings.ToUpper(fields[0])
+}
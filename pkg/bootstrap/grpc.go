@@ -0,0 +1,209 @@
+package bootstrap
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"os/signal"
+	"runtime/debug"
+	"syscall"
+	"time"
+
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc/filters"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+)
+
+// UserIDHeader is the metadata key the gateway forwards the authenticated user ID in. The services
+// only use it for logging, it is not an authorization decision.
+const UserIDHeader = "x-user-id"
+
+// ClientIPHeader is the metadata key the gateway forwards the address of the HTTP client in.
+const ClientIPHeader = "x-client-ip"
+
+// ClientIP returns the address of the HTTP client the call originates from, empty if unknown.
+func ClientIP(ctx context.Context) string {
+	if v := metadata.ValueFromIncomingContext(ctx, ClientIPHeader); len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+// UserAgentHeader is the metadata key the gateway forwards the User-Agent of the HTTP client in.
+const UserAgentHeader = "x-user-agent"
+
+// UserAgent returns the User-Agent of the HTTP client the call originates from, empty if unknown.
+func UserAgent(ctx context.Context) string {
+	if v := metadata.ValueFromIncomingContext(ctx, UserAgentHeader); len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+// SessionIDHeader is the metadata key the gateway forwards the session of the authenticated user in.
+const SessionIDHeader = "x-session-id"
+
+// NewGRPCServer returns a server with the standard interceptors: panics become Internal errors
+// instead of crashing the process, every call is counted and timed for /metrics and gets a JSON
+// access log line with its code, latency and caller.
+func NewGRPCServer(opts ...grpc.ServerOption) *grpc.Server {
+	opts = append([]grpc.ServerOption{
+		grpc.StatsHandler(otelgrpc.NewServerHandler(otelgrpc.WithFilter(filters.Not(filters.HealthCheck())))),
+		grpc.ChainUnaryInterceptor(metricsUnary, logUnary, recoverUnary),
+		grpc.ChainStreamInterceptor(metricsStream, logStream, recoverStream),
+	}, opts...)
+	return grpc.NewServer(opts...)
+}
+
+// Dial returns a client connection to another service of the cluster, carrying the trace context.
+func Dial(addr string, opts ...grpc.DialOption) (*grpc.ClientConn, error) {
+	opts = append([]grpc.DialOption{
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithStatsHandler(otelgrpc.NewClientHandler()),
+	}, opts...)
+	return grpc.NewClient(addr, opts...)
+}
+
+// ShutdownConfig bounds how long a server drains after SIGTERM before in-flight calls are cancelled.
+type ShutdownConfig struct {
+	// time between reporting NOT_SERVING and draining, so clients stop picking this instance first
+	Delay   time.Duration `env:"SHUTDOWN_DELAY" default:"0s"`
+	Timeout time.Duration `env:"SHUTDOWN_TIMEOUT" default:"15s"`
+}
+
+// SignalContext returns a context that is cancelled on SIGINT or SIGTERM.
+func SignalContext() (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+}
+
+// ServeGRPC registers reflection and serves on addr until ctx is done. It then reports NOT_SERVING,
+// lets in-flight calls finish within cfg.Timeout and returns, so the deferred cleanup of main runs.
+func ServeGRPC(ctx context.Context, srv *grpc.Server, addr string, health *Health, cfg ShutdownConfig) error {
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("listen %s: %w", addr, err)
+	}
+	reflection.Register(srv)
+
+	served := make(chan error, 1)
+	go func() {
+		slog.Info("gRPC server listening", "addr", addr)
+		served <- srv.Serve(lis)
+	}()
+
+	select {
+	case err := <-served:
+		return err
+	case <-ctx.Done():
+	}
+
+	slog.Info("shutting down, draining in-flight calls", "timeout", cfg.Timeout.String())
+	health.Shutdown()
+	time.Sleep(cfg.Delay)
+
+	stopped := make(chan struct{})
+	go func() {
+		srv.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(cfg.Timeout):
+		slog.Warn("calls still running after the shutdown timeout, cancelling them", "timeout", cfg.Timeout.String())
+		srv.Stop()
+	}
+	return nil
+}
+
+func recoverUnary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = recovered(info.FullMethod, r)
+		}
+	}()
+	return handler(ctx, req)
+}
+
+func recoverStream(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = recovered(info.FullMethod, r)
+		}
+	}()
+	return handler(srv, ss)
+}
+
+func recovered(method string, r any) error {
+	slog.Error("panic in handler", "method", method, "panic", fmt.Sprint(r), "stack", string(debug.Stack()))
+	return status.Error(codes.Internal, "internal error")
+}
+
+func logUnary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	start := time.Now()
+	resp, err := handler(ctx, req)
+	logCall(ctx, info.FullMethod, err, start)
+	return resp, err
+}
+
+func logStream(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	start := time.Now()
+	err := handler(srv, ss)
+	logCall(ss.Context(), info.FullMethod, err, start)
+	return err
+}
+
+// logCall writes the access log line of a call; request and response bodies are never logged
+func logCall(ctx context.Context, method string, err error, start time.Time) {
+	code := status.Code(err)
+	attrs := []any{"method", method, "code", code.String(), "latency_ms", LatencyMS(start)}
+	if id := callerUserID(ctx); id != "" {
+		attrs = append(attrs, "user_id", id)
+	}
+	if id := TraceID(ctx); id != "" {
+		attrs = append(attrs, "trace_id", id)
+	}
+
+	level := slog.LevelInfo
+	if err != nil {
+		attrs = append(attrs, "error", status.Convert(err).Message())
+		if serverError(code) {
+			level = slog.LevelError
+		}
+	}
+	slog.Log(ctx, level, "grpc call", attrs...)
+}
+
+// LatencyMS is the time since start in milliseconds, as the access logs report it.
+func LatencyMS(start time.Time) float64 {
+	return float64(time.Since(start).Microseconds()) / 1000
+}
+
+// TraceID returns the ID of the trace ctx belongs to, empty if there is none.
+func TraceID(ctx context.Context) string {
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		return sc.TraceID().String()
+	}
+	return ""
+}
+
+func serverError(code codes.Code) bool {
+	switch code {
+	case codes.Internal, codes.Unknown, codes.DataLoss, codes.Unavailable:
+		return true
+	}
+	return false
+}
+
+func callerUserID(ctx context.Context) string {
+	if v := metadata.ValueFromIncomingContext(ctx, UserIDHeader); len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
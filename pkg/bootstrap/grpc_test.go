@@ -0,0 +1,55 @@
+package bootstrap
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	errchecks "github.com/Costin2000/GoChat---Schwarz-Internship---2025/pkg"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func Test_recoverUnary(t *testing.T) {
+	info := &grpc.UnaryServerInfo{FullMethod: "/user.UserService/GetUser"}
+	panicking := func(ctx context.Context, req any) (any, error) {
+		var m map[string]int
+		m["boom"]++ // nil map write
+		return nil, nil
+	}
+
+	resp, err := recoverUnary(context.Background(), nil, info, panicking)
+
+	errchecks.Assert(t, err, errchecks.HasStatusCode(codes.Internal))
+	if resp != nil {
+		t.Errorf("recoverUnary() resp = %v, want nil", resp)
+	}
+}
+
+func Test_ServeGRPC_StopsOnCancel(t *testing.T) {
+	srv := grpc.NewServer()
+	health := RegisterHealth(srv)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	served := make(chan error, 1)
+	go func() { served <- ServeGRPC(ctx, srv, "127.0.0.1:0", health, ShutdownConfig{Timeout: time.Second}) }()
+	cancel()
+
+	select {
+	case err := <-served:
+		if err != nil {
+			t.Fatalf("ServeGRPC() error = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("ServeGRPC() did not return after the context was cancelled")
+	}
+
+	resp, err := health.server.Check(context.Background(), &healthpb.HealthCheckRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.GetStatus() != healthpb.HealthCheckResponse_NOT_SERVING {
+		t.Errorf("health after shutdown = %s, want NOT_SERVING", resp.GetStatus())
+	}
+}
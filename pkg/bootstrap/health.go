@@ -0,0 +1,100 @@
+package bootstrap
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+const (
+	healthInterval = 5 * time.Second
+	probeTimeout   = 2 * time.Second
+)
+
+// Check probes one dependency of a service. Optional checks are reported under their name but
+// do not make the service NOT_SERVING, e.g. RabbitMQ for publishers that spool while it is down.
+type Check struct {
+	Name     string
+	Probe    func(ctx context.Context) error
+	Optional bool
+}
+
+// Health serves grpc.health.v1 for a server. The overall status ("") and the status of every
+// registered gRPC service are SERVING while all required checks pass, and each check is reported
+// under its own name as well, so List shows which dependency is down.
+type Health struct {
+	server   *health.Server
+	checks   []Check
+	services []string
+	last     map[string]bool
+}
+
+// RegisterHealth adds the health service to srv; call it after the service implementations are
+// registered and run Run to keep the statuses current.
+func RegisterHealth(srv *grpc.Server, checks ...Check) *Health {
+	h := &Health{server: health.NewServer(), checks: checks, last: map[string]bool{}}
+	for name := range srv.GetServiceInfo() {
+		h.services = append(h.services, name)
+	}
+	healthpb.RegisterHealthServer(srv, h.server)
+	h.update(context.Background())
+	return h
+}
+
+// Run probes the checks every few seconds until ctx is done.
+func (h *Health) Run(ctx context.Context) {
+	t := time.NewTicker(healthInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			h.update(ctx)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Shutdown reports NOT_SERVING for everything from now on, so clients stop sending new calls.
+func (h *Health) Shutdown() {
+	h.server.Shutdown()
+}
+
+func (h *Health) update(ctx context.Context) {
+	serving := true
+	for _, c := range h.checks {
+		pctx, cancel := context.WithTimeout(ctx, probeTimeout)
+		err := c.Probe(pctx)
+		cancel()
+
+		if prev, seen := h.last[c.Name]; !seen || prev != (err == nil) {
+			ok := err == nil
+			if ok {
+				slog.Info("dependency reachable", "dependency", c.Name)
+			} else {
+				slog.Warn("dependency unreachable", "dependency", c.Name, "error", err)
+			}
+			h.last[c.Name] = ok
+		}
+		h.server.SetServingStatus(c.Name, servingStatus(err == nil))
+		if err != nil && !c.Optional {
+			serving = false
+		}
+	}
+
+	h.server.SetServingStatus("", servingStatus(serving))
+	for _, name := range h.services {
+		h.server.SetServingStatus(name, servingStatus(serving))
+	}
+}
+
+func servingStatus(ok bool) healthpb.HealthCheckResponse_ServingStatus {
+	if ok {
+		return healthpb.HealthCheckResponse_SERVING
+	}
+	return healthpb.HealthCheckResponse_NOT_SERVING
+}
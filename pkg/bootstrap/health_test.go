@@ -0,0 +1,79 @@
+package bootstrap
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func Test_Health(t *testing.T) {
+	up := func(context.Context) error { return nil }
+	down := func(context.Context) error { return errors.New("connection refused") }
+
+	tests := []struct {
+		name   string
+		checks []Check
+		want   map[string]healthpb.HealthCheckResponse_ServingStatus
+	}{
+		{
+			name:   "all dependencies reachable",
+			checks: []Check{{Name: "postgres", Probe: up}, {Name: "rabbitmq", Probe: up}},
+			want: map[string]healthpb.HealthCheckResponse_ServingStatus{
+				"":         healthpb.HealthCheckResponse_SERVING,
+				"postgres": healthpb.HealthCheckResponse_SERVING,
+				"rabbitmq": healthpb.HealthCheckResponse_SERVING,
+			},
+		},
+		{
+			name:   "required dependency down",
+			checks: []Check{{Name: "postgres", Probe: down}, {Name: "rabbitmq", Probe: up}},
+			want: map[string]healthpb.HealthCheckResponse_ServingStatus{
+				"":         healthpb.HealthCheckResponse_NOT_SERVING,
+				"postgres": healthpb.HealthCheckResponse_NOT_SERVING,
+				"rabbitmq": healthpb.HealthCheckResponse_SERVING,
+			},
+		},
+		{
+			name:   "optional dependency down",
+			checks: []Check{{Name: "postgres", Probe: up}, {Name: "rabbitmq", Probe: down, Optional: true}},
+			want: map[string]healthpb.HealthCheckResponse_ServingStatus{
+				"":         healthpb.HealthCheckResponse_SERVING,
+				"postgres": healthpb.HealthCheckResponse_SERVING,
+				"rabbitmq": healthpb.HealthCheckResponse_NOT_SERVING,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := RegisterHealth(grpc.NewServer(), tt.checks...)
+
+			for service, want := range tt.want {
+				resp, err := h.server.Check(context.Background(), &healthpb.HealthCheckRequest{Service: service})
+				if err != nil {
+					t.Fatalf("Check(%q) error = %v", service, err)
+				}
+				if resp.GetStatus() != want {
+					t.Errorf("Check(%q) = %s, want %s", service, resp.GetStatus(), want)
+				}
+			}
+		})
+	}
+}
+
+func Test_Health_Shutdown(t *testing.T) {
+	h := RegisterHealth(grpc.NewServer(), Check{Name: "postgres", Probe: func(context.Context) error { return nil }})
+
+	h.Shutdown()
+
+	resp, err := h.server.Check(context.Background(), &healthpb.HealthCheckRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.GetStatus() != healthpb.HealthCheckResponse_NOT_SERVING {
+		t.Errorf("Check() after Shutdown = %s, want NOT_SERVING", resp.GetStatus())
+	}
+}
@@ -0,0 +1,66 @@
+package bootstrap
+
+import (
+	"io"
+	"log/slog"
+	"os"
+	"regexp"
+	"strings"
+)
+
+const redacted = "[REDACTED]"
+
+// attributes that are never logged, whatever their value
+var sensitiveKeys = map[string]bool{
+	"password":      true,
+	"password_hash": true,
+	"token":         true,
+	"authorization": true,
+	"secret":        true,
+	"email":         true,
+	"to":            true,
+	"recipient":     true,
+}
+
+var (
+	emailPattern  = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@([A-Za-z0-9\-]+\.)+[A-Za-z]{2,}`)
+	bearerPattern = regexp.MustCompile(`(?i)bearer\s+[A-Za-z0-9\-._~+/]+=*`)
+)
+
+// newLogger returns the JSON logger of the service; LOG_LEVEL is one of debug, info, warn or error.
+func newLogger(w io.Writer, service string) *slog.Logger {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(os.Getenv("LOG_LEVEL"))); err != nil {
+		level = slog.LevelInfo
+	}
+	h := slog.NewJSONHandler(w, &slog.HandlerOptions{Level: level, ReplaceAttr: redactAttr})
+	return slog.New(h).With("service", service)
+}
+
+// redactAttr keeps PII out of the logs, including the messages of plain log.Printf calls
+func redactAttr(_ []string, a slog.Attr) slog.Attr {
+	if sensitiveKeys[strings.ToLower(a.Key)] {
+		return slog.String(a.Key, redacted)
+	}
+	switch a.Value.Kind() {
+	case slog.KindString:
+		return slog.String(a.Key, Redact(a.Value.String()))
+	case slog.KindAny:
+		if err, ok := a.Value.Any().(error); ok {
+			return slog.String(a.Key, Redact(err.Error()))
+		}
+	}
+	return a
+}
+
+// Redact masks email addresses and bearer tokens in s.
+func Redact(s string) string {
+	s = bearerPattern.ReplaceAllString(s, "Bearer "+redacted)
+	return emailPattern.ReplaceAllStringFunc(s, maskEmail)
+}
+
+// maskEmail keeps the first letter and the domain, enough to tell addresses apart when debugging
+func maskEmail(email string) string {
+	local, domain, _ := strings.Cut(email, "@")
+	return local[:1] + "***@" + domain
+}
@@ -0,0 +1,104 @@
+package bootstrap
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// RED metrics of the gRPC servers, labelled like go-grpc-prometheus so the usual dashboards work
+var (
+	grpcHandled = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "grpc_server_handled_total",
+		Help: "RPCs completed on the server, by method and status code.",
+	}, []string{"grpc_service", "grpc_method", "grpc_code"})
+
+	grpcHandlingSeconds = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "grpc_server_handling_seconds",
+		Help:    "Time the server took to complete RPCs.",
+		Buckets: []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10},
+	}, []string{"grpc_service", "grpc_method"})
+
+	grpcStreamsActive = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "grpc_server_streams_active",
+		Help: "Streams currently open on the server, i.e. connected subscribers.",
+	}, []string{"grpc_service", "grpc_method"})
+)
+
+// ServeMetrics serves /metrics on addr until ctx is done. It only fails if addr cannot be listened on,
+// the gRPC port stays reserved for gRPC.
+func ServeMetrics(ctx context.Context, addr string) error {
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("listen %s: %w", addr, err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	srv := &http.Server{Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+
+	go func() {
+		<-ctx.Done()
+		_ = srv.Close()
+	}()
+	go func() {
+		slog.Info("metrics server listening", "addr", addr)
+		if err := srv.Serve(lis); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("metrics server stopped", "error", err)
+		}
+	}()
+	return nil
+}
+
+// registerDBStats exports the database/sql pool statistics, e.g. connections in use and wait time
+func registerDBStats(db *sql.DB, name string) {
+	if err := prometheus.Register(collectors.NewDBStatsCollector(db, name)); err != nil {
+		slog.Warn("could not register database metrics", "error", err)
+	}
+}
+
+func metricsUnary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	start := time.Now()
+	resp, err := handler(ctx, req)
+	observeCall(info.FullMethod, err, start)
+	return resp, err
+}
+
+func metricsStream(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	service, method := splitMethod(info.FullMethod)
+	active := grpcStreamsActive.WithLabelValues(service, method)
+	active.Inc()
+	defer active.Dec()
+
+	start := time.Now()
+	err := handler(srv, ss)
+	observeCall(info.FullMethod, err, start)
+	return err
+}
+
+func observeCall(fullMethod string, err error, start time.Time) {
+	service, method := splitMethod(fullMethod)
+	grpcHandled.WithLabelValues(service, method, status.Code(err).String()).Inc()
+	grpcHandlingSeconds.WithLabelValues(service, method).Observe(time.Since(start).Seconds())
+}
+
+// splitMethod splits "/user.UserService/GetUser" into "user.UserService" and "GetUser"
+func splitMethod(fullMethod string) (string, string) {
+	service, method, ok := strings.Cut(strings.TrimPrefix(fullMethod, "/"), "/")
+	if !ok {
+		return "unknown", fullMethod
+	}
+	return service, method
+}
@@ -0,0 +1,46 @@
+package bootstrap
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func Test_metricsUnary(t *testing.T) {
+	info := &grpc.UnaryServerInfo{FullMethod: "/user.UserService/GetUser"}
+	notFound := func(ctx context.Context, req any) (any, error) {
+		return nil, status.Error(codes.NotFound, "user not found")
+	}
+	counter := grpcHandled.WithLabelValues("user.UserService", "GetUser", "NotFound")
+	before := testutil.ToFloat64(counter)
+
+	_, _ = metricsUnary(context.Background(), nil, info, notFound)
+
+	if got := testutil.ToFloat64(counter) - before; got != 1 {
+		t.Errorf("grpc_server_handled_total{grpc_code=NotFound} increased by %v, want 1", got)
+	}
+}
+
+func Test_splitMethod(t *testing.T) {
+	tests := []struct {
+		fullMethod  string
+		wantService string
+		wantMethod  string
+	}{
+		{fullMethod: "/user.UserService/GetUser", wantService: "user.UserService", wantMethod: "GetUser"},
+		{fullMethod: "malformed", wantService: "unknown", wantMethod: "malformed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fullMethod, func(t *testing.T) {
+			service, method := splitMethod(tt.fullMethod)
+			if service != tt.wantService || method != tt.wantMethod {
+				t.Errorf("splitMethod() = %q, %q, want %q, %q", service, method, tt.wantService, tt.wantMethod)
+			}
+		})
+	}
+}
@@ -0,0 +1,188 @@
+// Package fanout broadcasts short-lived messages to every replica of a service through a RabbitMQ
+// fanout exchange. Each replica binds a queue of its own, exclusive and deleted with its connection,
+// so it only receives what is published while it is connected. Nothing is spooled or retried: a
+// message that cannot be sent while RabbitMQ is down is dropped, which suits signals that are stale
+// within seconds anyway. A replica does not receive its own messages, the caller delivers those locally.
+package fanout
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+const (
+	minBackoff = time.Second
+	maxBackoff = 30 * time.Second
+)
+
+// ErrDisconnected is returned by Publish while the exchange is not connected to RabbitMQ.
+var ErrDisconnected = errors.New("not connected to RabbitMQ")
+
+type Exchange struct {
+	addr   string
+	name   string
+	origin string // the AppId of the messages of this replica
+	handle func(body []byte)
+
+	mu   sync.Mutex
+	conn *amqp.Connection
+	ch   *amqp.Channel // nil while disconnected
+
+	done    chan struct{}
+	stopped chan struct{}
+}
+
+// Open starts connecting to addr in the background and calls handle with the body of every message
+// the other replicas publish to the exchange name. handle runs on a single goroutine and must not block.
+func Open(addr, name string, handle func(body []byte)) *Exchange {
+	var id [8]byte
+	_, _ = rand.Read(id[:])
+	e := &Exchange{
+		addr:    addr,
+		name:    name,
+		origin:  hex.EncodeToString(id[:]),
+		handle:  handle,
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	go e.run()
+	return e
+}
+
+// Publish sends body to the other replicas. The broker drops it if a replica has not received it
+// within ttl, e.g. because it is behind.
+func (e *Exchange) Publish(ctx context.Context, body []byte, ttl time.Duration) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.ch == nil {
+		return ErrDisconnected
+	}
+	return e.ch.PublishWithContext(ctx, e.name, "", false, false, amqp.Publishing{
+		AppId:        e.origin,
+		ContentType:  "application/json",
+		DeliveryMode: amqp.Transient,
+		Expiration:   strconv.FormatInt(max(ttl.Milliseconds(), 1), 10),
+		Timestamp:    time.Now(),
+		Body:         body,
+	})
+}
+
+// Ping returns an error while the exchange is not connected to RabbitMQ.
+func (e *Exchange) Ping(ctx context.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.ch == nil {
+		return ErrDisconnected
+	}
+	return nil
+}
+
+// Close stops reconnecting and closes the connection.
+func (e *Exchange) Close() error {
+	close(e.done)
+	<-e.stopped
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.disconnect()
+	return nil
+}
+
+func (e *Exchange) run() {
+	defer close(e.stopped)
+
+	backoff := minBackoff
+	for {
+		deliveries, err := e.connect()
+		if err == nil {
+			backoff = minBackoff
+			log.Printf("fanout: connected to RabbitMQ, exchange %s", e.name)
+			err = e.consume(deliveries)
+			e.mu.Lock()
+			e.disconnect()
+			e.mu.Unlock()
+			if err == nil {
+				return
+			}
+		}
+
+		log.Printf("fanout: RabbitMQ unavailable (%v), messages to %s are dropped, retrying in %v...", err, e.name, backoff)
+		select {
+		case <-time.After(backoff):
+		case <-e.done:
+			return
+		}
+		backoff = min(2*backoff, maxBackoff)
+	}
+}
+
+// connect declares the exchange and binds a queue of this replica to it
+func (e *Exchange) connect() (<-chan amqp.Delivery, error) {
+	conn, err := amqp.Dial(e.addr)
+	if err != nil {
+		return nil, fmt.Errorf("dial: %w", err)
+	}
+	ch, err := conn.Channel()
+	if err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("open channel: %w", err)
+	}
+	if err := ch.ExchangeDeclare(e.name, amqp.ExchangeFanout, true, false, false, false, nil); err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("declare exchange: %w", err)
+	}
+	q, err := ch.QueueDeclare("", false, true, true, false, nil)
+	if err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("declare queue: %w", err)
+	}
+	if err := ch.QueueBind(q.Name, "", e.name, false, nil); err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("bind queue: %w", err)
+	}
+	deliveries, err := ch.Consume(q.Name, "", true, true, false, false, nil)
+	if err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("consume: %w", err)
+	}
+
+	e.mu.Lock()
+	e.conn, e.ch = conn, ch
+	e.mu.Unlock()
+	return deliveries, nil
+}
+
+// consume hands the messages of the other replicas to handle until the connection closes (an
+// error) or Close is called (nil)
+func (e *Exchange) consume(deliveries <-chan amqp.Delivery) error {
+	for {
+		select {
+		case d, ok := <-deliveries:
+			if !ok {
+				return errors.New("connection closed")
+			}
+			if d.AppId != e.origin {
+				e.handle(d.Body)
+			}
+		case <-e.done:
+			return nil
+		}
+	}
+}
+
+// must be called with mu held
+func (e *Exchange) disconnect() {
+	if e.conn != nil {
+		_ = e.conn.Close()
+	}
+	e.conn, e.ch = nil, nil
+}
@@ -0,0 +1,326 @@
+// Package migrate applies numbered up/down SQL migrations and records them in schema_migrations.
+// Every change runs in its own transaction while holding a Postgres advisory lock, so several
+// instances starting at the same time apply each migration exactly once.
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// lockKey identifies the advisory lock, any constant shared by all runners works
+const lockKey int64 = 0x6d696772617465 // "migrate"
+
+var ErrSchemaBehind = errors.New("database schema is behind")
+
+var fileRe = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+type Status struct {
+	Migration
+	AppliedAt *time.Time // nil when pending
+}
+
+// Load reads the migrations from fsys, ordered by version. Every version needs an up file;
+// the down file is optional, but without it the migration cannot be rolled back.
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("read migrations: %w", err)
+	}
+
+	byVersion := map[int64]*Migration{}
+	for _, e := range entries {
+		m := fileRe.FindStringSubmatch(e.Name())
+		if e.IsDir() || m == nil {
+			continue
+		}
+		version, err := strconv.ParseInt(m[1], 10, 64)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("migration %s: invalid version", e.Name())
+		}
+		body, err := fs.ReadFile(fsys, e.Name())
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w", e.Name(), err)
+		}
+
+		mig, ok := byVersion[version]
+		if !ok {
+			mig = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = mig
+		}
+		if mig.Name != m[2] {
+			return nil, fmt.Errorf("migration %d has two names: %s and %s", version, mig.Name, m[2])
+		}
+		if m[3] == "up" {
+			mig.Up = string(body)
+		} else {
+			mig.Down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, mig := range byVersion {
+		if mig.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up file", mig.Version, mig.Name)
+		}
+		migrations = append(migrations, *mig)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+}
+
+func New(db *sql.DB, fsys fs.FS) (*Migrator, error) {
+	migrations, err := Load(fsys)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+// Up applies up to n pending migrations in order, all of them if n <= 0.
+func (m *Migrator) Up(ctx context.Context, n int) ([]Migration, error) {
+	var done []Migration
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, mig := range pending(m.migrations, applied) {
+			if n > 0 && len(done) == n {
+				break
+			}
+			if err := apply(ctx, conn, mig.Up, `INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`, mig.Version, mig.Name); err != nil {
+				return fmt.Errorf("up %d_%s: %w", mig.Version, mig.Name, err)
+			}
+			done = append(done, mig)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Down rolls back the last n applied migrations, newest first, all of them if n <= 0.
+func (m *Migrator) Down(ctx context.Context, n int) ([]Migration, error) {
+	var done []Migration
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		known := map[int64]Migration{}
+		for _, mig := range m.migrations {
+			known[mig.Version] = mig
+		}
+
+		versions := make([]int64, 0, len(applied))
+		for v := range applied {
+			versions = append(versions, v)
+		}
+		sort.Slice(versions, func(i, j int) bool { return versions[i] > versions[j] })
+
+		for _, v := range versions {
+			if n > 0 && len(done) == n {
+				break
+			}
+			mig, ok := known[v]
+			if !ok {
+				return fmt.Errorf("down %d: migration is applied but unknown to this binary", v)
+			}
+			if mig.Down == "" {
+				return fmt.Errorf("down %d_%s: migration has no down file", mig.Version, mig.Name)
+			}
+			if err := apply(ctx, conn, mig.Down, `DELETE FROM schema_migrations WHERE version = $1`, mig.Version); err != nil {
+				return fmt.Errorf("down %d_%s: %w", mig.Version, mig.Name, err)
+			}
+			done = append(done, mig)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Redo rolls back the last applied migration and applies it again, handy while writing a migration.
+func (m *Migrator) Redo(ctx context.Context) (*Migration, error) {
+	var redone *Migration
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for i := len(m.migrations) - 1; i >= 0; i-- {
+			if _, ok := applied[m.migrations[i].Version]; ok {
+				redone = &m.migrations[i]
+				break
+			}
+		}
+		if redone == nil {
+			return errors.New("no migration to redo")
+		}
+		if redone.Down == "" {
+			return fmt.Errorf("redo %d_%s: migration has no down file", redone.Version, redone.Name)
+		}
+		if err := apply(ctx, conn, redone.Down, `DELETE FROM schema_migrations WHERE version = $1`, redone.Version); err != nil {
+			return fmt.Errorf("down %d_%s: %w", redone.Version, redone.Name, err)
+		}
+		if err := apply(ctx, conn, redone.Up, `INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`, redone.Version, redone.Name); err != nil {
+			return fmt.Errorf("up %d_%s: %w", redone.Version, redone.Name, err)
+		}
+		return nil
+	})
+	return redone, err
+}
+
+// Status lists every known migration with the time it was applied. It only reads,
+// a database that was never migrated reports everything as pending.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	var exists bool
+	if err := m.db.QueryRowContext(ctx, `SELECT to_regclass('schema_migrations') IS NOT NULL`).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("check schema_migrations: %w", err)
+	}
+	applied := map[int64]time.Time{}
+	if exists {
+		var err error
+		if applied, err = appliedVersions(ctx, m.db); err != nil {
+			return nil, err
+		}
+	}
+
+	statuses := make([]Status, 0, len(m.migrations))
+	for _, mig := range m.migrations {
+		st := Status{Migration: mig}
+		if at, ok := applied[mig.Version]; ok {
+			st.AppliedAt = &at
+		}
+		statuses = append(statuses, st)
+	}
+	return statuses, nil
+}
+
+// RequireLatest returns ErrSchemaBehind if any known migration is not applied yet.
+// Services call it at startup so they never run against an older schema.
+func (m *Migrator) RequireLatest(ctx context.Context) error {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return err
+	}
+	for _, st := range statuses {
+		if st.AppliedAt == nil {
+			return fmt.Errorf("%w: %d_%s is not applied, run cmd/migrate up", ErrSchemaBehind, st.Version, st.Name)
+		}
+	}
+	return nil
+}
+
+// RequireLatest loads the migrations from fsys and checks they are all applied to db.
+func RequireLatest(ctx context.Context, db *sql.DB, fsys fs.FS) error {
+	m, err := New(db, fsys)
+	if err != nil {
+		return err
+	}
+	return m.RequireLatest(ctx)
+}
+
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+func ensureTable(ctx context.Context, q querier) error {
+	_, err := q.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version BIGINT PRIMARY KEY,
+			name TEXT NOT NULL,
+			applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		);
+	`)
+	if err != nil {
+		return fmt.Errorf("create schema_migrations: %w", err)
+	}
+	return nil
+}
+
+func appliedVersions(ctx context.Context, q querier) (map[int64]time.Time, error) {
+	rows, err := q.QueryContext(ctx, `SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("query schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := map[int64]time.Time{}
+	for rows.Next() {
+		var (
+			v  int64
+			at time.Time
+		)
+		if err := rows.Scan(&v, &at); err != nil {
+			return nil, fmt.Errorf("scan schema_migrations: %w", err)
+		}
+		applied[v] = at
+	}
+	return applied, rows.Err()
+}
+
+func pending(migrations []Migration, applied map[int64]time.Time) []Migration {
+	var out []Migration
+	for _, mig := range migrations {
+		if _, ok := applied[mig.Version]; !ok {
+			out = append(out, mig)
+		}
+	}
+	return out
+}
+
+// apply runs the migration script and the schema_migrations bookkeeping in one transaction
+func apply(ctx context.Context, conn *sql.Conn, script, record string, args ...any) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, record, args...); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// withLock runs fn on a single connection holding the advisory lock; a concurrent runner waits for it
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, lockKey); err != nil {
+		return fmt.Errorf("acquire migration lock: %w", err)
+	}
+	defer conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, lockKey)
+
+	if err := ensureTable(ctx, conn); err != nil {
+		return err
+	}
+	return fn(conn)
+}
@@ -0,0 +1,103 @@
+package migrate
+
+import (
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/Costin2000/GoChat---Schwarz-Internship---2025/db/migrations"
+	"github.com/google/go-cmp/cmp"
+)
+
+func Test_Load(t *testing.T) {
+	tests := []struct {
+		name    string
+		fsys    fstest.MapFS
+		want    []Migration
+		wantErr bool
+	}{
+		{
+			name: "ordered by version, other files ignored",
+			fsys: fstest.MapFS{
+				"0010_add_index.up.sql":     {Data: []byte("CREATE INDEX i;")},
+				"0002_users.up.sql":         {Data: []byte("CREATE TABLE u;")},
+				"0002_users.down.sql":       {Data: []byte("DROP TABLE u;")},
+				"migrations.go":             {Data: []byte("package migrations")},
+				"0003_notes_for_later.txt":  {Data: []byte("not a migration")},
+				"0010_add_index.down.sql":   {Data: []byte("DROP INDEX i;")},
+				"0011_no_down_file.up.sql":  {Data: []byte("SELECT 1;")},
+				"README_add_index.down.sql": {Data: []byte("ignored, no version")},
+			},
+			want: []Migration{
+				{Version: 2, Name: "users", Up: "CREATE TABLE u;", Down: "DROP TABLE u;"},
+				{Version: 10, Name: "add_index", Up: "CREATE INDEX i;", Down: "DROP INDEX i;"},
+				{Version: 11, Name: "no_down_file", Up: "SELECT 1;"},
+			},
+		},
+		{
+			name: "down file without up file",
+			fsys: fstest.MapFS{
+				"0001_users.down.sql": {Data: []byte("DROP TABLE u;")},
+			},
+			wantErr: true,
+		},
+		{
+			name: "same version with two names",
+			fsys: fstest.MapFS{
+				"0001_users.up.sql":    {Data: []byte("CREATE TABLE u;")},
+				"0001_people.up.sql":   {Data: []byte("CREATE TABLE p;")},
+				"0001_people.down.sql": {Data: []byte("DROP TABLE p;")},
+			},
+			wantErr: true,
+		},
+		{
+			name: "version zero",
+			fsys: fstest.MapFS{
+				"0000_users.up.sql": {Data: []byte("CREATE TABLE u;")},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Load(tt.fsys)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if diff := cmp.Diff(tt.want, got); !tt.wantErr && diff != "" {
+				t.Errorf("Load() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func Test_pending(t *testing.T) {
+	migrations := []Migration{{Version: 1}, {Version: 2}, {Version: 3}}
+	applied := map[int64]time.Time{1: time.Now(), 3: time.Now()}
+
+	got := pending(migrations, applied)
+
+	if diff := cmp.Diff([]Migration{{Version: 2}}, got); diff != "" {
+		t.Errorf("pending() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+// every migration shipped with the repo must load and be reversible
+func Test_RepoMigrations(t *testing.T) {
+	got, err := Load(migrations.FS)
+	if err != nil {
+		t.Fatalf("Load(db/migrations) error = %v", err)
+	}
+	if len(got) == 0 {
+		t.Fatal("no migrations found in db/migrations")
+	}
+	for i, mig := range got {
+		if mig.Version != int64(i+1) {
+			t.Errorf("migration %d_%s: versions must be consecutive, want %d", mig.Version, mig.Name, i+1)
+		}
+		if mig.Down == "" {
+			t.Errorf("migration %d_%s has no down file", mig.Version, mig.Name)
+		}
+	}
+}
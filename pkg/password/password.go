@@ -0,0 +1,110 @@
+// Package password hashes passwords and checks them against the password policy. New hashes use
+// argon2id in the PHC string format; Verify also accepts the bcrypt hashes of older accounts and
+// tells when a hash should be replaced by one with the current algorithm and parameters.
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	saltLen = 16
+	keyLen  = 32
+)
+
+// Params are the argon2id parameters of new hashes. Raising them upgrades every user at their next
+// login.
+type Params struct {
+	MemoryKiB   int `env:"PASSWORD_ARGON2_MEMORY_KIB" default:"65536"`
+	Iterations  int `env:"PASSWORD_ARGON2_ITERATIONS" default:"3"`
+	Parallelism int `env:"PASSWORD_ARGON2_PARALLELISM" default:"2"`
+}
+
+func (p *Params) Validate() error {
+	if p.MemoryKiB < 8*p.Parallelism || p.Iterations < 1 || p.Parallelism < 1 || p.Parallelism > 255 {
+		return fmt.Errorf("invalid argon2id parameters m=%d t=%d p=%d", p.MemoryKiB, p.Iterations, p.Parallelism)
+	}
+	return nil
+}
+
+// Hash returns the argon2id hash of plain, e.g. "$argon2id$v=19$m=65536,t=3,p=2$<salt>$<key>"
+func Hash(plain string, p Params) (string, error) {
+	if err := p.Validate(); err != nil {
+		return "", err
+	}
+	salt := make([]byte, saltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(plain), salt, uint32(p.Iterations), uint32(p.MemoryKiB), uint8(p.Parallelism), keyLen)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, p.MemoryKiB, p.Iterations, p.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// Verify reports whether plain matches hash, and if so whether hash is outdated and should be
+// replaced by Hash(plain, current). Unknown formats, like the marker of accounts without a
+// password, never match.
+func Verify(hash, plain string, current Params) (ok, rehash bool) {
+	switch {
+	case strings.HasPrefix(hash, "$argon2id$"):
+		h, err := parseArgon2id(hash)
+		if err != nil {
+			return false, false
+		}
+		key := argon2.IDKey([]byte(plain), h.salt, uint32(h.params.Iterations), uint32(h.params.MemoryKiB), uint8(h.params.Parallelism), uint32(len(h.key)))
+		if subtle.ConstantTimeCompare(key, h.key) != 1 {
+			return false, false
+		}
+		return true, h.params != current || len(h.key) != keyLen
+	case strings.HasPrefix(hash, "$2a$"), strings.HasPrefix(hash, "$2b$"), strings.HasPrefix(hash, "$2y$"):
+		if bcrypt.CompareHashAndPassword([]byte(hash), []byte(plain)) != nil {
+			return false, false
+		}
+		return true, true
+	default:
+		return false, false
+	}
+}
+
+type argon2idHash struct {
+	params Params
+	salt   []byte
+	key    []byte
+}
+
+var errMalformed = errors.New("malformed argon2id hash")
+
+func parseArgon2id(hash string) (*argon2idHash, error) {
+	// "", "argon2id", "v=19", "m=..,t=..,p=..", salt, key
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 {
+		return nil, errMalformed
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return nil, errMalformed
+	}
+	var h argon2idHash
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &h.params.MemoryKiB, &h.params.Iterations, &h.params.Parallelism); err != nil {
+		return nil, errMalformed
+	}
+	if h.params.Validate() != nil {
+		return nil, errMalformed
+	}
+	var err error
+	if h.salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return nil, errMalformed
+	}
+	if h.key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil || len(h.key) == 0 {
+		return nil, errMalformed
+	}
+	return &h, nil
+}
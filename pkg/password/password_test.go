@@ -0,0 +1,61 @@
+package password
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// cheap parameters, the tests do not need the memory of production hashes
+var testParams = Params{MemoryKiB: 64, Iterations: 1, Parallelism: 1}
+
+func TestHashVerify(t *testing.T) {
+	hash, err := Hash("s3cret-passphrase", testParams)
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+	if !strings.HasPrefix(hash, "$argon2id$v=19$m=64,t=1,p=1$") {
+		t.Errorf("unexpected hash format %q", hash)
+	}
+	if other, _ := Hash("s3cret-passphrase", testParams); other == hash {
+		t.Error("two hashes of the same password are equal, salt missing")
+	}
+
+	bcryptHash, err := bcrypt.GenerateFromPassword([]byte("s3cret-passphrase"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("bcrypt: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		hash       string
+		plain      string
+		current    Params
+		wantOK     bool
+		wantRehash bool
+	}{
+		{name: "current argon2id", hash: hash, plain: "s3cret-passphrase", current: testParams, wantOK: true},
+		{name: "wrong password", hash: hash, plain: "s3cret-passphrasE", current: testParams},
+		{name: "parameters raised", hash: hash, plain: "s3cret-passphrase", current: Params{MemoryKiB: 128, Iterations: 1, Parallelism: 1}, wantOK: true, wantRehash: true},
+		{name: "bcrypt is upgraded", hash: string(bcryptHash), plain: "s3cret-passphrase", current: testParams, wantOK: true, wantRehash: true},
+		{name: "wrong bcrypt password", hash: string(bcryptHash), plain: "nope", current: testParams},
+		{name: "account without a password", hash: "!", plain: "!", current: testParams},
+		{name: "empty hash", hash: "", plain: "", current: testParams},
+		{name: "malformed argon2id", hash: "$argon2id$v=19$m=64,t=1$AAAA$AAAA", plain: "x", current: testParams},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, rehash := Verify(tt.hash, tt.plain, tt.current)
+			if ok != tt.wantOK || rehash != tt.wantRehash {
+				t.Errorf("Verify = %v, %v; want %v, %v", ok, rehash, tt.wantOK, tt.wantRehash)
+			}
+		})
+	}
+}
+
+func TestHash_InvalidParams(t *testing.T) {
+	if _, err := Hash("x", Params{}); err == nil {
+		t.Error("want an error for zero parameters")
+	}
+}
@@ -0,0 +1,63 @@
+package password
+
+import (
+	"bufio"
+	_ "embed"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// breachedList are common passwords seen in public breaches, lower case, one per line
+//
+//go:embed breached.txt
+var breachedList string
+
+var breached = sync.OnceValue(func() map[string]struct{} {
+	set := map[string]struct{}{}
+	sc := bufio.NewScanner(strings.NewReader(breachedList))
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			set[line] = struct{}{}
+		}
+	}
+	return set
+})
+
+var ErrBreached = errors.New("password is too common, it appears in lists of breached passwords")
+
+// Policy is what new passwords must satisfy, at signup and password change. Existing passwords are
+// not checked at login.
+type Policy struct {
+	MinLength      int  `env:"PASSWORD_MIN_LENGTH" default:"10"`
+	MaxLength      int  `env:"PASSWORD_MAX_LENGTH" default:"128"`
+	RejectBreached bool `env:"PASSWORD_REJECT_BREACHED" default:"true"`
+}
+
+func (p *Policy) Validate() error {
+	if p.MinLength < 0 || (p.MaxLength > 0 && p.MaxLength < p.MinLength) {
+		return fmt.Errorf("invalid password length limits %d-%d", p.MinLength, p.MaxLength)
+	}
+	return nil
+}
+
+// Check returns why plain is not acceptable as a new password, nil if it is. The message can be shown
+// to the user.
+func (p Policy) Check(plain string) error {
+	n := utf8.RuneCountInString(plain)
+	if n < p.MinLength {
+		return fmt.Errorf("password must be at least %d characters long", p.MinLength)
+	}
+	if p.MaxLength > 0 && n > p.MaxLength {
+		return fmt.Errorf("password must be at most %d characters long", p.MaxLength)
+	}
+	if p.RejectBreached {
+		if _, ok := breached()[strings.ToLower(plain)]; ok {
+			return ErrBreached
+		}
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package password
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestPolicyCheck(t *testing.T) {
+	p := Policy{MinLength: 10, MaxLength: 64, RejectBreached: true}
+	tests := []struct {
+		plain   string
+		wantErr bool
+	}{
+		{plain: "a long enough passphrase", wantErr: false},
+		{plain: "short", wantErr: true},
+		{plain: strings.Repeat("x", 65), wantErr: true},
+		{plain: "Password123", wantErr: true},
+		{plain: "QWERTYUIOP!", wantErr: true},
+		// runes, not bytes
+		{plain: "ăîșțâăîșțâ", wantErr: false},
+	}
+	for _, tt := range tests {
+		if err := p.Check(tt.plain); (err != nil) != tt.wantErr {
+			t.Errorf("Check(%q) = %v, want error %v", tt.plain, err, tt.wantErr)
+		}
+	}
+
+	if err := p.Check("iloveyou123"); !errors.Is(err, ErrBreached) {
+		t.Errorf("got %v, want ErrBreached", err)
+	}
+	p.RejectBreached = false
+	if err := p.Check("iloveyou123"); err != nil {
+		t.Errorf("breached check off, got %v", err)
+	}
+}
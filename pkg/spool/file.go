@@ -0,0 +1,128 @@
+package spool
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// file is an append-only log of length prefixed records. The offset of the first record not yet
+// replayed is kept next to it in "<path>.offset", so a restart does not publish the replayed records again.
+type file struct {
+	f      *os.File
+	path   string
+	size   int64 // end of the last complete record
+	offset int64 // start of the next record to replay
+}
+
+const (
+	headerLen = 4
+	// maxRecordLen bounds a record, so a corrupt length cannot make readAt allocate gigabytes
+	maxRecordLen = 16 << 20
+)
+
+// errCorrupt is returned for a record whose length cannot be right, it is treated like a partial record
+var errCorrupt = errors.New("corrupt spool record")
+
+func openFile(path string) (*file, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("create spool dir: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("open spool: %w", err)
+	}
+	s := &file{f: f, path: path}
+
+	// a crash in the middle of an append leaves a partial record at the end, cut it off
+	for {
+		body, err := s.readAt(s.size)
+		if err != nil {
+			break
+		}
+		s.size += headerLen + int64(len(body))
+	}
+	if err := f.Truncate(s.size); err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("truncate spool: %w", err)
+	}
+
+	if raw, err := os.ReadFile(s.offsetPath()); err == nil {
+		if v, err := strconv.ParseInt(strings.TrimSpace(string(raw)), 10, 64); err == nil && v >= 0 && v <= s.size {
+			s.offset = v
+		}
+	}
+	return s, nil
+}
+
+func (s *file) empty() bool { return s.offset >= s.size }
+
+// pending is the size of the records not replayed yet
+func (s *file) pending() int64 { return s.size - s.offset }
+
+func (s *file) append(body []byte) error {
+	if len(body) > maxRecordLen {
+		return fmt.Errorf("message of %d bytes exceeds the spool limit of %d", len(body), maxRecordLen)
+	}
+	rec := make([]byte, headerLen+len(body))
+	binary.BigEndian.PutUint32(rec, uint32(len(body)))
+	copy(rec[headerLen:], body)
+
+	if _, err := s.f.WriteAt(rec, s.size); err != nil {
+		return fmt.Errorf("append to spool: %w", err)
+	}
+	if err := s.f.Sync(); err != nil {
+		return fmt.Errorf("sync spool: %w", err)
+	}
+	s.size += int64(len(rec))
+	return nil
+}
+
+// next returns the oldest record not replayed yet
+func (s *file) next() ([]byte, error) {
+	return s.readAt(s.offset)
+}
+
+// done marks the record returned by next as replayed; once everything is replayed the file is emptied
+func (s *file) done(body []byte) error {
+	s.offset += headerLen + int64(len(body))
+	if s.empty() {
+		if err := s.f.Truncate(0); err != nil {
+			return fmt.Errorf("truncate spool: %w", err)
+		}
+		s.size, s.offset = 0, 0
+	}
+	return os.WriteFile(s.offsetPath(), []byte(strconv.FormatInt(s.offset, 10)), 0o600)
+}
+
+func (s *file) readAt(off int64) ([]byte, error) {
+	var header [headerLen]byte
+	if _, err := s.f.ReadAt(header[:], off); err != nil {
+		return nil, err
+	}
+	fi, err := s.f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	n := int64(binary.BigEndian.Uint32(header[:]))
+	if n > maxRecordLen || off+headerLen+n > fi.Size() {
+		return nil, errCorrupt
+	}
+	body := make([]byte, n)
+	if _, err := s.f.ReadAt(body, off+headerLen); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return body, nil
+}
+
+func (s *file) offsetPath() string { return s.path + ".offset" }
+
+func (s *file) close() error { return s.f.Close() }
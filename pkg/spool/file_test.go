@@ -0,0 +1,155 @@
+package spool
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func Test_file_ReplaysInOrderAndEmpties(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "emails.spool")
+	s, err := openFile(path)
+	if err != nil {
+		t.Fatalf("openFile() error = %v", err)
+	}
+	defer s.close()
+
+	for _, body := range []string{"first", "second", ""} {
+		if err := s.append([]byte(body)); err != nil {
+			t.Fatalf("append(%q) error = %v", body, err)
+		}
+	}
+
+	var got []string
+	for !s.empty() {
+		body, err := s.next()
+		if err != nil {
+			t.Fatalf("next() error = %v", err)
+		}
+		got = append(got, string(body))
+		if err := s.done(body); err != nil {
+			t.Fatalf("done() error = %v", err)
+		}
+	}
+
+	if diff := cmp.Diff([]string{"first", "second", ""}, got); diff != "" {
+		t.Errorf("replayed records mismatch (-want +got):\n%s", diff)
+	}
+	if fi, err := os.Stat(path); err != nil || fi.Size() != 0 {
+		t.Errorf("spool not emptied after replay: size=%v err=%v", fi.Size(), err)
+	}
+}
+
+func Test_file_ReopenResumesAfterReplayedRecords(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "emails.spool")
+	s, err := openFile(path)
+	if err != nil {
+		t.Fatalf("openFile() error = %v", err)
+	}
+	for _, body := range []string{"first", "second"} {
+		if err := s.append([]byte(body)); err != nil {
+			t.Fatalf("append(%q) error = %v", body, err)
+		}
+	}
+	body, _ := s.next()
+	if err := s.done(body); err != nil {
+		t.Fatalf("done() error = %v", err)
+	}
+	s.close()
+
+	s, err = openFile(path)
+	if err != nil {
+		t.Fatalf("reopen error = %v", err)
+	}
+	defer s.close()
+
+	body, err = s.next()
+	if err != nil {
+		t.Fatalf("next() error = %v", err)
+	}
+	if string(body) != "second" {
+		t.Errorf("next() after reopen = %q, want %q", body, "second")
+	}
+}
+
+func Test_file_DropsPartialRecord(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "emails.spool")
+	s, err := openFile(path)
+	if err != nil {
+		t.Fatalf("openFile() error = %v", err)
+	}
+	if err := s.append([]byte("complete")); err != nil {
+		t.Fatalf("append() error = %v", err)
+	}
+	s.close()
+
+	// a record header promising 100 bytes, followed by 3
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Write([]byte{0, 0, 0, 100, 'a', 'b', 'c'}); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	s, err = openFile(path)
+	if err != nil {
+		t.Fatalf("reopen error = %v", err)
+	}
+	defer s.close()
+
+	body, err := s.next()
+	if err != nil || string(body) != "complete" {
+		t.Fatalf("next() = %q, %v; want %q", body, err, "complete")
+	}
+	if err := s.done(body); err != nil {
+		t.Fatalf("done() error = %v", err)
+	}
+	if !s.empty() {
+		t.Errorf("partial record was not dropped")
+	}
+	if err := s.append([]byte("after")); err != nil {
+		t.Fatalf("append() error = %v", err)
+	}
+	if body, err := s.next(); err != nil || string(body) != "after" {
+		t.Errorf("next() = %q, %v; want %q", body, err, "after")
+	}
+}
+
+func Test_file_DropsRecordWithCorruptLength(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "emails.spool")
+	s, err := openFile(path)
+	if err != nil {
+		t.Fatalf("openFile() error = %v", err)
+	}
+	if err := s.append([]byte("complete")); err != nil {
+		t.Fatalf("append() error = %v", err)
+	}
+	s.close()
+
+	// a torn header claiming almost 4 GiB must not be allocated
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Write([]byte{0xff, 0xff, 0xff, 0xf0, 'a', 'b', 'c'}); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	s, err = openFile(path)
+	if err != nil {
+		t.Fatalf("reopen error = %v", err)
+	}
+	defer s.close()
+
+	if want := int64(headerLen + len("complete")); s.pending() != want {
+		t.Errorf("pending() = %d, want %d", s.pending(), want)
+	}
+	if body, err := s.next(); err != nil || string(body) != "complete" {
+		t.Errorf("next() = %q, %v; want %q", body, err, "complete")
+	}
+}
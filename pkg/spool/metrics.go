@@ -0,0 +1,28 @@
+package spool
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	published = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "rabbitmq_published_total",
+		Help: "Messages handed to the publisher, by queue and outcome (sent, spooled or failed).",
+	}, []string{"queue", "outcome"})
+
+	replayed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "rabbitmq_spool_replayed_total",
+		Help: "Spooled messages published after RabbitMQ came back.",
+	}, []string{"queue"})
+
+	pendingBytes = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "rabbitmq_spool_pending_bytes",
+		Help: "Size of the spooled messages not yet replayed.",
+	}, []string{"queue"})
+
+	connected = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "rabbitmq_publisher_connected",
+		Help: "1 while the publisher is connected to RabbitMQ.",
+	}, []string{"queue"})
+)
@@ -0,0 +1,284 @@
+// Package spool publishes messages to a RabbitMQ queue without depending on the broker being up.
+// The Publisher reconnects in the background; while it is disconnected messages are appended to a
+// local spool file, and once the connection is back they are replayed in order before anything new
+// is sent. Delivery is at least once: a crash during replay may publish the last record twice.
+package spool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Costin2000/GoChat---Schwarz-Internship---2025/pkg/tracing"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+const (
+	minBackoff     = time.Second
+	maxBackoff     = 30 * time.Second
+	confirmTimeout = 10 * time.Second
+)
+
+type Publisher struct {
+	addr  string
+	queue string
+
+	mu    sync.Mutex
+	conn  *amqp.Connection
+	ch    *amqp.Channel // nil while disconnected
+	spool *file
+
+	connected atomic.Bool // readable without mu, replay holds it while waiting for a confirm
+
+	done    chan struct{}
+	stopped chan struct{}
+}
+
+// NewPublisher opens (or creates) the spool at path and starts connecting to addr in the background.
+// It only fails if the spool file cannot be used.
+func NewPublisher(addr, queue, path string) (*Publisher, error) {
+	s, err := openFile(path)
+	if err != nil {
+		return nil, err
+	}
+	p := &Publisher{
+		addr:    addr,
+		queue:   queue,
+		spool:   s,
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	pendingBytes.WithLabelValues(queue).Set(float64(s.pending()))
+	connected.WithLabelValues(queue).Set(0)
+	go p.run()
+	return p, nil
+}
+
+// Publish sends body to the queue, or spools it if the broker is unreachable or older messages
+// are still waiting to be replayed. A nil error means the message is either confirmed by the broker
+// or safely on disk. Only messages published directly carry the trace context of ctx, the spool
+// keeps the body alone.
+//
+// The broker confirm is awaited without holding the lock and regardless of ctx: a caller giving up
+// must neither hold up the other publishers nor spool a message the broker may already have.
+func (p *Publisher) Publish(ctx context.Context, body []byte) error {
+	_, span, headers := tracing.StartPublish(ctx, p.queue)
+	defer span.End()
+
+	p.mu.Lock()
+	ch := p.ch
+	direct := ch != nil && p.spool.empty()
+	var (
+		dc  *amqp.DeferredConfirmation
+		err error
+	)
+	if direct {
+		dc, err = send(ch, p.queue, body, headers)
+	}
+	p.mu.Unlock()
+
+	if direct {
+		if err == nil {
+			err = waitConfirm(dc)
+		}
+		if err == nil {
+			published.WithLabelValues(p.queue, "sent").Inc()
+			return nil
+		}
+		log.Printf("spool: publish to %s failed, spooling: %v", p.queue, err)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if direct && p.ch == ch {
+		// another Publish may already have dropped this connection, or run reconnected meanwhile
+		p.disconnect()
+	}
+	span.SetAttributes(attribute.Bool("messaging.spooled", true))
+	if err := p.spool.append(body); err != nil {
+		published.WithLabelValues(p.queue, "failed").Inc()
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+	published.WithLabelValues(p.queue, "spooled").Inc()
+	pendingBytes.WithLabelValues(p.queue).Set(float64(p.spool.pending()))
+	return nil
+}
+
+// Ping returns an error while the publisher is not connected to RabbitMQ.
+func (p *Publisher) Ping(ctx context.Context) error {
+	if !p.connected.Load() {
+		return fmt.Errorf("not connected to RabbitMQ, spooling to %s", p.spool.path)
+	}
+	return nil
+}
+
+// Close stops reconnecting and closes the connection; spooled messages stay on disk for the next start.
+func (p *Publisher) Close() error {
+	close(p.done)
+	<-p.stopped
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.disconnect()
+	return p.spool.close()
+}
+
+func (p *Publisher) run() {
+	defer close(p.stopped)
+
+	backoff := minBackoff
+	for {
+		closed, err := p.connect()
+		if err == nil {
+			backoff = minBackoff
+			log.Printf("spool: connected to RabbitMQ")
+			if err = p.replay(); err == nil {
+				select {
+				case <-closed:
+					err = errors.New("connection closed")
+				case <-p.done:
+					return
+				}
+			}
+			p.mu.Lock()
+			p.disconnect()
+			p.mu.Unlock()
+		}
+
+		log.Printf("spool: RabbitMQ unavailable (%v), spooling to %s, retrying in %v...", err, p.spool.path, backoff)
+		select {
+		case <-time.After(backoff):
+		case <-p.done:
+			return
+		}
+		backoff = min(2*backoff, maxBackoff)
+	}
+}
+
+// connect opens a channel in confirm mode; the returned channel fires when the connection or the channel closes
+func (p *Publisher) connect() (<-chan struct{}, error) {
+	conn, err := amqp.Dial(p.addr)
+	if err != nil {
+		return nil, fmt.Errorf("dial: %w", err)
+	}
+	ch, err := conn.Channel()
+	if err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("open channel: %w", err)
+	}
+	if _, err := ch.QueueDeclare(p.queue, true, false, false, false, nil); err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("declare queue: %w", err)
+	}
+	if err := ch.Confirm(false); err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("enable confirms: %w", err)
+	}
+
+	// the library closes every notify channel it was given, so the connection and the channel need one each
+	connClosed := conn.NotifyClose(make(chan *amqp.Error, 1))
+	chClosed := ch.NotifyClose(make(chan *amqp.Error, 1))
+	closed := make(chan struct{})
+	go func() {
+		select {
+		case <-connClosed:
+		case <-chClosed:
+		}
+		close(closed)
+	}()
+
+	p.mu.Lock()
+	p.conn, p.ch = conn, ch
+	p.connected.Store(true)
+	connected.WithLabelValues(p.queue).Set(1)
+	p.mu.Unlock()
+	return closed, nil
+}
+
+// replay publishes the spooled messages oldest first; Publish spools meanwhile, so the order is kept
+func (p *Publisher) replay() error {
+	replayed := 0
+	for {
+		p.mu.Lock()
+		if p.spool.empty() {
+			p.mu.Unlock()
+			if replayed > 0 {
+				log.Printf("spool: replayed %d messages to %s", replayed, p.queue)
+			}
+			return nil
+		}
+		err := p.replayNext()
+		p.mu.Unlock()
+		if err != nil {
+			return err
+		}
+		replayed++
+	}
+}
+
+func (p *Publisher) replayNext() error {
+	if p.ch == nil {
+		// a failed Publish dropped the connection in the meantime
+		return errors.New("disconnected")
+	}
+	body, err := p.spool.next()
+	if err != nil {
+		return fmt.Errorf("read spool: %w", err)
+	}
+	dc, err := send(p.ch, p.queue, body, nil)
+	if err != nil {
+		return err
+	}
+	if err := waitConfirm(dc); err != nil {
+		return err
+	}
+	if err := p.spool.done(body); err != nil {
+		return err
+	}
+	replayed.WithLabelValues(p.queue).Inc()
+	pendingBytes.WithLabelValues(p.queue).Set(float64(p.spool.pending()))
+	return nil
+}
+
+// send publishes body without waiting, the confirm tells whether RabbitMQ has it
+func send(ch *amqp.Channel, queue string, body []byte, headers amqp.Table) (*amqp.DeferredConfirmation, error) {
+	return ch.PublishWithDeferredConfirmWithContext(context.Background(), "", queue, false, false, amqp.Publishing{
+		Headers:      headers,
+		ContentType:  "application/json",
+		DeliveryMode: amqp.Persistent,
+		Timestamp:    time.Now(),
+		Body:         body,
+	})
+}
+
+// waitConfirm waits up to confirmTimeout, a message is only dropped from the caller once RabbitMQ has it
+func waitConfirm(dc *amqp.DeferredConfirmation) error {
+	ctx, cancel := context.WithTimeout(context.Background(), confirmTimeout)
+	defer cancel()
+	ok, err := dc.WaitContext(ctx)
+	if err != nil {
+		return fmt.Errorf("wait for confirm: %w", err)
+	}
+	if !ok {
+		return errors.New("message nacked by broker")
+	}
+	return nil
+}
+
+// must be called with mu held
+func (p *Publisher) disconnect() {
+	if p.conn != nil {
+		_ = p.conn.Close()
+	}
+	p.conn, p.ch = nil, nil
+	p.connected.Store(false)
+	connected.WithLabelValues(p.queue).Set(0)
+}
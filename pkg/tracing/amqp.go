@@ -0,0 +1,63 @@
+// Package tracing carries the OpenTelemetry trace context through RabbitMQ, so a message consumed
+// by the email service belongs to the trace of the request that published it.
+package tracing
+
+import (
+	"context"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/Costin2000/GoChat---Schwarz-Internship---2025/pkg/tracing"
+
+// headerCarrier adapts AMQP headers to the propagators
+type headerCarrier amqp.Table
+
+func (c headerCarrier) Get(key string) string {
+	v, _ := c[key].(string)
+	return v
+}
+
+func (c headerCarrier) Set(key, value string) {
+	c[key] = value
+}
+
+func (c headerCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// StartPublish starts the producer span of a message sent to queue and returns the headers that
+// carry its context (W3C traceparent) to the consumer.
+func StartPublish(ctx context.Context, queue string) (context.Context, trace.Span, amqp.Table) {
+	ctx, span := otel.Tracer(tracerName).Start(ctx, queue+" publish",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(messagingAttrs(queue)...))
+
+	headers := amqp.Table{}
+	otel.GetTextMapPropagator().Inject(ctx, headerCarrier(headers))
+	return ctx, span, headers
+}
+
+// StartConsume starts the consumer span of a delivery, as a child of the span that published it.
+func StartConsume(ctx context.Context, queue string, headers amqp.Table) (context.Context, trace.Span) {
+	if headers != nil {
+		ctx = otel.GetTextMapPropagator().Extract(ctx, headerCarrier(headers))
+	}
+	return otel.Tracer(tracerName).Start(ctx, queue+" process",
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(messagingAttrs(queue)...))
+}
+
+func messagingAttrs(queue string) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String("messaging.system", "rabbitmq"),
+		attribute.String("messaging.destination.name", queue),
+	}
+}
@@ -0,0 +1,39 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func Test_StartConsume_ContinuesPublishTrace(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	_, pub, headers := StartPublish(context.Background(), "email")
+	pub.End()
+	if _, ok := headers["traceparent"]; !ok {
+		t.Fatalf("StartPublish() headers = %v, want a traceparent", headers)
+	}
+
+	_, consume := StartConsume(context.Background(), "email", headers)
+	consume.End()
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("recorded %d spans, want 2", len(spans))
+	}
+	producer, consumer := spans[0], spans[1]
+	if consumer.Parent().SpanID() != producer.SpanContext().SpanID() {
+		t.Errorf("consumer parent = %s, want the producer span %s", consumer.Parent().SpanID(), producer.SpanContext().SpanID())
+	}
+	if consumer.SpanKind() != trace.SpanKindConsumer {
+		t.Errorf("consumer kind = %s, want %s", consumer.SpanKind(), trace.SpanKindConsumer)
+	}
+}
@@ -0,0 +1,57 @@
+// Package unsubscribe signs and verifies the tokens used by the one-click unsubscribe links.
+// The email service signs a token for the recipient and event of every opt-out-able email,
+// the gateway verifies it before turning the notification off.
+package unsubscribe
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidToken = errors.New("invalid unsubscribe token")
+
+// Sign returns a url safe token binding the user to the event, e.g. "friend_request".
+func Sign(secret []byte, userID int64, event string) string {
+	payload := fmt.Sprintf("%d:%s", userID, event)
+	return base64.RawURLEncoding.EncodeToString([]byte(payload)) + "." + base64.RawURLEncoding.EncodeToString(mac(secret, payload))
+}
+
+// Verify checks the signature of the token and returns the user id and event it was signed for.
+func Verify(secret []byte, token string) (int64, string, error) {
+	encPayload, encSig, ok := strings.Cut(token, ".")
+	if !ok {
+		return 0, "", ErrInvalidToken
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encPayload)
+	if err != nil {
+		return 0, "", ErrInvalidToken
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(encSig)
+	if err != nil {
+		return 0, "", ErrInvalidToken
+	}
+	if !hmac.Equal(sig, mac(secret, string(payload))) {
+		return 0, "", ErrInvalidToken
+	}
+
+	idStr, event, ok := strings.Cut(string(payload), ":")
+	if !ok || event == "" {
+		return 0, "", ErrInvalidToken
+	}
+	userID, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil || userID <= 0 {
+		return 0, "", ErrInvalidToken
+	}
+	return userID, event, nil
+}
+
+func mac(secret []byte, payload string) []byte {
+	h := hmac.New(sha256.New, secret)
+	h.Write([]byte(payload))
+	return h.Sum(nil)
+}
@@ -0,0 +1,53 @@
+package unsubscribe
+
+import (
+	"testing"
+
+	errchecks "github.com/Costin2000/GoChat---Schwarz-Internship---2025/pkg"
+)
+
+func Test_SignVerify(t *testing.T) {
+	secret := []byte("test-secret")
+	valid := Sign(secret, 42, "friend_request")
+
+	tests := []struct {
+		name        string
+		token       string
+		wantUserID  int64
+		wantEvent   string
+		expectedErr errchecks.Check
+	}{
+		{
+			name:       "valid token",
+			token:      valid,
+			wantUserID: 42,
+			wantEvent:  "friend_request",
+		},
+		{
+			name:        "signed with another secret",
+			token:       Sign([]byte("other-secret"), 42, "friend_request"),
+			expectedErr: errchecks.Is(ErrInvalidToken),
+		},
+		{
+			name:        "tampered payload",
+			token:       Sign(secret, 43, "friend_request")[:10] + valid[10:],
+			expectedErr: errchecks.Is(ErrInvalidToken),
+		},
+		{
+			name:        "missing signature",
+			token:       "bm90LWEtdG9rZW4",
+			expectedErr: errchecks.Is(ErrInvalidToken),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userID, event, err := Verify(secret, tt.token)
+
+			errchecks.Assert(t, err, tt.expectedErr)
+			if userID != tt.wantUserID || event != tt.wantEvent {
+				t.Errorf("want (%d, %s), got (%d, %s)", tt.wantUserID, tt.wantEvent, userID, event)
+			}
+		})
+	}
+}
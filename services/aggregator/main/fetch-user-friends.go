@@ -12,11 +12,15 @@ import (
 
 	aggrpb "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/aggregator/proto"
 	frpb "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/friend-request-base/proto"
+	presencepb "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/presence/proto"
 	userpb "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/user-base/proto"
 )
 
 const accStatus = "accepted"
 
+// presenceBatchSize is the most user ids presence takes in one GetPresence
+const presenceBatchSize = 1000
+
 func (svc *AggregatorService) FetchUserFriends(ctx context.Context, req *aggrpb.FetchUserFriendsRequest) (*aggrpb.FetchUserFriendsResponse, error) {
 
 	if req.UserId == "" {
@@ -156,6 +160,8 @@ func (svc *AggregatorService) FetchUserFriends(ctx context.Context, req *aggrpb.
 				PageSize:      1000,
 				NextPageToken: userNextPageToken,
 				Filters:       userFilters,
+				// the user sees the emails of the friends who share them
+				ViewerId: reqIdInt,
 			}
 
 			userRsp, err := svc.userBaseClient.ListUsers(ctx, listUsersReq)
@@ -172,11 +178,23 @@ func (svc *AggregatorService) FetchUserFriends(ctx context.Context, req *aggrpb.
 			}
 		}
 
+		if req.IncludePresence {
+			return &aggrpb.FetchUserFriendsResponse{Users: Users, Presences: svc.friendsPresence(ctx, reqIdInt, uniqueIds)}, nil
+		}
+
 	} else { // Fetch all users and filter out those who are already involved.
 		allUsers := []*userpb.User{}
 		nextPageToken := ""
 		for {
-			listUsersReq := &userpb.ListUsersRequest{PageSize: 1000, NextPageToken: nextPageToken}
+			listUsersReq := &userpb.ListUsersRequest{
+				PageSize:      1000,
+				NextPageToken: nextPageToken,
+				// users who chose not to be discoverable are never suggested
+				Filters: []*userpb.ListUsersFiltersOneOf{
+					{Filter: &userpb.ListUsersFiltersOneOf_Discoverable{Discoverable: &userpb.FilterByDiscoverable{Equals: true}}},
+				},
+				ViewerId: reqIdInt,
+			}
 			userRsp, err := svc.userBaseClient.ListUsers(ctx, listUsersReq)
 			if err != nil {
 				log.Printf("Error fetching all users: %v", err)
@@ -199,3 +217,17 @@ func (svc *AggregatorService) FetchUserFriends(ctx context.Context, req *aggrpb.
 
 	return &aggrpb.FetchUserFriendsResponse{Users: Users}, nil
 }
+
+// friendsPresence is best effort, the friends are still listed when presence is down
+func (svc *AggregatorService) friendsPresence(ctx context.Context, viewerID int64, friendIDs []int64) []*presencepb.Presence {
+	var presences []*presencepb.Presence
+	for batch := range slices.Chunk(friendIDs, presenceBatchSize) {
+		rsp, err := svc.presenceClient.GetPresence(ctx, &presencepb.GetPresenceRequest{UserIds: batch, ViewerId: viewerID})
+		if err != nil {
+			log.Printf("WARN: could not fetch the presence of the friends of user %d: %v", viewerID, err)
+			return nil
+		}
+		presences = append(presences, rsp.Presences...)
+	}
+	return presences
+}
@@ -3,15 +3,19 @@ package main
 import (
 	"context"
 	"errors"
+	"fmt"
 	"testing"
+	"time"
 
 	errchecks "github.com/Costin2000/GoChat---Schwarz-Internship---2025/pkg"
 	aggrpb "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/aggregator/proto"
 	frpb "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/friend-request-base/proto"
+	presencepb "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/presence/proto"
 	userpb "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/user-base/proto"
 	"github.com/google/go-cmp/cmp"
 	"google.golang.org/grpc"
 	"google.golang.org/protobuf/testing/protocmp"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 func hasSenderID(filters []*frpb.ListFriendRequestsFiltersOneOf, id string) bool {
@@ -63,6 +67,9 @@ func Test_FetchFriendsUnit(t *testing.T) {
 			given: Given{
 				userClient: &userClientMock{
 					ListUsersFunc: func(ctx context.Context, req *userpb.ListUsersRequest, opts ...grpc.CallOption) (*userpb.ListUsersResponse, error) {
+						if req.ViewerId != 1 {
+							return nil, fmt.Errorf("listed users for viewer %d, want the requesting user", req.ViewerId)
+						}
 						return &userpb.ListUsersResponse{Users: []*userpb.User{user2, user3}}, nil
 					},
 				},
@@ -152,6 +159,9 @@ func Test_FetchFriendsUnit(t *testing.T) {
 				},
 				userClient: &userClientMock{
 					ListUsersFunc: func(ctx context.Context, req *userpb.ListUsersRequest, opts ...grpc.CallOption) (*userpb.ListUsersResponse, error) {
+						if len(req.Filters) != 1 || !req.Filters[0].GetDiscoverable().GetEquals() {
+							return nil, fmt.Errorf("listed users with filters %v, want only the discoverable ones", req.Filters)
+						}
 						return &userpb.ListUsersResponse{Users: []*userpb.User{user1, user2, user3, user4, user5}}, nil
 					},
 				},
@@ -200,3 +210,86 @@ func Test_FetchFriendsUnit(t *testing.T) {
 		})
 	}
 }
+
+func Test_FetchFriendsPresence(t *testing.T) {
+	friend := &userpb.User{Id: 2, UserName: "friend_one"}
+	online := &presencepb.Presence{UserId: 2, Online: true, LastSeenAt: timestamppb.New(time.Date(2025, 8, 1, 12, 0, 0, 0, time.UTC))}
+
+	newFrClient := func() *frClientMock {
+		return &frClientMock{
+			ListFrFunc: func(ctx context.Context, req *frpb.ListFriendRequestsRequest, opts ...grpc.CallOption) (*frpb.ListFriendRequestsResponse, error) {
+				if hasSenderID(req.Filters, "1") {
+					return &frpb.ListFriendRequestsResponse{Requests: []*frpb.FriendRequest{{SenderId: "1", ReceiverId: "2"}}}, nil
+				}
+				return &frpb.ListFriendRequestsResponse{}, nil
+			},
+		}
+	}
+	newUserClient := func() *userClientMock {
+		return &userClientMock{
+			ListUsersFunc: func(ctx context.Context, req *userpb.ListUsersRequest, opts ...grpc.CallOption) (*userpb.ListUsersResponse, error) {
+				return &userpb.ListUsersResponse{Users: []*userpb.User{friend}}, nil
+			},
+		}
+	}
+
+	tests := []struct {
+		name         string
+		req          *aggrpb.FetchUserFriendsRequest
+		getPresence  func(ctx context.Context, req *presencepb.GetPresenceRequest, opts ...grpc.CallOption) (*presencepb.GetPresenceResponse, error)
+		expectedRsp  *aggrpb.FetchUserFriendsResponse
+		wantPresence bool
+	}{
+		{
+			name: "Success: presence included",
+			req:  &aggrpb.FetchUserFriendsRequest{UserId: "1", ShowFriends: true, IncludePresence: true},
+			getPresence: func(ctx context.Context, req *presencepb.GetPresenceRequest, opts ...grpc.CallOption) (*presencepb.GetPresenceResponse, error) {
+				return &presencepb.GetPresenceResponse{Presences: []*presencepb.Presence{online}}, nil
+			},
+			expectedRsp:  &aggrpb.FetchUserFriendsResponse{Users: []*userpb.User{friend}, Presences: []*presencepb.Presence{online}},
+			wantPresence: true,
+		},
+		{
+			name: "Success: presence down, friends still listed",
+			req:  &aggrpb.FetchUserFriendsRequest{UserId: "1", ShowFriends: true, IncludePresence: true},
+			getPresence: func(ctx context.Context, req *presencepb.GetPresenceRequest, opts ...grpc.CallOption) (*presencepb.GetPresenceResponse, error) {
+				return nil, errors.New("presence unavailable")
+			},
+			expectedRsp:  &aggrpb.FetchUserFriendsResponse{Users: []*userpb.User{friend}},
+			wantPresence: true,
+		},
+		{
+			name: "Success: presence not asked for",
+			req:  &aggrpb.FetchUserFriendsRequest{UserId: "1", ShowFriends: true},
+			getPresence: func(ctx context.Context, req *presencepb.GetPresenceRequest, opts ...grpc.CallOption) (*presencepb.GetPresenceResponse, error) {
+				return nil, errors.New("presence should not be called")
+			},
+			expectedRsp: &aggrpb.FetchUserFriendsResponse{Users: []*userpb.User{friend}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			presenceClient := &presenceClientMock{GetPresenceFunc: tt.getPresence}
+			svc := NewMockService(ServiceMockOptions{
+				userClient:     newUserClient(),
+				frClient:       newFrClient(),
+				presenceClient: presenceClient,
+			})
+
+			resp, err := svc.FetchUserFriends(context.Background(), tt.req)
+
+			errchecks.Assert(t, err, errchecks.IsNil)
+			if diff := cmp.Diff(tt.expectedRsp, resp, protocmp.Transform()); diff != "" {
+				t.Errorf("FetchUserFriends response mismatch (-want +got):\n%s", diff)
+			}
+			got := presenceClient.capturedGetPresenceReq
+			if (got != nil) != tt.wantPresence {
+				t.Fatalf("presence called: %v, want %v", got != nil, tt.wantPresence)
+			}
+			if got != nil && (got.ViewerId != 1 || len(got.UserIds) != 1 || got.UserIds[0] != 2) {
+				t.Errorf("asked presence for %v as viewer %d, want the friend as the requesting user", got.UserIds, got.ViewerId)
+			}
+		})
+	}
+}
@@ -4,13 +4,15 @@ import (
 	"context"
 
 	frpb "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/friend-request-base/proto"
+	presencepb "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/presence/proto"
 	userpb "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/user-base/proto"
 	"google.golang.org/grpc"
 )
 
 type ServiceMockOptions struct {
-	userClient UserClient
-	frClient   FriendRequestClient
+	userClient     UserClient
+	frClient       FriendRequestClient
+	presenceClient PresenceClient
 }
 
 type userClientMock struct {
@@ -22,6 +24,16 @@ type frClientMock struct {
 	ListFrFunc                func(ctx context.Context, req *frpb.ListFriendRequestsRequest, opts ...grpc.CallOption) (*frpb.ListFriendRequestsResponse, error)
 }
 
+type presenceClientMock struct {
+	capturedGetPresenceReq *presencepb.GetPresenceRequest
+	GetPresenceFunc        func(ctx context.Context, req *presencepb.GetPresenceRequest, opts ...grpc.CallOption) (*presencepb.GetPresenceResponse, error)
+}
+
+func (client *presenceClientMock) GetPresence(ctx context.Context, req *presencepb.GetPresenceRequest, opts ...grpc.CallOption) (*presencepb.GetPresenceResponse, error) {
+	client.capturedGetPresenceReq = req
+	return client.GetPresenceFunc(ctx, req)
+}
+
 func (client *userClientMock) ListUsers(ctx context.Context, req *userpb.ListUsersRequest, opts ...grpc.CallOption) (*userpb.ListUsersResponse, error) {
 	client.capturedListUsersReq = req
 	return client.ListUsersFunc(ctx, req)
@@ -56,5 +68,15 @@ func NewMockService(opts ServiceMockOptions) *AggregatorService {
 		service.frClient = opts.frClient
 	}
 
+	if opts.presenceClient == nil {
+		service.presenceClient = &presenceClientMock{
+			GetPresenceFunc: func(ctx context.Context, req *presencepb.GetPresenceRequest, opts ...grpc.CallOption) (*presencepb.GetPresenceResponse, error) {
+				return &presencepb.GetPresenceResponse{}, nil
+			},
+		}
+	} else {
+		service.presenceClient = opts.presenceClient
+	}
+
 	return service
 }
@@ -2,17 +2,14 @@ package main
 
 import (
 	"context"
-	"log"
-	"net"
-	"os"
 
+	"github.com/Costin2000/GoChat---Schwarz-Internship---2025/pkg/bootstrap"
 	aggrpb "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/aggregator/proto"
 	frpb "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/friend-request-base/proto"
+	presencepb "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/presence/proto"
 	userpb "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/user-base/proto"
 
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
-	"google.golang.org/grpc/reflection"
 )
 
 type FriendRequestClient interface {
@@ -21,56 +18,80 @@ type FriendRequestClient interface {
 type UserClient interface {
 	ListUsers(ctx context.Context, req *userpb.ListUsersRequest, opts ...grpc.CallOption) (*userpb.ListUsersResponse, error)
 }
+type PresenceClient interface {
+	GetPresence(ctx context.Context, req *presencepb.GetPresenceRequest, opts ...grpc.CallOption) (*presencepb.GetPresenceResponse, error)
+}
 
 type AggregatorService struct {
 	aggrpb.UnimplementedAggregatorServiceServer
 	frClient       FriendRequestClient
 	userBaseClient UserClient
+	presenceClient PresenceClient
+}
+
+type config struct {
+	Addr              string `env:"AGGREGATOR_PORT" default:":50054"`
+	MetricsAddr       string `env:"AGGREGATOR_METRICS_ADDR" default:":9104"`
+	FriendRequestAddr string `env:"FRIEND_REQUEST_ADDR" default:"localhost:50052"`
+	UserBaseAddr      string `env:"USER_BASE_ADDR" default:"localhost:50051"`
+	PresenceAddr      string `env:"PRESENCE_ADDR" default:"localhost:50058"`
+	Shutdown          bootstrap.ShutdownConfig
+	Tracing           bootstrap.TracingConfig
 }
 
 func main() {
+	bootstrap.Init("aggregator")
+
+	var cfg config
+	if err := bootstrap.LoadConfig(&cfg); err != nil {
+		bootstrap.Fail("load config", err)
+	}
 
-	aggrPort := getEnv("AGGREGATOR_PORT", ":50054")
-	frAddr := getEnv("FRIEND_REQUEST_ADDR", "localhost:50052")
-	userAddr := getEnv("USER_BASE_ADDR", "localhost:50051")
+	ctx, stop := bootstrap.SignalContext()
+	defer stop()
 
-	// network connection
-	lis, err := net.Listen("tcp", aggrPort)
+	shutdownTracing, err := bootstrap.InitTracing(ctx, cfg.Tracing)
 	if err != nil {
-		log.Fatalf("Error polling aggrPort %s %v", aggrPort, err)
+		bootstrap.Fail("init tracing", err)
 	}
+	defer shutdownTracing(context.Background())
 
 	// user and friend-request client connections
-	userConn, err := grpc.NewClient(userAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
-	check(err, "dial user-base")
+	userConn, err := bootstrap.Dial(cfg.UserBaseAddr)
+	if err != nil {
+		bootstrap.Fail("dial user-base", err)
+	}
 	defer userConn.Close()
 
-	frConn, err := grpc.NewClient(frAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
-	check(err, "dial friend request service")
+	frConn, err := bootstrap.Dial(cfg.FriendRequestAddr)
+	if err != nil {
+		bootstrap.Fail("dial friend request service", err)
+	}
 	defer frConn.Close()
-	frClient := frpb.NewFriendRequestServiceClient(frConn)
-	userBaseClient := userpb.NewUserServiceClient(userConn)
 
-	aggrSvc := &AggregatorService{
-		frClient:       frClient,
-		userBaseClient: userBaseClient,
+	presenceConn, err := bootstrap.Dial(cfg.PresenceAddr)
+	if err != nil {
+		bootstrap.Fail("dial presence", err)
 	}
+	defer presenceConn.Close()
 
-	grpcServer := grpc.NewServer()
+	aggrSvc := &AggregatorService{
+		frClient:       frpb.NewFriendRequestServiceClient(frConn),
+		userBaseClient: userpb.NewUserServiceClient(userConn),
+		presenceClient: presencepb.NewPresenceServiceClient(presenceConn),
+	}
 
+	grpcServer := bootstrap.NewGRPCServer()
 	aggrpb.RegisterAggregatorServiceServer(grpcServer, aggrSvc)
-	reflection.Register(grpcServer)
+	health := bootstrap.RegisterHealth(grpcServer)
+	go health.Run(ctx)
 
-	log.Printf("gRPC polling on aggrPort %s...", aggrPort)
-
-	if err := grpcServer.Serve(lis); err != nil {
-		log.Fatalf("Error starting server: %v", err)
+	if err := bootstrap.ServeMetrics(ctx, cfg.MetricsAddr); err != nil {
+		bootstrap.Fail("serve metrics", err)
 	}
-}
 
-func check(err error, msg string) {
-	if err != nil {
-		log.Fatalf("%s: %v", msg, err)
+	if err := bootstrap.ServeGRPC(ctx, grpcServer, cfg.Addr, health, cfg.Shutdown); err != nil {
+		bootstrap.Fail("serve", err)
 	}
 }
 
@@ -81,10 +102,3 @@ func (s *AggregatorService) ListFriendRequests(ctx context.Context, req *frpb.Li
 func (s *AggregatorService) ListUsers(ctx context.Context, req *userpb.ListUsersRequest) (*userpb.ListUsersResponse, error) {
 	return s.userBaseClient.ListUsers(ctx, req)
 }
-
-func getEnv(key, fallback string) string {
-	if value, ok := os.LookupEnv(key); ok {
-		return value
-	}
-	return fallback
-}
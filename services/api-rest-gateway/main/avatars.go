@@ -0,0 +1,216 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/Costin2000/GoChat---Schwarz-Internship---2025/pkg/avatar"
+	"github.com/Costin2000/GoChat---Schwarz-Internship---2025/pkg/blob"
+	userbasepb "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/user-base/proto"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// avatarField is the multipart field of the uploaded picture
+const avatarField = "avatar"
+
+// Handles PUT /v1/users/{user_id}/avatar, a multipart/form-data upload with the picture in the
+// "avatar" field. The thumbnails are stored before user-base points the user at them, and the
+// thumbnails of the previous avatar are deleted after. Answers with the user, like GetUser.
+// grpc-gateway cannot stream file uploads, so the route is registered on httpMux behind withAuth.
+// At most maxDecodes pictures are decoded at once, each may take up to 64 MiB.
+func (s *server) uploadAvatarHandler(maxBytes int64, maxDecodes int) http.Handler {
+	decodes := make(chan struct{}, max(maxDecodes, 1))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := s.avatarOwner(w, r)
+		if !ok {
+			return
+		}
+
+		// the picture and a little room for the multipart headers
+		r.Body = http.MaxBytesReader(w, r.Body, maxBytes+64<<10)
+		mr, err := r.MultipartReader()
+		if err != nil {
+			http.Error(w, "expected a multipart/form-data body", http.StatusBadRequest)
+			return
+		}
+		var data []byte
+		for {
+			part, err := mr.NextPart()
+			if err != nil {
+				var tooLarge *http.MaxBytesError
+				if errors.As(err, &tooLarge) {
+					http.Error(w, fmt.Sprintf("avatar must be at most %d bytes", maxBytes), http.StatusRequestEntityTooLarge)
+				} else {
+					http.Error(w, `missing the "avatar" field`, http.StatusBadRequest)
+				}
+				return
+			}
+			if part.FormName() != avatarField {
+				continue
+			}
+			if ct, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type")); !slices.Contains([]string{"image/png", "image/jpeg", "image/gif"}, ct) {
+				http.Error(w, avatar.ErrUnsupportedType.Error(), http.StatusUnsupportedMediaType)
+				return
+			}
+			data, err = io.ReadAll(io.LimitReader(part, maxBytes+1))
+			if int64(len(data)) > maxBytes || errors.As(err, new(*http.MaxBytesError)) {
+				http.Error(w, fmt.Sprintf("avatar must be at most %d bytes", maxBytes), http.StatusRequestEntityTooLarge)
+				return
+			}
+			if err != nil {
+				http.Error(w, "could not read the avatar", http.StatusBadRequest)
+				return
+			}
+			break
+		}
+
+		select {
+		case decodes <- struct{}{}:
+		case <-r.Context().Done():
+			return
+		}
+		thumbs, err := avatar.Process(userID, data)
+		<-decodes
+		switch {
+		case errors.Is(err, avatar.ErrUnsupportedType):
+			http.Error(w, err.Error(), http.StatusUnsupportedMediaType)
+			return
+		case errors.Is(err, avatar.ErrTooManyPixels):
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+			return
+		case err != nil:
+			log.Printf("process avatar of user %d: %v", userID, err)
+			http.Error(w, "could not process the avatar", http.StatusInternalServerError)
+			return
+		}
+
+		for _, size := range avatar.Sizes {
+			err := s.blobs.Put(r.Context(), avatar.BlobKey(thumbs.Key, size), bytes.NewReader(thumbs.Images[size]), thumbs.ContentType)
+			if err != nil {
+				log.Printf("store avatar %s: %v", avatar.BlobKey(thumbs.Key, size), err)
+				http.Error(w, "could not store the avatar, try again", http.StatusServiceUnavailable)
+				return
+			}
+		}
+
+		// nothing is deleted when this fails: the key is the same for the same picture, so the
+		// thumbnails may be those of the current avatar
+		s.setAvatar(w, r, userID, thumbs.Key)
+	})
+}
+
+// Handles DELETE /v1/users/{user_id}/avatar
+func (s *server) deleteAvatarHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if userID, ok := s.avatarOwner(w, r); ok {
+			s.setAvatar(w, r, userID, "")
+		}
+	})
+}
+
+// avatarOwner returns the user of the path if it is the caller, or answers the request
+func (s *server) avatarOwner(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	if !s.avatarURL.Enabled() {
+		http.Error(w, "avatars are not configured", http.StatusServiceUnavailable)
+		return 0, false
+	}
+	userID, err := strconv.ParseInt(r.PathValue("user_id"), 10, 64)
+	if err != nil || userID <= 0 {
+		http.Error(w, "invalid user_id", http.StatusBadRequest)
+		return 0, false
+	}
+	// like the rest of the account, the avatar needs a login
+	if _, ok := r.Context().Value(scopesKey).([]string); ok {
+		http.Error(w, "access tokens cannot change the avatar", http.StatusForbidden)
+		return 0, false
+	}
+	if err := requireSelf(r.Context(), userID); err != nil {
+		http.Error(w, status.Convert(err).Message(), http.StatusForbidden)
+		return 0, false
+	}
+	return userID, true
+}
+
+// setAvatar points the user at key and deletes the thumbnails it replaced
+func (s *server) setAvatar(w http.ResponseWriter, r *http.Request, userID int64, key string) {
+	c, cancel := context.WithTimeout(r.Context(), s.upstreamTO)
+	defer cancel()
+	resp, err := s.userBaseClient.SetAvatar(c, &userbasepb.SetAvatarRequest{UserId: userID, AvatarKey: key})
+	if err != nil {
+		st := status.Convert(err)
+		http.Error(w, st.Message(), runtime.HTTPStatusFromCode(st.Code()))
+		return
+	}
+
+	if prev := resp.PreviousAvatarKey; prev != "" && prev != key {
+		for _, size := range avatar.Sizes {
+			if err := s.blobs.Delete(r.Context(), avatar.BlobKey(prev, size)); err != nil {
+				log.Printf("delete avatar %s: %v", avatar.BlobKey(prev, size), err)
+			}
+		}
+	}
+
+	body, err := protojson.MarshalOptions{UseProtoNames: true, EmitUnpopulated: true}.Marshal(resp.User)
+	if err != nil {
+		log.Printf("marshal user: %v", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(body)
+}
+
+// Handles GET /v1/avatars/{key}?exp=&sig=&size=, the avatar_url of users. The signature replaces
+// the JWT, so the route is registered outside of withAuth and the URLs work in <img> tags. A key
+// never changes its picture, so the thumbnails are cached until the URL expires.
+func (s *server) avatarHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.PathValue("key")
+		if !avatar.ValidKey(key) {
+			http.NotFound(w, r)
+			return
+		}
+		size := avatar.DefaultSize
+		if v := r.URL.Query().Get("size"); v != "" {
+			size, _ = strconv.Atoi(v)
+			if !slices.Contains(avatar.Sizes, size) {
+				http.Error(w, fmt.Sprintf("size must be one of %v", avatar.Sizes), http.StatusBadRequest)
+				return
+			}
+		}
+		now := time.Now()
+		expires, ok := s.avatarURL.Verify(key, r.URL.Query().Get("exp"), r.URL.Query().Get("sig"), now)
+		if !ok {
+			http.Error(w, "invalid or expired avatar link", http.StatusForbidden)
+			return
+		}
+
+		body, err := s.blobs.Get(r.Context(), avatar.BlobKey(key, size))
+		if errors.Is(err, blob.ErrNotFound) {
+			http.NotFound(w, r)
+			return
+		}
+		if err != nil {
+			log.Printf("read avatar %s: %v", avatar.BlobKey(key, size), err)
+			http.Error(w, "could not read the avatar", http.StatusServiceUnavailable)
+			return
+		}
+		defer body.Close()
+
+		w.Header().Set("Content-Type", avatar.ContentType(key))
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d, immutable", int(expires.Sub(now).Seconds())))
+		_, _ = io.Copy(w, body)
+	})
+}
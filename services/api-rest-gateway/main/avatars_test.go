@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"net/url"
+	"testing"
+	"time"
+
+	errchecks "github.com/Costin2000/GoChat---Schwarz-Internship---2025/pkg"
+	"github.com/Costin2000/GoChat---Schwarz-Internship---2025/pkg/avatar"
+	"github.com/Costin2000/GoChat---Schwarz-Internship---2025/pkg/blob"
+)
+
+func newAvatarServer(t *testing.T) (*server, *fakeUserBaseClient, http.Handler) {
+	t.Helper()
+	blobs, err := blob.NewLocal(t.TempDir())
+	errchecks.Assert(t, err, errchecks.IsNil)
+	userBase := &fakeUserBaseClient{}
+	s := &server{
+		userBaseClient: userBase,
+		upstreamTO:     time.Second,
+		blobs:          blobs,
+		avatarURL:      avatar.URLConfig{Secret: "test-secret", BaseURL: "/v1/avatars", TTL: time.Hour},
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("PUT /v1/users/{user_id}/avatar", s.uploadAvatarHandler(1<<20, 1))
+	mux.Handle("DELETE /v1/users/{user_id}/avatar", s.deleteAvatarHandler())
+	mux.Handle("GET /v1/avatars/{key}", s.avatarHandler())
+	return s, userBase, mux
+}
+
+func avatarUpload(t *testing.T, userID string, contentType string, data []byte) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	part, err := mw.CreatePart(textproto.MIMEHeader{
+		"Content-Disposition": {`form-data; name="avatar"; filename="me.png"`},
+		"Content-Type":        {contentType},
+	})
+	errchecks.Assert(t, err, errchecks.IsNil)
+	part.Write(data)
+	mw.Close()
+
+	r := httptest.NewRequest(http.MethodPut, "/v1/users/"+userID+"/avatar", &body)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+	return r.WithContext(context.WithValue(r.Context(), userIDKey, int64(42)))
+}
+
+func testPNG(t *testing.T) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	errchecks.Assert(t, png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 300, 200))), errchecks.IsNil)
+	return buf.Bytes()
+}
+
+func TestUploadAvatar(t *testing.T) {
+	tests := []struct {
+		name     string
+		req      func(t *testing.T) *http.Request
+		wantCode int
+	}{
+		{
+			name:     "stores the thumbnails",
+			req:      func(t *testing.T) *http.Request { return avatarUpload(t, "42", "image/png", testPNG(t)) },
+			wantCode: http.StatusOK,
+		},
+		{
+			name:     "avatar of another user",
+			req:      func(t *testing.T) *http.Request { return avatarUpload(t, "7", "image/png", testPNG(t)) },
+			wantCode: http.StatusForbidden,
+		},
+		{
+			name: "access token",
+			req: func(t *testing.T) *http.Request {
+				r := avatarUpload(t, "42", "image/png", testPNG(t))
+				return r.WithContext(context.WithValue(r.Context(), scopesKey, []string{"messages:write"}))
+			},
+			wantCode: http.StatusForbidden,
+		},
+		{
+			name:     "declared type is not an image",
+			req:      func(t *testing.T) *http.Request { return avatarUpload(t, "42", "image/svg+xml", testPNG(t)) },
+			wantCode: http.StatusUnsupportedMediaType,
+		},
+		{
+			name:     "content is not an image",
+			req:      func(t *testing.T) *http.Request { return avatarUpload(t, "42", "image/png", []byte("<html></html>")) },
+			wantCode: http.StatusUnsupportedMediaType,
+		},
+		{
+			name:     "too large",
+			req:      func(t *testing.T) *http.Request { return avatarUpload(t, "42", "image/png", make([]byte, 1<<20+1)) },
+			wantCode: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name: "not multipart",
+			req: func(t *testing.T) *http.Request {
+				r := httptest.NewRequest(http.MethodPut, "/v1/users/42/avatar", bytes.NewReader(testPNG(t)))
+				r.Header.Set("Content-Type", "image/png")
+				return r.WithContext(context.WithValue(r.Context(), userIDKey, int64(42)))
+			},
+			wantCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, userBase, mux := newAvatarServer(t)
+			rec := httptest.NewRecorder()
+
+			mux.ServeHTTP(rec, tt.req(t))
+
+			if rec.Code != tt.wantCode {
+				t.Fatalf("got %d %q, want %d", rec.Code, rec.Body.String(), tt.wantCode)
+			}
+			if key := userBase.avatars[42]; (tt.wantCode == http.StatusOK) != avatar.ValidKey(key) {
+				t.Errorf("unexpected avatar key %q", key)
+			}
+		})
+	}
+}
+
+func TestAvatar_ReplaceAndServe(t *testing.T) {
+	s, userBase, mux := newAvatarServer(t)
+	ctx := context.Background()
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, avatarUpload(t, "42", "image/png", testPNG(t)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("upload: %d %s", rec.Code, rec.Body.String())
+	}
+	key := userBase.avatars[42]
+
+	signed, err := url.Parse(s.avatarURL.URL(key, time.Now()))
+	errchecks.Assert(t, err, errchecks.IsNil)
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, signed.String()+"&size=64", nil))
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "image/png" {
+		t.Fatalf("serve: %d %v", rec.Code, rec.Header())
+	}
+	if cfg, err := png.DecodeConfig(rec.Body); err != nil || cfg.Width != 64 {
+		t.Errorf("served %v, %v; want the 64px thumbnail", cfg, err)
+	}
+
+	// a forged signature, a size without thumbnails and a key that is no avatar key
+	for target, wantCode := range map[string]int{
+		"/v1/avatars/" + key + "?exp=" + signed.Query().Get("exp") + "&sig=forged": http.StatusForbidden,
+		signed.String() + "&size=100": http.StatusBadRequest,
+		"/v1/avatars/..%2Fsecret.png": http.StatusNotFound,
+	} {
+		rec = httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		if rec.Code != wantCode {
+			t.Errorf("GET %s: got %d, want %d", target, rec.Code, wantCode)
+		}
+	}
+
+	// removing the avatar deletes its thumbnails
+	del := httptest.NewRequest(http.MethodDelete, "/v1/users/42/avatar", nil)
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, del.WithContext(context.WithValue(ctx, userIDKey, int64(42))))
+	if rec.Code != http.StatusOK || userBase.avatars[42] != "" {
+		t.Fatalf("delete: %d, avatar %q", rec.Code, userBase.avatars[42])
+	}
+	for _, size := range avatar.Sizes {
+		_, err := s.blobs.Get(ctx, avatar.BlobKey(key, size))
+		errchecks.Assert(t, err, errchecks.Is(blob.ErrNotFound))
+	}
+}
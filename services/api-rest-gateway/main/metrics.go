@@ -0,0 +1,35 @@
+package main
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// RED metrics per route; the route is the template (/v1/conversations/{conversation_id}/messages),
+// so IDs do not create a series each
+var (
+	httpRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "HTTP requests served by the gateway, by method, route and status code.",
+	}, []string{"method", "route", "code"})
+
+	httpRequestSeconds = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "Time the gateway took to answer HTTP requests.",
+		Buckets: []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10},
+	}, []string{"method", "route"})
+
+	// open server-sent event streams, each one holds a goroutine and a subscription until the client leaves
+	eventStreams = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "gateway_event_streams",
+		Help: "Server-sent event streams currently open, by stream (typing or presence).",
+	}, []string{"stream"})
+)
+
+func observeRequest(method, route string, status int, start time.Time) {
+	httpRequests.WithLabelValues(method, route, strconv.Itoa(status)).Inc()
+	httpRequestSeconds.WithLabelValues(method, route).Observe(time.Since(start).Seconds())
+}
@@ -2,6 +2,8 @@ package main
 
 import (
 	"context"
+	"errors"
+	"net"
 	"net/http"
 	"os"
 	"strings"
@@ -11,20 +13,58 @@ import (
 
 type contextKey string
 
-const userIDKey contextKey = "user_id"
+const (
+	userIDKey      contextKey = "user_id"
+	accessEntryKey contextKey = "access_entry"
+	clientIPKey    contextKey = "client_ip"
+	sessionIDKey   contextKey = "session_id"
+	// the User-Agent of requests that do not go through grpc-gateway
+	userAgentKey contextKey = "user_agent"
+	// the scopes of the personal access token of the request, not set for JWTs
+	scopesKey contextKey = "scopes"
+)
 
 // Claims struct
 type Claims struct {
-	UserID int64 `json:"user_id"`
+	UserID    int64  `json:"user_id"`
+	SessionID int64  `json:"sid"`
+	Type      string `json:"typ"`
 	jwt.RegisteredClaims
 }
 
+// withClientIP puts the address of the client in the context, for rate limiting and the login lockout.
+// X-Forwarded-For is only used when trusted, i.e. behind a proxy that sets it.
+func withClientIP(next http.Handler, trustForwarded bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), clientIPKey, clientIP(r, trustForwarded))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func clientIP(r *http.Request, trustForwarded bool) string {
+	if trustForwarded {
+		// the proxy appends the address it saw last, everything before it is up to the client
+		if values := r.Header.Values("X-Forwarded-For"); len(values) > 0 {
+			hops := strings.Split(values[len(values)-1], ",")
+			if ip := strings.TrimSpace(hops[len(hops)-1]); ip != "" {
+				return ip
+			}
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
 // Middleware de auth
-func withAuth(next http.Handler) http.Handler {
+func withAuth(next http.Handler, sessions *sessionCache, tokens *tokenCache) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 
-		// Exceptii: login & register
-		if r.URL.Path == "/v1/auth/login" || (r.URL.Path == "/v1/user" && r.Method == http.MethodPost) {
+		// Exceptii: login, second factor & register
+		if r.URL.Path == "/v1/auth/login" || r.URL.Path == "/v1/auth/login/verify" ||
+			(r.URL.Path == "/v1/user" && r.Method == http.MethodPost) {
 			next.ServeHTTP(w, r)
 			return
 		}
@@ -43,6 +83,25 @@ func withAuth(next http.Handler) http.Handler {
 		}
 
 		tokenStr := parts[1]
+		if strings.HasPrefix(tokenStr, accessTokenPrefix) {
+			grant, err := tokens.authenticate(r.Context(), tokenStr)
+			if errors.Is(err, errInvalidAccessToken) {
+				http.Error(w, "invalid, expired or revoked access token", http.StatusUnauthorized)
+				return
+			}
+			if err != nil {
+				http.Error(w, "cannot check the access token, try again", http.StatusServiceUnavailable)
+				return
+			}
+			if entry, ok := r.Context().Value(accessEntryKey).(*accessEntry); ok {
+				entry.userID = grant.userID
+			}
+			ctx := context.WithValue(r.Context(), userIDKey, grant.userID)
+			ctx = context.WithValue(ctx, scopesKey, grant.scopes)
+			next.ServeHTTP(w, r.WithContext(ctx))
+			return
+		}
+
 		jwtSecret := []byte(os.Getenv("AUTH_JWT_SECRET"))
 
 		claims := &Claims{}
@@ -54,9 +113,32 @@ func withAuth(next http.Handler) http.Handler {
 			http.Error(w, "invalid or expired token", http.StatusUnauthorized)
 			return
 		}
+		// the challenge of a login waiting for its second factor is signed with the same secret
+		if claims.Type == "mfa_challenge" {
+			http.Error(w, "login is waiting for the second factor", http.StatusUnauthorized)
+			return
+		}
+
+		// tokens issued before sessions existed have no sid, they have to log in again
+		if claims.SessionID == 0 {
+			http.Error(w, "invalid or expired token", http.StatusUnauthorized)
+			return
+		}
+		if err := sessions.check(r.Context(), claims.UserID, claims.SessionID); err != nil {
+			if errors.Is(err, errSessionRevoked) {
+				http.Error(w, "session was signed out", http.StatusUnauthorized)
+			} else {
+				http.Error(w, "cannot check the session, try again", http.StatusServiceUnavailable)
+			}
+			return
+		}
 
 		// Adaugam user_id in context pentru servicii
+		if entry, ok := r.Context().Value(accessEntryKey).(*accessEntry); ok {
+			entry.userID = claims.UserID
+		}
 		ctx := context.WithValue(r.Context(), userIDKey, claims.UserID)
+		ctx = context.WithValue(ctx, sessionIDKey, claims.SessionID)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
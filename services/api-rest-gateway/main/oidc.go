@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	authpb "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/auth/proto"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+const (
+	// oidcStateCookie ties the callback to the browser that started the login
+	oidcStateCookie = "gochat_oidc_state"
+	oidcCookiePath  = "/v1/auth/oidc"
+	oidcCookieAge   = 600 // seconds, the time auth keeps the login
+)
+
+// Handles GET /v1/auth/oidc/login: sends the browser to the identity provider.
+// The login goes through browser redirects, so both routes are registered outside of withAuth.
+func (s *server) oidcLoginHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", "GET")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		c, cancel := context.WithTimeout(r.Context(), s.upstreamTO)
+		defer cancel()
+		resp, err := s.authClient.StartOIDCLogin(c, &authpb.StartOIDCLoginRequest{
+			DeviceId:    r.URL.Query().Get("device_id"),
+			DeviceLabel: r.URL.Query().Get("device_label"),
+		})
+		if err != nil {
+			st := status.Convert(err)
+			http.Error(w, st.Message(), runtime.HTTPStatusFromCode(st.Code()))
+			return
+		}
+
+		http.SetCookie(w, &http.Cookie{
+			Name:     oidcStateCookie,
+			Value:    resp.State,
+			Path:     oidcCookiePath,
+			MaxAge:   oidcCookieAge,
+			HttpOnly: true,
+			Secure:   true,
+			// sent on the top level redirect back from the provider
+			SameSite: http.SameSiteLaxMode,
+		})
+		http.Redirect(w, r, resp.AuthorizationUrl, http.StatusFound)
+	})
+}
+
+// Handles GET /v1/auth/oidc/callback, where the identity provider sends the browser back.
+// Answers like POST /v1/auth/login, or redirects to successRedirect with the answer in the fragment.
+func (s *server) oidcCallbackHandler(successRedirect string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", "GET")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		fail := func(code int, msg string) {
+			if successRedirect != "" {
+				http.Redirect(w, r, successRedirect+"#"+url.Values{"error": {msg}}.Encode(), http.StatusFound)
+				return
+			}
+			http.Error(w, msg, code)
+		}
+
+		q := r.URL.Query()
+		cookie, err := r.Cookie(oidcStateCookie)
+		if err != nil || q.Get("state") == "" || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(q.Get("state"))) != 1 {
+			fail(http.StatusBadRequest, "invalid login state, start again")
+			return
+		}
+		http.SetCookie(w, &http.Cookie{Name: oidcStateCookie, Path: oidcCookiePath, MaxAge: -1, HttpOnly: true, Secure: true})
+		if e := q.Get("error"); e != "" {
+			fail(http.StatusUnauthorized, "the identity provider refused the login: "+e)
+			return
+		}
+
+		c, cancel := context.WithTimeout(r.Context(), s.upstreamTO)
+		defer cancel()
+		c = context.WithValue(c, userAgentKey, r.UserAgent())
+		resp, err := s.authClient.CompleteOIDCLogin(c, &authpb.CompleteOIDCLoginRequest{State: q.Get("state"), Code: q.Get("code")})
+		if err != nil {
+			st := status.Convert(err)
+			fail(runtime.HTTPStatusFromCode(st.Code()), st.Message())
+			return
+		}
+
+		if successRedirect != "" {
+			fragment := url.Values{"user_id": {strconv.FormatInt(resp.UserId, 10)}}
+			if resp.MfaRequired {
+				fragment.Set("mfa_required", "true")
+				fragment.Set("challenge_token", resp.ChallengeToken)
+			} else {
+				fragment.Set("token", resp.Token)
+			}
+			http.Redirect(w, r, successRedirect+"#"+fragment.Encode(), http.StatusFound)
+			return
+		}
+
+		body, err := protojson.MarshalOptions{UseProtoNames: true, EmitUnpopulated: true}.Marshal(resp)
+		if err != nil {
+			log.Printf("marshal login response: %v", err)
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		_, _ = w.Write(body)
+	})
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	authpb "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/auth/proto"
+	"google.golang.org/grpc"
+)
+
+type fakeOIDCAuthClient struct {
+	authpb.AuthServiceClient
+	completed *authpb.CompleteOIDCLoginRequest
+}
+
+func (f *fakeOIDCAuthClient) StartOIDCLogin(ctx context.Context, in *authpb.StartOIDCLoginRequest, _ ...grpc.CallOption) (*authpb.StartOIDCLoginResponse, error) {
+	return &authpb.StartOIDCLoginResponse{AuthorizationUrl: "https://idp.example.com/authorize?state=st-1", State: "st-1"}, nil
+}
+
+func (f *fakeOIDCAuthClient) CompleteOIDCLogin(ctx context.Context, in *authpb.CompleteOIDCLoginRequest, _ ...grpc.CallOption) (*authpb.LoginResponse, error) {
+	f.completed = in
+	return &authpb.LoginResponse{UserId: 42, Token: "jwt"}, nil
+}
+
+func Test_oidcLoginHandler(t *testing.T) {
+	s := &server{authClient: &fakeOIDCAuthClient{}, upstreamTO: time.Second}
+	rec := httptest.NewRecorder()
+
+	s.oidcLoginHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/auth/oidc/login", nil))
+
+	if rec.Code != http.StatusFound || rec.Header().Get("Location") != "https://idp.example.com/authorize?state=st-1" {
+		t.Fatalf("got %d to %q, want a redirect to the provider", rec.Code, rec.Header().Get("Location"))
+	}
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != oidcStateCookie || cookies[0].Value != "st-1" || !cookies[0].HttpOnly {
+		t.Errorf("unexpected cookies %v", cookies)
+	}
+}
+
+func Test_oidcCallbackHandler(t *testing.T) {
+	tests := []struct {
+		name            string
+		cookie          string
+		query           string
+		successRedirect string
+		wantCode        int
+		wantBody        string
+		wantLocation    string
+		wantCompleted   bool
+	}{
+		{
+			name:          "completes the login",
+			cookie:        "st-1",
+			query:         "state=st-1&code=c-1",
+			wantCode:      http.StatusOK,
+			wantBody:      `"token":"jwt"`,
+			wantCompleted: true,
+		},
+		{
+			name:            "redirects with the token in the fragment",
+			cookie:          "st-1",
+			query:           "state=st-1&code=c-1",
+			successRedirect: "https://app.example.com/login",
+			wantCode:        http.StatusFound,
+			wantLocation:    "https://app.example.com/login#token=jwt&user_id=42",
+			wantCompleted:   true,
+		},
+		{
+			name:     "without the cookie of the browser that started",
+			query:    "state=st-1&code=c-1",
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "state of another login",
+			cookie:   "st-2",
+			query:    "state=st-1&code=c-1",
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "refused at the provider",
+			cookie:   "st-1",
+			query:    "state=st-1&error=access_denied",
+			wantCode: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auth := &fakeOIDCAuthClient{}
+			s := &server{authClient: auth, upstreamTO: time.Second}
+			req := httptest.NewRequest(http.MethodGet, "/v1/auth/oidc/callback?"+tt.query, nil)
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: oidcStateCookie, Value: tt.cookie})
+			}
+			rec := httptest.NewRecorder()
+
+			s.oidcCallbackHandler(tt.successRedirect).ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantCode, rec.Body)
+			}
+			if !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Errorf("body %q does not contain %q", rec.Body, tt.wantBody)
+			}
+			if got := rec.Header().Get("Location"); got != tt.wantLocation {
+				t.Errorf("location = %q, want %q", got, tt.wantLocation)
+			}
+			if (auth.completed != nil) != tt.wantCompleted {
+				t.Errorf("completed %v, want %v", auth.completed, tt.wantCompleted)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	presencepb "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/presence/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// Handles GET /v1/presence/events?user_ids=1&user_ids=2, a text/event-stream of the presence of the
+// users the caller may see: their current presence first, then every change. Clients reconnect when
+// it ends, e.g. on an "error" event.
+func (s *server) presenceEventsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := r.Context().Value(scopesKey).([]string); ok {
+			http.Error(w, "not available to access tokens, log in instead", http.StatusForbidden)
+			return
+		}
+		viewerID, ok := r.Context().Value(userIDKey).(int64)
+		if !ok {
+			http.Error(w, "login required", http.StatusUnauthorized)
+			return
+		}
+		var userIDs []int64
+		for _, v := range r.URL.Query()["user_ids"] {
+			id, err := strconv.ParseInt(v, 10, 64)
+			if err != nil || id <= 0 {
+				http.Error(w, "invalid user_ids", http.StatusBadRequest)
+				return
+			}
+			userIDs = append(userIDs, id)
+		}
+		if len(userIDs) == 0 {
+			http.Error(w, "user_ids cannot be empty", http.StatusBadRequest)
+			return
+		}
+
+		stream, err := s.presenceClient.SubscribePresence(r.Context(), &presencepb.SubscribePresenceRequest{UserIds: userIDs, ViewerId: viewerID})
+		if err != nil {
+			http.Error(w, "presence is unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		// the subscription ends with the request context
+		eventStreams.WithLabelValues("presence").Inc()
+		defer eventStreams.WithLabelValues("presence").Dec()
+		events := startEventStream(w)
+
+		// Recv blocks, so it runs apart from the keep-alive ticker
+		changes := make(chan *presencepb.Presence)
+		errc := make(chan error, 1)
+		go func() {
+			for {
+				p, err := stream.Recv()
+				if err != nil {
+					errc <- err
+					return
+				}
+				select {
+				case changes <- p:
+				case <-r.Context().Done():
+					return
+				}
+			}
+		}()
+
+		ticker := time.NewTicker(eventsKeepAlive)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case err := <-errc:
+				if st := status.Convert(err); st.Code() != codes.Canceled {
+					_ = events.send("error", []byte(st.Message()))
+				}
+				return
+			case p := <-changes:
+				body, err := protojson.MarshalOptions{UseProtoNames: true, EmitUnpopulated: true}.Marshal(p)
+				if err != nil {
+					log.Printf("marshal presence: %v", err)
+					return
+				}
+				if err := events.send("", body); err != nil {
+					return
+				}
+			case <-ticker.C:
+				if err := events.keepAlive(); err != nil {
+					return
+				}
+			}
+		}
+	})
+}
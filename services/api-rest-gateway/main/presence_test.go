@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	errchecks "github.com/Costin2000/GoChat---Schwarz-Internship---2025/pkg"
+	presencepb "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/presence/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type fakePresenceClient struct {
+	presencepb.PresenceServiceClient
+	got    *presencepb.GetPresenceRequest
+	subReq *presencepb.SubscribePresenceRequest
+	events chan *presencepb.Presence
+}
+
+func (f *fakePresenceClient) GetPresence(ctx context.Context, in *presencepb.GetPresenceRequest, _ ...grpc.CallOption) (*presencepb.GetPresenceResponse, error) {
+	f.got = in
+	return &presencepb.GetPresenceResponse{}, nil
+}
+
+func (f *fakePresenceClient) SubscribePresence(ctx context.Context, in *presencepb.SubscribePresenceRequest, _ ...grpc.CallOption) (grpc.ServerStreamingClient[presencepb.Presence], error) {
+	f.subReq = in
+	return &fakePresenceStream{ctx: ctx, events: f.events}, nil
+}
+
+type fakePresenceStream struct {
+	grpc.ServerStreamingClient[presencepb.Presence]
+	ctx    context.Context
+	events chan *presencepb.Presence
+}
+
+func (s *fakePresenceStream) Recv() (*presencepb.Presence, error) {
+	select {
+	case p, ok := <-s.events:
+		if !ok {
+			return nil, status.Error(codes.Unavailable, "presence is shutting down, subscribe again")
+		}
+		return p, nil
+	case <-s.ctx.Done():
+		return nil, status.FromContextError(s.ctx.Err()).Err()
+	}
+}
+
+func TestGetPresence_ViewerIsTheCaller(t *testing.T) {
+	presence := &fakePresenceClient{}
+	s := &server{presenceClient: presence, upstreamTO: time.Second}
+	ctx := context.WithValue(context.Background(), userIDKey, int64(42))
+
+	// a viewer_id of 0 would see everyone
+	_, err := s.GetPresence(ctx, &presencepb.GetPresenceRequest{UserIds: []int64{7}, ViewerId: 0})
+
+	errchecks.Assert(t, err, errchecks.IsNil)
+	if presence.got.ViewerId != 42 {
+		t.Errorf("viewer_id = %d, want the caller", presence.got.ViewerId)
+	}
+}
+
+func TestHeartbeat_OnlyOwnPresence(t *testing.T) {
+	s := &server{upstreamTO: time.Second}
+	ctx := context.WithValue(context.Background(), userIDKey, int64(42))
+
+	_, err := s.Heartbeat(ctx, &presencepb.HeartbeatRequest{UserId: 7, ConnectionId: "tab-1"})
+
+	errchecks.Assert(t, err, errchecks.HasStatusCode(codes.PermissionDenied))
+}
+
+func TestPresenceEvents(t *testing.T) {
+	presence := &fakePresenceClient{events: make(chan *presencepb.Presence, 1)}
+	s := &server{presenceClient: presence}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.presenceEventsHandler().ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), userIDKey, int64(42))))
+	}))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/v1/presence/events?user_ids=7&user_ids=8")
+	errchecks.Assert(t, err, errchecks.IsNil)
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("got %d %v", resp.StatusCode, resp.Header)
+	}
+	if presence.subReq.ViewerId != 42 || len(presence.subReq.UserIds) != 2 {
+		t.Fatalf("subscribed with %v, want both users as the caller", presence.subReq)
+	}
+
+	presence.events <- &presencepb.Presence{UserId: 7, Online: true}
+	body := bufio.NewReader(resp.Body)
+	line, err := body.ReadString('\n')
+	errchecks.Assert(t, err, errchecks.IsNil)
+	if !strings.HasPrefix(line, "data: ") || !strings.Contains(line, `"user_id":"7"`) || !strings.Contains(line, `"online":true`) {
+		t.Errorf("unexpected event %q", line)
+	}
+
+	// the stream ends with an error event when presence goes away
+	close(presence.events)
+	rest, _ := body.ReadString(0)
+	if !strings.Contains(rest, "event: error\n") {
+		t.Errorf("got %q, want an error event", rest)
+	}
+}
+
+func TestPresenceEvents_Rejected(t *testing.T) {
+	tests := []struct {
+		name     string
+		target   string
+		ctx      func(ctx context.Context) context.Context
+		wantCode int
+	}{
+		{
+			name:     "no user ids",
+			target:   "/v1/presence/events",
+			ctx:      func(ctx context.Context) context.Context { return context.WithValue(ctx, userIDKey, int64(42)) },
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "invalid user id",
+			target:   "/v1/presence/events?user_ids=abc",
+			ctx:      func(ctx context.Context) context.Context { return context.WithValue(ctx, userIDKey, int64(42)) },
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:   "access token",
+			target: "/v1/presence/events?user_ids=7",
+			ctx: func(ctx context.Context) context.Context {
+				ctx = context.WithValue(ctx, userIDKey, int64(42))
+				return context.WithValue(ctx, scopesKey, []string{"messages:read"})
+			},
+			wantCode: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &server{presenceClient: &fakePresenceClient{}}
+			r := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			rec := httptest.NewRecorder()
+
+			s.presenceEventsHandler().ServeHTTP(rec, r.WithContext(tt.ctx(r.Context())))
+
+			if rec.Code != tt.wantCode {
+				t.Errorf("got %d %q, want %d", rec.Code, rec.Body.String(), tt.wantCode)
+			}
+		})
+	}
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/redis/go-redis/v9"
+)
+
+type rateLimitConfig struct {
+	// comma separated "<METHOD> <route>=<requests>/<period>", the routes are the templates of gateway.proto
+	Routes string `env:"RATE_LIMITS" default:"POST /v1/auth/login=5/1m,PUT /v1/users/{user_id}/password=5/1m,POST /v1/friend-request=20/1m,POST /v1/message=60/1m,POST /v1/conversations/{conversation_id}/typing=40/1m,POST /v1/users/{user_id}/presence/heartbeat=60/1m"`
+	// shared by all gateway replicas; without it every replica keeps its own buckets in memory
+	RedisURL string `env:"RATE_LIMIT_REDIS_URL"`
+}
+
+func (c *rateLimitConfig) Validate() error {
+	_, err := parseRateLimits(c.Routes)
+	return err
+}
+
+// rateLimit is a token bucket of Burst tokens that refills Burst tokens per Per
+type rateLimit struct {
+	Burst int
+	Per   time.Duration
+}
+
+// interval is the time it takes to refill one token
+func (l rateLimit) interval() time.Duration {
+	return l.Per / time.Duration(l.Burst)
+}
+
+type limitResult struct {
+	allowed    bool
+	remaining  int
+	retryAfter time.Duration // until the next token, when not allowed
+	reset      time.Duration // until the bucket is full again
+}
+
+// take spends a token of the bucket whose state is tat, the time at which it is full again (GCRA,
+// the token bucket without a refill timer). It returns the new state and the outcome.
+func (l rateLimit) take(tat, now time.Time) (time.Time, limitResult) {
+	interval := l.interval()
+	if tat.Before(now) {
+		tat = now
+	}
+	newTAT := tat.Add(interval)
+	allowAt := newTAT.Add(-interval * time.Duration(l.Burst))
+	if now.Before(allowAt) {
+		return tat, limitResult{retryAfter: allowAt.Sub(now), reset: tat.Sub(now)}
+	}
+	return newTAT, limitResult{allowed: true, remaining: int(now.Sub(allowAt) / interval), reset: newTAT.Sub(now)}
+}
+
+// parseRateLimits parses RATE_LIMITS into the limits by "<METHOD> <route>"
+func parseRateLimits(s string) (map[string]rateLimit, error) {
+	limits := map[string]rateLimit{}
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		route, spec, ok := strings.Cut(entry, "=")
+		method, path, ok2 := strings.Cut(strings.TrimSpace(route), " ")
+		count, period, ok3 := strings.Cut(strings.TrimSpace(spec), "/")
+		if !ok || !ok2 || !ok3 {
+			return nil, fmt.Errorf("RATE_LIMITS: %q is not <METHOD> <route>=<requests>/<period>", entry)
+		}
+		n, err := strconv.Atoi(count)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("RATE_LIMITS: %q: requests must be a positive number", entry)
+		}
+		d, err := time.ParseDuration(period)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("RATE_LIMITS: %q: period must be a positive duration, e.g. 1m", entry)
+		}
+		limits[strings.ToUpper(method)+" "+strings.TrimSpace(path)] = rateLimit{Burst: n, Per: d}
+	}
+	return limits, nil
+}
+
+// limiterStore keeps the buckets; take spends a token of the bucket of key
+type limiterStore interface {
+	take(ctx context.Context, key string, limit rateLimit) (limitResult, error)
+}
+
+// memoryStore keeps the buckets of a single gateway replica
+type memoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]time.Time
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{buckets: map[string]time.Time{}, now: time.Now}
+}
+
+func (s *memoryStore) take(_ context.Context, key string, limit rateLimit) (limitResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if now.Sub(s.lastSweep) > time.Minute {
+		// full buckets are the same as no bucket
+		for k, tat := range s.buckets {
+			if !tat.After(now) {
+				delete(s.buckets, k)
+			}
+		}
+		s.lastSweep = now
+	}
+
+	tat, res := limit.take(s.buckets[key], now)
+	s.buckets[key] = tat
+	return res, nil
+}
+
+// the same algorithm as rateLimit.take, in Redis time so replicas with skewed clocks agree
+var takeScript = redis.NewScript(`
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+local interval = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local tat = tonumber(redis.call('GET', KEYS[1]) or now)
+if tat < now then tat = now end
+local new_tat = tat + interval
+local allow_at = new_tat - interval * burst
+if now < allow_at then
+  return {0, 0, allow_at - now, tat - now}
+end
+redis.call('SET', KEYS[1], new_tat, 'PX', new_tat - now)
+return {1, math.floor((now - allow_at) / interval), 0, new_tat - now}
+`)
+
+// redisStore shares the buckets between the gateway replicas
+type redisStore struct {
+	client *redis.Client
+}
+
+func (s *redisStore) take(ctx context.Context, key string, limit rateLimit) (limitResult, error) {
+	v, err := takeScript.Run(ctx, s.client, []string{key}, limit.interval().Milliseconds(), limit.Burst).Int64Slice()
+	if err != nil {
+		return limitResult{}, err
+	}
+	if len(v) != 4 {
+		return limitResult{}, fmt.Errorf("unexpected rate limit script result %v", v)
+	}
+	return limitResult{
+		allowed:    v[0] == 1,
+		remaining:  int(v[1]),
+		retryAfter: time.Duration(v[2]) * time.Millisecond,
+		reset:      time.Duration(v[3]) * time.Millisecond,
+	}, nil
+}
+
+// rateLimiter limits the configured routes per user, or per client IP before login
+type rateLimiter struct {
+	limits map[string]rateLimit
+	store  limiterStore
+	close  func() error
+}
+
+func newRateLimiter(cfg rateLimitConfig) (*rateLimiter, error) {
+	limits, err := parseRateLimits(cfg.Routes)
+	if err != nil {
+		return nil, err
+	}
+	rl := &rateLimiter{limits: limits, close: func() error { return nil }}
+
+	if cfg.RedisURL == "" {
+		slog.Warn("RATE_LIMIT_REDIS_URL not set, every gateway replica applies the rate limits on its own")
+		rl.store = newMemoryStore()
+		return rl, nil
+	}
+	opts, err := redis.ParseURL(cfg.RedisURL)
+	if err != nil {
+		return nil, fmt.Errorf("RATE_LIMIT_REDIS_URL: %w", err)
+	}
+	client := redis.NewClient(opts)
+	rl.store = &redisStore{client: client}
+	rl.close = client.Close
+	return rl, nil
+}
+
+// Close releases the connection to the shared store.
+func (rl *rateLimiter) Close() error {
+	return rl.close()
+}
+
+// middleware answers 429 once the caller ran out of tokens for the route. Runs inside grpc-gateway,
+// where the route template is known, and after withAuth, so authenticated calls count per user.
+func (rl *rateLimiter) middleware(next runtime.HandlerFunc) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, pathParams map[string]string) {
+		route, _ := routeTemplate(r.Context())
+		if rl.allow(w, r, route, route) {
+			next(w, r, pathParams)
+		}
+	}
+}
+
+// handler is middleware for the routes of httpMux, whose pattern is the route; wrap it in withAuth.
+// The buckets are per path, e.g. typing in one conversation does not use up the tokens of another.
+func (rl *rateLimiter) handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if rl.allow(w, r, strings.TrimPrefix(r.Pattern, r.Method+" "), r.URL.Path) {
+			next.ServeHTTP(w, r)
+		}
+	})
+}
+
+// allow spends a token of the caller from the bucket of the route limit, or answers 429 and returns false
+func (rl *rateLimiter) allow(w http.ResponseWriter, r *http.Request, route, bucket string) bool {
+	limit, ok := rl.limits[r.Method+" "+route]
+	if !ok {
+		return true
+	}
+
+	res, err := rl.store.take(r.Context(), "ratelimit:"+r.Method+" "+bucket+":"+rl.clientKey(r), limit)
+	if err != nil {
+		// an unreachable store must not take the API down with it
+		slog.Warn("rate limit store unavailable, request not limited", "route", route, "error", err)
+		return true
+	}
+
+	h := w.Header()
+	h.Set("RateLimit-Limit", strconv.Itoa(limit.Burst))
+	h.Set("RateLimit-Remaining", strconv.Itoa(res.remaining))
+	h.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(res.reset)))
+	if !res.allowed {
+		h.Set("Retry-After", strconv.Itoa(ceilSeconds(res.retryAfter)))
+		http.Error(w, "rate limit exceeded", http.StatusTooManyRequests)
+		return false
+	}
+	return true
+}
+
+// clientKey identifies the caller: the user of the token, or the client IP for anonymous routes like login
+func (rl *rateLimiter) clientKey(r *http.Request) string {
+	if id, ok := r.Context().Value(userIDKey).(int64); ok {
+		return "user:" + strconv.FormatInt(id, 10)
+	}
+	ip, _ := r.Context().Value(clientIPKey).(string)
+	return "ip:" + ip
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+)
+
+func Test_rateLimit_take(t *testing.T) {
+	limit := rateLimit{Burst: 3, Per: 3 * time.Second}
+	now := time.Unix(1000, 0)
+
+	var tat time.Time
+	var got []limitResult
+	for i := 0; i < 4; i++ {
+		var res limitResult
+		tat, res = limit.take(tat, now)
+		got = append(got, res)
+	}
+	// one token is back after a second
+	_, res := limit.take(tat, now.Add(time.Second))
+	got = append(got, res)
+
+	want := []limitResult{
+		{allowed: true, remaining: 2, reset: time.Second},
+		{allowed: true, remaining: 1, reset: 2 * time.Second},
+		{allowed: true, remaining: 0, reset: 3 * time.Second},
+		{retryAfter: time.Second, reset: 3 * time.Second},
+		{allowed: true, remaining: 0, reset: 3 * time.Second},
+	}
+	if diff := cmp.Diff(want, got, cmp.AllowUnexported(limitResult{})); diff != "" {
+		t.Errorf("take() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func Test_parseRateLimits(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		want    map[string]rateLimit
+		wantErr bool
+	}{
+		{
+			name: "several routes",
+			in:   "POST /v1/auth/login=5/1m, post /v1/message=60/1m",
+			want: map[string]rateLimit{
+				"POST /v1/auth/login": {Burst: 5, Per: time.Minute},
+				"POST /v1/message":    {Burst: 60, Per: time.Minute},
+			},
+		},
+		{
+			name: "empty disables limiting",
+			in:   "",
+			want: map[string]rateLimit{},
+		},
+		{
+			name:    "missing method",
+			in:      "/v1/auth/login=5/1m",
+			wantErr: true,
+		},
+		{
+			name:    "period without a number",
+			in:      "POST /v1/auth/login=5/m",
+			wantErr: true,
+		},
+		{
+			name:    "zero requests",
+			in:      "POST /v1/auth/login=0/1m",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseRateLimits(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseRateLimits() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if diff := cmp.Diff(tt.want, got); !tt.wantErr && diff != "" {
+				t.Errorf("parseRateLimits() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func Test_rateLimiter_middleware(t *testing.T) {
+	rl := &rateLimiter{
+		limits: map[string]rateLimit{"POST /v1/auth/login": {Burst: 2, Per: time.Minute}},
+		store:  newMemoryStore(),
+	}
+	mux := runtime.NewServeMux(runtime.WithMiddlewares(rl.middleware))
+	ok := func(w http.ResponseWriter, _ *http.Request, _ map[string]string) { w.WriteHeader(http.StatusOK) }
+	if err := mux.HandlePath(http.MethodPost, "/v1/auth/login", ok); err != nil {
+		t.Fatal(err)
+	}
+
+	login := func(remoteAddr string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/v1/auth/login", nil)
+		r.RemoteAddr = remoteAddr
+		w := httptest.NewRecorder()
+		withClientIP(mux, false).ServeHTTP(w, r)
+		return w
+	}
+
+	for i := 0; i < 2; i++ {
+		if w := login("10.0.0.1:5000"); w.Code != http.StatusOK {
+			t.Fatalf("request %d: status = %d, want 200", i+1, w.Code)
+		}
+	}
+
+	w := login("10.0.0.1:5001")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("third request: status = %d, want 429", w.Code)
+	}
+	wantHeaders := map[string]string{"Retry-After": "30", "RateLimit-Limit": "2", "RateLimit-Remaining": "0", "RateLimit-Reset": "60"}
+	for k, v := range wantHeaders {
+		if got := w.Header().Get(k); got != v {
+			t.Errorf("%s = %q, want %q", k, got, v)
+		}
+	}
+
+	if w := login("10.0.0.2:5000"); w.Code != http.StatusOK {
+		t.Errorf("other client: status = %d, want 200", w.Code)
+	}
+}
+
+func Test_rateLimiter_handler(t *testing.T) {
+	rl := &rateLimiter{
+		limits: map[string]rateLimit{"POST /v1/conversations/{conversation_id}/typing": {Burst: 1, Per: time.Minute}},
+		store:  newMemoryStore(),
+	}
+	mux := http.NewServeMux()
+	mux.Handle("POST /v1/conversations/{conversation_id}/typing", rl.handler(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})))
+
+	typing := func(userID int64) int {
+		r := httptest.NewRequest(http.MethodPost, "/v1/conversations/7/typing", nil)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), userIDKey, userID)))
+		return w.Code
+	}
+
+	if code := typing(42); code != http.StatusNoContent {
+		t.Fatalf("first request: status = %d, want 204", code)
+	}
+	if code := typing(42); code != http.StatusTooManyRequests {
+		t.Fatalf("second request: status = %d, want 429", code)
+	}
+	if code := typing(43); code != http.StatusNoContent {
+		t.Errorf("other user: status = %d, want 204", code)
+	}
+}
+
+func Test_rateLimiter_TypingCadenceIsNotLimited(t *testing.T) {
+	field, _ := reflect.TypeOf(rateLimitConfig{}).FieldByName("Routes")
+	limits, err := parseRateLimits(field.Tag.Get("default"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2025, 8, 1, 12, 0, 0, 0, time.UTC)
+	store := newMemoryStore()
+	store.now = func() time.Time { return now }
+	rl := &rateLimiter{limits: limits, store: store}
+	mux := http.NewServeMux()
+	mux.Handle("POST /v1/conversations/{conversation_id}/typing", rl.handler(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})))
+
+	typing := func(conversationID int) int {
+		r := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/v1/conversations/%d/typing", conversationID), nil)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), userIDKey, int64(42))))
+		return w.Code
+	}
+
+	// the documented client repeats the signal every TTL/2 (3s) while typing, stops after each
+	// message and types in a second conversation at the same time
+	ttl := 6 * time.Second
+	for i := 0; i < 100; i++ {
+		for _, conversationID := range []int{7, 8} {
+			if code := typing(conversationID); code != http.StatusNoContent {
+				t.Fatalf("signal %d in conversation %d: status = %d, want 204", i, conversationID, code)
+			}
+			if i%5 == 4 {
+				if code := typing(conversationID); code != http.StatusNoContent {
+					t.Fatalf("stop %d in conversation %d: status = %d, want 204", i, conversationID, code)
+				}
+			}
+		}
+		now = now.Add(ttl / 2)
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+type healthLister interface {
+	List(ctx context.Context, in *healthpb.HealthListRequest, opts ...grpc.CallOption) (*healthpb.HealthListResponse, error)
+}
+
+// upstream is a backend the gateway needs to serve requests
+type upstream struct {
+	name   string
+	health healthLister
+}
+
+type upstreamReadiness struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"` // per dependency of the upstream, e.g. postgres
+	Error  string            `json:"error,omitempty"`
+}
+
+type readiness struct {
+	Ready     bool                         `json:"ready"`
+	Upstreams map[string]upstreamReadiness `json:"upstreams"`
+}
+
+// readyzHandler asks every upstream for its grpc.health.v1 statuses; the gateway is ready when all of them serve
+func readyzHandler(upstreams []upstream, timeout time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+
+		res := readiness{Ready: true, Upstreams: make(map[string]upstreamReadiness, len(upstreams))}
+		var mu sync.Mutex
+		var wg sync.WaitGroup
+		for _, u := range upstreams {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				ur := checkUpstream(ctx, u)
+
+				mu.Lock()
+				defer mu.Unlock()
+				res.Upstreams[u.name] = ur
+				if ur.Status != healthpb.HealthCheckResponse_SERVING.String() {
+					res.Ready = false
+				}
+			}()
+		}
+		wg.Wait()
+
+		w.Header().Set("Content-Type", "application/json")
+		if !res.Ready {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		_ = json.NewEncoder(w).Encode(res)
+	})
+}
+
+func checkUpstream(ctx context.Context, u upstream) upstreamReadiness {
+	resp, err := u.health.List(ctx, &healthpb.HealthListRequest{})
+	if err != nil {
+		return upstreamReadiness{Status: "UNREACHABLE", Error: err.Error()}
+	}
+
+	ur := upstreamReadiness{Status: healthpb.HealthCheckResponse_UNKNOWN.String(), Checks: map[string]string{}}
+	for name, st := range resp.GetStatuses() {
+		if name == "" {
+			ur.Status = st.GetStatus().String()
+			continue
+		}
+		ur.Checks[name] = st.GetStatus().String()
+	}
+	return ur
+}
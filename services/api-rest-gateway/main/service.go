@@ -2,24 +2,37 @@ package main
 
 import (
 	"context"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
+	"github.com/Costin2000/GoChat---Schwarz-Internship---2025/pkg/avatar"
+	"github.com/Costin2000/GoChat---Schwarz-Internship---2025/pkg/blob"
+	"github.com/Costin2000/GoChat---Schwarz-Internship---2025/pkg/bootstrap"
 	aggrpb "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/aggregator/proto"
 	gatewaypb "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/api-rest-gateway/proto"
 	authpb "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/auth/proto"
 	conversationpb "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/conversation-base/proto"
 	friendrequestpb "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/friend-request-base/proto"
 	messagepb "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/message-base/proto"
+	presencepb "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/presence/proto"
 	userbasepb "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/user-base/proto"
 
 	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/encoding/protojson"
 )
 
@@ -31,51 +44,140 @@ type server struct {
 	aggrClient         aggrpb.AggregatorServiceClient
 	messageClient      messagepb.MessageServiceClient
 	conversationClient conversationpb.ConversationServiceClient
+	presenceClient     presencepb.PresenceServiceClient
 	upstreamTO         time.Duration
+	sessions           *sessionCache
+	tokens             *tokenCache
+	blobs              blob.Store
+	avatarURL          avatar.URLConfig
+	typing             *typingHub
+	typingFanout       typingFanout // nil without RabbitMQ
+	typingTTL          time.Duration
+}
+
+type corsConfig struct {
+	AllowOrigin  string `env:"CORS_ALLOW_ORIGIN" default:"*"`
+	AllowHeaders string `env:"CORS_ALLOW_HEADERS" default:"Content-Type, Authorization"`
+	AllowMethods string `env:"CORS_ALLOW_METHODS" default:"GET, POST, PUT, PATCH, DELETE, OPTIONS"`
+}
+
+type config struct {
+	HTTPAddr          string        `env:"GATEWAY_HTTP_ADDR" default:":8080"`
+	AuthAddr          string        `env:"AUTH_ADDR" default:"auth:50053"`
+	FriendRequestAddr string        `env:"FRIEND_REQUEST_ADDR" default:"friend-request:50052"`
+	UserBaseAddr      string        `env:"USER_BASE_ADDR" default:"user-base:50051"`
+	AggregatorAddr    string        `env:"AGGR_REQUEST_ADDR" default:"aggregator:50054"`
+	MessageAddr       string        `env:"MESSAGE_BASE_ADDR" default:"message-base:50055"`
+	ConversationAddr  string        `env:"CONVERSATION_ADDR" default:"conversation:50056"`
+	PresenceAddr      string        `env:"PRESENCE_ADDR" default:"presence:50058"`
+	UpstreamTimeout   time.Duration `env:"UPSTREAM_REQUEST_TIMEOUT" default:"5s"`
+	ReadyTimeout      time.Duration `env:"READY_CHECK_TIMEOUT" default:"2s"`
+	UnsubscribeSecret string        `env:"UNSUBSCRIBE_SECRET"`
+	// where the browser goes after a login with the identity provider, with the token in the URL
+	// fragment; without it the callback answers with JSON
+	OIDCSuccessRedirect string `env:"OIDC_SUCCESS_REDIRECT"`
+	TrustForwarded      bool   `env:"GATEWAY_TRUST_FORWARDED_FOR" default:"false"`
+	// how long an active session or access token is trusted before auth is asked again, and so how
+	// long a revoked one may keep working
+	SessionCheckTTL time.Duration `env:"SESSION_CHECK_TTL" default:"30s"`
+	// the largest avatar upload, in bytes
+	AvatarMaxBytes int64 `env:"AVATAR_MAX_BYTES" default:"5242880"`
+	// how many uploaded avatars are decoded at the same time, the others wait
+	AvatarMaxDecodes int `env:"AVATAR_MAX_DECODES" default:"4"`
+	AvatarURL        avatar.URLConfig
+	Blob             blob.Config
+	CORS             corsConfig
+	RateLimit        rateLimitConfig
+	Typing           typingConfig
+	Tracing          bootstrap.TracingConfig
 }
 
 func main() {
-	httpAddr := env("GATEWAY_HTTP_ADDR", ":8080")
-	authAddr := env("AUTH_ADDR", "auth:50053")
-	friendRequestAddr := env("FRIEND_REQUEST_ADDR", "friend-request:50052")
-	userBaseAddr := env("USER_BASE_ADDR", "user-base:50051")
-	aggrReqAddr := env("AGGR_REQUEST_ADDR", "aggregator:50054")
-	upstreamTimeout := durEnv("UPSTREAM_REQUEST_TIMEOUT", 5*time.Second)
-	messageAddr := env("MESSAGE_BASE_ADDR", "message-base:50055")
-	conversationAddr := env("CONVERSATION_ADDR", "conversation:50056")
-
-	authConn, err := grpc.NewClient(authAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
-	check(err, "dial auth")
+	bootstrap.Init("api-rest-gateway")
+
+	var cfg config
+	if err := bootstrap.LoadConfig(&cfg); err != nil {
+		bootstrap.Fail("load config", err)
+	}
+
+	shutdownTracing, err := bootstrap.InitTracing(context.Background(), cfg.Tracing)
+	if err != nil {
+		bootstrap.Fail("init tracing", err)
+	}
+	defer shutdownTracing(context.Background())
+
+	dialOpts := []grpc.DialOption{grpc.WithUnaryInterceptor(forwardCaller)}
+
+	authConn, err := bootstrap.Dial(cfg.AuthAddr, dialOpts...)
+	if err != nil {
+		bootstrap.Fail("dial auth", err)
+	}
 	defer authConn.Close()
 
-	userBaseConn, err := grpc.NewClient(userBaseAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
-	check(err, "dial user-base")
+	userBaseConn, err := bootstrap.Dial(cfg.UserBaseAddr, dialOpts...)
+	if err != nil {
+		bootstrap.Fail("dial user-base", err)
+	}
 	defer userBaseConn.Close()
 
-	frConn, err := grpc.NewClient(friendRequestAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
-	check(err, "dial friend request service")
+	frConn, err := bootstrap.Dial(cfg.FriendRequestAddr, dialOpts...)
+	if err != nil {
+		bootstrap.Fail("dial friend request service", err)
+	}
 	defer frConn.Close()
 
-	aggrConn, err := grpc.NewClient(aggrReqAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
-	check(err, "dial aggregator service")
+	aggrConn, err := bootstrap.Dial(cfg.AggregatorAddr, dialOpts...)
+	if err != nil {
+		bootstrap.Fail("dial aggregator service", err)
+	}
 	defer aggrConn.Close()
 
-	msgConn, err := grpc.NewClient(messageAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
-	check(err, "dial message-base")
+	msgConn, err := bootstrap.Dial(cfg.MessageAddr, dialOpts...)
+	if err != nil {
+		bootstrap.Fail("dial message-base", err)
+	}
 	defer msgConn.Close()
 
-	convConn, err := grpc.NewClient(conversationAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
-	check(err, "dial conversation service")
+	convConn, err := bootstrap.Dial(cfg.ConversationAddr, dialOpts...)
+	if err != nil {
+		bootstrap.Fail("dial conversation service", err)
+	}
 	defer convConn.Close()
 
+	presenceConn, err := bootstrap.Dial(cfg.PresenceAddr, dialOpts...)
+	if err != nil {
+		bootstrap.Fail("dial presence", err)
+	}
+	defer presenceConn.Close()
+
+	blobs, err := blob.Open(cfg.Blob)
+	if err != nil {
+		bootstrap.Fail("open blob store", err)
+	}
+	if !cfg.AvatarURL.Enabled() {
+		slog.Warn("AVATAR_URL_SECRET not set, avatar uploads are disabled")
+	}
+
+	authClient := authpb.NewAuthServiceClient(authConn)
 	s := &server{
-		authClient:         authpb.NewAuthServiceClient(authConn),
+		authClient:         authClient,
 		frClient:           friendrequestpb.NewFriendRequestServiceClient(frConn),
 		userBaseClient:     userbasepb.NewUserServiceClient(userBaseConn),
 		aggrClient:         aggrpb.NewAggregatorServiceClient(aggrConn),
-		upstreamTO:         upstreamTimeout,
+		upstreamTO:         cfg.UpstreamTimeout,
 		messageClient:      messagepb.NewMessageServiceClient(msgConn),
 		conversationClient: conversationpb.NewConversationServiceClient(convConn),
+		presenceClient:     presencepb.NewPresenceServiceClient(presenceConn),
+		sessions:           newSessionCache(authClient, cfg.SessionCheckTTL, cfg.UpstreamTimeout),
+		tokens:             newTokenCache(authClient, cfg.SessionCheckTTL, cfg.UpstreamTimeout),
+		blobs:              blobs,
+		avatarURL:          cfg.AvatarURL,
+		typing:             newTypingHub(),
+		typingTTL:          cfg.Typing.TTL,
+	}
+	if exchange := openTypingFanout(cfg.Typing, s.typing); exchange != nil {
+		defer exchange.Close()
+		s.typingFanout = exchange
 	}
 
 	json := &runtime.JSONPb{
@@ -88,34 +190,61 @@ func main() {
 		},
 	}
 
-	mux := runtime.NewServeMux(runtime.WithMarshalerOption(runtime.MIMEWildcard, json))
+	limiter, err := newRateLimiter(cfg.RateLimit)
+	if err != nil {
+		bootstrap.Fail("create rate limiter", err)
+	}
+	defer limiter.Close()
+
+	mux := runtime.NewServeMux(
+		runtime.WithMarshalerOption(runtime.MIMEWildcard, json),
+		runtime.WithMiddlewares(nameSpanByRoute, requireScopes, limiter.middleware),
+	)
 	if err := gatewaypb.RegisterGatewayServiceHandlerServer(context.Background(), mux, s); err != nil {
-		log.Fatalf("register gateway handler: %v", err)
+		bootstrap.Fail("register gateway handler", err)
 	}
 
 	httpMux := http.NewServeMux()
 	httpMux.Handle("/healthz", http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusOK) }))
-	httpMux.Handle("/readyz", http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusOK) }))
-	httpMux.Handle("/", withLogging(withCORS(withAuth(withTimeout(mux, upstreamTimeout)))))
+	httpMux.Handle("/readyz", readyzHandler([]upstream{
+		{name: "auth", health: healthpb.NewHealthClient(authConn)},
+		{name: "user-base", health: healthpb.NewHealthClient(userBaseConn)},
+		{name: "friend-request-base", health: healthpb.NewHealthClient(frConn)},
+		{name: "aggregator", health: healthpb.NewHealthClient(aggrConn)},
+		{name: "message-base", health: healthpb.NewHealthClient(msgConn)},
+		{name: "conversation-base", health: healthpb.NewHealthClient(convConn)},
+		{name: "presence", health: healthpb.NewHealthClient(presenceConn)},
+	}, cfg.ReadyTimeout))
+	httpMux.Handle("/metrics", promhttp.Handler())
+	httpMux.Handle("/v1/auth/oidc/login", withLogging(s.oidcLoginHandler()))
+	httpMux.Handle("/v1/auth/oidc/callback", withLogging(s.oidcCallbackHandler(cfg.OIDCSuccessRedirect)))
+	httpMux.Handle("/v1/unsubscribe", withLogging(withCORS(s.unsubscribeHandler([]byte(cfg.UnsubscribeSecret)), cfg.CORS)))
+	httpMux.Handle("PUT /v1/users/{user_id}/avatar", withLogging(withCORS(withAuth(s.uploadAvatarHandler(cfg.AvatarMaxBytes, cfg.AvatarMaxDecodes), s.sessions, s.tokens), cfg.CORS)))
+	httpMux.Handle("DELETE /v1/users/{user_id}/avatar", withLogging(withCORS(withAuth(s.deleteAvatarHandler(), s.sessions, s.tokens), cfg.CORS)))
+	httpMux.Handle("GET /v1/avatars/{key}", withLogging(s.avatarHandler()))
+	httpMux.Handle("POST /v1/conversations/{conversation_id}/typing", withLogging(withCORS(withAuth(limiter.handler(s.typingHandler()), s.sessions, s.tokens), cfg.CORS)))
+	httpMux.Handle("GET /v1/typing/events", withLogging(withCORS(withAuth(s.typingEventsHandler(), s.sessions, s.tokens), cfg.CORS)))
+	httpMux.Handle("GET /v1/presence/events", withLogging(withCORS(withAuth(s.presenceEventsHandler(), s.sessions, s.tokens), cfg.CORS)))
+	httpMux.Handle("/", withLogging(withCORS(withAuth(withTimeout(mux, cfg.UpstreamTimeout), s.sessions, s.tokens), cfg.CORS)))
 
 	srv := &http.Server{
-		Addr:              httpAddr,
-		Handler:           httpMux,
+		Addr:              cfg.HTTPAddr,
+		Handler:           withTracing(withClientIP(httpMux, cfg.TrustForwarded)),
 		ReadHeaderTimeout: 5 * time.Second,
 		IdleTimeout:       60 * time.Second,
 	}
 
 	go func() {
-		log.Printf("api-rest-gateway HTTP on %s (auth=%s, user-base=%s)", httpAddr, authAddr, userBaseAddr)
+		slog.Info("HTTP server listening", "addr", cfg.HTTPAddr, "auth", cfg.AuthAddr, "user_base", cfg.UserBaseAddr)
 		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("http serve: %v", err)
+			bootstrap.Fail("http serve", err)
 		}
 	}()
 
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
 	<-sigCh
-	log.Println("shutting down gateway...")
+	slog.Info("shutting down gateway")
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
@@ -129,6 +258,10 @@ func (s *server) Ping(ctx context.Context, _ *authpb.Empty) (*authpb.Pong, error
 }
 
 func (s *server) CreateFriendRequest(ctx context.Context, req *friendrequestpb.CreateFriendRequestRequest) (*friendrequestpb.CreateFriendRequestResponse, error) {
+	// friend-request-base checks the policy of the receiver against the sender, so the sender must be the caller
+	if id, ok := ctx.Value(userIDKey).(int64); !ok || strconv.FormatInt(id, 10) != req.SenderId {
+		return nil, status.Error(codes.PermissionDenied, "you can only send friend requests as yourself")
+	}
 	c, cancel := context.WithTimeout(ctx, s.upstreamTO)
 	defer cancel()
 	return s.frClient.CreateFriendRequest(c, req)
@@ -146,6 +279,118 @@ func (s *server) Login(ctx context.Context, req *authpb.LoginRequest) (*authpb.L
 	return s.authClient.Login(c, req)
 }
 
+func (s *server) VerifyLoginChallenge(ctx context.Context, req *authpb.VerifyLoginChallengeRequest) (*authpb.LoginResponse, error) {
+	c, cancel := context.WithTimeout(ctx, s.upstreamTO)
+	defer cancel()
+	return s.authClient.VerifyLoginChallenge(c, req)
+}
+
+func (s *server) EnrollTOTP(ctx context.Context, req *authpb.EnrollTOTPRequest) (*authpb.EnrollTOTPResponse, error) {
+	if err := requireSelf(ctx, req.UserId); err != nil {
+		return nil, err
+	}
+	c, cancel := context.WithTimeout(ctx, s.upstreamTO)
+	defer cancel()
+	return s.authClient.EnrollTOTP(c, req)
+}
+
+func (s *server) ConfirmTOTP(ctx context.Context, req *authpb.ConfirmTOTPRequest) (*authpb.ConfirmTOTPResponse, error) {
+	if err := requireSelf(ctx, req.UserId); err != nil {
+		return nil, err
+	}
+	c, cancel := context.WithTimeout(ctx, s.upstreamTO)
+	defer cancel()
+	return s.authClient.ConfirmTOTP(c, req)
+}
+
+func (s *server) DisableTOTP(ctx context.Context, req *authpb.DisableTOTPRequest) (*authpb.DisableTOTPResponse, error) {
+	if err := requireSelf(ctx, req.UserId); err != nil {
+		return nil, err
+	}
+	c, cancel := context.WithTimeout(ctx, s.upstreamTO)
+	defer cancel()
+	return s.authClient.DisableTOTP(c, req)
+}
+
+// requireSelf lets users change only their own account
+func requireSelf(ctx context.Context, userID int64) error {
+	if id, ok := ctx.Value(userIDKey).(int64); !ok || id != userID {
+		return status.Error(codes.PermissionDenied, "you can only change your own account")
+	}
+	return nil
+}
+
+func (s *server) ListSessions(ctx context.Context, req *authpb.ListSessionsRequest) (*authpb.ListSessionsResponse, error) {
+	if err := requireSelf(ctx, req.UserId); err != nil {
+		return nil, err
+	}
+	c, cancel := context.WithTimeout(ctx, s.upstreamTO)
+	defer cancel()
+	return s.authClient.ListSessions(c, req)
+}
+
+func (s *server) RevokeSession(ctx context.Context, req *authpb.RevokeSessionRequest) (*authpb.RevokeSessionResponse, error) {
+	if err := requireSelf(ctx, req.UserId); err != nil {
+		return nil, err
+	}
+	c, cancel := context.WithTimeout(ctx, s.upstreamTO)
+	defer cancel()
+	resp, err := s.authClient.RevokeSession(c, req)
+	if err == nil {
+		s.sessions.forget(req.UserId, req.SessionId)
+	}
+	return resp, err
+}
+
+func (s *server) CreateAccessToken(ctx context.Context, req *authpb.CreateAccessTokenRequest) (*authpb.CreateAccessTokenResponse, error) {
+	if err := requireSelf(ctx, req.UserId); err != nil {
+		return nil, err
+	}
+	c, cancel := context.WithTimeout(ctx, s.upstreamTO)
+	defer cancel()
+	return s.authClient.CreateAccessToken(c, req)
+}
+
+func (s *server) ListAccessTokens(ctx context.Context, req *authpb.ListAccessTokensRequest) (*authpb.ListAccessTokensResponse, error) {
+	if err := requireSelf(ctx, req.UserId); err != nil {
+		return nil, err
+	}
+	c, cancel := context.WithTimeout(ctx, s.upstreamTO)
+	defer cancel()
+	return s.authClient.ListAccessTokens(c, req)
+}
+
+func (s *server) RevokeAccessToken(ctx context.Context, req *authpb.RevokeAccessTokenRequest) (*authpb.RevokeAccessTokenResponse, error) {
+	if err := requireSelf(ctx, req.UserId); err != nil {
+		return nil, err
+	}
+	c, cancel := context.WithTimeout(ctx, s.upstreamTO)
+	defer cancel()
+	resp, err := s.authClient.RevokeAccessToken(c, req)
+	if err == nil {
+		s.tokens.forget(req.TokenId)
+	}
+	return resp, err
+}
+
+func (s *server) CreateBot(ctx context.Context, req *userbasepb.CreateBotRequest) (*userbasepb.CreateBotResponse, error) {
+	if err := requireSelf(ctx, req.OwnerId); err != nil {
+		return nil, err
+	}
+	c, cancel := context.WithTimeout(ctx, s.upstreamTO)
+	defer cancel()
+	return s.userBaseClient.CreateBot(c, req)
+}
+
+func (s *server) ChangePassword(ctx context.Context, req *userbasepb.ChangePasswordRequest) (*userbasepb.ChangePasswordResponse, error) {
+	if err := requireSelf(ctx, req.UserId); err != nil {
+		return nil, err
+	}
+	c, cancel := context.WithTimeout(ctx, s.upstreamTO)
+	defer cancel()
+	return s.userBaseClient.ChangePassword(c, req)
+}
+
 func (s *server) CreateUser(ctx context.Context, req *userbasepb.CreateUserRequest) (*userbasepb.CreateUserResponse, error) {
 	c, cancel := context.WithTimeout(ctx, s.upstreamTO)
 	defer cancel()
@@ -159,12 +404,22 @@ func (s *server) ListFriendRequests(ctx context.Context, req *friendrequestpb.Li
 }
 
 func (s *server) ListUsers(ctx context.Context, req *userbasepb.ListUsersRequest) (*userbasepb.ListUsersResponse, error) {
+	// which emails are listed depends on who asks, never on the body
+	id, ok := ctx.Value(userIDKey).(int64)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "login required")
+	}
+	req.ViewerId = id
 	c, cancel := context.WithTimeout(ctx, s.upstreamTO)
 	defer cancel()
 	return s.userBaseClient.ListUsers(c, req)
 }
 
 func (s *server) FetchUserFriends(ctx context.Context, req *aggrpb.FetchUserFriendsRequest) (*aggrpb.FetchUserFriendsResponse, error) {
+	// the aggregator lists the users with the emails req.UserId may see
+	if id, ok := ctx.Value(userIDKey).(int64); !ok || strconv.FormatInt(id, 10) != req.UserId {
+		return nil, status.Error(codes.PermissionDenied, "you can only list your own friends")
+	}
 	c, cancel := context.WithTimeout(ctx, s.upstreamTO)
 	defer cancel()
 	return s.aggrClient.FetchUserFriends(c, req)
@@ -182,6 +437,16 @@ func (s *server) ListMessages(ctx context.Context, req *messagepb.ListMessagesRe
 	return s.messageClient.ListMessages(c, req)
 }
 
+func (s *server) MarkConversationRead(ctx context.Context, req *messagepb.MarkConversationReadRequest) (*messagepb.MarkConversationReadResponse, error) {
+	// marking read suppresses the unread digest of the user, so only they may do it
+	if err := requireSelf(ctx, req.UserId); err != nil {
+		return nil, err
+	}
+	c, cancel := context.WithTimeout(ctx, s.upstreamTO)
+	defer cancel()
+	return s.messageClient.MarkConversationRead(c, req)
+}
+
 func (s *server) CreateConversation(ctx context.Context, req *conversationpb.CreateConversationRequest) (*conversationpb.CreateConversationResponse, error) {
 	c, cancel := context.WithTimeout(ctx, s.upstreamTO)
 	defer cancel()
@@ -194,20 +459,79 @@ func (s *server) ListConversations(ctx context.Context, req *conversationpb.List
 	return s.conversationClient.ListConversations(c, req)
 }
 
-func env(k, def string) string {
-	if v := os.Getenv(k); v != "" {
-		return v
+func (s *server) GetNotificationPreferences(ctx context.Context, req *userbasepb.GetNotificationPreferencesRequest) (*userbasepb.GetNotificationPreferencesResponse, error) {
+	if err := requireSelf(ctx, req.UserId); err != nil {
+		return nil, err
 	}
-	return def
+	c, cancel := context.WithTimeout(ctx, s.upstreamTO)
+	defer cancel()
+	return s.userBaseClient.GetNotificationPreferences(c, req)
 }
 
-func durEnv(k string, def time.Duration) time.Duration {
-	if v := os.Getenv(k); v != "" {
-		if d, err := time.ParseDuration(v); err == nil {
-			return d
-		}
+func (s *server) UpdateNotificationPreference(ctx context.Context, req *userbasepb.UpdateNotificationPreferenceRequest) (*userbasepb.UpdateNotificationPreferenceResponse, error) {
+	if err := requireSelf(ctx, req.GetPreference().GetUserId()); err != nil {
+		return nil, err
+	}
+	c, cancel := context.WithTimeout(ctx, s.upstreamTO)
+	defer cancel()
+	return s.userBaseClient.UpdateNotificationPreference(c, req)
+}
+
+func (s *server) GetUserSettings(ctx context.Context, req *userbasepb.GetUserSettingsRequest) (*userbasepb.UserSettings, error) {
+	if err := requireSelf(ctx, req.UserId); err != nil {
+		return nil, err
+	}
+	c, cancel := context.WithTimeout(ctx, s.upstreamTO)
+	defer cancel()
+	return s.userBaseClient.GetUserSettings(c, req)
+}
+
+func (s *server) UpdateUserSettings(ctx context.Context, req *userbasepb.UpdateUserSettingsRequest) (*userbasepb.UserSettings, error) {
+	if err := requireSelf(ctx, req.GetSettings().GetUserId()); err != nil {
+		return nil, err
+	}
+	c, cancel := context.WithTimeout(ctx, s.upstreamTO)
+	defer cancel()
+	return s.userBaseClient.UpdateUserSettings(c, req)
+}
+
+func (s *server) UpdateProfile(ctx context.Context, req *userbasepb.UpdateProfileRequest) (*userbasepb.User, error) {
+	if err := requireSelf(ctx, req.UserId); err != nil {
+		return nil, err
 	}
-	return def
+	c, cancel := context.WithTimeout(ctx, s.upstreamTO)
+	defer cancel()
+	return s.userBaseClient.UpdateProfile(c, req)
+}
+
+func (s *server) Heartbeat(ctx context.Context, req *presencepb.HeartbeatRequest) (*presencepb.HeartbeatResponse, error) {
+	if err := requireSelf(ctx, req.UserId); err != nil {
+		return nil, err
+	}
+	c, cancel := context.WithTimeout(ctx, s.upstreamTO)
+	defer cancel()
+	return s.presenceClient.Heartbeat(c, req)
+}
+
+func (s *server) Disconnect(ctx context.Context, req *presencepb.DisconnectRequest) (*presencepb.DisconnectResponse, error) {
+	if err := requireSelf(ctx, req.UserId); err != nil {
+		return nil, err
+	}
+	c, cancel := context.WithTimeout(ctx, s.upstreamTO)
+	defer cancel()
+	return s.presenceClient.Disconnect(c, req)
+}
+
+func (s *server) GetPresence(ctx context.Context, req *presencepb.GetPresenceRequest) (*presencepb.GetPresenceResponse, error) {
+	// users see the presence of themselves and their friends, never of everyone like a service
+	id, ok := ctx.Value(userIDKey).(int64)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "login required")
+	}
+	req.ViewerId = id
+	c, cancel := context.WithTimeout(ctx, s.upstreamTO)
+	defer cancel()
+	return s.presenceClient.GetPresence(c, req)
 }
 
 func withTimeout(next http.Handler, d time.Duration) http.Handler {
@@ -218,14 +542,11 @@ func withTimeout(next http.Handler, d time.Duration) http.Handler {
 	})
 }
 
-func withCORS(next http.Handler) http.Handler {
-	allowOrigin := env("CORS_ALLOW_ORIGIN", "*")
-	allowHeaders := env("CORS_ALLOW_HEADERS", "Content-Type, Authorization")
-	allowMethods := env("CORS_ALLOW_METHODS", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+func withCORS(next http.Handler, cors corsConfig) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", allowOrigin)
-		w.Header().Set("Access-Control-Allow-Headers", allowHeaders)
-		w.Header().Set("Access-Control-Allow-Methods", allowMethods)
+		w.Header().Set("Access-Control-Allow-Origin", cors.AllowOrigin)
+		w.Header().Set("Access-Control-Allow-Headers", cors.AllowHeaders)
+		w.Header().Set("Access-Control-Allow-Methods", cors.AllowMethods)
 		if r.Method == http.MethodOptions {
 			w.WriteHeader(http.StatusNoContent)
 			return
@@ -234,16 +555,115 @@ func withCORS(next http.Handler) http.Handler {
 	})
 }
 
+// withLogging writes a JSON access log line per request, in the same format as the gRPC services,
+// and records the request metrics
 func withLogging(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
-		next.ServeHTTP(w, r)
-		log.Printf("%s %s %s", r.Method, r.URL.Path, time.Since(start))
+		entry := &accessEntry{}
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r.WithContext(context.WithValue(r.Context(), accessEntryKey, entry)))
+
+		route := entry.route
+		if route == "" {
+			// not a grpc-gateway route: the pattern of httpMux, "/" for requests rejected before
+			// routing (e.g. by withAuth) or not matching any route
+			route = r.Pattern
+		}
+		observeRequest(r.Method, route, rec.status, start)
+
+		attrs := []any{"method", r.Method, "path", r.URL.Path, "status", rec.status, "latency_ms", bootstrap.LatencyMS(start)}
+		if entry.userID != 0 {
+			attrs = append(attrs, "user_id", strconv.FormatInt(entry.userID, 10))
+		}
+		if id := bootstrap.TraceID(r.Context()); id != "" {
+			attrs = append(attrs, "trace_id", id)
+		}
+		level := slog.LevelInfo
+		if rec.status >= http.StatusInternalServerError {
+			level = slog.LevelError
+		}
+		slog.Log(r.Context(), level, "http request", attrs...)
 	})
 }
 
-func check(err error, msg string) {
-	if err != nil {
-		log.Fatalf("%s: %v", msg, err)
+// withTracing starts the root span of every request except the health probes; the gRPC clients
+// carry it on to the services
+func withTracing(next http.Handler) http.Handler {
+	return otelhttp.NewHandler(next, "gateway",
+		otelhttp.WithFilter(func(r *http.Request) bool {
+			return r.URL.Path != "/healthz" && r.URL.Path != "/readyz" && r.URL.Path != "/metrics"
+		}),
+		otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+			return r.Method + " " + r.URL.Path
+		}),
+	)
+}
+
+// nameSpanByRoute renames the request span after the route template and labels the metrics with
+// it, paths with IDs would give every resource a span name and a series of its own
+func nameSpanByRoute(next runtime.HandlerFunc) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, pathParams map[string]string) {
+		if route, ok := routeTemplate(r.Context()); ok {
+			span := trace.SpanFromContext(r.Context())
+			span.SetName(r.Method + " " + route)
+			span.SetAttributes(attribute.String("http.route", route))
+			if entry, ok := r.Context().Value(accessEntryKey).(*accessEntry); ok {
+				entry.route = route
+			}
+		}
+		next(w, r, pathParams)
+	}
+}
+
+// routeTemplate returns the gateway.proto route a request matched, e.g. /v1/conversations/{conversation_id}/read.
+// Middlewares run before grpc-gateway sets runtime.HTTPPathPattern, the compiled pattern is already there.
+func routeTemplate(ctx context.Context) (string, bool) {
+	pattern, ok := runtime.HTTPPattern(ctx)
+	if !ok {
+		return "", false
+	}
+	return strings.ReplaceAll(pattern.String(), "=*}", "}"), true
+}
+
+// accessEntry collects what the access log reports about a request while it passes the other middlewares
+type accessEntry struct {
+	userID int64
+	route  string
+}
+
+// statusRecorder remembers the status code written by the handler
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(code int) {
+	r.status = code
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// forwardCaller passes the authenticated user and session and the client IP and user agent to the
+// services, for their access logs, the login lockout and the sessions of auth
+func forwardCaller(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	if id, ok := ctx.Value(userIDKey).(int64); ok {
+		ctx = metadata.AppendToOutgoingContext(ctx, bootstrap.UserIDHeader, strconv.FormatInt(id, 10))
+	}
+	if id, ok := ctx.Value(sessionIDKey).(int64); ok {
+		ctx = metadata.AppendToOutgoingContext(ctx, bootstrap.SessionIDHeader, strconv.FormatInt(id, 10))
+	}
+	if ip, ok := ctx.Value(clientIPKey).(string); ok && ip != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, bootstrap.ClientIPHeader, ip)
+	}
+	// grpc-gateway puts the User-Agent of the request in the incoming metadata
+	if ua := metadata.ValueFromIncomingContext(ctx, "grpcgateway-user-agent"); len(ua) > 0 {
+		ctx = metadata.AppendToOutgoingContext(ctx, bootstrap.UserAgentHeader, ua[0])
+	} else if ua, ok := ctx.Value(userAgentKey).(string); ok && ua != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, bootstrap.UserAgentHeader, ua)
 	}
+	return invoker(ctx, method, req, reply, cc, opts...)
 }
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	errchecks "github.com/Costin2000/GoChat---Schwarz-Internship---2025/pkg"
+	aggrpb "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/aggregator/proto"
+	friendrequestpb "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/friend-request-base/proto"
+	messagepb "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/message-base/proto"
+	userbasepb "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/user-base/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+type fakeUserBaseClient struct {
+	userbasepb.UserServiceClient
+	listed *userbasepb.ListUsersRequest
+	// the avatar key of every user, set by SetAvatar
+	avatars map[int64]string
+}
+
+func (f *fakeUserBaseClient) ListUsers(ctx context.Context, in *userbasepb.ListUsersRequest, _ ...grpc.CallOption) (*userbasepb.ListUsersResponse, error) {
+	f.listed = in
+	return &userbasepb.ListUsersResponse{}, nil
+}
+
+func (f *fakeUserBaseClient) SetAvatar(ctx context.Context, in *userbasepb.SetAvatarRequest, _ ...grpc.CallOption) (*userbasepb.SetAvatarResponse, error) {
+	if f.avatars == nil {
+		f.avatars = map[int64]string{}
+	}
+	prev := f.avatars[in.UserId]
+	f.avatars[in.UserId] = in.AvatarKey
+	return &userbasepb.SetAvatarResponse{User: &userbasepb.User{Id: in.UserId}, PreviousAvatarKey: prev}, nil
+}
+
+func TestListUsers_ViewerIsTheCaller(t *testing.T) {
+	userBase := &fakeUserBaseClient{}
+	s := &server{userBaseClient: userBase, upstreamTO: time.Second}
+	ctx := context.WithValue(context.Background(), userIDKey, int64(42))
+
+	// a viewer of 0 would list every email
+	_, err := s.ListUsers(ctx, &userbasepb.ListUsersRequest{ViewerId: 0})
+
+	errchecks.Assert(t, err, errchecks.IsNil)
+	if userBase.listed.GetViewerId() != 42 {
+		t.Errorf("listed users for viewer %d, want 42", userBase.listed.GetViewerId())
+	}
+}
+
+func TestFetchUserFriends_OnlyOwnFriends(t *testing.T) {
+	s := &server{upstreamTO: time.Second}
+	ctx := context.WithValue(context.Background(), userIDKey, int64(42))
+
+	_, err := s.FetchUserFriends(ctx, &aggrpb.FetchUserFriendsRequest{UserId: "7", ShowFriends: true})
+
+	errchecks.Assert(t, err, errchecks.HasStatusCode(codes.PermissionDenied))
+}
+
+func TestUpdateProfile_OnlyOwnProfile(t *testing.T) {
+	s := &server{upstreamTO: time.Second}
+	ctx := context.WithValue(context.Background(), userIDKey, int64(42))
+
+	_, err := s.UpdateProfile(ctx, &userbasepb.UpdateProfileRequest{UserId: 7, Bio: "not mine"})
+
+	errchecks.Assert(t, err, errchecks.HasStatusCode(codes.PermissionDenied))
+}
+
+func TestNotificationPreferences_OnlyOwnPreferences(t *testing.T) {
+	s := &server{upstreamTO: time.Second}
+	ctx := context.WithValue(context.Background(), userIDKey, int64(42))
+
+	_, err := s.GetNotificationPreferences(ctx, &userbasepb.GetNotificationPreferencesRequest{UserId: 7})
+	errchecks.Assert(t, err, errchecks.HasStatusCode(codes.PermissionDenied))
+
+	_, err = s.UpdateNotificationPreference(ctx, &userbasepb.UpdateNotificationPreferenceRequest{
+		Preference: &userbasepb.NotificationPreference{UserId: 7},
+	})
+	errchecks.Assert(t, err, errchecks.HasStatusCode(codes.PermissionDenied))
+}
+
+func TestMarkConversationRead_OnlyOwnReadState(t *testing.T) {
+	s := &server{upstreamTO: time.Second}
+	ctx := context.WithValue(context.Background(), userIDKey, int64(42))
+
+	_, err := s.MarkConversationRead(ctx, &messagepb.MarkConversationReadRequest{ConversationId: 1, UserId: 7})
+
+	errchecks.Assert(t, err, errchecks.HasStatusCode(codes.PermissionDenied))
+}
+
+func TestCreateFriendRequest_ForgedSender(t *testing.T) {
+	s := &server{upstreamTO: time.Second}
+	ctx := context.WithValue(context.Background(), userIDKey, int64(42))
+
+	// 7 may be a friend of a friend of the receiver, the caller must not borrow that
+	_, err := s.CreateFriendRequest(ctx, &friendrequestpb.CreateFriendRequestRequest{SenderId: "7", ReceiverId: "9"})
+
+	errchecks.Assert(t, err, errchecks.HasStatusCode(codes.PermissionDenied))
+}
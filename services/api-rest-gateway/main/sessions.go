@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	authpb "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/auth/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// errSessionRevoked means the session of a valid JWT was signed out or expired
+var errSessionRevoked = errors.New("session was revoked")
+
+type sessionKey struct {
+	userID    int64
+	sessionID int64
+}
+
+// sessionCache asks auth whether the session of a token is still active, and remembers active
+// sessions for ttl so that not every request costs a call. A session revoked on another replica
+// keeps working here for at most ttl.
+type sessionCache struct {
+	auth    authpb.AuthServiceClient
+	ttl     time.Duration
+	timeout time.Duration
+	now     func() time.Time
+
+	mu    sync.Mutex
+	until map[sessionKey]time.Time
+}
+
+func newSessionCache(auth authpb.AuthServiceClient, ttl, timeout time.Duration) *sessionCache {
+	return &sessionCache{auth: auth, ttl: ttl, timeout: timeout, now: time.Now, until: map[sessionKey]time.Time{}}
+}
+
+// check returns errSessionRevoked if the session is not active, other errors if auth cannot tell
+func (c *sessionCache) check(ctx context.Context, userID, sessionID int64) error {
+	key := sessionKey{userID: userID, sessionID: sessionID}
+	now := c.now()
+
+	c.mu.Lock()
+	until, ok := c.until[key]
+	c.mu.Unlock()
+	if ok && now.Before(until) {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	_, err := c.auth.CheckSession(ctx, &authpb.CheckSessionRequest{UserId: userID, SessionId: sessionID})
+	if status.Code(err) == codes.Unauthenticated {
+		c.forget(userID, sessionID)
+		return errSessionRevoked
+	}
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.until[key] = now.Add(c.ttl)
+	// drop the expired entries now and then, the map would otherwise grow with every login
+	if len(c.until) > 10000 {
+		for k, u := range c.until {
+			if !now.Before(u) {
+				delete(c.until, k)
+			}
+		}
+	}
+	return nil
+}
+
+// forget makes the next request of the session ask auth again
+func (c *sessionCache) forget(userID, sessionID int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.until, sessionKey{userID: userID, sessionID: sessionID})
+}
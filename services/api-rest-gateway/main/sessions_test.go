@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	authpb "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/auth/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type fakeAuthClient struct {
+	authpb.AuthServiceClient
+	checks int
+	err    error
+}
+
+func (f *fakeAuthClient) CheckSession(ctx context.Context, in *authpb.CheckSessionRequest, _ ...grpc.CallOption) (*authpb.CheckSessionResponse, error) {
+	f.checks++
+	return &authpb.CheckSessionResponse{}, f.err
+}
+
+func Test_sessionCache_check(t *testing.T) {
+	auth := &fakeAuthClient{}
+	now := time.Unix(1000, 0)
+	c := newSessionCache(auth, 30*time.Second, time.Second)
+	c.now = func() time.Time { return now }
+
+	// active sessions are asked once per ttl
+	for i := 0; i < 3; i++ {
+		if err := c.check(context.Background(), 42, 7); err != nil {
+			t.Fatalf("check: %v", err)
+		}
+	}
+	if auth.checks != 1 {
+		t.Errorf("asked auth %d times, want 1", auth.checks)
+	}
+
+	now = now.Add(31 * time.Second)
+	auth.err = status.Error(codes.Unauthenticated, "revoked")
+	if err := c.check(context.Background(), 42, 7); !errors.Is(err, errSessionRevoked) {
+		t.Errorf("after ttl got %v, want errSessionRevoked", err)
+	}
+
+	// auth unavailable is not a sign out
+	auth.err = status.Error(codes.Unavailable, "down")
+	if err := c.check(context.Background(), 42, 7); err == nil || errors.Is(err, errSessionRevoked) {
+		t.Errorf("auth down got %v, want another error", err)
+	}
+
+	auth.err = nil
+	if err := c.check(context.Background(), 42, 7); err != nil {
+		t.Fatalf("check: %v", err)
+	}
+	c.forget(42, 7)
+	if err := c.check(context.Background(), 42, 7); err != nil {
+		t.Fatalf("check: %v", err)
+	}
+	if auth.checks != 5 {
+		t.Errorf("asked auth %d times, want 5", auth.checks)
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	authpb "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/auth/proto"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// accessTokenPrefix tells personal access tokens from JWTs, see auth
+const accessTokenPrefix = "gcp_"
+
+// errInvalidAccessToken means auth does not know the token, or it expired or was revoked
+var errInvalidAccessToken = errors.New("invalid access token")
+
+// routeScopes are the routes personal access tokens may call, with the scope they need ("" for
+// any token). Everything else, e.g. managing the account, its sessions and its tokens, needs a login.
+var routeScopes = map[string]string{
+	"GET /v1/ping": "",
+
+	"POST /v1/conversations":                                  "messages:read",
+	"GET /v1/conversations/{filter.conversation_id}/messages": "messages:read",
+	"POST /v1/conversations/{conversation_id}/read":           "messages:read",
+	"POST /v1/users:list":                                     "messages:read",
+
+	"POST /v1/message":      "messages:write",
+	"POST /v1/conversation": "messages:write",
+
+	"POST /v1/friend-request":                      "friends:manage",
+	"PATCH /v1/friend-request/{friend_request.id}": "friends:manage",
+	"POST /v1/friend-requests":                     "friends:manage",
+	"POST /v1/friends":                             "friends:manage",
+}
+
+// requireScopes is a grpc-gateway middleware that keeps personal access tokens to the routes their
+// scopes allow; requests with a JWT pass unchanged
+func requireScopes(next runtime.HandlerFunc) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, pathParams map[string]string) {
+		scopes, ok := r.Context().Value(scopesKey).([]string)
+		if !ok {
+			next(w, r, pathParams)
+			return
+		}
+		route, _ := routeTemplate(r.Context())
+		scope, allowed := routeScopes[r.Method+" "+route]
+		if !allowed {
+			http.Error(w, "not available to access tokens, log in instead", http.StatusForbidden)
+			return
+		}
+		if scope != "" && !slices.Contains(scopes, scope) {
+			http.Error(w, "access token lacks the "+scope+" scope", http.StatusForbidden)
+			return
+		}
+		next(w, r, pathParams)
+	}
+}
+
+type tokenGrant struct {
+	userID  int64
+	tokenID int64
+	scopes  []string
+	until   time.Time
+}
+
+// tokenCache asks auth who a personal access token acts as, and remembers the answer for ttl like
+// sessionCache does. Tokens are cached by their hash, not in plain text.
+type tokenCache struct {
+	auth    authpb.AuthServiceClient
+	ttl     time.Duration
+	timeout time.Duration
+	now     func() time.Time
+
+	mu     sync.Mutex
+	grants map[[sha256.Size]byte]tokenGrant
+}
+
+func newTokenCache(auth authpb.AuthServiceClient, ttl, timeout time.Duration) *tokenCache {
+	return &tokenCache{auth: auth, ttl: ttl, timeout: timeout, now: time.Now, grants: map[[sha256.Size]byte]tokenGrant{}}
+}
+
+// authenticate returns errInvalidAccessToken if auth rejects the token, other errors if auth cannot tell
+func (c *tokenCache) authenticate(ctx context.Context, token string) (tokenGrant, error) {
+	key := sha256.Sum256([]byte(token))
+	now := c.now()
+
+	c.mu.Lock()
+	grant, ok := c.grants[key]
+	c.mu.Unlock()
+	if ok && now.Before(grant.until) {
+		return grant, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	resp, err := c.auth.AuthenticateAccessToken(ctx, &authpb.AuthenticateAccessTokenRequest{Token: token})
+	if status.Code(err) == codes.Unauthenticated {
+		c.mu.Lock()
+		delete(c.grants, key)
+		c.mu.Unlock()
+		return tokenGrant{}, errInvalidAccessToken
+	}
+	if err != nil {
+		return tokenGrant{}, err
+	}
+
+	grant = tokenGrant{userID: resp.UserId, tokenID: resp.TokenId, scopes: resp.Scopes, until: now.Add(c.ttl)}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.grants[key] = grant
+	if len(c.grants) > 10000 {
+		for k, g := range c.grants {
+			if !now.Before(g.until) {
+				delete(c.grants, k)
+			}
+		}
+	}
+	return grant, nil
+}
+
+// forget makes the next request with the token ask auth again
+func (c *tokenCache) forget(tokenID int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for k, g := range c.grants {
+		if g.tokenID == tokenID {
+			delete(c.grants, k)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	gatewaypb "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/api-rest-gateway/proto"
+	authpb "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/auth/proto"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func Test_requireScopes(t *testing.T) {
+	mux := runtime.NewServeMux(runtime.WithMiddlewares(requireScopes))
+	if err := gatewaypb.RegisterGatewayServiceHandlerServer(context.Background(), mux, &gatewaypb.UnimplementedGatewayServiceServer{}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		method string
+		path   string
+		scopes []string // nil for a JWT
+		want   int
+	}{
+		// every route of routeScopes, reaching the unimplemented server
+		{method: http.MethodGet, path: "/v1/ping", scopes: []string{}, want: http.StatusNotImplemented},
+		{method: http.MethodPost, path: "/v1/conversations", scopes: []string{"messages:read"}, want: http.StatusNotImplemented},
+		{method: http.MethodGet, path: "/v1/conversations/5/messages", scopes: []string{"messages:read"}, want: http.StatusNotImplemented},
+		{method: http.MethodPost, path: "/v1/conversations/5/read", scopes: []string{"messages:read"}, want: http.StatusNotImplemented},
+		{method: http.MethodPost, path: "/v1/users:list", scopes: []string{"messages:read"}, want: http.StatusNotImplemented},
+		{method: http.MethodPost, path: "/v1/message", scopes: []string{"messages:write"}, want: http.StatusNotImplemented},
+		{method: http.MethodPost, path: "/v1/conversation", scopes: []string{"messages:write"}, want: http.StatusNotImplemented},
+		{method: http.MethodPost, path: "/v1/friend-request", scopes: []string{"friends:manage"}, want: http.StatusNotImplemented},
+		{method: http.MethodPatch, path: "/v1/friend-request/5", scopes: []string{"friends:manage"}, want: http.StatusNotImplemented},
+		{method: http.MethodPost, path: "/v1/friend-requests", scopes: []string{"friends:manage"}, want: http.StatusNotImplemented},
+		{method: http.MethodPost, path: "/v1/friends", scopes: []string{"friends:manage"}, want: http.StatusNotImplemented},
+
+		{method: http.MethodPost, path: "/v1/message", scopes: []string{"messages:read"}, want: http.StatusForbidden},
+		{method: http.MethodGet, path: "/v1/users/42/sessions", scopes: []string{"messages:read", "messages:write", "friends:manage"}, want: http.StatusForbidden},
+		{method: http.MethodPost, path: "/v1/users/42/tokens", scopes: []string{"messages:read", "messages:write", "friends:manage"}, want: http.StatusForbidden},
+		{method: http.MethodGet, path: "/v1/users/42/sessions", want: http.StatusNotImplemented},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, tt.path, strings.NewReader("{}"))
+			if tt.scopes != nil {
+				r = r.WithContext(context.WithValue(r.Context(), scopesKey, tt.scopes))
+			}
+			w := httptest.NewRecorder()
+
+			mux.ServeHTTP(w, r)
+
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", w.Code, tt.want, w.Body.String())
+			}
+		})
+	}
+}
+
+func (f *fakeAuthClient) AuthenticateAccessToken(ctx context.Context, in *authpb.AuthenticateAccessTokenRequest, _ ...grpc.CallOption) (*authpb.AuthenticateAccessTokenResponse, error) {
+	f.checks++
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &authpb.AuthenticateAccessTokenResponse{UserId: 7, TokenId: 3, Scopes: []string{"messages:read"}}, nil
+}
+
+func Test_tokenCache_authenticate(t *testing.T) {
+	auth := &fakeAuthClient{}
+	c := newTokenCache(auth, 30*time.Second, time.Second)
+
+	for i := 0; i < 3; i++ {
+		grant, err := c.authenticate(context.Background(), "gcp_secret")
+		if err != nil || grant.userID != 7 {
+			t.Fatalf("authenticate = %+v, %v", grant, err)
+		}
+	}
+	if auth.checks != 1 {
+		t.Errorf("asked auth %d times, want 1", auth.checks)
+	}
+
+	c.forget(3)
+	auth.err = status.Error(codes.Unauthenticated, "revoked")
+	if _, err := c.authenticate(context.Background(), "gcp_secret"); !errors.Is(err, errInvalidAccessToken) {
+		t.Errorf("after revoke got %v, want errInvalidAccessToken", err)
+	}
+}
@@ -0,0 +1,290 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Costin2000/GoChat---Schwarz-Internship---2025/pkg/fanout"
+	conversationpb "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/conversation-base/proto"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// typingExchange carries the typing signals between the gateway replicas
+	typingExchange = "typing"
+	// typingBuffer is how many signals a stream may fall behind, later ones are dropped for it
+	typingBuffer = 16
+)
+
+type typingConfig struct {
+	// how long a "typing" signal shows without a new one; clients repeat it about every TTL/2
+	TTL time.Duration `env:"TYPING_TTL" default:"6s"`
+	// fans the signals out to the other gateway replicas; without it they reach only the streams
+	// of this one
+	RabbitMQAddr string `env:"RABBITMQ_ADDR"`
+}
+
+func (c *typingConfig) Validate() error {
+	if c.TTL <= 0 {
+		return fmt.Errorf("TYPING_TTL must be positive")
+	}
+	return nil
+}
+
+// typingEvent is what the streams of the other participants receive. It is never stored.
+type typingEvent struct {
+	ConversationID int64 `json:"conversation_id,string"`
+	UserID         int64 `json:"user_id,string"`
+	Typing         bool  `json:"typing"`
+	// when the signal runs out, a "typing": false event follows then unless it is repeated
+	ExpiresAt time.Time `json:"expires_at,omitzero"`
+}
+
+// typingSignal is a typingEvent on its way to the streams of the recipients, on any replica
+type typingSignal struct {
+	Event      typingEvent `json:"event"`
+	Recipients []int64     `json:"recipients"`
+}
+
+type typingKey struct {
+	conversationID int64
+	userID         int64
+}
+
+// typingHub delivers the signals to the event streams of this replica, and ends the signals that
+// are not repeated within the TTL with a "typing": false event. Every replica runs the same timers
+// from ExpiresAt, so they agree on when a signal ends.
+type typingHub struct {
+	mu      sync.Mutex
+	streams map[int64]map[chan typingEvent]struct{} // by user
+	active  map[typingKey]*time.Timer
+	now     func() time.Time
+}
+
+func newTypingHub() *typingHub {
+	return &typingHub{
+		streams: map[int64]map[chan typingEvent]struct{}{},
+		active:  map[typingKey]*time.Timer{},
+		now:     time.Now,
+	}
+}
+
+func (h *typingHub) subscribe(userID int64) chan typingEvent {
+	ch := make(chan typingEvent, typingBuffer)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.streams[userID] == nil {
+		h.streams[userID] = map[chan typingEvent]struct{}{}
+	}
+	h.streams[userID][ch] = struct{}{}
+	return ch
+}
+
+func (h *typingHub) unsubscribe(userID int64, ch chan typingEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.streams[userID], ch)
+	if len(h.streams[userID]) == 0 {
+		delete(h.streams, userID)
+	}
+}
+
+func (h *typingHub) deliver(sig typingSignal) {
+	ev := sig.Event
+	if ev.Typing && !ev.ExpiresAt.After(h.now()) {
+		// held up on the way, it has run out already
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	key := typingKey{conversationID: ev.ConversationID, userID: ev.UserID}
+	if t, ok := h.active[key]; ok {
+		t.Stop()
+		delete(h.active, key)
+	}
+	if ev.Typing {
+		var t *time.Timer
+		t = time.AfterFunc(ev.ExpiresAt.Sub(h.now()), func() {
+			h.mu.Lock()
+			defer h.mu.Unlock()
+			// a newer signal replaced the timer after it fired
+			if h.active[key] != t {
+				return
+			}
+			delete(h.active, key)
+			h.send(sig.Recipients, typingEvent{ConversationID: ev.ConversationID, UserID: ev.UserID})
+		})
+		h.active[key] = t
+	}
+	h.send(sig.Recipients, ev)
+}
+
+// send must be called with mu held; it never blocks, a stream that fell behind misses the event
+func (h *typingHub) send(recipients []int64, ev typingEvent) {
+	for _, userID := range recipients {
+		for ch := range h.streams[userID] {
+			select {
+			case ch <- ev:
+			default:
+			}
+		}
+	}
+}
+
+// typingFanout publishes the signals to the other replicas
+type typingFanout interface {
+	Publish(ctx context.Context, body []byte, ttl time.Duration) error
+}
+
+// openTypingFanout connects the hub to the other replicas, or returns nil without RABBITMQ_ADDR
+func openTypingFanout(cfg typingConfig, hub *typingHub) *fanout.Exchange {
+	if cfg.RabbitMQAddr == "" {
+		slog.Warn("RABBITMQ_ADDR not set, typing signals reach only the streams of this gateway replica")
+		return nil
+	}
+	return fanout.Open(cfg.RabbitMQAddr, typingExchange, func(body []byte) {
+		var sig typingSignal
+		if err := json.Unmarshal(body, &sig); err != nil {
+			slog.Warn("invalid typing signal", "error", err)
+			return
+		}
+		hub.deliver(sig)
+	})
+}
+
+// Handles POST /v1/conversations/{conversation_id}/typing, with no body or {"typing": true} while the
+// user types and {"typing": false} when they stop or send the message. Only the other participant of
+// the conversation is told, through GET /v1/typing/events. Rate limited like the grpc-gateway routes.
+func (s *server) typingHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := r.Context().Value(scopesKey).([]string); ok {
+			http.Error(w, "not available to access tokens, log in instead", http.StatusForbidden)
+			return
+		}
+		userID, ok := r.Context().Value(userIDKey).(int64)
+		if !ok {
+			http.Error(w, "login required", http.StatusUnauthorized)
+			return
+		}
+		convID, err := strconv.ParseInt(r.PathValue("conversation_id"), 10, 64)
+		if err != nil || convID <= 0 {
+			http.Error(w, "invalid conversation_id", http.StatusBadRequest)
+			return
+		}
+		body := struct {
+			Typing *bool `json:"typing"`
+		}{}
+		if err := json.NewDecoder(io.LimitReader(r.Body, 1<<10)).Decode(&body); err != nil && !errors.Is(err, io.EOF) {
+			http.Error(w, `expected {"typing": true} or {"typing": false}`, http.StatusBadRequest)
+			return
+		}
+
+		recipients, err := s.otherParticipants(r.Context(), convID, userID)
+		if err != nil {
+			st := status.Convert(err)
+			http.Error(w, st.Message(), runtime.HTTPStatusFromCode(st.Code()))
+			return
+		}
+
+		sig := typingSignal{
+			Event:      typingEvent{ConversationID: convID, UserID: userID, Typing: body.Typing == nil || *body.Typing},
+			Recipients: recipients,
+		}
+		if sig.Event.Typing {
+			sig.Event.ExpiresAt = time.Now().Add(s.typingTTL)
+		}
+		s.typing.deliver(sig)
+		if s.typingFanout != nil {
+			msg, _ := json.Marshal(sig)
+			if err := s.typingFanout.Publish(r.Context(), msg, s.typingTTL); err != nil {
+				// the other replicas miss it, the next signal may reach them
+				slog.Debug("typing signal not fanned out", "error", err)
+			}
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+// otherParticipants returns who is told that userID types in the conversation; NotFound if userID
+// is not part of it, so the conversations of others are not revealed
+func (s *server) otherParticipants(ctx context.Context, convID, userID int64) ([]int64, error) {
+	c, cancel := context.WithTimeout(ctx, s.upstreamTO)
+	defer cancel()
+	resp, err := s.conversationClient.GetConversation(c, &conversationpb.GetConversationRequest{Id: strconv.FormatInt(convID, 10)})
+	if err != nil {
+		return nil, err
+	}
+	user := strconv.FormatInt(userID, 10)
+	conv := resp.GetConversation()
+	var other string
+	switch user {
+	case conv.GetUser1Id():
+		other = conv.GetUser2Id()
+	case conv.GetUser2Id():
+		other = conv.GetUser1Id()
+	default:
+		return nil, status.Error(codes.NotFound, "conversation not found")
+	}
+	otherID, err := strconv.ParseInt(other, 10, 64)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "invalid participant %q", other)
+	}
+	return []int64{otherID}, nil
+}
+
+// Handles GET /v1/typing/events, a text/event-stream of who starts and stops typing in the
+// conversations of the caller.
+func (s *server) typingEventsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := r.Context().Value(scopesKey).([]string); ok {
+			http.Error(w, "not available to access tokens, log in instead", http.StatusForbidden)
+			return
+		}
+		userID, ok := r.Context().Value(userIDKey).(int64)
+		if !ok {
+			http.Error(w, "login required", http.StatusUnauthorized)
+			return
+		}
+
+		ch := s.typing.subscribe(userID)
+		eventStreams.WithLabelValues("typing").Inc()
+		defer func() {
+			s.typing.unsubscribe(userID, ch)
+			eventStreams.WithLabelValues("typing").Dec()
+		}()
+		events := startEventStream(w)
+
+		ticker := time.NewTicker(eventsKeepAlive)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case ev := <-ch:
+				body, err := json.Marshal(ev)
+				if err != nil {
+					slog.Error("marshal typing event", "error", err)
+					return
+				}
+				if err := events.send("", body); err != nil {
+					return
+				}
+			case <-ticker.C:
+				if err := events.keepAlive(); err != nil {
+					return
+				}
+			}
+		}
+	})
+}
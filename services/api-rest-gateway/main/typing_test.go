@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	conversationpb "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/conversation-base/proto"
+	"github.com/google/go-cmp/cmp"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type fakeConversationClient struct {
+	conversationpb.ConversationServiceClient
+}
+
+func (f *fakeConversationClient) GetConversation(ctx context.Context, in *conversationpb.GetConversationRequest, _ ...grpc.CallOption) (*conversationpb.GetConversationResponse, error) {
+	if in.Id != "7" {
+		return nil, status.Error(codes.NotFound, "conversation not found")
+	}
+	return &conversationpb.GetConversationResponse{Conversation: &conversationpb.Conversation{Id: "7", User1Id: "42", User2Id: "43"}}, nil
+}
+
+type fakeTypingFanout struct {
+	published []typingSignal
+}
+
+func (f *fakeTypingFanout) Publish(ctx context.Context, body []byte, ttl time.Duration) error {
+	var sig typingSignal
+	if err := json.Unmarshal(body, &sig); err != nil {
+		return err
+	}
+	f.published = append(f.published, sig)
+	return nil
+}
+
+func nextTypingEvent(t *testing.T, ch chan typingEvent, within time.Duration) typingEvent {
+	t.Helper()
+	select {
+	case ev := <-ch:
+		return ev
+	case <-time.After(within):
+		t.Fatal("no typing event")
+		return typingEvent{}
+	}
+}
+
+func TestTypingHub_SignalsExpire(t *testing.T) {
+	hub := newTypingHub()
+	recipient := hub.subscribe(43)
+	typer := hub.subscribe(42)
+	expiresAt := time.Now().Add(50 * time.Millisecond)
+
+	hub.deliver(typingSignal{
+		Event:      typingEvent{ConversationID: 7, UserID: 42, Typing: true, ExpiresAt: expiresAt},
+		Recipients: []int64{43},
+	})
+
+	if diff := cmp.Diff(typingEvent{ConversationID: 7, UserID: 42, Typing: true, ExpiresAt: expiresAt}, nextTypingEvent(t, recipient, time.Second)); diff != "" {
+		t.Errorf("unexpected event (-want +got):\n%s", diff)
+	}
+	// not repeated, so it stops on its own
+	if diff := cmp.Diff(typingEvent{ConversationID: 7, UserID: 42}, nextTypingEvent(t, recipient, time.Second)); diff != "" {
+		t.Errorf("unexpected event (-want +got):\n%s", diff)
+	}
+	if len(typer) != 0 {
+		t.Error("the typer should not be told about themselves")
+	}
+}
+
+func TestTypingHub_StopCancelsExpiry(t *testing.T) {
+	hub := newTypingHub()
+	recipient := hub.subscribe(43)
+	start := typingSignal{Event: typingEvent{ConversationID: 7, UserID: 42, Typing: true, ExpiresAt: time.Now().Add(20 * time.Millisecond)}, Recipients: []int64{43}}
+
+	hub.deliver(start)
+	hub.deliver(typingSignal{Event: typingEvent{ConversationID: 7, UserID: 42}, Recipients: []int64{43}})
+
+	nextTypingEvent(t, recipient, time.Second)
+	if ev := nextTypingEvent(t, recipient, time.Second); ev.Typing {
+		t.Fatalf("got %+v, want the stop", ev)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if len(recipient) != 0 {
+		t.Error("a stopped signal should not expire again")
+	}
+}
+
+func TestTypingHub_DropsStaleSignals(t *testing.T) {
+	hub := newTypingHub()
+	recipient := hub.subscribe(43)
+
+	hub.deliver(typingSignal{
+		Event:      typingEvent{ConversationID: 7, UserID: 42, Typing: true, ExpiresAt: time.Now().Add(-time.Second)},
+		Recipients: []int64{43},
+	})
+
+	if len(recipient) != 0 || len(hub.active) != 0 {
+		t.Error("a signal that ran out on the way should be dropped")
+	}
+}
+
+func TestTyping_OnlyOtherParticipant(t *testing.T) {
+	fan := &fakeTypingFanout{}
+	s := &server{
+		conversationClient: &fakeConversationClient{},
+		upstreamTO:         time.Second,
+		typing:             newTypingHub(),
+		typingFanout:       fan,
+		typingTTL:          time.Minute,
+	}
+	mux := http.NewServeMux()
+	mux.Handle("POST /v1/conversations/{conversation_id}/typing", s.typingHandler())
+	recipient := s.typing.subscribe(43)
+
+	post := func(userID int64, target, body string) int {
+		r := httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), userIDKey, userID)))
+		return w.Code
+	}
+
+	if code := post(42, "/v1/conversations/7/typing", ""); code != http.StatusNoContent {
+		t.Fatalf("typing: status = %d, want 204", code)
+	}
+	if ev := nextTypingEvent(t, recipient, time.Second); !ev.Typing || ev.UserID != 42 || ev.ConversationID != 7 {
+		t.Errorf("unexpected event %+v", ev)
+	}
+	if code := post(42, "/v1/conversations/7/typing", `{"typing": false}`); code != http.StatusNoContent {
+		t.Fatalf("stop: status = %d, want 204", code)
+	}
+	if ev := nextTypingEvent(t, recipient, time.Second); ev.Typing {
+		t.Errorf("got %+v, want the stop", ev)
+	}
+	if len(fan.published) != 2 || fan.published[0].Recipients[0] != 43 {
+		t.Errorf("published %+v, want both signals for user 43", fan.published)
+	}
+
+	for name, tc := range map[string]struct {
+		userID   int64
+		target   string
+		body     string
+		wantCode int
+	}{
+		"not a participant":    {userID: 44, target: "/v1/conversations/7/typing", wantCode: http.StatusNotFound},
+		"unknown conversation": {userID: 42, target: "/v1/conversations/8/typing", wantCode: http.StatusNotFound},
+		"invalid conversation": {userID: 42, target: "/v1/conversations/abc/typing", wantCode: http.StatusBadRequest},
+		"invalid body":         {userID: 42, target: "/v1/conversations/7/typing", body: `typing`, wantCode: http.StatusBadRequest},
+	} {
+		if code := post(tc.userID, tc.target, tc.body); code != tc.wantCode {
+			t.Errorf("%s: status = %d, want %d", name, code, tc.wantCode)
+		}
+	}
+	if len(recipient) != 0 {
+		t.Error("rejected signals should not reach anyone")
+	}
+}
+
+func TestTypingEvents_Stream(t *testing.T) {
+	s := &server{typing: newTypingHub()}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.typingEventsHandler().ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), userIDKey, int64(43))))
+	}))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/v1/typing/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	s.typing.deliver(typingSignal{Event: typingEvent{ConversationID: 7, UserID: 42}, Recipients: []int64{43}})
+
+	buf := make([]byte, 256)
+	n, err := resp.Body.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(buf[:n]), `data: {"conversation_id":"7","user_id":"42","typing":false}`+"\n\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got := testutil.ToFloat64(eventStreams.WithLabelValues("typing")); got != 1 {
+		t.Errorf("open typing streams: got %v, want 1", got)
+	}
+
+	// the gauge goes back down once the client leaves
+	resp.Body.Close()
+	deadline := time.Now().Add(2 * time.Second)
+	for testutil.ToFloat64(eventStreams.WithLabelValues("typing")) != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("typing stream still counted as open after the client left")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
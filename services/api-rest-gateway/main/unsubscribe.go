@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/Costin2000/GoChat---Schwarz-Internship---2025/pkg/unsubscribe"
+	userbasepb "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/user-base/proto"
+)
+
+// Handles the links from the List-Unsubscribe header and the email footer.
+// GET shows a confirmation page (link scanners must not unsubscribe anyone), POST turns the emails off.
+// The signed token replaces the JWT, so the route is registered outside of withAuth.
+func (s *server) unsubscribeHandler(secret []byte) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(secret) == 0 {
+			http.Error(w, "unsubscribe is not configured", http.StatusServiceUnavailable)
+			return
+		}
+
+		token := r.URL.Query().Get("token")
+		userID, eventType, err := unsubscribe.Verify(secret, token)
+		if err != nil {
+			http.Error(w, "invalid unsubscribe link", http.StatusBadRequest)
+			return
+		}
+
+		event, ok := userbasepb.NotificationEvent_value["NOTIFICATION_EVENT_"+strings.ToUpper(eventType)]
+		if !ok {
+			http.Error(w, "invalid unsubscribe link", http.StatusBadRequest)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			fmt.Fprintf(w, `<html><body><form method="post" action="?token=%s"><p>Stop receiving %s emails from GoChat?</p><button type="submit">Unsubscribe</button></form></body></html>`,
+				html.EscapeString(token), html.EscapeString(strings.ReplaceAll(eventType, "_", " ")))
+		case http.MethodPost:
+			c, cancel := context.WithTimeout(r.Context(), s.upstreamTO)
+			defer cancel()
+
+			// keep the digest flag, only the email switch is turned off
+			digest := false
+			if prefs, err := s.userBaseClient.GetNotificationPreferences(c, &userbasepb.GetNotificationPreferencesRequest{UserId: userID}); err == nil {
+				for _, pref := range prefs.Preferences {
+					if pref.Event == userbasepb.NotificationEvent(event) {
+						digest = pref.Digest
+					}
+				}
+			}
+
+			_, err := s.userBaseClient.UpdateNotificationPreference(c, &userbasepb.UpdateNotificationPreferenceRequest{
+				Preference: &userbasepb.NotificationPreference{
+					UserId:       userID,
+					Event:        userbasepb.NotificationEvent(event),
+					EmailEnabled: false,
+					Digest:       digest,
+				},
+			})
+			if err != nil {
+				log.Printf("unsubscribe user %d from %s: %v", userID, eventType, err)
+				http.Error(w, "could not unsubscribe, please try again later", http.StatusBadGateway)
+				return
+			}
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			fmt.Fprint(w, "<html><body><p>You have been unsubscribed.</p></body></html>")
+		default:
+			w.Header().Set("Allow", "GET, POST")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	errchecks "github.com/Costin2000/GoChat---Schwarz-Internship---2025/pkg"
+	authpb "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/auth/proto"
+	userbasepb "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/user-base/proto"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+func TestCreateAccessToken(t *testing.T) {
+	now := time.Date(2025, 8, 1, 12, 0, 0, 0, time.UTC)
+	users := map[int64]*userbasepb.User{
+		7: {Id: 7, IsBot: true, OwnerId: 42},
+		8: {Id: 8, IsBot: true, OwnerId: 1},
+		9: {Id: 9},
+	}
+
+	tests := []struct {
+		name          string
+		req           *authpb.CreateAccessTokenRequest
+		wantCode      codes.Code
+		wantUserID    int64
+		wantScopes    []string
+		wantExpiresAt time.Time
+	}{
+		{
+			name:          "token of the user",
+			req:           &authpb.CreateAccessTokenRequest{UserId: 42, Name: "backup script", Scopes: []string{"messages:read"}},
+			wantCode:      codes.OK,
+			wantUserID:    42,
+			wantScopes:    []string{"messages:read"},
+			wantExpiresAt: now.Add(90 * 24 * time.Hour),
+		},
+		{
+			name:          "token of an own bot",
+			req:           &authpb.CreateAccessTokenRequest{UserId: 42, BotId: 7, Name: "deploy", Scopes: []string{"messages:write", " Messages:Read", "messages:write"}, ExpiresInDays: 7},
+			wantCode:      codes.OK,
+			wantUserID:    7,
+			wantScopes:    []string{"messages:read", "messages:write"},
+			wantExpiresAt: now.Add(7 * 24 * time.Hour),
+		},
+		{
+			name:     "bot of another user",
+			req:      &authpb.CreateAccessTokenRequest{UserId: 42, BotId: 8, Name: "deploy", Scopes: []string{"messages:read"}},
+			wantCode: codes.PermissionDenied,
+		},
+		{
+			name:     "not a bot",
+			req:      &authpb.CreateAccessTokenRequest{UserId: 42, BotId: 9, Name: "deploy", Scopes: []string{"messages:read"}},
+			wantCode: codes.PermissionDenied,
+		},
+		{
+			name:     "unknown bot",
+			req:      &authpb.CreateAccessTokenRequest{UserId: 42, BotId: 10, Name: "deploy", Scopes: []string{"messages:read"}},
+			wantCode: codes.NotFound,
+		},
+		{
+			name:     "unknown scope",
+			req:      &authpb.CreateAccessTokenRequest{UserId: 42, Name: "x", Scopes: []string{"admin"}},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name:     "no scope",
+			req:      &authpb.CreateAccessTokenRequest{UserId: 42, Name: "x"},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name:     "never expires",
+			req:      &authpb.CreateAccessTokenRequest{UserId: 42, Name: "x", Scopes: []string{"messages:read"}, ExpiresInDays: 10000},
+			wantCode: codes.InvalidArgument,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stored *accessToken
+			s := newAuthServerWithMock(&mockUserBaseClient{
+				listUsersFunc: func(ctx context.Context, in *userbasepb.ListUsersRequest, _ ...grpc.CallOption) (*userbasepb.ListUsersResponse, error) {
+					id := in.Filters[0].GetUserIds().UserId[0]
+					if u, ok := users[id]; ok {
+						return &userbasepb.ListUsersResponse{Users: []*userbasepb.User{u}}, nil
+					}
+					return &userbasepb.ListUsersResponse{}, nil
+				},
+			})
+			s.now = func() time.Time { return now }
+			s.storageAccess = newMockStorageAccess(StorageMockOptions{
+				CreateAccessTokenFunc: func(ctx context.Context, tok *accessToken) (int64, error) {
+					stored = tok
+					return 3, nil
+				},
+			})
+
+			resp, err := s.CreateAccessToken(context.Background(), tt.req)
+
+			errchecks.Assert(t, err, errchecks.HasStatusCode(tt.wantCode))
+			if tt.wantCode != codes.OK {
+				return
+			}
+			if !strings.HasPrefix(resp.Token, accessTokenPrefix) || !strings.HasPrefix(resp.Token, resp.AccessToken.Prefix) {
+				t.Errorf("token %q does not start with %q", resp.Token, resp.AccessToken.Prefix)
+			}
+			if stored.hash != hashAccessToken(resp.Token) || strings.Contains(stored.hash, resp.Token) {
+				t.Errorf("stored %q, want the hash of the token", stored.hash)
+			}
+			if stored.userID != tt.wantUserID || stored.ownerID != tt.req.UserId || !stored.expiresAt.Equal(tt.wantExpiresAt) {
+				t.Errorf("unexpected token %+v", stored)
+			}
+			if diff := cmp.Diff(tt.wantScopes, resp.AccessToken.Scopes); diff != "" {
+				t.Errorf("scopes mismatch (-want +got):\n%s", diff)
+			}
+			if resp.AccessToken.Id != 3 || resp.AccessToken.LastUsedAt != nil {
+				t.Errorf("unexpected access token %v", resp.AccessToken)
+			}
+		})
+	}
+}
+
+func TestAuthenticateAccessToken(t *testing.T) {
+	token, hash, err := newAccessToken()
+	if err != nil {
+		t.Fatalf("newAccessToken: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		token    string
+		wantCode codes.Code
+	}{
+		{name: "active token", token: token, wantCode: codes.OK},
+		{name: "unknown, expired or revoked token", token: accessTokenPrefix + "unknown", wantCode: codes.Unauthenticated},
+		{name: "a JWT", token: "eyJhbGciOiJIUzI1NiJ9.e30.x", wantCode: codes.Unauthenticated},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newAuthServerWithMock(&mockUserBaseClient{})
+			s.storageAccess = newMockStorageAccess(StorageMockOptions{
+				UseAccessTokenFunc: func(ctx context.Context, h string, _ time.Time) (*accessToken, error) {
+					if h != hash {
+						return nil, nil
+					}
+					return &accessToken{id: 3, userID: 7, scopes: []string{"messages:write"}}, nil
+				},
+			})
+
+			resp, err := s.AuthenticateAccessToken(context.Background(), &authpb.AuthenticateAccessTokenRequest{Token: tt.token})
+
+			errchecks.Assert(t, err, errchecks.HasStatusCode(tt.wantCode))
+			if tt.wantCode == codes.OK && (resp.UserId != 7 || resp.TokenId != 3 || len(resp.Scopes) != 1) {
+				t.Errorf("unexpected response %v", resp)
+			}
+		})
+	}
+}
+
+func TestLogin_BotsCannotUsePasswords(t *testing.T) {
+	s := newAuthServerWithMock(&mockUserBaseClient{
+		getUserCredentialsFunc: func(ctx context.Context, in *userbasepb.GetUserRequest, _ ...grpc.CallOption) (*userbasepb.UserCredentials, error) {
+			// even if a bot somehow had a password hash
+			return &userbasepb.UserCredentials{User: &userbasepb.User{Id: 7, Email: in.GetEmail(), IsBot: true, OwnerId: 42}, PasswordHash: hashPwd(t, "right")}, nil
+		},
+	})
+
+	_, err := s.Login(context.Background(), &authpb.LoginRequest{Identifier: &authpb.LoginRequest_Email{Email: "deploy@bots.gochat.invalid"}, Password: "right"})
+
+	errchecks.Assert(t, err, errchecks.HasStatusCode(codes.Unauthenticated))
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"context"
+	"strings"
+
+	proto "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/auth/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var errInvalidAccessToken = status.Error(codes.Unauthenticated, "invalid, expired or revoked access token")
+
+func (s *authServer) AuthenticateAccessToken(ctx context.Context, req *proto.AuthenticateAccessTokenRequest) (*proto.AuthenticateAccessTokenResponse, error) {
+	if !strings.HasPrefix(req.Token, accessTokenPrefix) {
+		return nil, errInvalidAccessToken
+	}
+
+	t, err := s.storageAccess.useAccessToken(ctx, hashAccessToken(req.Token), s.now())
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to check access token: %v", err)
+	}
+	if t == nil {
+		return nil, errInvalidAccessToken
+	}
+
+	return &proto.AuthenticateAccessTokenResponse{UserId: t.userID, TokenId: t.id, Scopes: t.scopes}, nil
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"context"
+
+	proto "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/auth/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (s *authServer) CheckSession(ctx context.Context, req *proto.CheckSessionRequest) (*proto.CheckSessionResponse, error) {
+	if req.UserId <= 0 || req.SessionId <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "user_id and session_id are required")
+	}
+
+	active, err := s.storageAccess.touchSession(ctx, req.UserId, req.SessionId, s.now())
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to check session: %v", err)
+	}
+	if !active {
+		return nil, status.Error(codes.Unauthenticated, "session was revoked or expired")
+	}
+	return &proto.CheckSessionResponse{}, nil
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	proto "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/auth/proto"
+	userbasepb "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/user-base/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// provisionAttempts is how many user names are tried for a new user before giving up
+const provisionAttempts = 5
+
+func (s *authServer) CompleteOIDCLogin(ctx context.Context, req *proto.CompleteOIDCLoginRequest) (*proto.LoginResponse, error) {
+	if s.oidc == nil {
+		return nil, errOIDCDisabled
+	}
+	if req.State == "" || req.Code == "" {
+		return nil, status.Error(codes.InvalidArgument, "state and code are required")
+	}
+
+	now := s.now()
+	st, err := s.storageAccess.takeOIDCState(ctx, req.State)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to get login state: %v", err)
+	}
+	if st == nil || !now.Before(st.expiresAt) {
+		return nil, status.Error(codes.Unauthenticated, "unknown or expired login, start again")
+	}
+
+	idToken, claims, err := s.oidc.exchange(ctx, req.Code, st)
+	if err != nil {
+		log.Printf("WARN: login with the identity provider failed: %v", err)
+		return nil, status.Error(codes.Unauthenticated, "the identity provider did not confirm the login")
+	}
+
+	user, err := s.oidcUser(ctx, idToken.Issuer, idToken.Subject, claims, now)
+	if err != nil {
+		return nil, err
+	}
+	return s.finishLogin(ctx, user, st.device, now)
+}
+
+// oidcUser returns the user linked to the provider account. The first login links it to the user
+// with the same email, which the provider must have verified, or creates a user for it.
+func (s *authServer) oidcUser(ctx context.Context, issuer, subject string, claims *oidcClaims, now time.Time) (*userbasepb.User, error) {
+	userID, err := s.storageAccess.findIdentity(ctx, issuer, subject, now)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to find identity: %v", err)
+	}
+	if userID != 0 {
+		user := s.lookupUser(ctx, userID)
+		if user == nil {
+			// still log in, only without the new device email
+			user = &userbasepb.User{Id: userID}
+		}
+		return user, nil
+	}
+
+	email := strings.TrimSpace(claims.Email)
+	if email == "" || !claims.EmailVerified {
+		return nil, status.Error(codes.PermissionDenied, "the identity provider did not verify the email address")
+	}
+
+	user, err := s.userByEmail(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		if user, err = s.provisionUser(ctx, email, claims); err != nil {
+			return nil, err
+		}
+	}
+	if user.IsBot {
+		return nil, status.Error(codes.PermissionDenied, "bots cannot log in with an identity provider")
+	}
+
+	if err := s.storageAccess.linkIdentity(ctx, issuer, subject, user.Id, email, now); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to link identity: %v", err)
+	}
+	log.Printf("Linked %s account %s to user %d", issuer, subject, user.Id)
+	return user, nil
+}
+
+// userByEmail returns nil if no user has the email
+func (s *authServer) userByEmail(ctx context.Context, email string) (*userbasepb.User, error) {
+	user, err := s.userBaseClient.GetUser(ctx, &userbasepb.GetUserRequest{Identifier: &userbasepb.GetUserRequest_Email{Email: email}})
+	if status.Code(err) == codes.NotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to get user: %v", err)
+	}
+	return user, nil
+}
+
+// provisionUser creates the user of a provider account, with another user name if the one of the
+// claims is taken
+func (s *authServer) provisionUser(ctx context.Context, email string, claims *oidcClaims) (*userbasepb.User, error) {
+	first, last, userName := claims.names()
+	for attempt := 0; attempt < provisionAttempts; attempt++ {
+		name := userName
+		if attempt > 0 {
+			name = userName + "-" + randomSuffix()
+		}
+		resp, err := s.userBaseClient.ProvisionUser(ctx, &userbasepb.ProvisionUserRequest{
+			FirstName: first,
+			LastName:  last,
+			UserName:  name,
+			Email:     email,
+		})
+		if err == nil {
+			log.Printf("Created user %d for a login with the identity provider", resp.User.Id)
+			return resp.User, nil
+		}
+		if status.Code(err) != codes.AlreadyExists {
+			return nil, status.Errorf(codes.Internal, "failed to create user: %v", err)
+		}
+
+		// the email may have been registered meanwhile, rather than the user name
+		user, err := s.userByEmail(ctx, email)
+		if err != nil {
+			return nil, err
+		}
+		if user != nil {
+			return user, nil
+		}
+	}
+	return nil, status.Errorf(codes.Internal, "failed to find a free user name for %q", userName)
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"log"
+
+	proto "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/auth/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ConfirmTOTP enables two-factor authentication once the user typed a code of the new secret, and
+// returns the recovery codes. They are shown only this once.
+func (s *authServer) ConfirmTOTP(ctx context.Context, req *proto.ConfirmTOTPRequest) (*proto.ConfirmTOTPResponse, error) {
+	if s.secrets == nil {
+		return nil, errTOTPDisabled
+	}
+	if req.UserId <= 0 || req.Code == "" {
+		return nil, status.Error(codes.InvalidArgument, "user_id and code are required")
+	}
+
+	enrolment, err := s.storageAccess.getTOTP(ctx, req.UserId)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to get two-factor authentication: %v", err)
+	}
+	if enrolment == nil {
+		return nil, status.Error(codes.FailedPrecondition, "two-factor authentication was not enrolled")
+	}
+	if enrolment.confirmed {
+		return nil, status.Error(codes.FailedPrecondition, "two-factor authentication is already enabled")
+	}
+
+	secret, err := s.secrets.open(enrolment.secret)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to decrypt the TOTP secret: %v", err)
+	}
+	step, ok := matchTOTP(secret, req.Code, s.now())
+	if !ok {
+		return nil, status.Error(codes.InvalidArgument, "invalid code, check the clock of your phone")
+	}
+
+	recoveryCodes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to generate recovery codes: %v", err)
+	}
+	confirmed, err := s.storageAccess.confirmTOTP(ctx, req.UserId, step, hashes)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to enable two-factor authentication: %v", err)
+	}
+	if !confirmed {
+		return nil, status.Error(codes.FailedPrecondition, "two-factor authentication is already enabled")
+	}
+	log.Printf("User %d enabled two-factor authentication", req.UserId)
+
+	return &proto.ConfirmTOTPResponse{RecoveryCodes: recoveryCodes}, nil
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"log"
+	"strings"
+
+	proto "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/auth/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (s *authServer) CreateAccessToken(ctx context.Context, req *proto.CreateAccessTokenRequest) (*proto.CreateAccessTokenResponse, error) {
+	name := strings.TrimSpace(req.Name)
+	if req.UserId <= 0 || name == "" {
+		return nil, status.Error(codes.InvalidArgument, "user_id and name are required")
+	}
+	if len(name) > 100 {
+		return nil, status.Error(codes.InvalidArgument, "name is longer than 100 characters")
+	}
+	scopes, err := normalizeScopes(req.Scopes)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	lifetime, err := accessTokenLifetime(req.ExpiresInDays)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	// users create tokens for themselves or for the bots they own
+	principal := req.UserId
+	if req.BotId != 0 {
+		bot := s.lookupUser(ctx, req.BotId)
+		if bot == nil {
+			return nil, status.Errorf(codes.NotFound, "bot %d not found", req.BotId)
+		}
+		if !bot.IsBot || bot.OwnerId != req.UserId {
+			return nil, status.Errorf(codes.PermissionDenied, "user %d is not a bot of user %d", req.BotId, req.UserId)
+		}
+		principal = req.BotId
+	}
+
+	token, hash, err := newAccessToken()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to generate token: %v", err)
+	}
+	now := s.now()
+	t := &accessToken{
+		userID:    principal,
+		ownerID:   req.UserId,
+		name:      name,
+		hash:      hash,
+		prefix:    token[:len(accessTokenPrefix)+6],
+		scopes:    scopes,
+		createdAt: now,
+		expiresAt: now.Add(lifetime),
+	}
+	if t.id, err = s.storageAccess.createAccessToken(ctx, t); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to save token: %v", err)
+	}
+	log.Printf("User %d created access token %d for user %d with scopes %v", req.UserId, t.id, principal, scopes)
+
+	return &proto.CreateAccessTokenResponse{AccessToken: accessTokenToProto(t), Token: token}, nil
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"log"
+
+	"github.com/Costin2000/GoChat---Schwarz-Internship---2025/pkg/bootstrap"
+	proto "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/auth/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// DisableTOTP turns two-factor authentication off. It needs a current code or a recovery code, a
+// stolen session alone must not be enough.
+func (s *authServer) DisableTOTP(ctx context.Context, req *proto.DisableTOTPRequest) (*proto.DisableTOTPResponse, error) {
+	if req.UserId <= 0 || req.Code == "" {
+		return nil, status.Error(codes.InvalidArgument, "user_id and code are required")
+	}
+
+	now := s.now()
+	ip := bootstrap.ClientIP(ctx)
+	if err := s.checkLocked(ctx, now, mfaKey(req.UserId), ipKey(ip)); err != nil {
+		return nil, err
+	}
+
+	ok, err := s.verifySecondFactor(ctx, req.UserId, req.Code, now)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		s.recordFailure(ctx, mfaKey(req.UserId), ip, nil, now)
+		return nil, errInvalidCode
+	}
+
+	if err := s.storageAccess.deleteTOTP(ctx, req.UserId); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to disable two-factor authentication: %v", err)
+	}
+	if err := s.storageAccess.resetAttempts(ctx, mfaKey(req.UserId)); err != nil {
+		log.Printf("WARN: could not reset failed codes of user %d: %v", req.UserId, err)
+	}
+	log.Printf("User %d disabled two-factor authentication", req.UserId)
+
+	return &proto.DisableTOTPResponse{}, nil
+}
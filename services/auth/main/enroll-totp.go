@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+
+	proto "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/auth/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var errTOTPDisabled = status.Error(codes.FailedPrecondition, "two-factor authentication is not configured")
+
+// EnrollTOTP creates a new TOTP secret for the user. It is not required at login until ConfirmTOTP
+// proves that the authenticator app has it; enrolling again before that replaces the secret.
+func (s *authServer) EnrollTOTP(ctx context.Context, req *proto.EnrollTOTPRequest) (*proto.EnrollTOTPResponse, error) {
+	if s.secrets == nil {
+		return nil, errTOTPDisabled
+	}
+	if req.UserId <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "user_id is required")
+	}
+
+	user := s.lookupUser(ctx, req.UserId)
+	if user == nil {
+		return nil, status.Errorf(codes.NotFound, "user %d not found", req.UserId)
+	}
+
+	secret, err := newTOTPSecret()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to generate secret: %v", err)
+	}
+	sealed, err := s.secrets.seal(secret)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to encrypt secret: %v", err)
+	}
+	saved, err := s.storageAccess.saveTOTP(ctx, req.UserId, sealed)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to save secret: %v", err)
+	}
+	if !saved {
+		return nil, status.Error(codes.FailedPrecondition, "two-factor authentication is already enabled")
+	}
+
+	return &proto.EnrollTOTPResponse{
+		Secret:          base32NoPadding.EncodeToString(secret),
+		ProvisioningUri: provisioningURI(secret, user.Email),
+	}, nil
+}
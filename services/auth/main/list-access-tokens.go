@@ -0,0 +1,26 @@
+package main
+
+import (
+	"context"
+
+	proto "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/auth/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (s *authServer) ListAccessTokens(ctx context.Context, req *proto.ListAccessTokensRequest) (*proto.ListAccessTokensResponse, error) {
+	if req.UserId <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "user_id is required")
+	}
+
+	tokens, err := s.storageAccess.listAccessTokens(ctx, req.UserId, s.now())
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list access tokens: %v", err)
+	}
+
+	resp := &proto.ListAccessTokensResponse{AccessTokens: make([]*proto.AccessToken, 0, len(tokens))}
+	for _, t := range tokens {
+		resp.AccessTokens = append(resp.AccessTokens, accessTokenToProto(t))
+	}
+	return resp, nil
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+
+	proto "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/auth/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func (s *authServer) ListSessions(ctx context.Context, req *proto.ListSessionsRequest) (*proto.ListSessionsResponse, error) {
+	if req.UserId <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "user_id is required")
+	}
+
+	sessions, err := s.storageAccess.listSessions(ctx, req.UserId, s.now())
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list sessions: %v", err)
+	}
+
+	current := currentSession(ctx)
+	resp := &proto.ListSessionsResponse{Sessions: make([]*proto.Session, 0, len(sessions))}
+	for _, sess := range sessions {
+		pb := &proto.Session{
+			Id:          sess.id,
+			DeviceLabel: sess.deviceLabel,
+			UserAgent:   sess.userAgent,
+			Ip:          sess.ip,
+			CreatedAt:   timestamppb.New(sess.createdAt),
+			LastSeenAt:  timestamppb.New(sess.lastSeenAt),
+			Current:     sess.id == current,
+		}
+		// the device the user is looking from goes first
+		if pb.Current {
+			resp.Sessions = append([]*proto.Session{pb}, resp.Sessions...)
+		} else {
+			resp.Sessions = append(resp.Sessions, pb)
+		}
+	}
+	return resp, nil
+}
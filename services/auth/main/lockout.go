@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"time"
+
+	userbasepb "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/user-base/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const templateAccountLocked = "account_locked"
+
+// lockoutConfig slows down and then stops password guessing. Every failed login of an account
+// after the first doubles the wait before the next try, until the account is locked; an IP
+// address is locked after more failures, across all accounts it tried.
+type lockoutConfig struct {
+	MaxAccountFailures int           `env:"LOGIN_MAX_ACCOUNT_FAILURES" default:"5"`
+	MaxIPFailures      int           `env:"LOGIN_MAX_IP_FAILURES" default:"20"`
+	Window             time.Duration `env:"LOGIN_FAILURE_WINDOW" default:"15m"`
+	Lockout            time.Duration `env:"LOGIN_LOCKOUT_DURATION" default:"15m"`
+	BaseDelay          time.Duration `env:"LOGIN_BASE_DELAY" default:"1s"`
+}
+
+func (c *lockoutConfig) Validate() error {
+	if c.MaxAccountFailures < 1 || c.MaxIPFailures < 1 {
+		return fmt.Errorf("LOGIN_MAX_ACCOUNT_FAILURES and LOGIN_MAX_IP_FAILURES must be positive")
+	}
+	if c.Window <= 0 || c.Lockout <= 0 {
+		return fmt.Errorf("LOGIN_FAILURE_WINDOW and LOGIN_LOCKOUT_DURATION must be positive")
+	}
+	return nil
+}
+
+// accountDelay is how long an account must wait after its failures-th failed login
+func (c lockoutConfig) accountDelay(failures int) time.Duration {
+	switch {
+	case failures >= c.MaxAccountFailures:
+		return c.Lockout
+	case failures < 2:
+		// a single typo costs nothing
+		return 0
+	}
+	return min(c.BaseDelay<<(failures-2), c.Lockout)
+}
+
+func accountKey(email string) string {
+	return "account:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+// mfaKey counts the wrong second factors of a user, whose password is already known to the caller
+func mfaKey(userID int64) string {
+	return fmt.Sprintf("mfa:%d", userID)
+}
+
+func ipKey(ip string) string {
+	if ip == "" {
+		return ""
+	}
+	return "ip:" + ip
+}
+
+// checkLocked fails with ResourceExhausted while the account or the IP address has to wait. Storage
+// errors let the login through, auth must keep working while Postgres is down.
+func (s *authServer) checkLocked(ctx context.Context, now time.Time, keys ...string) error {
+	attempts, err := s.storageAccess.getAttempts(ctx, keys...)
+	if err != nil {
+		log.Printf("WARN: could not check login attempts, not enforcing the lockout: %v", err)
+		return nil
+	}
+	var until time.Time
+	for _, a := range attempts {
+		if a.lockedUntil.After(until) {
+			until = a.lockedUntil
+		}
+	}
+	if !until.After(now) {
+		return nil
+	}
+	wait := int(math.Ceil(until.Sub(now).Seconds()))
+	return status.Errorf(codes.ResourceExhausted, "too many failed login attempts, try again in %d seconds", wait)
+}
+
+// recordFailure counts a failed login against the account (accountKey or mfaKey) and the IP address
+// and locks them when they are over the limit. user is nil if the email is not registered.
+func (s *authServer) recordFailure(ctx context.Context, key, ip string, user *userbasepb.User, now time.Time) {
+	failures, err := s.storageAccess.recordFailure(ctx, key, now, s.lockout.Window)
+	if err != nil {
+		log.Printf("WARN: could not record failed login: %v", err)
+	} else if d := s.lockout.accountDelay(failures); d > 0 {
+		if err := s.storageAccess.lock(ctx, key, now.Add(d)); err != nil {
+			log.Printf("WARN: could not lock account: %v", err)
+		}
+		if failures == s.lockout.MaxAccountFailures && user != nil {
+			log.Printf("Account of user %d locked after %d failed logins", user.Id, failures)
+			s.notifyLocked(ctx, user, now.Add(d))
+		}
+	}
+
+	if key := ipKey(ip); key != "" {
+		failures, err := s.storageAccess.recordFailure(ctx, key, now, s.lockout.Window)
+		if err != nil {
+			log.Printf("WARN: could not record failed login: %v", err)
+			return
+		}
+		if failures >= s.lockout.MaxIPFailures {
+			if err := s.storageAccess.lock(ctx, key, now.Add(s.lockout.Lockout)); err != nil {
+				log.Printf("WARN: could not lock address: %v", err)
+			}
+			if failures == s.lockout.MaxIPFailures {
+				log.Printf("Address %s locked after %d failed logins", ip, failures)
+			}
+		}
+	}
+}
+
+// notifyLocked tells the owner, someone may be guessing their password
+func (s *authServer) notifyLocked(ctx context.Context, user *userbasepb.User, until time.Time) {
+	if s.emailPub == nil {
+		return
+	}
+	msg := EmailMessage{
+		To:      user.Email,
+		Subject: "Your GoChat account was temporarily locked",
+		Body: fmt.Sprintf("Hi %s,\n\nthere were %d failed attempts to log in to your GoChat account, so we locked it until %s.\n\n"+
+			"If this was you, just wait and try again. If it was not, someone may be guessing your password: "+
+			"choose a new one that you do not use anywhere else.",
+			user.FirstName, s.lockout.MaxAccountFailures, until.UTC().Format("15:04 MST")),
+		UserID:   user.Id,
+		Template: templateAccountLocked,
+	}
+	if err := s.emailPub.Publish(ctx, msg); err != nil {
+		log.Printf("WARN: could not send the lockout email to user %d: %v", user.Id, err)
+	}
+}
+
+// purgeLoop deletes the expired failures, the sessions past their history and the abandoned logins
+// with the identity provider every hour until ctx is done
+func (s *authServer) purgeLoop(ctx context.Context) {
+	t := time.NewTicker(time.Hour)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			now := s.now()
+			if n, err := s.storageAccess.purgeAttempts(ctx, now.Add(-s.lockout.Window)); err != nil {
+				log.Printf("WARN: %v", err)
+			} else if n > 0 {
+				log.Printf("Purged %d expired login attempts", n)
+			}
+			if n, err := s.storageAccess.purgeSessions(ctx, now.Add(-sessionHistory)); err != nil {
+				log.Printf("WARN: %v", err)
+			} else if n > 0 {
+				log.Printf("Purged %d old sessions", n)
+			}
+			if n, err := s.storageAccess.purgeOIDCStates(ctx, now); err != nil {
+				log.Printf("WARN: %v", err)
+			} else if n > 0 {
+				log.Printf("Purged %d abandoned identity provider logins", n)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	errchecks "github.com/Costin2000/GoChat---Schwarz-Internship---2025/pkg"
+	"github.com/Costin2000/GoChat---Schwarz-Internship---2025/pkg/bootstrap"
+	authpb "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/auth/proto"
+	userbasepb "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/user-base/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func Test_lockoutConfig_accountDelay(t *testing.T) {
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{failures: 1, want: 0},
+		{failures: 2, want: time.Second},
+		{failures: 3, want: 2 * time.Second},
+		{failures: 4, want: 4 * time.Second},
+		{failures: 5, want: 15 * time.Minute},
+		{failures: 9, want: 15 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		if got := testLockout.accountDelay(tt.failures); got != tt.want {
+			t.Errorf("accountDelay(%d) = %v, want %v", tt.failures, got, tt.want)
+		}
+	}
+}
+
+func TestLogin_Lockout(t *testing.T) {
+	now := time.Date(2025, 8, 1, 12, 0, 0, 0, time.UTC)
+	user := &userbasepb.User{Id: 42, FirstName: "Ana", Email: "ana@example.com"}
+	hash := hashPwd(t, "right")
+
+	tests := []struct {
+		name         string
+		email        string
+		password     string
+		attempts     map[string]loginAttempts
+		failures     map[string]int // returned by recordFailure, per key
+		wantCode     codes.Code
+		wantLocked   map[string]time.Time
+		wantEmails   int
+		wantGetUsers int
+	}{
+		{
+			name:     "locked account is refused before the password is checked",
+			email:    "ana@example.com",
+			password: "right",
+			attempts: map[string]loginAttempts{
+				"account:ana@example.com": {failures: 5, lockedUntil: now.Add(time.Minute)},
+			},
+			wantCode: codes.ResourceExhausted,
+		},
+		{
+			name:     "locked address is refused for every account",
+			email:    "bob@example.com",
+			password: "whatever",
+			attempts: map[string]loginAttempts{
+				"ip:203.0.113.7": {failures: 20, lockedUntil: now.Add(time.Minute)},
+			},
+			wantCode: codes.ResourceExhausted,
+		},
+		{
+			name:         "expired lock lets the login through",
+			email:        "ana@example.com",
+			password:     "right",
+			attempts:     map[string]loginAttempts{"account:ana@example.com": {failures: 5, lockedUntil: now.Add(-time.Second)}},
+			wantCode:     codes.OK,
+			wantGetUsers: 1,
+		},
+		{
+			name:         "third failure delays the next try",
+			email:        "Ana@Example.com",
+			password:     "wrong",
+			failures:     map[string]int{"account:ana@example.com": 3},
+			wantCode:     codes.Unauthenticated,
+			wantLocked:   map[string]time.Time{"account:ana@example.com": now.Add(2 * time.Second)},
+			wantGetUsers: 1,
+		},
+		{
+			name:         "fifth failure locks the account and tells the owner",
+			email:        "ana@example.com",
+			password:     "wrong",
+			failures:     map[string]int{"account:ana@example.com": 5},
+			wantCode:     codes.Unauthenticated,
+			wantLocked:   map[string]time.Time{"account:ana@example.com": now.Add(15 * time.Minute)},
+			wantEmails:   1,
+			wantGetUsers: 1,
+		},
+		{
+			name:         "unknown email is locked the same way, without an email",
+			email:        "nobody@example.com",
+			password:     "wrong",
+			failures:     map[string]int{"account:nobody@example.com": 5},
+			wantCode:     codes.Unauthenticated,
+			wantLocked:   map[string]time.Time{"account:nobody@example.com": now.Add(15 * time.Minute)},
+			wantGetUsers: 1,
+		},
+		{
+			name:         "too many failures from one address lock it",
+			email:        "bob@example.com",
+			password:     "wrong",
+			failures:     map[string]int{"ip:203.0.113.7": 20},
+			wantCode:     codes.Unauthenticated,
+			wantLocked:   map[string]time.Time{"ip:203.0.113.7": now.Add(15 * time.Minute)},
+			wantGetUsers: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			getUsers := 0
+			locked := map[string]time.Time{}
+			pub := &mockEmailPublisher{}
+			s := &authServer{
+				userBaseClient: &mockUserBaseClient{
+					getUserCredentialsFunc: func(ctx context.Context, in *userbasepb.GetUserRequest, _ ...grpc.CallOption) (*userbasepb.UserCredentials, error) {
+						getUsers++
+						if in.GetEmail() != user.Email {
+							return nil, status.Error(codes.NotFound, "user not found")
+						}
+						return &userbasepb.UserCredentials{User: user, PasswordHash: hash}, nil
+					},
+				},
+				storageAccess: newMockStorageAccess(StorageMockOptions{
+					GetAttemptsFunc: func(ctx context.Context, keys ...string) (map[string]loginAttempts, error) {
+						return tt.attempts, nil
+					},
+					RecordFailureFunc: func(ctx context.Context, key string, _ time.Time, _ time.Duration) (int, error) {
+						if n, ok := tt.failures[key]; ok {
+							return n, nil
+						}
+						return 1, nil
+					},
+					LockFunc: func(ctx context.Context, key string, until time.Time) error {
+						locked[key] = until
+						return nil
+					},
+				}),
+				emailPub:  pub,
+				lockout:   testLockout,
+				passwords: testPasswords,
+				dummyHash: testDummyHash,
+				now:       func() time.Time { return now },
+			}
+			ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(bootstrap.ClientIPHeader, "203.0.113.7"))
+
+			_, err := s.Login(ctx, &authpb.LoginRequest{Identifier: &authpb.LoginRequest_Email{Email: tt.email}, Password: tt.password})
+
+			errchecks.Assert(t, err, errchecks.HasStatusCode(tt.wantCode))
+			if getUsers != tt.wantGetUsers {
+				t.Errorf("GetUser called %d times, want %d", getUsers, tt.wantGetUsers)
+			}
+			if len(locked) != len(tt.wantLocked) {
+				t.Errorf("locked %v, want %v", locked, tt.wantLocked)
+			}
+			for key, want := range tt.wantLocked {
+				if got := locked[key]; !got.Equal(want) {
+					t.Errorf("%s locked until %v, want %v", key, got, want)
+				}
+			}
+			if len(pub.published) != tt.wantEmails {
+				t.Errorf("sent %d emails, want %d", len(pub.published), tt.wantEmails)
+			}
+		})
+	}
+}
+
+func TestLogin_SameErrorForUnknownEmailAndWrongPassword(t *testing.T) {
+	s := newAuthServerWithMock(&mockUserBaseClient{
+		getUserCredentialsFunc: func(ctx context.Context, in *userbasepb.GetUserRequest, _ ...grpc.CallOption) (*userbasepb.UserCredentials, error) {
+			if in.GetEmail() == "ana@example.com" {
+				return &userbasepb.UserCredentials{User: &userbasepb.User{Id: 42, Email: in.GetEmail()}, PasswordHash: hashPwd(t, "right")}, nil
+			}
+			return nil, status.Error(codes.NotFound, "user not found")
+		},
+	})
+
+	_, wrongPassword := s.Login(context.Background(), &authpb.LoginRequest{Identifier: &authpb.LoginRequest_Email{Email: "ana@example.com"}, Password: "wrong"})
+	_, unknownEmail := s.Login(context.Background(), &authpb.LoginRequest{Identifier: &authpb.LoginRequest_Email{Email: "nobody@example.com"}, Password: "wrong"})
+
+	if wrongPassword == nil || unknownEmail == nil || wrongPassword.Error() != unknownEmail.Error() {
+		t.Errorf("errors differ: wrong password %v, unknown email %v", wrongPassword, unknownEmail)
+	}
+}
+
+func TestLogin_UserNameSharesTheLockoutOfTheEmail(t *testing.T) {
+	now := time.Date(2025, 8, 1, 12, 0, 0, 0, time.UTC)
+	var failed []string
+	s := newAuthServerWithMock(&mockUserBaseClient{
+		getUserCredentialsFunc: func(ctx context.Context, in *userbasepb.GetUserRequest, _ ...grpc.CallOption) (*userbasepb.UserCredentials, error) {
+			if in.GetUserName() == "ana" {
+				return &userbasepb.UserCredentials{User: &userbasepb.User{Id: 42, UserName: "ana", Email: "Ana@example.com"}, PasswordHash: hashPwd(t, "right")}, nil
+			}
+			return nil, status.Error(codes.NotFound, "user not found")
+		},
+	})
+	s.now = func() time.Time { return now }
+	s.storageAccess = newMockStorageAccess(StorageMockOptions{
+		GetAttemptsFunc: func(ctx context.Context, keys ...string) (map[string]loginAttempts, error) {
+			attempts := map[string]loginAttempts{}
+			for _, key := range keys {
+				if key == "account:ana@example.com" {
+					attempts[key] = loginAttempts{failures: 5, lockedUntil: now.Add(time.Minute)}
+				}
+			}
+			return attempts, nil
+		},
+		RecordFailureFunc: func(ctx context.Context, key string, _ time.Time, _ time.Duration) (int, error) {
+			failed = append(failed, key)
+			return 1, nil
+		},
+	})
+
+	_, err := s.Login(context.Background(), &authpb.LoginRequest{Identifier: &authpb.LoginRequest_UserName{UserName: "ana"}, Password: "right"})
+	errchecks.Assert(t, err, errchecks.HasStatusCode(codes.ResourceExhausted))
+
+	_, err = s.Login(context.Background(), &authpb.LoginRequest{Identifier: &authpb.LoginRequest_UserName{UserName: "Nobody"}, Password: "wrong"})
+	errchecks.Assert(t, err, errchecks.HasStatusCode(codes.Unauthenticated))
+	if len(failed) != 1 || failed[0] != "account:@nobody" {
+		t.Errorf("recorded failures %v, want the unknown user name counted on its own", failed)
+	}
+}
@@ -3,51 +3,137 @@ package main
 
 import (
 	"context"
+	"errors"
+	"log"
+	"strings"
 	"time"
 
+	"github.com/Costin2000/GoChat---Schwarz-Internship---2025/pkg/bootstrap"
+	"github.com/Costin2000/GoChat---Schwarz-Internship---2025/pkg/password"
 	userbasepb "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/user-base/proto"
 	"github.com/golang-jwt/jwt/v5"
-	"golang.org/x/crypto/bcrypt"
 
 	proto "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/auth/proto"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
 
+// errInvalidCredentials is the answer to every failed login, so callers cannot tell unknown users
+// from wrong passwords
+var errInvalidCredentials = status.Error(codes.Unauthenticated, "invalid credentials")
+
 func (s *authServer) Login(ctx context.Context, req *proto.LoginRequest) (*proto.LoginResponse, error) {
-	if req.Email == "" || req.Password == "" {
-		return nil, status.Error(codes.InvalidArgument, "email and password are required")
+	var userReq *userbasepb.GetUserRequest
+	// counts the failures while the user is unknown
+	var key string
+	switch id := req.Identifier.(type) {
+	case *proto.LoginRequest_Email:
+		if strings.TrimSpace(id.Email) != "" {
+			userReq = &userbasepb.GetUserRequest{Identifier: &userbasepb.GetUserRequest_Email{Email: id.Email}}
+			key = accountKey(id.Email)
+		}
+	case *proto.LoginRequest_UserName:
+		if strings.TrimSpace(id.UserName) != "" {
+			userReq = &userbasepb.GetUserRequest{Identifier: &userbasepb.GetUserRequest_UserName{UserName: id.UserName}}
+			// user names have no '@', so this is never the key of an email
+			key = accountKey("@" + id.UserName)
+		}
+	}
+	if userReq == nil || req.Password == "" {
+		return nil, status.Error(codes.InvalidArgument, "email or user_name, and password are required")
 	}
 
-	userReq := &userbasepb.GetUserRequest{Email: req.Email}
-	user, err := s.userBaseClient.GetUser(ctx, userReq)
-	if err != nil {
-		if status.Code(err) == codes.NotFound {
-			return nil, status.Errorf(codes.NotFound, "user not found: %v", err)
-		}
+	now := s.now()
+	ip := bootstrap.ClientIP(ctx)
+	device := loginDevice{id: strings.TrimSpace(req.DeviceId), label: strings.TrimSpace(req.DeviceLabel)}
+	if err := s.checkLocked(ctx, now, key, ipKey(ip)); err != nil {
+		return nil, err
+	}
+
+	creds, err := s.userBaseClient.GetUserCredentials(ctx, userReq)
+	if err != nil && status.Code(err) != codes.NotFound {
 		return nil, status.Errorf(codes.Internal, "failed to get user: %v", err)
 	}
+	user := creds.GetUser()
+	if user != nil && accountKey(user.Email) != key {
+		// logging in by user name shares the lockout of the email
+		key = accountKey(user.Email)
+		if err := s.checkLocked(ctx, now, key); err != nil {
+			return nil, err
+		}
+	}
 
-	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password))
+	// bots have no password, they only authenticate with access tokens
+	hash := s.dummyHash
+	if user != nil && !user.IsBot {
+		hash = creds.PasswordHash
+	}
+	ok, rehash := password.Verify(hash, req.Password, s.passwords)
+	if !ok || user == nil || user.IsBot {
+		s.recordFailure(ctx, key, ip, user, now)
+		return nil, errInvalidCredentials
+	}
+	if rehash {
+		s.upgradePasswordHash(ctx, user, creds.PasswordHash, req.Password)
+	}
+
+	if err := s.storageAccess.resetAttempts(ctx, key); err != nil {
+		log.Printf("WARN: could not reset failed logins of user %d: %v", user.Id, err)
+	}
+
+	return s.finishLogin(ctx, user, device, now)
+}
+
+// upgradePasswordHash replaces a bcrypt hash, or an argon2id hash with outdated parameters, with one
+// of the current parameters. The login goes on if it fails, the next one tries again.
+func (s *authServer) upgradePasswordHash(ctx context.Context, user *userbasepb.User, oldHash, plain string) {
+	hash, err := password.Hash(plain, s.passwords)
 	if err != nil {
-		return nil, status.Errorf(codes.Unauthenticated, "invalid credentials: %v", err)
+		log.Printf("WARN: could not rehash the password of user %d: %v", user.Id, err)
+		return
 	}
+	resp, err := s.userBaseClient.UpdatePasswordHash(ctx, &userbasepb.UpdatePasswordHashRequest{
+		UserId:  user.Id,
+		OldHash: oldHash,
+		NewHash: hash,
+	})
+	if err != nil {
+		log.Printf("WARN: could not store the new password hash of user %d: %v", user.Id, err)
+		return
+	}
+	if resp.Updated {
+		log.Printf("Upgraded the password hash of user %d", user.Id)
+	}
+}
 
-	tokenString, err := generateJWT(user.Id)
+// finishLogin returns a challenge for the second factor if the user has one, and starts the session
+// otherwise
+func (s *authServer) finishLogin(ctx context.Context, user *userbasepb.User, device loginDevice, now time.Time) (*proto.LoginResponse, error) {
+	enrolment, err := s.storageAccess.getTOTP(ctx, user.Id)
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "failed to generate token: %v", err)
+		return nil, status.Errorf(codes.Internal, "failed to check two-factor authentication: %v", err)
+	}
+	if enrolment != nil && enrolment.confirmed {
+		challenge, err := generateChallengeToken(user.Id, device, now)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to generate challenge: %v", err)
+		}
+		return &proto.LoginResponse{
+			UserId:         user.Id,
+			MfaRequired:    true,
+			ChallengeToken: challenge,
+		}, nil
 	}
 
-	return &proto.LoginResponse{
-		UserId: user.Id,
-		Token:  tokenString,
-	}, nil
+	return s.startSession(ctx, user, device, now)
 }
 
-func generateJWT(userID int64) (string, error) {
+// generateJWT signs the token of a session; the gateway checks sid is still active on every request
+func generateJWT(userID, sessionID int64, expires time.Time) (string, error) {
 	claims := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
 		"user_id": userID,
-		"exp":     time.Now().Add(time.Hour * 24).Unix(),
+		"sid":     sessionID,
+		"exp":     expires.Unix(),
 	})
 
 	tokenString, err := claims.SignedString(jwtSecret)
@@ -57,3 +143,42 @@ func generateJWT(userID int64) (string, error) {
 
 	return tokenString, nil
 }
+
+// challengeType marks challenge tokens, the gateway does not accept them in place of a JWT
+const challengeType = "mfa_challenge"
+
+// challengeTTL is how long the user has to type the code after the password
+const challengeTTL = 5 * time.Minute
+
+// generateChallengeToken proves that the password of userID was right, for VerifyLoginChallenge. It
+// carries the device of the login to its session.
+func generateChallengeToken(userID int64, device loginDevice, now time.Time) (string, error) {
+	claims := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"user_id":      userID,
+		"typ":          challengeType,
+		"device_id":    device.id,
+		"device_label": device.label,
+		"exp":          now.Add(challengeTTL).Unix(),
+	})
+	return claims.SignedString(jwtSecret)
+}
+
+func parseChallengeToken(tokenString string) (int64, loginDevice, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return jwtSecret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
+	if err != nil {
+		return 0, loginDevice{}, err
+	}
+	if claims["typ"] != challengeType {
+		return 0, loginDevice{}, errors.New("not a challenge token")
+	}
+	id, ok := claims["user_id"].(float64)
+	if !ok || id <= 0 {
+		return 0, loginDevice{}, errors.New("challenge token without user_id")
+	}
+	deviceID, _ := claims["device_id"].(string)
+	deviceLabel, _ := claims["device_label"].(string)
+	return int64(id), loginDevice{id: deviceID, label: deviceLabel}, nil
+}
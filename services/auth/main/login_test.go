@@ -6,6 +6,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/Costin2000/GoChat---Schwarz-Internship---2025/pkg/password"
 	authpb "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/auth/proto"
 	userbasepb "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/user-base/proto"
 	"github.com/golang-jwt/jwt/v5"
@@ -17,7 +18,11 @@ import (
 
 // gRPC client mock for user-base
 type mockUserBaseClient struct {
-	getUserFunc func(ctx context.Context, in *userbasepb.GetUserRequest, opts ...grpc.CallOption) (*userbasepb.User, error)
+	getUserFunc            func(ctx context.Context, in *userbasepb.GetUserRequest, opts ...grpc.CallOption) (*userbasepb.User, error)
+	getUserCredentialsFunc func(ctx context.Context, in *userbasepb.GetUserRequest, opts ...grpc.CallOption) (*userbasepb.UserCredentials, error)
+	listUsersFunc          func(ctx context.Context, in *userbasepb.ListUsersRequest, opts ...grpc.CallOption) (*userbasepb.ListUsersResponse, error)
+	provisionUserFunc      func(ctx context.Context, in *userbasepb.ProvisionUserRequest, opts ...grpc.CallOption) (*userbasepb.ProvisionUserResponse, error)
+	updatePasswordHashFunc func(ctx context.Context, in *userbasepb.UpdatePasswordHashRequest, opts ...grpc.CallOption) (*userbasepb.UpdatePasswordHashResponse, error)
 }
 
 func (m *mockUserBaseClient) GetUser(ctx context.Context, in *userbasepb.GetUserRequest, opts ...grpc.CallOption) (*userbasepb.User, error) {
@@ -27,8 +32,44 @@ func (m *mockUserBaseClient) GetUser(ctx context.Context, in *userbasepb.GetUser
 	return nil, status.Error(codes.NotFound, "not implemented")
 }
 
+func (m *mockUserBaseClient) GetUserCredentials(ctx context.Context, in *userbasepb.GetUserRequest, opts ...grpc.CallOption) (*userbasepb.UserCredentials, error) {
+	if m.getUserCredentialsFunc != nil {
+		return m.getUserCredentialsFunc(ctx, in, opts...)
+	}
+	return nil, status.Error(codes.NotFound, "not implemented")
+}
+
+func (m *mockUserBaseClient) ListUsers(ctx context.Context, in *userbasepb.ListUsersRequest, opts ...grpc.CallOption) (*userbasepb.ListUsersResponse, error) {
+	if m.listUsersFunc != nil {
+		return m.listUsersFunc(ctx, in, opts...)
+	}
+	return &userbasepb.ListUsersResponse{}, nil
+}
+
+func (m *mockUserBaseClient) ProvisionUser(ctx context.Context, in *userbasepb.ProvisionUserRequest, opts ...grpc.CallOption) (*userbasepb.ProvisionUserResponse, error) {
+	if m.provisionUserFunc != nil {
+		return m.provisionUserFunc(ctx, in, opts...)
+	}
+	return nil, status.Error(codes.Unimplemented, "not implemented")
+}
+
+func (m *mockUserBaseClient) UpdatePasswordHash(ctx context.Context, in *userbasepb.UpdatePasswordHashRequest, opts ...grpc.CallOption) (*userbasepb.UpdatePasswordHashResponse, error) {
+	if m.updatePasswordHashFunc != nil {
+		return m.updatePasswordHashFunc(ctx, in, opts...)
+	}
+	return &userbasepb.UpdatePasswordHashResponse{Updated: true}, nil
+}
+
 func newAuthServerWithMock(m *mockUserBaseClient) *authServer {
-	return &authServer{userBaseClient: m}
+	return &authServer{
+		userBaseClient: m,
+		storageAccess:  newMockStorageAccess(StorageMockOptions{}),
+		lockout:        testLockout,
+		secrets:        testSecrets,
+		passwords:      testPasswords,
+		dummyHash:      testDummyHash,
+		now:            time.Now,
+	}
 }
 
 // testing helper, returns the hash bcrypt password for the original one
@@ -83,31 +124,31 @@ func TestLogin(t *testing.T) {
 	}{
 		{
 			name:       "invalid args - empty email",
-			req:        &authpb.LoginRequest{Email: "", Password: "x"},
+			req:        &authpb.LoginRequest{Identifier: &authpb.LoginRequest_Email{Email: ""}, Password: "x"},
 			mockClient: &mockUserBaseClient{},
 			wantCode:   codes.InvalidArgument,
 		},
 		{
 			name:       "invalid args - empty password",
-			req:        &authpb.LoginRequest{Email: "a@b.com", Password: ""},
+			req:        &authpb.LoginRequest{Identifier: &authpb.LoginRequest_Email{Email: "a@b.com"}, Password: ""},
 			mockClient: &mockUserBaseClient{},
 			wantCode:   codes.InvalidArgument,
 		},
 		{
-			name: "user not found",
-			req:  &authpb.LoginRequest{Email: "not@found.com", Password: "anything"},
+			name: "user not found looks like a wrong password",
+			req:  &authpb.LoginRequest{Identifier: &authpb.LoginRequest_Email{Email: "not@found.com"}, Password: "anything"},
 			mockClient: &mockUserBaseClient{
-				getUserFunc: func(ctx context.Context, in *userbasepb.GetUserRequest, _ ...grpc.CallOption) (*userbasepb.User, error) {
+				getUserCredentialsFunc: func(ctx context.Context, in *userbasepb.GetUserRequest, _ ...grpc.CallOption) (*userbasepb.UserCredentials, error) {
 					return nil, status.Error(codes.NotFound, "no such user")
 				},
 			},
-			wantCode: codes.NotFound,
+			wantCode: codes.Unauthenticated,
 		},
 		{
 			name: "user-base internal error",
-			req:  &authpb.LoginRequest{Email: "x@y.com", Password: "x"},
+			req:  &authpb.LoginRequest{Identifier: &authpb.LoginRequest_Email{Email: "x@y.com"}, Password: "x"},
 			mockClient: &mockUserBaseClient{
-				getUserFunc: func(ctx context.Context, in *userbasepb.GetUserRequest, _ ...grpc.CallOption) (*userbasepb.User, error) {
+				getUserCredentialsFunc: func(ctx context.Context, in *userbasepb.GetUserRequest, _ ...grpc.CallOption) (*userbasepb.UserCredentials, error) {
 					return nil, errors.New("db down")
 				},
 			},
@@ -115,13 +156,15 @@ func TestLogin(t *testing.T) {
 		},
 		{
 			name: "invalid password",
-			req:  &authpb.LoginRequest{Email: "u@ex.com", Password: "wrong-password"},
+			req:  &authpb.LoginRequest{Identifier: &authpb.LoginRequest_Email{Email: "u@ex.com"}, Password: "wrong-password"},
 			mockClient: &mockUserBaseClient{
-				getUserFunc: func(ctx context.Context, in *userbasepb.GetUserRequest, _ ...grpc.CallOption) (*userbasepb.User, error) {
-					return &userbasepb.User{
-						Id:       42,
-						Email:    in.Email,
-						Password: hashedOK,
+				getUserCredentialsFunc: func(ctx context.Context, in *userbasepb.GetUserRequest, _ ...grpc.CallOption) (*userbasepb.UserCredentials, error) {
+					return &userbasepb.UserCredentials{
+						User: &userbasepb.User{
+							Id:    42,
+							Email: in.GetEmail(),
+						},
+						PasswordHash: hashedOK,
 					}, nil
 				},
 			},
@@ -129,16 +172,46 @@ func TestLogin(t *testing.T) {
 		},
 		{
 			name: "success",
-			req:  &authpb.LoginRequest{Email: emailOK, Password: passOK},
+			req:  &authpb.LoginRequest{Identifier: &authpb.LoginRequest_Email{Email: emailOK}, Password: passOK},
 			mockClient: &mockUserBaseClient{
-				getUserFunc: func(ctx context.Context, in *userbasepb.GetUserRequest, _ ...grpc.CallOption) (*userbasepb.User, error) {
-					if in.Email != emailOK {
+				getUserCredentialsFunc: func(ctx context.Context, in *userbasepb.GetUserRequest, _ ...grpc.CallOption) (*userbasepb.UserCredentials, error) {
+					if in.GetEmail() != emailOK {
 						return nil, status.Error(codes.NotFound, "unexpected email in test")
 					}
-					return &userbasepb.User{
-						Id:       userIDOK,
-						Email:    emailOK,
-						Password: hashedOK,
+					return &userbasepb.UserCredentials{
+						User: &userbasepb.User{
+							Id:    userIDOK,
+							Email: emailOK,
+						},
+						PasswordHash: hashedOK,
+					}, nil
+				},
+			},
+			wantCode:   codes.OK,
+			wantUserID: userIDOK,
+			checkToken: true,
+		},
+		{
+			name:       "invalid args - no identifier",
+			req:        &authpb.LoginRequest{Password: "x"},
+			mockClient: &mockUserBaseClient{},
+			wantCode:   codes.InvalidArgument,
+		},
+		{
+			name: "success with the user name",
+			req:  &authpb.LoginRequest{Identifier: &authpb.LoginRequest_UserName{UserName: "Tester"}, Password: passOK},
+			mockClient: &mockUserBaseClient{
+				getUserCredentialsFunc: func(ctx context.Context, in *userbasepb.GetUserRequest, _ ...grpc.CallOption) (*userbasepb.UserCredentials, error) {
+					if in.GetUserName() != "Tester" {
+						return nil, status.Error(codes.NotFound, "unexpected user name in test")
+					}
+					return &userbasepb.UserCredentials{
+						User: &userbasepb.User{
+							Id:       userIDOK,
+							UserName: "tester",
+							Email:    emailOK,
+						},
+						PasswordHash: hashedOK,
 					}, nil
 				},
 			},
@@ -214,3 +287,72 @@ func TestLogin(t *testing.T) {
 		})
 	}
 }
+
+func TestLogin_UpgradesPasswordHash(t *testing.T) {
+	current, err := password.Hash("right", testPasswords)
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+	outdated, err := password.Hash("right", password.Params{MemoryKiB: 32, Iterations: 1, Parallelism: 1})
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		stored      string
+		wantUpgrade bool
+	}{
+		{name: "bcrypt", stored: hashPwd(t, "right"), wantUpgrade: true},
+		{name: "argon2id with outdated parameters", stored: outdated, wantUpgrade: true},
+		{name: "current argon2id", stored: current},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var upgrade *userbasepb.UpdatePasswordHashRequest
+			s := newAuthServerWithMock(&mockUserBaseClient{
+				getUserCredentialsFunc: func(ctx context.Context, in *userbasepb.GetUserRequest, _ ...grpc.CallOption) (*userbasepb.UserCredentials, error) {
+					return &userbasepb.UserCredentials{User: &userbasepb.User{Id: 42, Email: in.GetEmail()}, PasswordHash: tt.stored}, nil
+				},
+				updatePasswordHashFunc: func(ctx context.Context, in *userbasepb.UpdatePasswordHashRequest, _ ...grpc.CallOption) (*userbasepb.UpdatePasswordHashResponse, error) {
+					upgrade = in
+					return &userbasepb.UpdatePasswordHashResponse{Updated: true}, nil
+				},
+			})
+
+			if _, err := s.Login(context.Background(), &authpb.LoginRequest{Identifier: &authpb.LoginRequest_Email{Email: "ana@example.com"}, Password: "right"}); err != nil {
+				t.Fatalf("Login: %v", err)
+			}
+
+			if (upgrade != nil) != tt.wantUpgrade {
+				t.Fatalf("upgraded %v, want %v", upgrade, tt.wantUpgrade)
+			}
+			if upgrade == nil {
+				return
+			}
+			if upgrade.UserId != 42 || upgrade.OldHash != tt.stored {
+				t.Errorf("unexpected upgrade %v", upgrade)
+			}
+			if ok, rehash := password.Verify(upgrade.NewHash, "right", testPasswords); !ok || rehash {
+				t.Errorf("new hash %q is not a current hash of the password", upgrade.NewHash)
+			}
+		})
+	}
+}
+
+func TestLogin_UpgradeFailureDoesNotFailLogin(t *testing.T) {
+	s := newAuthServerWithMock(&mockUserBaseClient{
+		getUserCredentialsFunc: func(ctx context.Context, in *userbasepb.GetUserRequest, _ ...grpc.CallOption) (*userbasepb.UserCredentials, error) {
+			return &userbasepb.UserCredentials{User: &userbasepb.User{Id: 42, Email: in.GetEmail()}, PasswordHash: hashPwd(t, "right")}, nil
+		},
+		updatePasswordHashFunc: func(ctx context.Context, in *userbasepb.UpdatePasswordHashRequest, _ ...grpc.CallOption) (*userbasepb.UpdatePasswordHashResponse, error) {
+			return nil, status.Error(codes.Unavailable, "user-base down")
+		},
+	})
+
+	resp, err := s.Login(context.Background(), &authpb.LoginRequest{Identifier: &authpb.LoginRequest_Email{Email: "ana@example.com"}, Password: "right"})
+	if err != nil || resp.Token == "" {
+		t.Fatalf("Login = %v, %v; want a token", resp, err)
+	}
+}
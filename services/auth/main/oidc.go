@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+// oidcStateTTL is how long the user has to log in at the provider
+const oidcStateTTL = 10 * time.Minute
+
+// oidcConfig is the OpenID Connect provider users can log in with; disabled without an issuer
+type oidcConfig struct {
+	Issuer       string `env:"OIDC_ISSUER"`
+	ClientID     string `env:"OIDC_CLIENT_ID"`
+	ClientSecret string `env:"OIDC_CLIENT_SECRET"`
+	// the callback of the gateway, registered at the provider
+	RedirectURL string `env:"OIDC_REDIRECT_URL"`
+	Scopes      string `env:"OIDC_SCOPES" default:"openid email profile"`
+}
+
+func (c *oidcConfig) Validate() error {
+	if c.Issuer == "" {
+		return nil
+	}
+	if c.ClientID == "" || c.RedirectURL == "" {
+		return fmt.Errorf("OIDC_CLIENT_ID and OIDC_REDIRECT_URL are required with OIDC_ISSUER")
+	}
+	return nil
+}
+
+// oidcClaims are the claims of the ID token used to link or create the user
+type oidcClaims struct {
+	Email             string `json:"email"`
+	EmailVerified     bool   `json:"email_verified"`
+	Name              string `json:"name"`
+	GivenName         string `json:"given_name"`
+	FamilyName        string `json:"family_name"`
+	PreferredUsername string `json:"preferred_username"`
+}
+
+// oidcProvider talks to the provider. The discovery document is fetched on first use, so auth
+// starts while the provider is down.
+type oidcProvider struct {
+	cfg    oidcConfig
+	client *http.Client
+
+	mu       sync.Mutex
+	provider *oidc.Provider
+}
+
+func newOIDCProvider(cfg oidcConfig) *oidcProvider {
+	return &oidcProvider{cfg: cfg, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// context makes the oauth2 and oidc calls of ctx use the client of the provider
+func (o *oidcProvider) context(ctx context.Context) context.Context {
+	return oidc.ClientContext(ctx, o.client)
+}
+
+func (o *oidcProvider) discover(ctx context.Context) (*oidc.Provider, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.provider != nil {
+		return o.provider, nil
+	}
+	p, err := oidc.NewProvider(o.context(ctx), o.cfg.Issuer)
+	if err != nil {
+		return nil, err
+	}
+	o.provider = p
+	return p, nil
+}
+
+func (o *oidcProvider) oauth2Config(p *oidc.Provider) *oauth2.Config {
+	return &oauth2.Config{
+		ClientID:     o.cfg.ClientID,
+		ClientSecret: o.cfg.ClientSecret,
+		Endpoint:     p.Endpoint(),
+		RedirectURL:  o.cfg.RedirectURL,
+		Scopes:       strings.Fields(o.cfg.Scopes),
+	}
+}
+
+// exchange trades the code for the ID token and verifies it belongs to st
+func (o *oidcProvider) exchange(ctx context.Context, code string, st *oidcState) (*oidc.IDToken, *oidcClaims, error) {
+	p, err := o.discover(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	ctx = o.context(ctx)
+	tok, err := o.oauth2Config(p).Exchange(ctx, code, oauth2.VerifierOption(st.verifier))
+	if err != nil {
+		return nil, nil, fmt.Errorf("exchange code: %w", err)
+	}
+	raw, ok := tok.Extra("id_token").(string)
+	if !ok {
+		return nil, nil, fmt.Errorf("no id_token in the token response")
+	}
+	idToken, err := p.Verifier(&oidc.Config{ClientID: o.cfg.ClientID}).Verify(ctx, raw)
+	if err != nil {
+		return nil, nil, fmt.Errorf("verify id_token: %w", err)
+	}
+	if idToken.Nonce != st.nonce {
+		return nil, nil, fmt.Errorf("id_token nonce does not match")
+	}
+	var claims oidcClaims
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, nil, fmt.Errorf("parse id_token claims: %w", err)
+	}
+	return idToken, &claims, nil
+}
+
+// names returns the names of a new user for the claims; the user name may be taken already
+func (c *oidcClaims) names() (first, last, userName string) {
+	first, last = strings.TrimSpace(c.GivenName), strings.TrimSpace(c.FamilyName)
+	if first == "" {
+		parts := strings.Fields(c.Name)
+		if len(parts) > 0 {
+			first, last = parts[0], strings.Join(parts[1:], " ")
+		}
+	}
+
+	userName = sanitizeUserName(c.PreferredUsername)
+	if userName == "" {
+		local, _, _ := strings.Cut(c.Email, "@")
+		userName = sanitizeUserName(local)
+	}
+	if userName == "" {
+		userName = "user"
+	}
+	if first == "" {
+		first = userName
+	}
+	return first, last, userName
+}
+
+// sanitizeUserName keeps the letters, digits, dots, dashes and underscores of name, lower case
+func sanitizeUserName(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '.' || r == '-' || r == '_' {
+			b.WriteRune(r)
+		}
+		if b.Len() == 30 {
+			break
+		}
+	}
+	return b.String()
+}
+
+// randomSuffix tells apart a user name that is taken, e.g. "ana-k3xq"
+func randomSuffix() string {
+	return strings.ToLower(rand.Text()[:4])
+}
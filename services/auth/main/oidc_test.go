@@ -0,0 +1,277 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	errchecks "github.com/Costin2000/GoChat---Schwarz-Internship---2025/pkg"
+	"github.com/Costin2000/GoChat---Schwarz-Internship---2025/pkg/oidctest"
+	authpb "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/auth/proto"
+	userbasepb "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/user-base/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// oidcStore keeps the login states and the identity links of a test in memory
+type oidcStore struct {
+	states     map[string]*oidcState
+	identities map[string]int64
+}
+
+func (st *oidcStore) options(opts StorageMockOptions) StorageMockOptions {
+	opts.SaveOIDCStateFunc = func(ctx context.Context, s *oidcState) error {
+		st.states[s.state] = s
+		return nil
+	}
+	opts.TakeOIDCStateFunc = func(ctx context.Context, state string) (*oidcState, error) {
+		s := st.states[state]
+		delete(st.states, state)
+		return s, nil
+	}
+	opts.FindIdentityFunc = func(ctx context.Context, issuer, subject string, _ time.Time) (int64, error) {
+		return st.identities[issuer+"|"+subject], nil
+	}
+	opts.LinkIdentityFunc = func(ctx context.Context, issuer, subject string, userID int64, _ string, _ time.Time) error {
+		st.identities[issuer+"|"+subject] = userID
+		return nil
+	}
+	return opts
+}
+
+// loginWithProvider starts a login, approves it at the provider and completes it
+func loginWithProvider(t *testing.T, s *authServer, provider *oidctest.Provider) (*authpb.LoginResponse, error) {
+	t.Helper()
+	start, err := s.StartOIDCLogin(context.Background(), &authpb.StartOIDCLoginRequest{DeviceId: "d-1"})
+	if err != nil {
+		t.Fatalf("StartOIDCLogin: %v", err)
+	}
+	code, state, err := provider.Authorize(start.AuthorizationUrl)
+	if err != nil {
+		t.Fatalf("Authorize: %v", err)
+	}
+	if state != start.State {
+		t.Fatalf("state = %q, want %q", state, start.State)
+	}
+	return s.CompleteOIDCLogin(context.Background(), &authpb.CompleteOIDCLoginRequest{State: state, Code: code})
+}
+
+func TestOIDCLogin(t *testing.T) {
+	provider := oidctest.New("gochat", "secret")
+	defer provider.Close()
+
+	ana := &userbasepb.User{Id: 42, FirstName: "Ana", Email: "ana@example.com"}
+	tests := []struct {
+		name          string
+		identity      oidctest.Identity
+		linked        int64
+		users         map[string]*userbasepb.User
+		takenNames    map[string]bool
+		totp          bool
+		wantCode      codes.Code
+		wantUserID    int64
+		wantProvision bool
+		wantMFA       bool
+	}{
+		{
+			name:       "linked account",
+			identity:   oidctest.Identity{Subject: "s-1", Email: "other@example.com"},
+			linked:     42,
+			wantUserID: 42,
+		},
+		{
+			name:       "links the user with the verified email",
+			identity:   oidctest.Identity{Subject: "s-1", Email: "ana@example.com", EmailVerified: true},
+			users:      map[string]*userbasepb.User{"ana@example.com": ana},
+			wantUserID: 42,
+		},
+		{
+			name:     "does not link an unverified email",
+			identity: oidctest.Identity{Subject: "s-1", Email: "ana@example.com"},
+			users:    map[string]*userbasepb.User{"ana@example.com": ana},
+			wantCode: codes.PermissionDenied,
+		},
+		{
+			name:     "does not link a bot",
+			identity: oidctest.Identity{Subject: "s-1", Email: "deploy@example.com", EmailVerified: true},
+			users:    map[string]*userbasepb.User{"deploy@example.com": {Id: 7, IsBot: true, OwnerId: 42}},
+			wantCode: codes.PermissionDenied,
+		},
+		{
+			name:          "creates a new user",
+			identity:      oidctest.Identity{Subject: "s-1", Email: "Bob.Smith@example.com", EmailVerified: true, GivenName: "Bob"},
+			wantUserID:    100,
+			wantProvision: true,
+		},
+		{
+			name:          "creates a new user with a free user name",
+			identity:      oidctest.Identity{Subject: "s-1", Email: "bob@example.com", EmailVerified: true, PreferredUsername: "bob"},
+			takenNames:    map[string]bool{"bob": true},
+			wantUserID:    100,
+			wantProvision: true,
+		},
+		{
+			name:       "two-factor authentication still applies",
+			identity:   oidctest.Identity{Subject: "s-1", Email: "ana@example.com", EmailVerified: true},
+			users:      map[string]*userbasepb.User{"ana@example.com": ana},
+			totp:       true,
+			wantUserID: 42,
+			wantMFA:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider.SetIdentity(tt.identity)
+			var provisioned *userbasepb.ProvisionUserRequest
+			s := newAuthServerWithMock(&mockUserBaseClient{
+				getUserFunc: func(ctx context.Context, in *userbasepb.GetUserRequest, _ ...grpc.CallOption) (*userbasepb.User, error) {
+					if u, ok := tt.users[in.GetEmail()]; ok {
+						return u, nil
+					}
+					return nil, status.Error(codes.NotFound, "user not found")
+				},
+				listUsersFunc: func(ctx context.Context, in *userbasepb.ListUsersRequest, _ ...grpc.CallOption) (*userbasepb.ListUsersResponse, error) {
+					return &userbasepb.ListUsersResponse{Users: []*userbasepb.User{ana}}, nil
+				},
+				provisionUserFunc: func(ctx context.Context, in *userbasepb.ProvisionUserRequest, _ ...grpc.CallOption) (*userbasepb.ProvisionUserResponse, error) {
+					if tt.takenNames[in.UserName] {
+						return nil, status.Error(codes.AlreadyExists, "user with this email or username already exists")
+					}
+					provisioned = in
+					return &userbasepb.ProvisionUserResponse{User: &userbasepb.User{Id: 100, FirstName: in.FirstName, UserName: in.UserName, Email: in.Email}}, nil
+				},
+			})
+			s.oidc = newOIDCProvider(oidcConfig{
+				Issuer:       provider.Issuer(),
+				ClientID:     "gochat",
+				ClientSecret: "secret",
+				RedirectURL:  "http://gateway.test/v1/auth/oidc/callback",
+				Scopes:       "openid email profile",
+			})
+			store := &oidcStore{states: map[string]*oidcState{}, identities: map[string]int64{}}
+			if tt.linked != 0 {
+				store.identities[provider.Issuer()+"|"+tt.identity.Subject] = tt.linked
+			}
+			var sessionUser int64
+			s.storageAccess = newMockStorageAccess(store.options(StorageMockOptions{
+				GetTOTPFunc: func(ctx context.Context, userID int64) (*totpEnrolment, error) {
+					if tt.totp {
+						return &totpEnrolment{confirmed: true}, nil
+					}
+					return nil, nil
+				},
+				CreateSessionFunc: func(ctx context.Context, sess *session) (int64, error) {
+					sessionUser = sess.userID
+					if sess.deviceKey != "id:d-1" {
+						t.Errorf("device key = %q, want the device of the start", sess.deviceKey)
+					}
+					return 1, nil
+				},
+			}))
+
+			resp, err := loginWithProvider(t, s, provider)
+
+			errchecks.Assert(t, err, errchecks.HasStatusCode(tt.wantCode))
+			if tt.wantCode != codes.OK {
+				if len(store.identities) > 0 && tt.linked == 0 {
+					t.Errorf("linked %v after a failed login", store.identities)
+				}
+				return
+			}
+			if resp.UserId != tt.wantUserID || resp.MfaRequired != tt.wantMFA {
+				t.Errorf("unexpected response %v", resp)
+			}
+			if !tt.wantMFA && (resp.Token == "" || sessionUser != tt.wantUserID) {
+				t.Errorf("no session for user %d, got token %q of user %d", tt.wantUserID, resp.Token, sessionUser)
+			}
+			if got := store.identities[provider.Issuer()+"|"+tt.identity.Subject]; got != tt.wantUserID {
+				t.Errorf("identity linked to %d, want %d", got, tt.wantUserID)
+			}
+			if (provisioned != nil) != tt.wantProvision {
+				t.Fatalf("provisioned %v, want %v", provisioned, tt.wantProvision)
+			}
+			if provisioned != nil && (provisioned.Email != tt.identity.Email || !strings.HasPrefix(provisioned.UserName, strings.ToLower(provisioned.FirstName))) {
+				t.Errorf("unexpected new user %v", provisioned)
+			}
+			if tt.takenNames[provisioned.GetUserName()] {
+				t.Errorf("user name %q is taken", provisioned.UserName)
+			}
+		})
+	}
+}
+
+func TestCompleteOIDCLogin_StateIsUsedOnce(t *testing.T) {
+	provider := oidctest.New("gochat", "secret")
+	defer provider.Close()
+	provider.SetIdentity(oidctest.Identity{Subject: "s-1", Email: "ana@example.com", EmailVerified: true})
+
+	s := newAuthServerWithMock(&mockUserBaseClient{
+		getUserFunc: func(ctx context.Context, in *userbasepb.GetUserRequest, _ ...grpc.CallOption) (*userbasepb.User, error) {
+			return &userbasepb.User{Id: 42, Email: in.GetEmail()}, nil
+		},
+	})
+	s.oidc = newOIDCProvider(oidcConfig{Issuer: provider.Issuer(), ClientID: "gochat", ClientSecret: "secret", RedirectURL: "http://gateway.test/cb"})
+	store := &oidcStore{states: map[string]*oidcState{}, identities: map[string]int64{}}
+	s.storageAccess = newMockStorageAccess(store.options(StorageMockOptions{}))
+
+	start, err := s.StartOIDCLogin(context.Background(), &authpb.StartOIDCLoginRequest{})
+	if err != nil {
+		t.Fatalf("StartOIDCLogin: %v", err)
+	}
+	if !strings.Contains(start.AuthorizationUrl, "code_challenge_method=S256") {
+		t.Errorf("authorization url without PKCE: %s", start.AuthorizationUrl)
+	}
+	code, state, err := provider.Authorize(start.AuthorizationUrl)
+	if err != nil {
+		t.Fatalf("Authorize: %v", err)
+	}
+	if _, err := s.CompleteOIDCLogin(context.Background(), &authpb.CompleteOIDCLoginRequest{State: state, Code: code}); err != nil {
+		t.Fatalf("CompleteOIDCLogin: %v", err)
+	}
+
+	_, err = s.CompleteOIDCLogin(context.Background(), &authpb.CompleteOIDCLoginRequest{State: state, Code: code})
+
+	errchecks.Assert(t, err, errchecks.HasStatusCode(codes.Unauthenticated))
+}
+
+func TestCompleteOIDCLogin_ExpiredState(t *testing.T) {
+	s := newAuthServerWithMock(&mockUserBaseClient{})
+	s.oidc = newOIDCProvider(oidcConfig{Issuer: "http://unused.test", ClientID: "gochat", RedirectURL: "http://gateway.test/cb"})
+	s.storageAccess = newMockStorageAccess(StorageMockOptions{
+		TakeOIDCStateFunc: func(ctx context.Context, state string) (*oidcState, error) {
+			return &oidcState{state: state, expiresAt: time.Now().Add(-time.Second)}, nil
+		},
+	})
+
+	_, err := s.CompleteOIDCLogin(context.Background(), &authpb.CompleteOIDCLoginRequest{State: "s", Code: "c"})
+
+	errchecks.Assert(t, err, errchecks.HasStatusCode(codes.Unauthenticated))
+}
+
+func TestStartOIDCLogin_Disabled(t *testing.T) {
+	s := newAuthServerWithMock(&mockUserBaseClient{})
+
+	_, err := s.StartOIDCLogin(context.Background(), &authpb.StartOIDCLoginRequest{})
+
+	errchecks.Assert(t, err, errchecks.HasStatusCode(codes.FailedPrecondition))
+}
+
+func TestOIDCClaimsNames(t *testing.T) {
+	tests := []struct {
+		claims                        oidcClaims
+		wantFirst, wantLast, wantUser string
+	}{
+		{oidcClaims{GivenName: "Ana", FamilyName: "Pop", PreferredUsername: "Ana.Pop"}, "Ana", "Pop", "ana.pop"},
+		{oidcClaims{Name: "Ana Maria Pop", Email: "ana+chat@example.com"}, "Ana", "Maria Pop", "anachat"},
+		{oidcClaims{Email: "@example.com"}, "user", "", "user"},
+	}
+	for _, tt := range tests {
+		first, last, user := tt.claims.names()
+		if first != tt.wantFirst || last != tt.wantLast || user != tt.wantUser {
+			t.Errorf("names(%+v) = %q, %q, %q; want %q, %q, %q", tt.claims, first, last, user, tt.wantFirst, tt.wantLast, tt.wantUser)
+		}
+	}
+}
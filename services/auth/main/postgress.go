@@ -0,0 +1,524 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// loginAttempts are the recent failed logins of an account or an IP address
+type loginAttempts struct {
+	failures    int
+	lockedUntil time.Time // zero when not locked
+}
+
+// totpEnrolment is the TOTP secret of a user, sealed by secretBox
+type totpEnrolment struct {
+	secret       string
+	confirmed    bool
+	lastUsedStep int64
+}
+
+// session is a device the user logged in on, one per issued JWT
+type session struct {
+	id          int64
+	userID      int64
+	deviceKey   string
+	deviceLabel string
+	userAgent   string
+	ip          string
+	createdAt   time.Time
+	lastSeenAt  time.Time
+	expiresAt   time.Time
+}
+
+// accessToken is a personal access token, stored by the SHA-256 of its secret
+type accessToken struct {
+	id         int64
+	userID     int64
+	ownerID    int64
+	name       string
+	hash       string
+	prefix     string
+	scopes     []string
+	createdAt  time.Time
+	expiresAt  time.Time
+	lastUsedAt time.Time // zero if never used
+}
+
+// oidcState is a login with the OpenID Connect provider waiting for its callback
+type oidcState struct {
+	state     string
+	verifier  string
+	nonce     string
+	device    loginDevice
+	expiresAt time.Time
+}
+
+type StorageAccess interface {
+	getAttempts(ctx context.Context, keys ...string) (map[string]loginAttempts, error)
+	recordFailure(ctx context.Context, key string, now time.Time, window time.Duration) (int, error)
+	lock(ctx context.Context, key string, until time.Time) error
+	resetAttempts(ctx context.Context, key string) error
+	purgeAttempts(ctx context.Context, before time.Time) (int64, error)
+
+	getTOTP(ctx context.Context, userID int64) (*totpEnrolment, error)
+	saveTOTP(ctx context.Context, userID int64, secret string) (bool, error)
+	confirmTOTP(ctx context.Context, userID int64, step int64, recoveryHashes []string) (bool, error)
+	useTOTPStep(ctx context.Context, userID int64, step int64) (bool, error)
+	useRecoveryCode(ctx context.Context, userID int64, hash string) (bool, error)
+	deleteTOTP(ctx context.Context, userID int64) error
+
+	createSession(ctx context.Context, s *session) (int64, error)
+	deviceHistory(ctx context.Context, userID int64, deviceKey string) (sessions int, sameDevice int, err error)
+	listSessions(ctx context.Context, userID int64, now time.Time) ([]*session, error)
+	revokeSession(ctx context.Context, userID, id int64, now time.Time) (bool, error)
+	touchSession(ctx context.Context, userID, id int64, now time.Time) (bool, error)
+	purgeSessions(ctx context.Context, before time.Time) (int64, error)
+
+	createAccessToken(ctx context.Context, t *accessToken) (int64, error)
+	listAccessTokens(ctx context.Context, ownerID int64, now time.Time) ([]*accessToken, error)
+	revokeAccessToken(ctx context.Context, ownerID, id int64, now time.Time) (bool, error)
+	useAccessToken(ctx context.Context, hash string, now time.Time) (*accessToken, error)
+
+	saveOIDCState(ctx context.Context, st *oidcState) error
+	takeOIDCState(ctx context.Context, state string) (*oidcState, error)
+	purgeOIDCStates(ctx context.Context, before time.Time) (int64, error)
+	findIdentity(ctx context.Context, issuer, subject string, now time.Time) (int64, error)
+	linkIdentity(ctx context.Context, issuer, subject string, userID int64, email string, now time.Time) error
+}
+
+type PostgresAccess struct{ db *sql.DB }
+
+func newPostgresAccess(db *sql.DB) *PostgresAccess { return &PostgresAccess{db: db} }
+
+func (pa *PostgresAccess) getAttempts(ctx context.Context, keys ...string) (map[string]loginAttempts, error) {
+	query := `SELECT key, failures, locked_until FROM login_attempts WHERE key = ANY($1);`
+	rows, err := pa.db.QueryContext(ctx, query, keys)
+	if err != nil {
+		return nil, fmt.Errorf("select login attempts: %w", err)
+	}
+	defer rows.Close()
+
+	attempts := map[string]loginAttempts{}
+	for rows.Next() {
+		var (
+			key         string
+			a           loginAttempts
+			lockedUntil sql.NullTime
+		)
+		if err := rows.Scan(&key, &a.failures, &lockedUntil); err != nil {
+			return nil, fmt.Errorf("scan login attempts: %w", err)
+		}
+		a.lockedUntil = lockedUntil.Time
+		attempts[key] = a
+	}
+	return attempts, rows.Err()
+}
+
+// recordFailure counts a failed login and returns the failures within window, this one included
+func (pa *PostgresAccess) recordFailure(ctx context.Context, key string, now time.Time, window time.Duration) (int, error) {
+	query := `
+		INSERT INTO login_attempts (key, failures, last_failure_at)
+		VALUES ($1, 1, $2)
+		ON CONFLICT (key) DO UPDATE SET
+			failures = CASE
+				WHEN login_attempts.last_failure_at < $2 - make_interval(secs => $3) THEN 1
+				ELSE login_attempts.failures + 1
+			END,
+			last_failure_at = $2
+		RETURNING failures;
+	`
+	var failures int
+	if err := pa.db.QueryRowContext(ctx, query, key, now, window.Seconds()).Scan(&failures); err != nil {
+		return 0, fmt.Errorf("record login failure: %w", err)
+	}
+	return failures, nil
+}
+
+func (pa *PostgresAccess) lock(ctx context.Context, key string, until time.Time) error {
+	query := `UPDATE login_attempts SET locked_until = $2 WHERE key = $1;`
+	if _, err := pa.db.ExecContext(ctx, query, key, until); err != nil {
+		return fmt.Errorf("lock login: %w", err)
+	}
+	return nil
+}
+
+func (pa *PostgresAccess) resetAttempts(ctx context.Context, key string) error {
+	query := `DELETE FROM login_attempts WHERE key = $1;`
+	if _, err := pa.db.ExecContext(ctx, query, key); err != nil {
+		return fmt.Errorf("reset login attempts: %w", err)
+	}
+	return nil
+}
+
+// purgeAttempts drops the rows whose failures and lock are both over
+func (pa *PostgresAccess) purgeAttempts(ctx context.Context, before time.Time) (int64, error) {
+	query := `
+		DELETE FROM login_attempts
+		WHERE last_failure_at < $1 AND (locked_until IS NULL OR locked_until < $1);
+	`
+	res, err := pa.db.ExecContext(ctx, query, before)
+	if err != nil {
+		return 0, fmt.Errorf("purge login attempts: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+// getTOTP returns nil if the user never started enrolment
+func (pa *PostgresAccess) getTOTP(ctx context.Context, userID int64) (*totpEnrolment, error) {
+	query := `SELECT secret, confirmed_at IS NOT NULL, last_used_step FROM user_totp WHERE user_id = $1;`
+	var e totpEnrolment
+	err := pa.db.QueryRowContext(ctx, query, userID).Scan(&e.secret, &e.confirmed, &e.lastUsedStep)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("select totp: %w", err)
+	}
+	return &e, nil
+}
+
+// saveTOTP starts an enrolment; false if the user already confirmed one
+func (pa *PostgresAccess) saveTOTP(ctx context.Context, userID int64, secret string) (bool, error) {
+	query := `
+		INSERT INTO user_totp (user_id, secret)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET secret = EXCLUDED.secret, last_used_step = 0, created_at = NOW()
+		WHERE user_totp.confirmed_at IS NULL;
+	`
+	res, err := pa.db.ExecContext(ctx, query, userID, secret)
+	if err != nil {
+		return false, fmt.Errorf("save totp: %w", err)
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// confirmTOTP enables the enrolment and replaces the recovery codes; false if it was confirmed meanwhile
+func (pa *PostgresAccess) confirmTOTP(ctx context.Context, userID int64, step int64, recoveryHashes []string) (bool, error) {
+	tx, err := pa.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("begin: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `
+		UPDATE user_totp SET confirmed_at = NOW(), last_used_step = $2
+		WHERE user_id = $1 AND confirmed_at IS NULL AND last_used_step < $2;
+	`, userID, step)
+	if err != nil {
+		return false, fmt.Errorf("confirm totp: %w", err)
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return false, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM totp_recovery_codes WHERE user_id = $1;`, userID); err != nil {
+		return false, fmt.Errorf("delete recovery codes: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO totp_recovery_codes (user_id, code_hash)
+		SELECT $1, unnest($2::TEXT[]);
+	`, userID, recoveryHashes); err != nil {
+		return false, fmt.Errorf("insert recovery codes: %w", err)
+	}
+	return true, tx.Commit()
+}
+
+// useTOTPStep marks the code of a time step as used; false if it, or a later one, was used before
+func (pa *PostgresAccess) useTOTPStep(ctx context.Context, userID int64, step int64) (bool, error) {
+	query := `UPDATE user_totp SET last_used_step = $2 WHERE user_id = $1 AND last_used_step < $2;`
+	res, err := pa.db.ExecContext(ctx, query, userID, step)
+	if err != nil {
+		return false, fmt.Errorf("use totp step: %w", err)
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// useRecoveryCode spends a recovery code; false if it does not exist or was used before
+func (pa *PostgresAccess) useRecoveryCode(ctx context.Context, userID int64, hash string) (bool, error) {
+	query := `
+		UPDATE totp_recovery_codes SET used_at = NOW()
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL;
+	`
+	res, err := pa.db.ExecContext(ctx, query, userID, hash)
+	if err != nil {
+		return false, fmt.Errorf("use recovery code: %w", err)
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (pa *PostgresAccess) deleteTOTP(ctx context.Context, userID int64) error {
+	tx, err := pa.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM totp_recovery_codes WHERE user_id = $1;`, userID); err != nil {
+		return fmt.Errorf("delete recovery codes: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM user_totp WHERE user_id = $1;`, userID); err != nil {
+		return fmt.Errorf("delete totp: %w", err)
+	}
+	return tx.Commit()
+}
+
+func (pa *PostgresAccess) createSession(ctx context.Context, s *session) (int64, error) {
+	query := `
+		INSERT INTO sessions (user_id, device_key, device_label, user_agent, ip, created_at, last_seen_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $6, $7)
+		RETURNING id;
+	`
+	var id int64
+	err := pa.db.QueryRowContext(ctx, query,
+		s.userID, s.deviceKey, s.deviceLabel, s.userAgent, s.ip, s.createdAt, s.expiresAt,
+	).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("insert session: %w", err)
+	}
+	return id, nil
+}
+
+// deviceHistory counts the sessions the user ever had, and those of them on deviceKey
+func (pa *PostgresAccess) deviceHistory(ctx context.Context, userID int64, deviceKey string) (int, int, error) {
+	query := `
+		SELECT COUNT(*), COUNT(*) FILTER (WHERE device_key = $2)
+		FROM sessions WHERE user_id = $1;
+	`
+	var all, same int
+	if err := pa.db.QueryRowContext(ctx, query, userID, deviceKey).Scan(&all, &same); err != nil {
+		return 0, 0, fmt.Errorf("count sessions: %w", err)
+	}
+	return all, same, nil
+}
+
+// listSessions returns the sessions that are neither revoked nor expired, most recently used first
+func (pa *PostgresAccess) listSessions(ctx context.Context, userID int64, now time.Time) ([]*session, error) {
+	query := `
+		SELECT id, user_id, device_key, device_label, user_agent, ip, created_at, last_seen_at, expires_at
+		FROM sessions
+		WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > $2
+		ORDER BY last_seen_at DESC, id DESC;
+	`
+	rows, err := pa.db.QueryContext(ctx, query, userID, now)
+	if err != nil {
+		return nil, fmt.Errorf("select sessions: %w", err)
+	}
+	defer rows.Close()
+
+	var sessions []*session
+	for rows.Next() {
+		s := &session{}
+		if err := rows.Scan(&s.id, &s.userID, &s.deviceKey, &s.deviceLabel, &s.userAgent, &s.ip,
+			&s.createdAt, &s.lastSeenAt, &s.expiresAt); err != nil {
+			return nil, fmt.Errorf("scan session: %w", err)
+		}
+		sessions = append(sessions, s)
+	}
+	return sessions, rows.Err()
+}
+
+// revokeSession signs a session out; false if the user has no such active session
+func (pa *PostgresAccess) revokeSession(ctx context.Context, userID, id int64, now time.Time) (bool, error) {
+	query := `
+		UPDATE sessions SET revoked_at = $3
+		WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL AND expires_at > $3;
+	`
+	res, err := pa.db.ExecContext(ctx, query, id, userID, now)
+	if err != nil {
+		return false, fmt.Errorf("revoke session: %w", err)
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// touchSession records activity on a session; false if it was revoked or expired
+func (pa *PostgresAccess) touchSession(ctx context.Context, userID, id int64, now time.Time) (bool, error) {
+	query := `
+		UPDATE sessions SET last_seen_at = $3
+		WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL AND expires_at > $3;
+	`
+	res, err := pa.db.ExecContext(ctx, query, id, userID, now)
+	if err != nil {
+		return false, fmt.Errorf("touch session: %w", err)
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// purgeSessions drops the sessions that expired before before
+func (pa *PostgresAccess) purgeSessions(ctx context.Context, before time.Time) (int64, error) {
+	res, err := pa.db.ExecContext(ctx, `DELETE FROM sessions WHERE expires_at < $1;`, before)
+	if err != nil {
+		return 0, fmt.Errorf("purge sessions: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+func (pa *PostgresAccess) createAccessToken(ctx context.Context, t *accessToken) (int64, error) {
+	query := `
+		INSERT INTO personal_access_tokens (user_id, owner_id, name, token_hash, prefix, scopes, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id;
+	`
+	var id int64
+	err := pa.db.QueryRowContext(ctx, query,
+		t.userID, t.ownerID, t.name, t.hash, t.prefix, t.scopes, t.createdAt, t.expiresAt,
+	).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("insert access token: %w", err)
+	}
+	return id, nil
+}
+
+// accessTokenColumns are scanned by scanAccessToken; the scopes come as one comma separated string,
+// database/sql cannot scan arrays
+const accessTokenColumns = `id, user_id, owner_id, name, prefix, array_to_string(scopes, ','),
+	created_at, expires_at, last_used_at`
+
+func scanAccessToken(scan func(dest ...any) error) (*accessToken, error) {
+	var (
+		t        accessToken
+		scopes   string
+		lastUsed sql.NullTime
+	)
+	if err := scan(&t.id, &t.userID, &t.ownerID, &t.name, &t.prefix, &scopes,
+		&t.createdAt, &t.expiresAt, &lastUsed); err != nil {
+		return nil, err
+	}
+	if scopes != "" {
+		t.scopes = strings.Split(scopes, ",")
+	}
+	t.lastUsedAt = lastUsed.Time
+	return &t, nil
+}
+
+// listAccessTokens returns the active tokens of the owner and their bots, newest first
+func (pa *PostgresAccess) listAccessTokens(ctx context.Context, ownerID int64, now time.Time) ([]*accessToken, error) {
+	query := `SELECT ` + accessTokenColumns + `
+		FROM personal_access_tokens
+		WHERE owner_id = $1 AND revoked_at IS NULL AND expires_at > $2
+		ORDER BY id DESC;
+	`
+	rows, err := pa.db.QueryContext(ctx, query, ownerID, now)
+	if err != nil {
+		return nil, fmt.Errorf("select access tokens: %w", err)
+	}
+	defer rows.Close()
+
+	var tokens []*accessToken
+	for rows.Next() {
+		t, err := scanAccessToken(rows.Scan)
+		if err != nil {
+			return nil, fmt.Errorf("scan access token: %w", err)
+		}
+		tokens = append(tokens, t)
+	}
+	return tokens, rows.Err()
+}
+
+// revokeAccessToken revokes a token the owner manages; false if there is no such active token
+func (pa *PostgresAccess) revokeAccessToken(ctx context.Context, ownerID, id int64, now time.Time) (bool, error) {
+	query := `
+		UPDATE personal_access_tokens SET revoked_at = $3
+		WHERE id = $1 AND owner_id = $2 AND revoked_at IS NULL AND expires_at > $3;
+	`
+	res, err := pa.db.ExecContext(ctx, query, id, ownerID, now)
+	if err != nil {
+		return false, fmt.Errorf("revoke access token: %w", err)
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// useAccessToken records the use of the token with hash and returns it; nil if it is not active
+func (pa *PostgresAccess) useAccessToken(ctx context.Context, hash string, now time.Time) (*accessToken, error) {
+	query := `
+		UPDATE personal_access_tokens SET last_used_at = $2
+		WHERE token_hash = $1 AND revoked_at IS NULL AND expires_at > $2
+		RETURNING ` + accessTokenColumns + `;
+	`
+	t, err := scanAccessToken(pa.db.QueryRowContext(ctx, query, hash, now).Scan)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("use access token: %w", err)
+	}
+	return t, nil
+}
+
+func (pa *PostgresAccess) saveOIDCState(ctx context.Context, st *oidcState) error {
+	query := `
+		INSERT INTO oidc_login_states (state, code_verifier, nonce, device_id, device_label, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6);
+	`
+	_, err := pa.db.ExecContext(ctx, query, st.state, st.verifier, st.nonce, st.device.id, st.device.label, st.expiresAt)
+	if err != nil {
+		return fmt.Errorf("insert oidc state: %w", err)
+	}
+	return nil
+}
+
+// takeOIDCState deletes and returns a login state, so a callback can use it once; nil if unknown
+func (pa *PostgresAccess) takeOIDCState(ctx context.Context, state string) (*oidcState, error) {
+	query := `
+		DELETE FROM oidc_login_states WHERE state = $1
+		RETURNING code_verifier, nonce, device_id, device_label, expires_at;
+	`
+	st := oidcState{state: state}
+	err := pa.db.QueryRowContext(ctx, query, state).Scan(&st.verifier, &st.nonce, &st.device.id, &st.device.label, &st.expiresAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("take oidc state: %w", err)
+	}
+	return &st, nil
+}
+
+// purgeOIDCStates drops the logins abandoned at the provider that expired before before
+func (pa *PostgresAccess) purgeOIDCStates(ctx context.Context, before time.Time) (int64, error) {
+	res, err := pa.db.ExecContext(ctx, `DELETE FROM oidc_login_states WHERE expires_at < $1;`, before)
+	if err != nil {
+		return 0, fmt.Errorf("purge oidc states: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+// findIdentity returns the user linked to the provider account and records the login; 0 if none
+func (pa *PostgresAccess) findIdentity(ctx context.Context, issuer, subject string, now time.Time) (int64, error) {
+	query := `
+		UPDATE user_identities SET last_login_at = $3
+		WHERE issuer = $1 AND subject = $2
+		RETURNING user_id;
+	`
+	var userID int64
+	err := pa.db.QueryRowContext(ctx, query, issuer, subject, now).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("find identity: %w", err)
+	}
+	return userID, nil
+}
+
+// linkIdentity links the provider account to the user; a link made meanwhile by another login is kept
+func (pa *PostgresAccess) linkIdentity(ctx context.Context, issuer, subject string, userID int64, email string, now time.Time) error {
+	query := `
+		INSERT INTO user_identities (issuer, subject, user_id, email, created_at, last_login_at)
+		VALUES ($1, $2, $3, $4, $5, $5)
+		ON CONFLICT (issuer, subject) DO NOTHING;
+	`
+	if _, err := pa.db.ExecContext(ctx, query, issuer, subject, userID, email, now); err != nil {
+		return fmt.Errorf("link identity: %w", err)
+	}
+	return nil
+}
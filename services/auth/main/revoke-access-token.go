@@ -0,0 +1,27 @@
+package main
+
+import (
+	"context"
+	"log"
+
+	proto "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/auth/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (s *authServer) RevokeAccessToken(ctx context.Context, req *proto.RevokeAccessTokenRequest) (*proto.RevokeAccessTokenResponse, error) {
+	if req.UserId <= 0 || req.TokenId <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "user_id and token_id are required")
+	}
+
+	revoked, err := s.storageAccess.revokeAccessToken(ctx, req.UserId, req.TokenId, s.now())
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to revoke access token: %v", err)
+	}
+	if !revoked {
+		return nil, status.Errorf(codes.NotFound, "access token %d not found", req.TokenId)
+	}
+	log.Printf("User %d revoked access token %d", req.UserId, req.TokenId)
+
+	return &proto.RevokeAccessTokenResponse{}, nil
+}
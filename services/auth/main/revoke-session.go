@@ -0,0 +1,27 @@
+package main
+
+import (
+	"context"
+	"log"
+
+	proto "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/auth/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (s *authServer) RevokeSession(ctx context.Context, req *proto.RevokeSessionRequest) (*proto.RevokeSessionResponse, error) {
+	if req.UserId <= 0 || req.SessionId <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "user_id and session_id are required")
+	}
+
+	revoked, err := s.storageAccess.revokeSession(ctx, req.UserId, req.SessionId, s.now())
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to revoke session: %v", err)
+	}
+	if !revoked {
+		return nil, status.Errorf(codes.NotFound, "session %d not found", req.SessionId)
+	}
+	log.Printf("User %d revoked session %d", req.UserId, req.SessionId)
+
+	return &proto.RevokeSessionResponse{}, nil
+}
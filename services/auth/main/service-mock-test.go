@@ -0,0 +1,325 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/Costin2000/GoChat---Schwarz-Internship---2025/pkg/password"
+)
+
+type mockStorage struct {
+	getAttemptsFunc   func(ctx context.Context, keys ...string) (map[string]loginAttempts, error)
+	recordFailureFunc func(ctx context.Context, key string, now time.Time, window time.Duration) (int, error)
+	lockFunc          func(ctx context.Context, key string, until time.Time) error
+	resetAttemptsFunc func(ctx context.Context, key string) error
+	purgeAttemptsFunc func(ctx context.Context, before time.Time) (int64, error)
+
+	getTOTPFunc         func(ctx context.Context, userID int64) (*totpEnrolment, error)
+	saveTOTPFunc        func(ctx context.Context, userID int64, secret string) (bool, error)
+	confirmTOTPFunc     func(ctx context.Context, userID int64, step int64, recoveryHashes []string) (bool, error)
+	useTOTPStepFunc     func(ctx context.Context, userID int64, step int64) (bool, error)
+	useRecoveryCodeFunc func(ctx context.Context, userID int64, hash string) (bool, error)
+	deleteTOTPFunc      func(ctx context.Context, userID int64) error
+
+	createSessionFunc func(ctx context.Context, s *session) (int64, error)
+	deviceHistoryFunc func(ctx context.Context, userID int64, deviceKey string) (int, int, error)
+	listSessionsFunc  func(ctx context.Context, userID int64, now time.Time) ([]*session, error)
+	revokeSessionFunc func(ctx context.Context, userID, id int64, now time.Time) (bool, error)
+	touchSessionFunc  func(ctx context.Context, userID, id int64, now time.Time) (bool, error)
+	purgeSessionsFunc func(ctx context.Context, before time.Time) (int64, error)
+
+	createAccessTokenFunc func(ctx context.Context, t *accessToken) (int64, error)
+	listAccessTokensFunc  func(ctx context.Context, ownerID int64, now time.Time) ([]*accessToken, error)
+	revokeAccessTokenFunc func(ctx context.Context, ownerID, id int64, now time.Time) (bool, error)
+	useAccessTokenFunc    func(ctx context.Context, hash string, now time.Time) (*accessToken, error)
+
+	saveOIDCStateFunc   func(ctx context.Context, st *oidcState) error
+	takeOIDCStateFunc   func(ctx context.Context, state string) (*oidcState, error)
+	purgeOIDCStatesFunc func(ctx context.Context, before time.Time) (int64, error)
+	findIdentityFunc    func(ctx context.Context, issuer, subject string, now time.Time) (int64, error)
+	linkIdentityFunc    func(ctx context.Context, issuer, subject string, userID int64, email string, now time.Time) error
+}
+
+func (m *mockStorage) getAttempts(ctx context.Context, keys ...string) (map[string]loginAttempts, error) {
+	if m.getAttemptsFunc != nil {
+		return m.getAttemptsFunc(ctx, keys...)
+	}
+	return map[string]loginAttempts{}, nil
+}
+
+func (m *mockStorage) recordFailure(ctx context.Context, key string, now time.Time, window time.Duration) (int, error) {
+	if m.recordFailureFunc != nil {
+		return m.recordFailureFunc(ctx, key, now, window)
+	}
+	return 1, nil
+}
+
+func (m *mockStorage) lock(ctx context.Context, key string, until time.Time) error {
+	if m.lockFunc != nil {
+		return m.lockFunc(ctx, key, until)
+	}
+	return nil
+}
+
+func (m *mockStorage) resetAttempts(ctx context.Context, key string) error {
+	if m.resetAttemptsFunc != nil {
+		return m.resetAttemptsFunc(ctx, key)
+	}
+	return nil
+}
+
+func (m *mockStorage) purgeAttempts(ctx context.Context, before time.Time) (int64, error) {
+	if m.purgeAttemptsFunc != nil {
+		return m.purgeAttemptsFunc(ctx, before)
+	}
+	return 0, nil
+}
+
+func (m *mockStorage) getTOTP(ctx context.Context, userID int64) (*totpEnrolment, error) {
+	if m.getTOTPFunc != nil {
+		return m.getTOTPFunc(ctx, userID)
+	}
+	return nil, nil
+}
+
+func (m *mockStorage) saveTOTP(ctx context.Context, userID int64, secret string) (bool, error) {
+	if m.saveTOTPFunc != nil {
+		return m.saveTOTPFunc(ctx, userID, secret)
+	}
+	return true, nil
+}
+
+func (m *mockStorage) confirmTOTP(ctx context.Context, userID int64, step int64, recoveryHashes []string) (bool, error) {
+	if m.confirmTOTPFunc != nil {
+		return m.confirmTOTPFunc(ctx, userID, step, recoveryHashes)
+	}
+	return true, nil
+}
+
+func (m *mockStorage) useTOTPStep(ctx context.Context, userID int64, step int64) (bool, error) {
+	if m.useTOTPStepFunc != nil {
+		return m.useTOTPStepFunc(ctx, userID, step)
+	}
+	return true, nil
+}
+
+func (m *mockStorage) useRecoveryCode(ctx context.Context, userID int64, hash string) (bool, error) {
+	if m.useRecoveryCodeFunc != nil {
+		return m.useRecoveryCodeFunc(ctx, userID, hash)
+	}
+	return false, nil
+}
+
+func (m *mockStorage) deleteTOTP(ctx context.Context, userID int64) error {
+	if m.deleteTOTPFunc != nil {
+		return m.deleteTOTPFunc(ctx, userID)
+	}
+	return nil
+}
+
+func (m *mockStorage) createSession(ctx context.Context, s *session) (int64, error) {
+	if m.createSessionFunc != nil {
+		return m.createSessionFunc(ctx, s)
+	}
+	return 1, nil
+}
+
+func (m *mockStorage) deviceHistory(ctx context.Context, userID int64, deviceKey string) (int, int, error) {
+	if m.deviceHistoryFunc != nil {
+		return m.deviceHistoryFunc(ctx, userID, deviceKey)
+	}
+	return 0, 0, nil
+}
+
+func (m *mockStorage) listSessions(ctx context.Context, userID int64, now time.Time) ([]*session, error) {
+	if m.listSessionsFunc != nil {
+		return m.listSessionsFunc(ctx, userID, now)
+	}
+	return nil, nil
+}
+
+func (m *mockStorage) revokeSession(ctx context.Context, userID, id int64, now time.Time) (bool, error) {
+	if m.revokeSessionFunc != nil {
+		return m.revokeSessionFunc(ctx, userID, id, now)
+	}
+	return true, nil
+}
+
+func (m *mockStorage) touchSession(ctx context.Context, userID, id int64, now time.Time) (bool, error) {
+	if m.touchSessionFunc != nil {
+		return m.touchSessionFunc(ctx, userID, id, now)
+	}
+	return true, nil
+}
+
+func (m *mockStorage) purgeSessions(ctx context.Context, before time.Time) (int64, error) {
+	if m.purgeSessionsFunc != nil {
+		return m.purgeSessionsFunc(ctx, before)
+	}
+	return 0, nil
+}
+
+func (m *mockStorage) createAccessToken(ctx context.Context, t *accessToken) (int64, error) {
+	if m.createAccessTokenFunc != nil {
+		return m.createAccessTokenFunc(ctx, t)
+	}
+	return 1, nil
+}
+
+func (m *mockStorage) listAccessTokens(ctx context.Context, ownerID int64, now time.Time) ([]*accessToken, error) {
+	if m.listAccessTokensFunc != nil {
+		return m.listAccessTokensFunc(ctx, ownerID, now)
+	}
+	return nil, nil
+}
+
+func (m *mockStorage) revokeAccessToken(ctx context.Context, ownerID, id int64, now time.Time) (bool, error) {
+	if m.revokeAccessTokenFunc != nil {
+		return m.revokeAccessTokenFunc(ctx, ownerID, id, now)
+	}
+	return true, nil
+}
+
+func (m *mockStorage) useAccessToken(ctx context.Context, hash string, now time.Time) (*accessToken, error) {
+	if m.useAccessTokenFunc != nil {
+		return m.useAccessTokenFunc(ctx, hash, now)
+	}
+	return nil, nil
+}
+
+func (m *mockStorage) saveOIDCState(ctx context.Context, st *oidcState) error {
+	if m.saveOIDCStateFunc != nil {
+		return m.saveOIDCStateFunc(ctx, st)
+	}
+	return nil
+}
+
+func (m *mockStorage) takeOIDCState(ctx context.Context, state string) (*oidcState, error) {
+	if m.takeOIDCStateFunc != nil {
+		return m.takeOIDCStateFunc(ctx, state)
+	}
+	return nil, nil
+}
+
+func (m *mockStorage) purgeOIDCStates(ctx context.Context, before time.Time) (int64, error) {
+	if m.purgeOIDCStatesFunc != nil {
+		return m.purgeOIDCStatesFunc(ctx, before)
+	}
+	return 0, nil
+}
+
+func (m *mockStorage) findIdentity(ctx context.Context, issuer, subject string, now time.Time) (int64, error) {
+	if m.findIdentityFunc != nil {
+		return m.findIdentityFunc(ctx, issuer, subject, now)
+	}
+	return 0, nil
+}
+
+func (m *mockStorage) linkIdentity(ctx context.Context, issuer, subject string, userID int64, email string, now time.Time) error {
+	if m.linkIdentityFunc != nil {
+		return m.linkIdentityFunc(ctx, issuer, subject, userID, email, now)
+	}
+	return nil
+}
+
+type StorageMockOptions struct {
+	GetAttemptsFunc   func(ctx context.Context, keys ...string) (map[string]loginAttempts, error)
+	RecordFailureFunc func(ctx context.Context, key string, now time.Time, window time.Duration) (int, error)
+	LockFunc          func(ctx context.Context, key string, until time.Time) error
+	ResetAttemptsFunc func(ctx context.Context, key string) error
+	PurgeAttemptsFunc func(ctx context.Context, before time.Time) (int64, error)
+
+	GetTOTPFunc         func(ctx context.Context, userID int64) (*totpEnrolment, error)
+	SaveTOTPFunc        func(ctx context.Context, userID int64, secret string) (bool, error)
+	ConfirmTOTPFunc     func(ctx context.Context, userID int64, step int64, recoveryHashes []string) (bool, error)
+	UseTOTPStepFunc     func(ctx context.Context, userID int64, step int64) (bool, error)
+	UseRecoveryCodeFunc func(ctx context.Context, userID int64, hash string) (bool, error)
+	DeleteTOTPFunc      func(ctx context.Context, userID int64) error
+
+	CreateSessionFunc func(ctx context.Context, s *session) (int64, error)
+	DeviceHistoryFunc func(ctx context.Context, userID int64, deviceKey string) (int, int, error)
+	ListSessionsFunc  func(ctx context.Context, userID int64, now time.Time) ([]*session, error)
+	RevokeSessionFunc func(ctx context.Context, userID, id int64, now time.Time) (bool, error)
+	TouchSessionFunc  func(ctx context.Context, userID, id int64, now time.Time) (bool, error)
+	PurgeSessionsFunc func(ctx context.Context, before time.Time) (int64, error)
+
+	CreateAccessTokenFunc func(ctx context.Context, t *accessToken) (int64, error)
+	ListAccessTokensFunc  func(ctx context.Context, ownerID int64, now time.Time) ([]*accessToken, error)
+	RevokeAccessTokenFunc func(ctx context.Context, ownerID, id int64, now time.Time) (bool, error)
+	UseAccessTokenFunc    func(ctx context.Context, hash string, now time.Time) (*accessToken, error)
+
+	SaveOIDCStateFunc   func(ctx context.Context, st *oidcState) error
+	TakeOIDCStateFunc   func(ctx context.Context, state string) (*oidcState, error)
+	PurgeOIDCStatesFunc func(ctx context.Context, before time.Time) (int64, error)
+	FindIdentityFunc    func(ctx context.Context, issuer, subject string, now time.Time) (int64, error)
+	LinkIdentityFunc    func(ctx context.Context, issuer, subject string, userID int64, email string, now time.Time) error
+}
+
+func newMockStorageAccess(opts StorageMockOptions) StorageAccess {
+	return &mockStorage{
+		getAttemptsFunc:       opts.GetAttemptsFunc,
+		recordFailureFunc:     opts.RecordFailureFunc,
+		lockFunc:              opts.LockFunc,
+		resetAttemptsFunc:     opts.ResetAttemptsFunc,
+		purgeAttemptsFunc:     opts.PurgeAttemptsFunc,
+		getTOTPFunc:           opts.GetTOTPFunc,
+		saveTOTPFunc:          opts.SaveTOTPFunc,
+		confirmTOTPFunc:       opts.ConfirmTOTPFunc,
+		useTOTPStepFunc:       opts.UseTOTPStepFunc,
+		useRecoveryCodeFunc:   opts.UseRecoveryCodeFunc,
+		deleteTOTPFunc:        opts.DeleteTOTPFunc,
+		createSessionFunc:     opts.CreateSessionFunc,
+		deviceHistoryFunc:     opts.DeviceHistoryFunc,
+		listSessionsFunc:      opts.ListSessionsFunc,
+		revokeSessionFunc:     opts.RevokeSessionFunc,
+		touchSessionFunc:      opts.TouchSessionFunc,
+		purgeSessionsFunc:     opts.PurgeSessionsFunc,
+		createAccessTokenFunc: opts.CreateAccessTokenFunc,
+		listAccessTokensFunc:  opts.ListAccessTokensFunc,
+		revokeAccessTokenFunc: opts.RevokeAccessTokenFunc,
+		useAccessTokenFunc:    opts.UseAccessTokenFunc,
+		saveOIDCStateFunc:     opts.SaveOIDCStateFunc,
+		takeOIDCStateFunc:     opts.TakeOIDCStateFunc,
+		purgeOIDCStatesFunc:   opts.PurgeOIDCStatesFunc,
+		findIdentityFunc:      opts.FindIdentityFunc,
+		linkIdentityFunc:      opts.LinkIdentityFunc,
+	}
+}
+
+type mockEmailPublisher struct {
+	published []EmailMessage
+}
+
+func (m *mockEmailPublisher) Publish(ctx context.Context, msg EmailMessage) error {
+	m.published = append(m.published, msg)
+	return nil
+}
+
+func (m *mockEmailPublisher) Close() error { return nil }
+
+var testLockout = lockoutConfig{
+	MaxAccountFailures: 5,
+	MaxIPFailures:      20,
+	Window:             15 * time.Minute,
+	Lockout:            15 * time.Minute,
+	BaseDelay:          time.Second,
+}
+
+var testSecrets = func() *secretBox {
+	b, err := newSecretBox("test-totp-key")
+	if err != nil {
+		panic(err)
+	}
+	return b
+}()
+
+// cheap argon2id parameters, the tests do not need the memory of production hashes
+var testPasswords = password.Params{MemoryKiB: 64, Iterations: 1, Parallelism: 1}
+
+var testDummyHash = func() string {
+	h, err := password.Hash("not a password", testPasswords)
+	if err != nil {
+		panic(err)
+	}
+	return h
+}()
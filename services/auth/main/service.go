@@ -3,26 +3,74 @@ package main
 
 import (
 	"context"
-	"fmt"
 	"log"
-	"net"
-	"os"
+	"time"
 
+	"github.com/Costin2000/GoChat---Schwarz-Internship---2025/pkg/bootstrap"
+	"github.com/Costin2000/GoChat---Schwarz-Internship---2025/pkg/password"
 	proto "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/auth/proto"
 	userbasepb "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/user-base/proto"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
 )
 
 type userBaseClient interface {
 	GetUser(ctx context.Context, in *userbasepb.GetUserRequest, opts ...grpc.CallOption) (*userbasepb.User, error)
+	GetUserCredentials(ctx context.Context, in *userbasepb.GetUserRequest, opts ...grpc.CallOption) (*userbasepb.UserCredentials, error)
+	ListUsers(ctx context.Context, in *userbasepb.ListUsersRequest, opts ...grpc.CallOption) (*userbasepb.ListUsersResponse, error)
+	ProvisionUser(ctx context.Context, in *userbasepb.ProvisionUserRequest, opts ...grpc.CallOption) (*userbasepb.ProvisionUserResponse, error)
+	UpdatePasswordHash(ctx context.Context, in *userbasepb.UpdatePasswordHashRequest, opts ...grpc.CallOption) (*userbasepb.UpdatePasswordHashResponse, error)
 }
 
-var jwtSecret = []byte(os.Getenv("AUTH_JWT_SECRET"))
+// jwtSecret signs the issued tokens, set from AUTH_JWT_SECRET at startup
+var jwtSecret []byte
 
 type authServer struct {
 	proto.UnimplementedAuthServiceServer
 	userBaseClient userBaseClient
+	storageAccess  StorageAccess
+	emailPub       EmailPublisher
+	lockout        lockoutConfig
+	secrets        *secretBox // nil when AUTH_TOTP_KEY is not set
+	passwords      password.Params
+	// compared against when the email is not registered, so that a login takes as long as one with
+	// a wrong password
+	dummyHash string
+	oidc      *oidcProvider // nil when no identity provider is configured
+	now       func() time.Time
+}
+
+type config struct {
+	Addr         string `env:"AUTH_LISTEN_ADDR" default:":50053"`
+	MetricsAddr  string `env:"AUTH_METRICS_ADDR" default:":9103"`
+	UserBaseAddr string `env:"USER_BASE_ADDR" default:"user-base:50051"`
+	JWTSecret    string `env:"AUTH_JWT_SECRET" required:"true"`
+	TOTPKey      string `env:"AUTH_TOTP_KEY"` // empty disables enrolling in two-factor authentication
+	RabbitMQAddr string `env:"RABBITMQ_ADDR"`
+	SpoolPath    string `env:"EMAIL_SPOOL_PATH" default:"./spool/emails.spool"`
+	Password     password.Params
+	Lockout      lockoutConfig
+	OIDC         oidcConfig
+	DB           bootstrap.DBConfig
+	Shutdown     bootstrap.ShutdownConfig
+	Tracing      bootstrap.TracingConfig
+}
+
+// lookupUser gets a user by id; nil if it does not exist or user-base is unavailable
+func (s *authServer) lookupUser(ctx context.Context, id int64) *userbasepb.User {
+	resp, err := s.userBaseClient.ListUsers(ctx, &userbasepb.ListUsersRequest{
+		PageSize: 1,
+		Filters: []*userbasepb.ListUsersFiltersOneOf{{
+			Filter: &userbasepb.ListUsersFiltersOneOf_UserIds{UserIds: &userbasepb.FilterByIdIn{UserId: []int64{id}}},
+		}},
+	})
+	if err != nil {
+		log.Printf("WARN: could not get user %d: %v", id, err)
+		return nil
+	}
+	if len(resp.Users) == 0 {
+		return nil
+	}
+	return resp.Users[0]
 }
 
 func (s *authServer) Ping(ctx context.Context, in *proto.Empty) (*proto.Pong, error) {
@@ -30,32 +78,89 @@ func (s *authServer) Ping(ctx context.Context, in *proto.Empty) (*proto.Pong, er
 }
 
 func main() {
-	userBaseAddr := os.Getenv("USER_BASE_ADDR")
-	if userBaseAddr == "" {
-		userBaseAddr = "user-base:50051"
+	bootstrap.Init("auth")
+
+	var cfg config
+	if err := bootstrap.LoadConfig(&cfg); err != nil {
+		bootstrap.Fail("load config", err)
 	}
 
-	conn, err := grpc.NewClient(userBaseAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	ctx, stop := bootstrap.SignalContext()
+	defer stop()
+
+	shutdownTracing, err := bootstrap.InitTracing(ctx, cfg.Tracing)
 	if err != nil {
-		log.Fatalf("did not connect to user-base service: %v", err)
+		bootstrap.Fail("init tracing", err)
 	}
-	defer conn.Close()
+	defer shutdownTracing(context.Background())
+	jwtSecret = []byte(cfg.JWTSecret)
+
+	db, err := bootstrap.OpenDB(ctx, cfg.DB)
+	if err != nil {
+		bootstrap.Fail("open database", err)
+	}
+	defer db.Close()
+	// users with two-factor authentication cannot log in without Postgres
+	checks := []bootstrap.Check{db.Check()}
 
-	userBaseClient := userbasepb.NewUserServiceClient(conn)
+	var secrets *secretBox
+	if cfg.TOTPKey != "" {
+		if secrets, err = newSecretBox(cfg.TOTPKey); err != nil {
+			bootstrap.Fail("create secret box", err)
+		}
+	} else {
+		log.Println("WARN: AUTH_TOTP_KEY not set; two-factor authentication cannot be enrolled and only recovery codes are accepted")
+	}
 
-	lis, err := net.Listen("tcp", ":50053")
+	dummyHash, err := password.Hash("not a password", cfg.Password)
 	if err != nil {
-		log.Fatalf("failed to listen: %v", err)
+		bootstrap.Fail("hash dummy password", err)
 	}
 
-	grpcServer := grpc.NewServer()
-	proto.RegisterAuthServiceServer(grpcServer, &authServer{
-		userBaseClient: userBaseClient,
-	})
+	var emailPub EmailPublisher
+	if cfg.RabbitMQAddr != "" {
+		pub, err := newAmqpEmailPublisher(cfg.RabbitMQAddr, cfg.SpoolPath)
+		if err != nil {
+			bootstrap.Fail("create email publisher", err)
+		}
+		defer pub.Close()
+		emailPub = pub
+		checks = append(checks, bootstrap.Check{Name: "rabbitmq", Probe: pub.Ping, Optional: true})
+	} else {
+		log.Println("WARN: RABBITMQ_ADDR not set; lockout emails will not be sent")
+	}
 
-	fmt.Println("Auth gRPC server listening on :50053...")
+	conn, err := bootstrap.Dial(cfg.UserBaseAddr)
+	if err != nil {
+		bootstrap.Fail("dial user-base", err)
+	}
+	defer conn.Close()
+
+	server := &authServer{
+		userBaseClient: userbasepb.NewUserServiceClient(conn),
+		storageAccess:  newPostgresAccess(db.DB),
+		emailPub:       emailPub,
+		lockout:        cfg.Lockout,
+		secrets:        secrets,
+		passwords:      cfg.Password,
+		dummyHash:      dummyHash,
+		now:            time.Now,
+	}
+	if cfg.OIDC.Issuer != "" {
+		server.oidc = newOIDCProvider(cfg.OIDC)
+	}
+	go server.purgeLoop(ctx)
+
+	grpcServer := bootstrap.NewGRPCServer()
+	proto.RegisterAuthServiceServer(grpcServer, server)
+	health := bootstrap.RegisterHealth(grpcServer, checks...)
+	go health.Run(ctx)
+
+	if err := bootstrap.ServeMetrics(ctx, cfg.MetricsAddr); err != nil {
+		bootstrap.Fail("serve metrics", err)
+	}
 
-	if err := grpcServer.Serve(lis); err != nil {
-		log.Fatalf("failed to serve: %v", err)
+	if err := bootstrap.ServeGRPC(ctx, grpcServer, cfg.Addr, health, cfg.Shutdown); err != nil {
+		bootstrap.Fail("serve", err)
 	}
 }
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/Costin2000/GoChat---Schwarz-Internship---2025/pkg/bootstrap"
+	proto "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/auth/proto"
+	userbasepb "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/user-base/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	templateNewLogin = "new_login"
+
+	// tokenTTL is how long a JWT, and its session, is valid
+	tokenTTL = 24 * time.Hour
+	// sessionHistory is how long expired sessions are kept to recognise devices seen before
+	sessionHistory = 90 * 24 * time.Hour
+)
+
+// loginDevice is what the client told about itself at login
+type loginDevice struct {
+	id    string
+	label string
+}
+
+// key tells devices apart: by the id the client keeps, or else by its user agent
+func (d loginDevice) key(userAgent string) string {
+	if d.id != "" {
+		return "id:" + d.id
+	}
+	return "ua:" + userAgent
+}
+
+// startSession records the login of user on device and issues its JWT. The owner gets an email
+// when the device was never used with the account before.
+func (s *authServer) startSession(ctx context.Context, user *userbasepb.User, device loginDevice, now time.Time) (*proto.LoginResponse, error) {
+	sess := &session{
+		userID:      user.Id,
+		deviceLabel: device.label,
+		userAgent:   bootstrap.UserAgent(ctx),
+		ip:          bootstrap.ClientIP(ctx),
+		createdAt:   now,
+		expiresAt:   now.Add(tokenTTL),
+	}
+	sess.deviceKey = device.key(sess.userAgent)
+
+	// the first login of a new account is not worth an alert
+	all, same, err := s.storageAccess.deviceHistory(ctx, user.Id, sess.deviceKey)
+	newDevice := err == nil && all > 0 && same == 0
+	if err != nil {
+		log.Printf("WARN: could not check the devices of user %d: %v", user.Id, err)
+	}
+
+	id, err := s.storageAccess.createSession(ctx, sess)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to create session: %v", err)
+	}
+	sess.id = id
+
+	token, err := generateJWT(user.Id, id, sess.expiresAt)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to generate token: %v", err)
+	}
+
+	if newDevice {
+		s.notifyNewDevice(ctx, user, sess)
+	}
+	return &proto.LoginResponse{UserId: user.Id, Token: token}, nil
+}
+
+// notifyNewDevice tells the owner about a login from a device the account never used
+func (s *authServer) notifyNewDevice(ctx context.Context, user *userbasepb.User, sess *session) {
+	if s.emailPub == nil || user.Email == "" {
+		return
+	}
+	device := sess.deviceLabel
+	if device == "" {
+		device = sess.userAgent
+	}
+	if device == "" {
+		device = "an unknown device"
+	}
+	ip := sess.ip
+	if ip == "" {
+		ip = "unknown"
+	}
+	msg := EmailMessage{
+		To:      user.Email,
+		Subject: "New login to your GoChat account",
+		Body: fmt.Sprintf("Hi %s,\n\nyour GoChat account was just used to log in on a new device:\n\n"+
+			"Device: %s\nIP address: %s\nTime: %s\n\n"+
+			"If this was you, there is nothing to do. If it was not, sign the device out from the list of "+
+			"sessions in your settings and change your password.",
+			user.FirstName, device, ip, sess.createdAt.UTC().Format("2006-01-02 15:04 MST")),
+		UserID:   user.Id,
+		Template: templateNewLogin,
+	}
+	if err := s.emailPub.Publish(ctx, msg); err != nil {
+		log.Printf("WARN: could not send the new login email to user %d: %v", user.Id, err)
+	}
+}
+
+// currentSession is the session the gateway authenticated the call with, 0 if unknown
+func currentSession(ctx context.Context) int64 {
+	v := metadata.ValueFromIncomingContext(ctx, bootstrap.SessionIDHeader)
+	if len(v) == 0 {
+		return 0
+	}
+	id, _ := strconv.ParseInt(v[0], 10, 64)
+	return id
+}
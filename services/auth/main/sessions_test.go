@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	errchecks "github.com/Costin2000/GoChat---Schwarz-Internship---2025/pkg"
+	"github.com/Costin2000/GoChat---Schwarz-Internship---2025/pkg/bootstrap"
+	authpb "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/auth/proto"
+	userbasepb "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/user-base/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestLogin_StartsSession(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	tests := []struct {
+		name          string
+		req           *authpb.LoginRequest
+		allSessions   int
+		sameDevice    int
+		historyErr    error
+		wantDeviceKey string
+		wantEmail     bool
+	}{
+		{
+			name:          "first login of the account",
+			req:           &authpb.LoginRequest{Identifier: &authpb.LoginRequest_Email{Email: "ana@example.com"}, Password: "right"},
+			wantDeviceKey: "ua:Firefox",
+		},
+		{
+			name:          "device seen before",
+			req:           &authpb.LoginRequest{Identifier: &authpb.LoginRequest_Email{Email: "ana@example.com"}, Password: "right", DeviceId: "d-1", DeviceLabel: "laptop"},
+			allSessions:   3,
+			sameDevice:    1,
+			wantDeviceKey: "id:d-1",
+		},
+		{
+			name:          "new device",
+			req:           &authpb.LoginRequest{Identifier: &authpb.LoginRequest_Email{Email: "ana@example.com"}, Password: "right", DeviceId: "d-2"},
+			allSessions:   3,
+			wantDeviceKey: "id:d-2",
+			wantEmail:     true,
+		},
+		{
+			name:          "history unavailable, no alert",
+			req:           &authpb.LoginRequest{Identifier: &authpb.LoginRequest_Email{Email: "ana@example.com"}, Password: "right"},
+			historyErr:    errors.New("db down"),
+			wantDeviceKey: "ua:Firefox",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var created *session
+			pub := &mockEmailPublisher{}
+			s := newAuthServerWithMock(&mockUserBaseClient{
+				getUserCredentialsFunc: func(ctx context.Context, in *userbasepb.GetUserRequest, _ ...grpc.CallOption) (*userbasepb.UserCredentials, error) {
+					return &userbasepb.UserCredentials{User: &userbasepb.User{Id: 42, Email: in.GetEmail(), FirstName: "Ana"}, PasswordHash: hashPwd(t, "right")}, nil
+				},
+			})
+			s.emailPub = pub
+			s.now = func() time.Time { return now }
+			s.storageAccess = newMockStorageAccess(StorageMockOptions{
+				DeviceHistoryFunc: func(ctx context.Context, userID int64, deviceKey string) (int, int, error) {
+					return tt.allSessions, tt.sameDevice, tt.historyErr
+				},
+				CreateSessionFunc: func(ctx context.Context, sess *session) (int64, error) {
+					created = sess
+					return 7, nil
+				},
+			})
+			ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+				bootstrap.ClientIPHeader, "203.0.113.7",
+				bootstrap.UserAgentHeader, "Firefox",
+			))
+
+			resp, err := s.Login(ctx, tt.req)
+			if err != nil {
+				t.Fatalf("Login: %v", err)
+			}
+
+			if created == nil {
+				t.Fatal("no session created")
+			}
+			if created.userID != 42 || created.deviceKey != tt.wantDeviceKey || created.deviceLabel != tt.req.DeviceLabel ||
+				created.userAgent != "Firefox" || created.ip != "203.0.113.7" || !created.expiresAt.Equal(now.Add(tokenTTL)) {
+				t.Errorf("unexpected session %+v", created)
+			}
+			claims := parseJWT(t, resp.Token)
+			if sid, _ := claims["sid"].(float64); sid != 7 {
+				t.Errorf("sid = %v, want 7", claims["sid"])
+			}
+			if got := len(pub.published) == 1; got != tt.wantEmail {
+				t.Errorf("sent %d emails, want email %v", len(pub.published), tt.wantEmail)
+			}
+			if tt.wantEmail && pub.published[0].Template != templateNewLogin {
+				t.Errorf("template = %s", pub.published[0].Template)
+			}
+		})
+	}
+}
+
+func TestListSessions(t *testing.T) {
+	now := time.Now()
+	s := newAuthServerWithMock(&mockUserBaseClient{})
+	s.storageAccess = newMockStorageAccess(StorageMockOptions{
+		ListSessionsFunc: func(ctx context.Context, userID int64, _ time.Time) ([]*session, error) {
+			return []*session{
+				{id: 1, userID: userID, deviceLabel: "phone", lastSeenAt: now},
+				{id: 2, userID: userID, deviceLabel: "laptop", lastSeenAt: now.Add(-time.Hour)},
+			}, nil
+		},
+	})
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(bootstrap.SessionIDHeader, "2"))
+
+	resp, err := s.ListSessions(ctx, &authpb.ListSessionsRequest{UserId: 42})
+	if err != nil {
+		t.Fatalf("ListSessions: %v", err)
+	}
+	if len(resp.Sessions) != 2 || resp.Sessions[0].Id != 2 || !resp.Sessions[0].Current || resp.Sessions[1].Current {
+		t.Errorf("want the current session 2 first, got %v", resp.Sessions)
+	}
+}
+
+func TestCheckSession(t *testing.T) {
+	tests := []struct {
+		name     string
+		req      *authpb.CheckSessionRequest
+		active   bool
+		err      error
+		wantCode codes.Code
+	}{
+		{name: "active", req: &authpb.CheckSessionRequest{UserId: 42, SessionId: 7}, active: true, wantCode: codes.OK},
+		{name: "revoked or expired", req: &authpb.CheckSessionRequest{UserId: 42, SessionId: 7}, wantCode: codes.Unauthenticated},
+		{name: "storage error", req: &authpb.CheckSessionRequest{UserId: 42, SessionId: 7}, err: errors.New("db down"), wantCode: codes.Internal},
+		{name: "token without session", req: &authpb.CheckSessionRequest{UserId: 42}, wantCode: codes.InvalidArgument},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newAuthServerWithMock(&mockUserBaseClient{})
+			s.storageAccess = newMockStorageAccess(StorageMockOptions{
+				TouchSessionFunc: func(ctx context.Context, userID, id int64, _ time.Time) (bool, error) {
+					return tt.active, tt.err
+				},
+			})
+
+			_, err := s.CheckSession(context.Background(), tt.req)
+
+			errchecks.Assert(t, err, errchecks.HasStatusCode(tt.wantCode))
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"log"
+	"strings"
+
+	proto "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/auth/proto"
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var errOIDCDisabled = status.Error(codes.FailedPrecondition, "login with an identity provider is not configured")
+
+func (s *authServer) StartOIDCLogin(ctx context.Context, req *proto.StartOIDCLoginRequest) (*proto.StartOIDCLoginResponse, error) {
+	if s.oidc == nil {
+		return nil, errOIDCDisabled
+	}
+	p, err := s.oidc.discover(ctx)
+	if err != nil {
+		log.Printf("WARN: could not discover the identity provider: %v", err)
+		return nil, status.Error(codes.Unavailable, "the identity provider is unavailable")
+	}
+
+	// the verifier and the nonce stay here, the browser only carries the state
+	st := &oidcState{
+		state:     rand.Text(),
+		verifier:  oauth2.GenerateVerifier(),
+		nonce:     rand.Text(),
+		device:    loginDevice{id: strings.TrimSpace(req.DeviceId), label: strings.TrimSpace(req.DeviceLabel)},
+		expiresAt: s.now().Add(oidcStateTTL),
+	}
+	if err := s.storageAccess.saveOIDCState(ctx, st); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to save login state: %v", err)
+	}
+
+	authURL := s.oidc.oauth2Config(p).AuthCodeURL(st.state, oidc.Nonce(st.nonce), oauth2.S256ChallengeOption(st.verifier))
+	return &proto.StartOIDCLoginResponse{AuthorizationUrl: authURL, State: st.state}, nil
+}
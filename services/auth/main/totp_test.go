@@ -0,0 +1,115 @@
+package main
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// RFC 6238 appendix B, SHA-1; the test vectors have 8 digits, their last 6 are the 6 digit code
+func Test_totpCode(t *testing.T) {
+	secret := []byte("12345678901234567890")
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{unix: 59, want: "287082"},
+		{unix: 1111111109, want: "081804"},
+		{unix: 1234567890, want: "005924"},
+		{unix: 2000000000, want: "279037"},
+	}
+
+	for _, tt := range tests {
+		if got := totpCode(secret, totpStep(time.Unix(tt.unix, 0))); got != tt.want {
+			t.Errorf("totpCode at %d = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func Test_matchTOTP(t *testing.T) {
+	secret := []byte("12345678901234567890")
+	now := time.Unix(1111111109, 0)
+	step := totpStep(now)
+
+	tests := []struct {
+		name     string
+		code     string
+		wantStep int64
+		wantOK   bool
+	}{
+		{name: "current period", code: totpCode(secret, step), wantStep: step, wantOK: true},
+		{name: "previous period", code: totpCode(secret, step-1), wantStep: step - 1, wantOK: true},
+		{name: "next period", code: totpCode(secret, step+1), wantStep: step + 1, wantOK: true},
+		{name: "spaces are ignored", code: " 081 804 ", wantStep: step, wantOK: true},
+		{name: "two periods ago", code: totpCode(secret, step-2)},
+		{name: "wrong length", code: "81804"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotStep, gotOK := matchTOTP(secret, tt.code, now)
+			if gotOK != tt.wantOK || gotStep != tt.wantStep {
+				t.Errorf("matchTOTP(%q) = %d, %v, want %d, %v", tt.code, gotStep, gotOK, tt.wantStep, tt.wantOK)
+			}
+		})
+	}
+}
+
+func Test_provisioningURI(t *testing.T) {
+	uri, err := url.Parse(provisioningURI([]byte("12345678901234567890"), "ana@example.com"))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if uri.Scheme != "otpauth" || uri.Host != "totp" || uri.Path != "/GoChat:ana@example.com" {
+		t.Errorf("unexpected uri %s", uri)
+	}
+	if got := uri.Query().Get("secret"); got != "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ" {
+		t.Errorf("secret = %s", got)
+	}
+}
+
+func Test_secretBox(t *testing.T) {
+	sealed, err := testSecrets.seal([]byte("12345678901234567890"))
+	if err != nil {
+		t.Fatalf("seal: %v", err)
+	}
+	if strings.Contains(sealed, "MTIzNDU2Nzg5") {
+		t.Errorf("sealed secret contains the plain secret: %s", sealed)
+	}
+	got, err := testSecrets.open(sealed)
+	if err != nil || string(got) != "12345678901234567890" {
+		t.Errorf("open = %q, %v", got, err)
+	}
+
+	other, _ := newSecretBox("another-key")
+	if _, err := other.open(sealed); err == nil {
+		t.Errorf("opened with the wrong key")
+	}
+}
+
+func Test_recoveryCodes(t *testing.T) {
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		t.Fatalf("newRecoveryCodes: %v", err)
+	}
+	if len(codes) != recoveryCodeCount || len(hashes) != recoveryCodeCount {
+		t.Fatalf("got %d codes and %d hashes", len(codes), len(hashes))
+	}
+	seen := map[string]bool{}
+	for i, code := range codes {
+		if len(code) != 9 || code[4] != '-' {
+			t.Errorf("code %q is not xxxx-xxxx", code)
+		}
+		if seen[code] {
+			t.Errorf("duplicate code %q", code)
+		}
+		seen[code] = true
+
+		// typed without the dash or in capitals, it is still the same code
+		typed := " " + strings.ToUpper(strings.ReplaceAll(code, "-", "")) + " "
+		if hashRecoveryCode(typed) != hashes[i] {
+			t.Errorf("hash of %q differs from the hash of %q", typed, code)
+		}
+	}
+}
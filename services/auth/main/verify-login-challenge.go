@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/Costin2000/GoChat---Schwarz-Internship---2025/pkg/bootstrap"
+	proto "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/auth/proto"
+	userbasepb "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/user-base/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var errInvalidCode = status.Error(codes.Unauthenticated, "invalid code")
+
+func (s *authServer) VerifyLoginChallenge(ctx context.Context, req *proto.VerifyLoginChallengeRequest) (*proto.LoginResponse, error) {
+	if req.ChallengeToken == "" || req.Code == "" {
+		return nil, status.Error(codes.InvalidArgument, "challenge_token and code are required")
+	}
+	userID, device, err := parseChallengeToken(req.ChallengeToken)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "invalid or expired challenge, log in again")
+	}
+
+	now := s.now()
+	ip := bootstrap.ClientIP(ctx)
+	if err := s.checkLocked(ctx, now, mfaKey(userID), ipKey(ip)); err != nil {
+		return nil, err
+	}
+
+	ok, err := s.verifySecondFactor(ctx, userID, req.Code, now)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		s.recordFailure(ctx, mfaKey(userID), ip, s.lookupUser(ctx, userID), now)
+		return nil, errInvalidCode
+	}
+	if err := s.storageAccess.resetAttempts(ctx, mfaKey(userID)); err != nil {
+		log.Printf("WARN: could not reset failed codes of user %d: %v", userID, err)
+	}
+
+	user := s.lookupUser(ctx, userID)
+	if user == nil {
+		// still log in, only without the new device email
+		user = &userbasepb.User{Id: userID}
+	}
+	return s.startSession(ctx, user, device, now)
+}
+
+// verifySecondFactor checks a code of the authenticator app, or else a recovery code, and spends it
+func (s *authServer) verifySecondFactor(ctx context.Context, userID int64, code string, now time.Time) (bool, error) {
+	enrolment, err := s.storageAccess.getTOTP(ctx, userID)
+	if err != nil {
+		return false, status.Errorf(codes.Internal, "failed to get two-factor authentication: %v", err)
+	}
+	if enrolment == nil || !enrolment.confirmed {
+		return false, status.Error(codes.FailedPrecondition, "two-factor authentication is not enabled")
+	}
+
+	// without AUTH_TOTP_KEY the secret cannot be read, the recovery codes still work
+	if s.secrets != nil {
+		secret, err := s.secrets.open(enrolment.secret)
+		if err != nil {
+			return false, status.Errorf(codes.Internal, "failed to decrypt the TOTP secret: %v", err)
+		}
+		if step, ok := matchTOTP(secret, code, now); ok {
+			// a code is valid for a whole period, it must not log in twice
+			used, err := s.storageAccess.useTOTPStep(ctx, userID, step)
+			if err != nil {
+				return false, status.Errorf(codes.Internal, "failed to use code: %v", err)
+			}
+			return used, nil
+		}
+	}
+
+	used, err := s.storageAccess.useRecoveryCode(ctx, userID, hashRecoveryCode(code))
+	if err != nil {
+		return false, status.Errorf(codes.Internal, "failed to use recovery code: %v", err)
+	}
+	if used {
+		log.Printf("User %d logged in with a recovery code", userID)
+	}
+	return used, nil
+}
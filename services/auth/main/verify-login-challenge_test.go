@@ -0,0 +1,221 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	errchecks "github.com/Costin2000/GoChat---Schwarz-Internship---2025/pkg"
+	authpb "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/auth/proto"
+	userbasepb "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/user-base/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+var testTOTPSecret = []byte("12345678901234567890")
+
+func sealedTestSecret(t *testing.T) string {
+	t.Helper()
+	sealed, err := testSecrets.seal(testTOTPSecret)
+	if err != nil {
+		t.Fatalf("seal: %v", err)
+	}
+	return sealed
+}
+
+func TestLogin_RequiresSecondFactor(t *testing.T) {
+	now := time.Now()
+	s := newAuthServerWithMock(&mockUserBaseClient{
+		getUserCredentialsFunc: func(ctx context.Context, in *userbasepb.GetUserRequest, _ ...grpc.CallOption) (*userbasepb.UserCredentials, error) {
+			return &userbasepb.UserCredentials{User: &userbasepb.User{Id: 42, Email: in.GetEmail()}, PasswordHash: hashPwd(t, "right")}, nil
+		},
+	})
+	s.storageAccess = newMockStorageAccess(StorageMockOptions{
+		GetTOTPFunc: func(ctx context.Context, userID int64) (*totpEnrolment, error) {
+			return &totpEnrolment{secret: sealedTestSecret(t), confirmed: true}, nil
+		},
+	})
+	s.now = func() time.Time { return now }
+
+	resp, err := s.Login(context.Background(), &authpb.LoginRequest{Identifier: &authpb.LoginRequest_Email{Email: "ana@example.com"}, Password: "right"})
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	if !resp.MfaRequired || resp.Token != "" || resp.ChallengeToken == "" {
+		t.Fatalf("want a challenge and no token, got %+v", resp)
+	}
+	if id, _, err := parseChallengeToken(resp.ChallengeToken); err != nil || id != 42 {
+		t.Errorf("parseChallengeToken = %d, %v", id, err)
+	}
+}
+
+func TestVerifyLoginChallenge(t *testing.T) {
+	now := time.Unix(1111111109, 0)
+	step := totpStep(now)
+	// the challenge expiry is checked against the real clock, the codes against now
+	challenge, err := generateChallengeToken(42, loginDevice{}, time.Now())
+	if err != nil {
+		t.Fatalf("generateChallengeToken: %v", err)
+	}
+	expired, _ := generateChallengeToken(42, loginDevice{}, time.Now().Add(-time.Hour))
+	accessToken, _ := generateJWT(42, 1, time.Now().Add(time.Hour))
+
+	tests := []struct {
+		name         string
+		req          *authpb.VerifyLoginChallengeRequest
+		enrolment    *totpEnrolment
+		recoveryHash string // the only unused recovery code
+		wantCode     codes.Code
+		wantFailures int
+	}{
+		{
+			name:     "current code",
+			req:      &authpb.VerifyLoginChallengeRequest{ChallengeToken: challenge, Code: totpCode(testTOTPSecret, step)},
+			wantCode: codes.OK,
+		},
+		{
+			name:         "code used before",
+			req:          &authpb.VerifyLoginChallengeRequest{ChallengeToken: challenge, Code: totpCode(testTOTPSecret, step)},
+			enrolment:    &totpEnrolment{confirmed: true, lastUsedStep: step},
+			wantCode:     codes.Unauthenticated,
+			wantFailures: 1,
+		},
+		{
+			name:         "recovery code",
+			req:          &authpb.VerifyLoginChallengeRequest{ChallengeToken: challenge, Code: "ABCD-EFGH"},
+			recoveryHash: hashRecoveryCode("abcdefgh"),
+			wantCode:     codes.OK,
+		},
+		{
+			name:         "wrong code",
+			req:          &authpb.VerifyLoginChallengeRequest{ChallengeToken: challenge, Code: "000000"},
+			wantCode:     codes.Unauthenticated,
+			wantFailures: 1,
+		},
+		{
+			name:     "expired challenge",
+			req:      &authpb.VerifyLoginChallengeRequest{ChallengeToken: expired, Code: totpCode(testTOTPSecret, step)},
+			wantCode: codes.Unauthenticated,
+		},
+		{
+			name:     "access token is not a challenge",
+			req:      &authpb.VerifyLoginChallengeRequest{ChallengeToken: accessToken, Code: totpCode(testTOTPSecret, step)},
+			wantCode: codes.Unauthenticated,
+		},
+		{
+			name:      "two-factor authentication was disabled meanwhile",
+			req:       &authpb.VerifyLoginChallengeRequest{ChallengeToken: challenge, Code: totpCode(testTOTPSecret, step)},
+			enrolment: &totpEnrolment{},
+			wantCode:  codes.FailedPrecondition,
+		},
+		{
+			name:     "missing code",
+			req:      &authpb.VerifyLoginChallengeRequest{ChallengeToken: challenge},
+			wantCode: codes.InvalidArgument,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			enrolment := tt.enrolment
+			if enrolment == nil {
+				enrolment = &totpEnrolment{confirmed: true}
+			}
+			enrolment.secret = sealedTestSecret(t)
+			failures := 0
+
+			s := newAuthServerWithMock(&mockUserBaseClient{})
+			s.now = func() time.Time { return now }
+			s.storageAccess = newMockStorageAccess(StorageMockOptions{
+				GetTOTPFunc: func(ctx context.Context, userID int64) (*totpEnrolment, error) {
+					return enrolment, nil
+				},
+				UseTOTPStepFunc: func(ctx context.Context, userID int64, step int64) (bool, error) {
+					return enrolment.lastUsedStep < step, nil
+				},
+				UseRecoveryCodeFunc: func(ctx context.Context, userID int64, hash string) (bool, error) {
+					return tt.recoveryHash != "" && hash == tt.recoveryHash, nil
+				},
+				RecordFailureFunc: func(ctx context.Context, key string, _ time.Time, _ time.Duration) (int, error) {
+					failures++
+					return 1, nil
+				},
+			})
+
+			resp, err := s.VerifyLoginChallenge(context.Background(), tt.req)
+
+			errchecks.Assert(t, err, errchecks.HasStatusCode(tt.wantCode))
+			if tt.wantCode == codes.OK && (resp.Token == "" || resp.UserId != 42) {
+				t.Errorf("want a token for user 42, got %+v", resp)
+			}
+			// against the user only, the test has no client address
+			if failures != tt.wantFailures {
+				t.Errorf("recorded %d failures, want %d", failures, tt.wantFailures)
+			}
+		})
+	}
+}
+
+func TestConfirmTOTP(t *testing.T) {
+	now := time.Unix(1111111109, 0)
+	step := totpStep(now)
+
+	tests := []struct {
+		name      string
+		code      string
+		enrolment *totpEnrolment
+		wantCode  codes.Code
+	}{
+		{name: "right code", code: totpCode(testTOTPSecret, step), enrolment: &totpEnrolment{}, wantCode: codes.OK},
+		{name: "wrong code", code: "000000", enrolment: &totpEnrolment{}, wantCode: codes.InvalidArgument},
+		{name: "not enrolled", code: totpCode(testTOTPSecret, step), wantCode: codes.FailedPrecondition},
+		{name: "already enabled", code: totpCode(testTOTPSecret, step), enrolment: &totpEnrolment{confirmed: true}, wantCode: codes.FailedPrecondition},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.enrolment != nil {
+				tt.enrolment.secret = sealedTestSecret(t)
+			}
+			var stored []string
+			s := newAuthServerWithMock(&mockUserBaseClient{})
+			s.now = func() time.Time { return now }
+			s.storageAccess = newMockStorageAccess(StorageMockOptions{
+				GetTOTPFunc: func(ctx context.Context, userID int64) (*totpEnrolment, error) {
+					return tt.enrolment, nil
+				},
+				ConfirmTOTPFunc: func(ctx context.Context, userID int64, gotStep int64, recoveryHashes []string) (bool, error) {
+					if gotStep != step {
+						t.Errorf("confirmed at step %d, want %d", gotStep, step)
+					}
+					stored = recoveryHashes
+					return true, nil
+				},
+			})
+
+			resp, err := s.ConfirmTOTP(context.Background(), &authpb.ConfirmTOTPRequest{UserId: 42, Code: tt.code})
+
+			errchecks.Assert(t, err, errchecks.HasStatusCode(tt.wantCode))
+			if tt.wantCode != codes.OK {
+				return
+			}
+			if len(resp.RecoveryCodes) != recoveryCodeCount || len(stored) != recoveryCodeCount {
+				t.Fatalf("got %d codes, stored %d hashes", len(resp.RecoveryCodes), len(stored))
+			}
+			for i, code := range resp.RecoveryCodes {
+				if hashRecoveryCode(code) != stored[i] {
+					t.Errorf("stored hash %d does not match code %q", i, code)
+				}
+			}
+		})
+	}
+}
+
+func TestEnrollTOTP_DisabledWithoutKey(t *testing.T) {
+	s := newAuthServerWithMock(&mockUserBaseClient{})
+	s.secrets = nil
+
+	_, err := s.EnrollTOTP(context.Background(), &authpb.EnrollTOTPRequest{UserId: 42})
+
+	errchecks.Assert(t, err, errchecks.All(errchecks.HasStatusCode(codes.FailedPrecondition), errchecks.MsgContains("not configured")))
+}
@@ -9,6 +9,7 @@ import (
 	"testing"
 
 	errchecks "github.com/Costin2000/GoChat---Schwarz-Internship---2025/pkg"
+	"github.com/Costin2000/GoChat---Schwarz-Internship---2025/pkg/bootstrap"
 	pb "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/conversation-base/proto"
 	"github.com/google/go-cmp/cmp"
 	"google.golang.org/protobuf/testing/protocmp"
@@ -118,7 +119,7 @@ func TestCreateConversation_Integration(t *testing.T) {
 
 	// load env
 	envPath := "./../../../devtest-db/.env"
-	if err := loadEnv(envPath); err != nil {
+	if err := bootstrap.LoadEnvFile(envPath); err != nil {
 		t.Fatalf("Error loading env: %v", err)
 	}
 
@@ -0,0 +1,23 @@
+package main
+
+import (
+	"context"
+	"strconv"
+
+	proto "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/conversation-base/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (svc *conversationService) GetConversation(ctx context.Context, req *proto.GetConversationRequest) (*proto.GetConversationResponse, error) {
+	id, err := strconv.ParseInt(req.Id, 10, 64)
+	if err != nil || id <= 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "id must be a positive number")
+	}
+
+	conv, err := svc.storageAccess.getConversation(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return &proto.GetConversationResponse{Conversation: conv}, nil
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	errchecks "github.com/Costin2000/GoChat---Schwarz-Internship---2025/pkg"
+	pb "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/conversation-base/proto"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/testing/protocmp"
+)
+
+func Test_GetConversation(t *testing.T) {
+	conv := &pb.Conversation{Id: "1", User1Id: "111", User2Id: "222"}
+
+	tests := []struct {
+		name        string
+		req         *pb.GetConversationRequest
+		mockFunc    func(ctx context.Context, id int64) (*pb.Conversation, error)
+		expecterErr errchecks.Check
+		expected    *pb.GetConversationResponse
+	}{
+		{
+			name:        "Error - invalid id",
+			req:         &pb.GetConversationRequest{Id: "abc"},
+			expecterErr: errchecks.IsInvalidArgument(nil),
+		},
+		{
+			name: "Error - not found",
+			req:  &pb.GetConversationRequest{Id: "2"},
+			mockFunc: func(ctx context.Context, id int64) (*pb.Conversation, error) {
+				return nil, status.Error(codes.NotFound, "conversation not found")
+			},
+			expecterErr: errchecks.HasStatusCode(codes.NotFound),
+		},
+		{
+			name: "Happy path",
+			req:  &pb.GetConversationRequest{Id: "1"},
+			mockFunc: func(ctx context.Context, id int64) (*pb.Conversation, error) {
+				return conv, nil
+			},
+			expected: &pb.GetConversationResponse{Conversation: conv},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := NewMockService(ServiceMockOptions{
+				storageAccess: newMockStorageAccess(StorageMockOptions{
+					getConversationFunc: tt.mockFunc,
+				}),
+			})
+
+			resp, err := svc.GetConversation(context.Background(), tt.req)
+			errchecks.Assert(t, err, tt.expecterErr)
+
+			if diff := cmp.Diff(tt.expected, resp, protocmp.Transform()); diff != "" {
+				t.Errorf("Mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -22,6 +22,7 @@ const (
 type StorageAccess interface {
 	createConversation(ctx context.Context, req *proto.CreateConversationRequest) (*proto.CreateConversationResponse, error)
 	listConversations(ctx context.Context, req *proto.ListConversationsRequest) (*proto.ListConversationsResponse, error)
+	getConversation(ctx context.Context, id int64) (*proto.Conversation, error)
 }
 
 type PostgresAccess struct {
@@ -122,3 +123,32 @@ func (pa *PostgresAccess) listConversations(ctx context.Context, req *proto.List
 
 	return &proto.ListConversationsResponse{Conversations: conversations}, nil
 }
+
+func (pa *PostgresAccess) getConversation(ctx context.Context, id int64) (*proto.Conversation, error) {
+	query := `
+		SELECT user1_id, user2_id, created_at, updated_at
+		FROM "Conversation"
+		WHERE id = $1;
+	`
+	var (
+		user1ID int64
+		user2ID int64
+		created time.Time
+		updated time.Time
+	)
+	err := pa.db.QueryRowContext(ctx, query, id).Scan(&user1ID, &user2ID, &created, &updated)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, status.Error(codes.NotFound, "conversation not found")
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to get conversation: %v", err)
+	}
+
+	return &proto.Conversation{
+		Id:        strconv.FormatInt(id, 10),
+		User1Id:   strconv.FormatInt(user1ID, 10),
+		User2Id:   strconv.FormatInt(user2ID, 10),
+		CreatedAt: timestamppb.New(created),
+		UpdatedAt: timestamppb.New(updated),
+	}, nil
+}
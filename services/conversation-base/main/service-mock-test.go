@@ -10,6 +10,7 @@ import (
 type mockStorage struct {
 	createConversationFunc func(ctx context.Context, req *pb.CreateConversationRequest) (*pb.CreateConversationResponse, error)
 	listConversationsFunc  func(ctx context.Context, req *pb.ListConversationsRequest) (*pb.ListConversationsResponse, error)
+	getConversationFunc    func(ctx context.Context, id int64) (*pb.Conversation, error)
 }
 
 func (m *mockStorage) createConversation(ctx context.Context, req *pb.CreateConversationRequest) (*pb.CreateConversationResponse, error) {
@@ -20,9 +21,14 @@ func (m *mockStorage) listConversations(ctx context.Context, req *pb.ListConvers
 	return m.listConversationsFunc(ctx, req)
 }
 
+func (m *mockStorage) getConversation(ctx context.Context, id int64) (*pb.Conversation, error) {
+	return m.getConversationFunc(ctx, id)
+}
+
 type StorageMockOptions struct {
 	createConversationFunc func(ctx context.Context, req *pb.CreateConversationRequest) (*pb.CreateConversationResponse, error)
 	listConversationsFunc  func(ctx context.Context, req *pb.ListConversationsRequest) (*pb.ListConversationsResponse, error)
+	getConversationFunc    func(ctx context.Context, id int64) (*pb.Conversation, error)
 }
 
 func newMockStorageAccess(opts StorageMockOptions) StorageAccess {
@@ -37,6 +43,7 @@ func newMockStorageAccess(opts StorageMockOptions) StorageAccess {
 	return &mockStorage{
 		createConversationFunc: createConversationFunc,
 		listConversationsFunc:  opts.listConversationsFunc,
+		getConversationFunc:    opts.getConversationFunc,
 	}
 }
 
@@ -1,109 +1,61 @@
 package main
 
 import (
-	"bufio"
-	"database/sql"
-	"fmt"
-	"log"
-	"net"
-	"os"
-	"path/filepath"
-	"strings"
-
+	"context"
+	"github.com/Costin2000/GoChat---Schwarz-Internship---2025/pkg/bootstrap"
 	proto "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/conversation-base/proto"
-	_ "github.com/jackc/pgx/v5/stdlib"
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/reflection"
 )
 
-const port = ":50056"
-
 type conversationService struct {
 	proto.UnimplementedConversationServiceServer
 	storageAccess StorageAccess
 }
 
-// env loader
-func loadEnv(filename string) error {
-	file, err := os.Open(filename)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		line := scanner.Text()
-		if len(line) == 0 || strings.HasPrefix(line, "#") {
-			continue
-		}
-		envs := strings.SplitN(line, "=", 2)
-		if len(envs) != 2 {
-			continue
-		}
-		key := strings.TrimSpace(envs[0])
-		value := strings.TrimSpace(envs[1])
-		_ = os.Setenv(key, value)
-	}
-	return scanner.Err()
+type config struct {
+	Addr        string `env:"CONVERSATION_LISTEN_ADDR" default:":50056"`
+	MetricsAddr string `env:"CONVERSATION_METRICS_ADDR" default:":9106"`
+	DB          bootstrap.DBConfig
+	Shutdown    bootstrap.ShutdownConfig
+	Tracing     bootstrap.TracingConfig
 }
 
 func main() {
-	wd, err := os.Getwd()
-	if err != nil {
-		log.Fatalf("Could not get current working directory: %v", err)
-	}
+	bootstrap.Init("conversation-base")
 
-	envPath := "./db/.env"
-	if filepath.Base(wd) == "conversation-base" {
-		envPath = "./../../db/.env"
+	var cfg config
+	if err := bootstrap.LoadConfig(&cfg); err != nil {
+		bootstrap.Fail("load config", err)
 	}
 
-	if err := loadEnv(envPath); err != nil {
-		log.Fatalf("Error loading .env file: %v", err)
-	}
+	ctx, stop := bootstrap.SignalContext()
+	defer stop()
 
-	dbUser := os.Getenv("POSTGRES_USER")
-	dbPassword := os.Getenv("POSTGRES_PASSWORD")
-	dbName := os.Getenv("POSTGRES_DB")
-	dbPort := os.Getenv("DB_PORT")
-
-	var dbHost string
-	if os.Getenv("ENV") == "docker" {
-		dbHost = "postgres-db"
-	} else {
-		dbHost = os.Getenv("DB_HOST")
-	}
-
-	connStr := fmt.Sprintf("user=%s password=%s host=%s port=%s dbname=%s sslmode=disable", dbUser, dbPassword, dbHost, dbPort, dbName)
-	db, err := sql.Open("pgx", connStr)
+	shutdownTracing, err := bootstrap.InitTracing(ctx, cfg.Tracing)
 	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
-	}
-	defer db.Close()
-
-	if err := db.Ping(); err != nil {
-		log.Fatalf("Failed to ping database: %v", err)
+		bootstrap.Fail("init tracing", err)
 	}
-	log.Println("Successfully connected to PostgreSQL database.")
+	defer shutdownTracing(context.Background())
 
-	lis, err := net.Listen("tcp", port)
+	db, err := bootstrap.OpenDB(ctx, cfg.DB)
 	if err != nil {
-		log.Fatalf("Error polling port %s %v", port, err)
+		bootstrap.Fail("open database", err)
 	}
+	defer db.Close()
 
-	storage := newPostgresAccess(db)
 	ConversationServer := &conversationService{
-		storageAccess: storage,
+		storageAccess: newPostgresAccess(db.DB),
 	}
 
-	grpcServer := grpc.NewServer()
+	grpcServer := bootstrap.NewGRPCServer()
 	proto.RegisterConversationServiceServer(grpcServer, ConversationServer)
-	reflection.Register(grpcServer)
+	health := bootstrap.RegisterHealth(grpcServer, db.Check())
+	go health.Run(ctx)
 
-	fmt.Println("Conversation gRPC server listening on :50056...")
+	if err := bootstrap.ServeMetrics(ctx, cfg.MetricsAddr); err != nil {
+		bootstrap.Fail("serve metrics", err)
+	}
 
-	if err := grpcServer.Serve(lis); err != nil {
-		log.Fatalf("failed to serve: %v", err)
+	if err := bootstrap.ServeGRPC(ctx, grpcServer, cfg.Addr, health, cfg.Shutdown); err != nil {
+		bootstrap.Fail("serve", err)
 	}
 }
@@ -13,12 +13,6 @@ else
   exit 1
 fi
 
-# check for the init.sql file
-if [ ! -f ./../../../devtest-db/init.sql ]; then
-    echo "Error: init.sql file not found in devtest-db."
-    exit 1
-fi
-
 # start PostgreSQL server
 # ------------------------------------
 echo "Checking PostgreSQL server status..."
@@ -48,16 +42,16 @@ else
     echo "-> Role created successfully."
 fi
 
-# Drop + recreate the Database and Run Init Script
+# Drop + recreate the Database and apply the migrations
 # ------------------------------------
 echo "Dropping and recreating database '$POSTGRES_DB'..."
 psql -h "$POSTGRES_HOST" -U "$POSTGRES_USER" -d postgres -c "DROP DATABASE IF EXISTS \"$POSTGRES_DB\" WITH (FORCE);"
 createdb -h "$POSTGRES_HOST" -U "$POSTGRES_USER" "$POSTGRES_DB"
 echo "-> New empty database created."
 
-# execute the init.sql script against the new database
-echo "Running schema from init.sql..."
-psql -h "$POSTGRES_HOST" -U "$POSTGRES_USER" -d "$POSTGRES_DB" -f ./../../../devtest-db/init.sql
-echo "Successfully executed init.sql to set up schema and data."
+echo "Applying migrations from db/migrations..."
+(cd ./../../.. && DB_HOST="$POSTGRES_HOST" go run ./cmd/migrate -env ./devtest-db/.env up)
+echo "Successfully applied the migrations."
+
 
-echo "DB setup complete!"
\ No newline at end of file
+echo "DB setup complete!"
@@ -0,0 +1,293 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Costin2000/GoChat---Schwarz-Internship---2025/pkg/bootstrap"
+	"github.com/Costin2000/GoChat---Schwarz-Internship---2025/pkg/tracing"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"go.opentelemetry.io/otel/codes"
+)
+
+const (
+	consumerTag    = "email-service"
+	confirmTimeout = 10 * time.Second
+	// attemptHeader counts how many times a delivery was handled, it survives the trips through the retry queue
+	attemptHeader = "x-email-attempt"
+)
+
+// errDrop marks deliveries that can never succeed (e.g. invalid JSON); they are rejected instead of retried
+var errDrop = errors.New("drop delivery")
+
+// consumer reads the email queue with a pool of workers. Deliveries are acked only after they were handled,
+// so whatever is in flight when the process dies is redelivered by RabbitMQ instead of lost.
+// Failed deliveries are parked in a retry queue whose messages expire after retryDelay and are
+// dead-lettered back onto the email queue, until maxAttempts is reached.
+type consumer struct {
+	addr         string
+	queue        string
+	workers      int
+	prefetch     int
+	drainTimeout time.Duration
+	maxAttempts  int
+	retryDelay   time.Duration
+	handle       func(ctx context.Context, d amqp.Delivery) error
+
+	connected atomic.Bool
+
+	mu sync.Mutex
+	ch *amqp.Channel // nil while disconnected, used by enqueue
+}
+
+// ping returns an error while the consumer is not connected to RabbitMQ, used by the health check
+func (c *consumer) ping(ctx context.Context) error {
+	if !c.connected.Load() {
+		return errors.New("not connected to RabbitMQ")
+	}
+	return nil
+}
+
+// run consumes until ctx is cancelled, reconnecting whenever the connection or the channel closes
+func (c *consumer) run(ctx context.Context) {
+	backoff := time.Second
+	for {
+		connected, err := c.consume(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if connected {
+			// the connection worked, so the next failure is a new outage and not a retry of this one
+			backoff = time.Second
+		}
+		log.Printf("Consumer stopped: %v, reconnecting in %v...", err, backoff)
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return
+		}
+		backoff = min(2*backoff, 30*time.Second)
+	}
+}
+
+// retryQueue is named after the delay, the TTL of an existing queue cannot be changed
+func (c *consumer) retryQueue() string {
+	return fmt.Sprintf("%s.retry.%s", c.queue, c.retryDelay)
+}
+
+// consume reports whether it got as far as consuming, so run knows the connection worked
+func (c *consumer) consume(ctx context.Context) (bool, error) {
+	conn, err := amqp.Dial(c.addr)
+	if err != nil {
+		return false, fmt.Errorf("dial: %w", err)
+	}
+	defer conn.Close()
+
+	ch, err := conn.Channel()
+	if err != nil {
+		return false, fmt.Errorf("open channel: %w", err)
+	}
+	defer ch.Close()
+
+	// never hold more unacked messages than the workers can process soon
+	if err := ch.Qos(c.prefetch, 0, false); err != nil {
+		return false, fmt.Errorf("set qos: %w", err)
+	}
+	// a failed delivery is acked only after the broker confirmed its copy in the retry queue
+	if err := ch.Confirm(false); err != nil {
+		return false, fmt.Errorf("enable publisher confirms: %w", err)
+	}
+	if _, err := ch.QueueDeclare(c.queue, true, false, false, false, nil); err != nil {
+		return false, fmt.Errorf("declare queue: %w", err)
+	}
+	retryQueue := c.retryQueue()
+	if _, err := ch.QueueDeclare(retryQueue, true, false, false, false, amqp.Table{
+		"x-message-ttl":             c.retryDelay.Milliseconds(),
+		"x-dead-letter-exchange":    "",
+		"x-dead-letter-routing-key": c.queue,
+	}); err != nil {
+		return false, fmt.Errorf("declare retry queue: %w", err)
+	}
+	msgs, err := ch.Consume(c.queue, consumerTag, false, false, false, false, nil)
+	if err != nil {
+		return false, fmt.Errorf("register consumer: %w", err)
+	}
+
+	retry := func(ctx context.Context, msg amqp.Publishing) error {
+		return publishConfirmed(ctx, ch, retryQueue, msg)
+	}
+
+	connClosed := conn.NotifyClose(make(chan *amqp.Error, 1))
+	chClosed := ch.NotifyClose(make(chan *amqp.Error, 1))
+	c.mu.Lock()
+	c.ch = ch
+	c.mu.Unlock()
+	c.connected.Store(true)
+	defer func() {
+		c.connected.Store(false)
+		c.mu.Lock()
+		c.ch = nil
+		c.mu.Unlock()
+	}()
+	log.Printf(" [*] Waiting for email messages with %d workers (prefetch %d)", c.workers, c.prefetch)
+
+	// the workers use their own context, in-flight sends must finish even after shutdown started
+	workCtx, cancelWork := context.WithCancel(context.Background())
+	defer cancelWork()
+
+	var wg sync.WaitGroup
+	for i := 0; i < c.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for d := range msgs {
+				c.deliver(workCtx, d, retry)
+			}
+		}()
+	}
+
+	var result error
+	select {
+	case <-ctx.Done():
+		log.Println("Shutting down: no new deliveries, draining in-flight emails...")
+		// closes msgs; prefetched but unhandled deliveries are requeued by the broker
+		if err := ch.Cancel(consumerTag, false); err != nil {
+			log.Printf("Failed to cancel consumer: %v", err)
+		}
+	case amqpErr := <-connClosed:
+		result = fmt.Errorf("connection closed: %v", amqpErr)
+	case amqpErr := <-chClosed:
+		result = fmt.Errorf("channel closed: %v", amqpErr)
+	}
+
+	drained := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(drained)
+	}()
+	select {
+	case <-drained:
+	case <-time.After(c.drainTimeout):
+		log.Printf("WARN: workers did not finish within %v, unacked emails will be redelivered", c.drainTimeout)
+	}
+	return true, result
+}
+
+// enqueue publishes an email the service wrote itself (e.g. a digest) to the queue, so it gets the same retries
+// as the published ones. It fails while the consumer is not connected.
+func (c *consumer) enqueue(ctx context.Context, body []byte) error {
+	c.mu.Lock()
+	ch := c.ch
+	c.mu.Unlock()
+	if ch == nil {
+		return errors.New("not connected to RabbitMQ")
+	}
+
+	ctx, span, headers := tracing.StartPublish(ctx, c.queue)
+	defer span.End()
+	ctx, cancel := context.WithTimeout(ctx, confirmTimeout)
+	defer cancel()
+	err := publishConfirmed(ctx, ch, c.queue, amqp.Publishing{
+		Headers:      headers,
+		ContentType:  "application/json",
+		DeliveryMode: amqp.Persistent,
+		Timestamp:    time.Now(),
+		Body:         body,
+	})
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return err
+}
+
+// publishConfirmed returns once the broker confirmed msg, the channel must be in confirm mode
+func publishConfirmed(ctx context.Context, ch *amqp.Channel, queue string, msg amqp.Publishing) error {
+	confirm, err := ch.PublishWithDeferredConfirmWithContext(ctx, "", queue, false, false, msg)
+	if err != nil {
+		return err
+	}
+	acked, err := confirm.WaitContext(ctx)
+	if err != nil {
+		return err
+	}
+	if !acked {
+		return fmt.Errorf("publish to %s was not confirmed", queue)
+	}
+	return nil
+}
+
+// handles a delivery in a span that continues the trace of the request that published it
+func (c *consumer) deliver(ctx context.Context, d amqp.Delivery, retry func(context.Context, amqp.Publishing) error) {
+	ctx, span := tracing.StartConsume(ctx, c.queue, d.Headers)
+	defer span.End()
+
+	err := c.handle(ctx, d)
+	if err != nil {
+		span.SetStatus(codes.Error, bootstrap.Redact(err.Error()))
+	}
+	consumedTotal.WithLabelValues(c.queue, c.settle(ctx, d, err, retry)).Inc()
+}
+
+// attemptOf returns which attempt at handling d this is, starting at 1
+func attemptOf(d amqp.Delivery) int32 {
+	switch n := d.Headers[attemptHeader].(type) {
+	case int32:
+		return n
+	case int64:
+		return int32(n)
+	case int:
+		return int32(n)
+	}
+	return 1
+}
+
+// acks handled deliveries; failed ones go through the retry queue until the last attempt, then they are dropped.
+// Returns the outcome for the metrics: acked, retried, requeued or rejected.
+func (c *consumer) settle(ctx context.Context, d amqp.Delivery, err error, retry func(context.Context, amqp.Publishing) error) string {
+	attempt := attemptOf(d)
+	switch {
+	case err == nil:
+		if ackErr := d.Ack(false); ackErr != nil {
+			log.Printf("Failed to ack delivery %d: %v", d.DeliveryTag, ackErr)
+		}
+		return "acked"
+	case errors.Is(err, errDrop) || int(attempt) >= c.maxAttempts:
+		if !errors.Is(err, errDrop) {
+			log.Printf("Dropping delivery %d after %d attempts: %v", d.DeliveryTag, attempt, bootstrap.Redact(err.Error()))
+		}
+		if nackErr := d.Nack(false, false); nackErr != nil {
+			log.Printf("Failed to reject delivery %d: %v", d.DeliveryTag, nackErr)
+		}
+		return "rejected"
+	}
+
+	headers := amqp.Table{}
+	for k, v := range d.Headers {
+		headers[k] = v
+	}
+	headers[attemptHeader] = attempt + 1
+	if retryErr := retry(ctx, amqp.Publishing{
+		Headers:      headers,
+		ContentType:  d.ContentType,
+		DeliveryMode: amqp.Persistent,
+		MessageId:    d.MessageId,
+		Timestamp:    d.Timestamp,
+		Body:         d.Body,
+	}); retryErr != nil {
+		// could not park it, requeue right away rather than lose it
+		log.Printf("Failed to schedule retry of delivery %d: %v", d.DeliveryTag, retryErr)
+		if nackErr := d.Nack(false, true); nackErr != nil {
+			log.Printf("Failed to requeue delivery %d: %v", d.DeliveryTag, nackErr)
+		}
+		return "requeued"
+	}
+	if ackErr := d.Ack(false); ackErr != nil {
+		log.Printf("Failed to ack delivery %d: %v", d.DeliveryTag, ackErr)
+	}
+	return "retried"
+}
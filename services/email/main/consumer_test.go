@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+type acknowledgerMock struct {
+	acked   bool
+	nacked  bool
+	requeue bool
+}
+
+func (m *acknowledgerMock) Ack(tag uint64, multiple bool) error {
+	m.acked = true
+	return nil
+}
+
+func (m *acknowledgerMock) Nack(tag uint64, multiple bool, requeue bool) error {
+	m.nacked = true
+	m.requeue = requeue
+	return nil
+}
+
+func (m *acknowledgerMock) Reject(tag uint64, requeue bool) error {
+	return m.Nack(tag, false, requeue)
+}
+
+func Test_settle(t *testing.T) {
+	c := &consumer{maxAttempts: 3}
+	tests := []struct {
+		name        string
+		attempt     int32
+		err         error
+		retryErr    error
+		want        acknowledgerMock
+		wantRetry   int32
+		wantOutcome string
+	}{
+		{
+			name:        "handled delivery is acked",
+			want:        acknowledgerMock{acked: true},
+			wantOutcome: "acked",
+		},
+		{
+			name:        "first failure is parked for a retry",
+			err:         errors.New("smtp timeout"),
+			want:        acknowledgerMock{acked: true},
+			wantRetry:   2,
+			wantOutcome: "retried",
+		},
+		{
+			name:        "failure before the last attempt is parked for a retry",
+			attempt:     2,
+			err:         errors.New("smtp timeout"),
+			want:        acknowledgerMock{acked: true},
+			wantRetry:   3,
+			wantOutcome: "retried",
+		},
+		{
+			name:        "failure on the last attempt is dropped",
+			attempt:     3,
+			err:         errors.New("smtp timeout"),
+			want:        acknowledgerMock{nacked: true},
+			wantOutcome: "rejected",
+		},
+		{
+			name:        "undecodable delivery is dropped right away",
+			err:         fmt.Errorf("bad json: %w", errDrop),
+			want:        acknowledgerMock{nacked: true},
+			wantOutcome: "rejected",
+		},
+		{
+			name:        "delivery is requeued when the retry cannot be scheduled",
+			err:         errors.New("smtp timeout"),
+			retryErr:    errors.New("channel closed"),
+			want:        acknowledgerMock{nacked: true, requeue: true},
+			wantRetry:   2,
+			wantOutcome: "requeued",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ack := &acknowledgerMock{}
+			d := amqp.Delivery{Acknowledger: ack, DeliveryTag: 1, Body: []byte("{}"), Headers: amqp.Table{"traceparent": "00-abc-def-01"}}
+			if tt.attempt != 0 {
+				d.Headers[attemptHeader] = tt.attempt
+			}
+			var retried *amqp.Publishing
+			retry := func(ctx context.Context, msg amqp.Publishing) error {
+				retried = &msg
+				return tt.retryErr
+			}
+
+			outcome := c.settle(context.Background(), d, tt.err, retry)
+
+			if *ack != tt.want {
+				t.Errorf("want %+v, got %+v", tt.want, *ack)
+			}
+			if outcome != tt.wantOutcome {
+				t.Errorf("outcome: want %q, got %q", tt.wantOutcome, outcome)
+			}
+			switch {
+			case tt.wantRetry == 0 && retried != nil:
+				t.Errorf("unexpected retry %+v", *retried)
+			case tt.wantRetry != 0 && retried == nil:
+				t.Errorf("want a retry with attempt %d, got none", tt.wantRetry)
+			case tt.wantRetry != 0:
+				if got := retried.Headers[attemptHeader]; got != tt.wantRetry {
+					t.Errorf("retry attempt: want %d, got %v", tt.wantRetry, got)
+				}
+				if retried.Headers["traceparent"] != "00-abc-def-01" || string(retried.Body) != "{}" || retried.DeliveryMode != amqp.Persistent {
+					t.Errorf("retry must be a persistent copy of the delivery, got %+v", *retried)
+				}
+			}
+		})
+	}
+}
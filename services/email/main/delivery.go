@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"github.com/Costin2000/GoChat---Schwarz-Internship---2025/pkg/bootstrap"
+	pb "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/email/proto"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const templateDigest = "digest"
+
+// deliveryHandler filters and sends the queued emails and writes every attempt to the delivery log
+type deliveryHandler struct {
+	filter        *notificationFilter
+	digests       *digestQueue
+	send          func(EmailMessage) error
+	storageAccess StorageAccess
+	now           func() time.Time
+}
+
+// decodes, filters and sends one email; errDrop means the message must not be retried
+func (h *deliveryHandler) process(ctx context.Context, body []byte, attempt int32) error {
+	receivedAt := h.now()
+
+	var emailMsg EmailMessage
+	if err := json.Unmarshal(body, &emailMsg); err != nil {
+		log.Printf("Error decoding JSON: %s", err)
+		h.record(ctx, emailMsg, pb.DeliveryStatus_DELIVERY_STATUS_DROPPED, err, attempt, receivedAt)
+		return errDrop
+	}
+	log.Printf("Received a message for %s: %s", emailMsg.To, emailMsg.Subject)
+
+	switch h.filter.modeFor(ctx, emailMsg) {
+	case deliverNever:
+		log.Printf("Skipping %s email to %s: notifications disabled", emailMsg.Event, emailMsg.To)
+		h.record(ctx, emailMsg, pb.DeliveryStatus_DELIVERY_STATUS_DROPPED, errors.New("notifications disabled"), attempt, receivedAt)
+		return nil
+	case deliverInDigest:
+		// not acked before it is stored, a failure here is retried like a failed send
+		if err := h.digests.add(ctx, emailMsg); err != nil {
+			log.Printf("Failed to queue %s email to %s for the digest: %v", emailMsg.Event, emailMsg.To, err)
+			h.record(ctx, emailMsg, pb.DeliveryStatus_DELIVERY_STATUS_FAILED, err, attempt, receivedAt)
+			return err
+		}
+		log.Printf("Queued %s email to %s for the next digest", emailMsg.Event, emailMsg.To)
+		h.record(ctx, emailMsg, pb.DeliveryStatus_DELIVERY_STATUS_DIGESTED, nil, attempt, receivedAt)
+		return nil
+	}
+
+	err := h.sendTraced(ctx, emailMsg)
+	st := deliveryStatusFor(err)
+	h.record(ctx, emailMsg, st, err, attempt, receivedAt)
+	if err != nil {
+		log.Printf("Failed to send email to %s: %v", emailMsg.To, err)
+		if st == pb.DeliveryStatus_DELIVERY_STATUS_BOUNCED {
+			// the server rejected the message for good, sending it again would bounce too
+			return fmt.Errorf("%w: %v", errDrop, err)
+		}
+		return err
+	}
+	log.Printf("Successfully sent email to %s", emailMsg.To)
+	return nil
+}
+
+// a failing audit log must never block the mail, so errors are only logged
+func (h *deliveryHandler) record(ctx context.Context, msg EmailMessage, st pb.DeliveryStatus, sendErr error, attempt int32, receivedAt time.Time) {
+	emailsTotal.WithLabelValues(templateName(msg), statusLabel(st)).Inc()
+	if h.storageAccess == nil {
+		return
+	}
+
+	d := &pb.EmailDelivery{
+		Recipient:  msg.To,
+		UserId:     msg.UserID,
+		Template:   templateName(msg),
+		Status:     st,
+		Attempt:    attempt,
+		ReceivedAt: timestamppb.New(receivedAt),
+		FinishedAt: timestamppb.New(h.now()),
+	}
+	if sendErr != nil {
+		d.Error = sendErr.Error()
+	}
+
+	c, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	if err := h.storageAccess.recordDelivery(c, d); err != nil {
+		log.Printf("WARN: could not record %s delivery to %s: %v", d.Template, d.Recipient, err)
+	}
+}
+
+// sends in a span of its own, SMTP is usually the slow part of a delivery
+func (h *deliveryHandler) sendTraced(ctx context.Context, msg EmailMessage) error {
+	_, span := otel.Tracer("github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/email").Start(ctx, "smtp send",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("email.template", templateName(msg))))
+	defer span.End()
+
+	start := time.Now()
+	err := h.send(msg)
+	smtpSendSeconds.WithLabelValues(templateName(msg)).Observe(time.Since(start).Seconds())
+	if err != nil {
+		// SMTP replies may quote the recipient
+		span.SetStatus(codes.Error, bootstrap.Redact(err.Error()))
+	}
+	return err
+}
+
+// SMTP 5xx replies are permanent rejections, everything else may work on the next attempt
+func deliveryStatusFor(err error) pb.DeliveryStatus {
+	if err == nil {
+		return pb.DeliveryStatus_DELIVERY_STATUS_SENT
+	}
+	var smtpErr *textproto.Error
+	if errors.As(err, &smtpErr) && smtpErr.Code >= 500 {
+		return pb.DeliveryStatus_DELIVERY_STATUS_BOUNCED
+	}
+	return pb.DeliveryStatus_DELIVERY_STATUS_FAILED
+}
+
+// statusLabel is the metric label of a delivery status, e.g. "sent" for DELIVERY_STATUS_SENT
+func statusLabel(st pb.DeliveryStatus) string {
+	return strings.ToLower(strings.TrimPrefix(st.String(), "DELIVERY_STATUS_"))
+}
+
+// notifications are logged under their event, other emails under the template the publisher set
+func templateName(msg EmailMessage) string {
+	switch {
+	case msg.Template != "":
+		return msg.Template
+	case msg.Event != "":
+		return msg.Event
+	}
+	return "unknown"
+}
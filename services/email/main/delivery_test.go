@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/textproto"
+	"testing"
+	"time"
+
+	pb "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/email/proto"
+	userpb "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/user-base/proto"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/testing/protocmp"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func Test_process(t *testing.T) {
+	now := time.Date(2025, 8, 1, 12, 0, 0, 0, time.UTC)
+	optedOut := &preferencesClientMock{
+		getPreferencesFunc: func(ctx context.Context, req *userpb.GetNotificationPreferencesRequest) (*userpb.GetNotificationPreferencesResponse, error) {
+			return &userpb.GetNotificationPreferencesResponse{Preferences: []*userpb.NotificationPreference{
+				{UserId: req.UserId, Event: userpb.NotificationEvent_NOTIFICATION_EVENT_FRIEND_REQUEST, EmailEnabled: false},
+			}}, nil
+		},
+	}
+	inDigest := &preferencesClientMock{
+		getPreferencesFunc: func(ctx context.Context, req *userpb.GetNotificationPreferencesRequest) (*userpb.GetNotificationPreferencesResponse, error) {
+			return &userpb.GetNotificationPreferencesResponse{Preferences: []*userpb.NotificationPreference{
+				{UserId: req.UserId, Event: userpb.NotificationEvent_NOTIFICATION_EVENT_FRIEND_REQUEST, EmailEnabled: true, Digest: true},
+			}}, nil
+		},
+	}
+	delivery := func(mods ...func(*pb.EmailDelivery)) *pb.EmailDelivery {
+		d := &pb.EmailDelivery{
+			Recipient:  "ana@gochat.com",
+			UserId:     7,
+			Template:   "welcome",
+			Status:     pb.DeliveryStatus_DELIVERY_STATUS_SENT,
+			Attempt:    1,
+			ReceivedAt: timestamppb.New(now),
+			FinishedAt: timestamppb.New(now),
+		}
+		for _, m := range mods {
+			m(d)
+		}
+		return d
+	}
+
+	tests := []struct {
+		name      string
+		body      string
+		attempt   int32
+		prefs     preferencesClient
+		sendErr   error
+		digestErr error
+		wantErr   error
+		wantDrop  bool
+		want      *pb.EmailDelivery
+	}{
+		{
+			name: "sent email is recorded",
+			body: `{"to":"ana@gochat.com","subject":"Welcome","user_id":7,"template":"welcome"}`,
+			want: delivery(),
+		},
+		{
+			name:    "temporary smtp error is recorded as failed and retried",
+			body:    `{"to":"ana@gochat.com","subject":"Welcome","user_id":7,"template":"welcome"}`,
+			sendErr: errors.New("dial tcp: i/o timeout"),
+			wantErr: errors.New("dial tcp: i/o timeout"),
+			want: delivery(func(d *pb.EmailDelivery) {
+				d.Status = pb.DeliveryStatus_DELIVERY_STATUS_FAILED
+				d.Error = "dial tcp: i/o timeout"
+			}),
+		},
+		{
+			name:    "retried attempt is counted",
+			body:    `{"to":"ana@gochat.com","subject":"Welcome","user_id":7,"template":"welcome"}`,
+			attempt: 3,
+			want:    delivery(func(d *pb.EmailDelivery) { d.Attempt = 3 }),
+		},
+		{
+			name:     "permanent smtp rejection bounces and is not retried",
+			body:     `{"to":"ana@gochat.com","subject":"Welcome","user_id":7,"template":"welcome"}`,
+			sendErr:  &textproto.Error{Code: 550, Msg: "mailbox unavailable"},
+			wantDrop: true,
+			want: delivery(func(d *pb.EmailDelivery) {
+				d.Status = pb.DeliveryStatus_DELIVERY_STATUS_BOUNCED
+				d.Error = `550 "mailbox unavailable"`
+			}),
+		},
+		{
+			name:  "disabled notification is dropped under its event",
+			body:  `{"to":"ana@gochat.com","subject":"New friend request","user_id":7,"event":"friend_request"}`,
+			prefs: optedOut,
+			want: delivery(func(d *pb.EmailDelivery) {
+				d.Template = "friend_request"
+				d.Status = pb.DeliveryStatus_DELIVERY_STATUS_DROPPED
+				d.Error = "notifications disabled"
+			}),
+		},
+		{
+			name:  "digest notification is queued for the digest",
+			body:  `{"to":"ana@gochat.com","subject":"New friend request","user_id":7,"event":"friend_request"}`,
+			prefs: inDigest,
+			want: delivery(func(d *pb.EmailDelivery) {
+				d.Template = "friend_request"
+				d.Status = pb.DeliveryStatus_DELIVERY_STATUS_DIGESTED
+			}),
+		},
+		{
+			name:      "digest notification that cannot be stored is retried",
+			body:      `{"to":"ana@gochat.com","subject":"New friend request","user_id":7,"event":"friend_request"}`,
+			prefs:     inDigest,
+			digestErr: errors.New("insert digest item: connection refused"),
+			wantErr:   errors.New("insert digest item: connection refused"),
+			want: delivery(func(d *pb.EmailDelivery) {
+				d.Template = "friend_request"
+				d.Status = pb.DeliveryStatus_DELIVERY_STATUS_FAILED
+				d.Error = "insert digest item: connection refused"
+			}),
+		},
+		{
+			name:     "undecodable message is dropped",
+			body:     `not json`,
+			wantDrop: true,
+			want: delivery(func(d *pb.EmailDelivery) {
+				d.Recipient = ""
+				d.UserId = 0
+				d.Template = "unknown"
+				d.Status = pb.DeliveryStatus_DELIVERY_STATUS_DROPPED
+				d.Error = "invalid character 'o' in literal null (expecting 'u')"
+			}),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var recorded *pb.EmailDelivery
+			storage := newMockStorageAccess(StorageMockOptions{
+				RecordDeliveryFunc: func(ctx context.Context, d *pb.EmailDelivery) error {
+					recorded = d
+					return nil
+				},
+				AddDigestItemFunc: func(ctx context.Context, msg EmailMessage) error { return tt.digestErr },
+			})
+			h := &deliveryHandler{
+				filter:        &notificationFilter{client: tt.prefs, timeout: time.Second},
+				digests:       newDigestQueue(storage),
+				send:          func(EmailMessage) error { return tt.sendErr },
+				storageAccess: storage,
+				now:           func() time.Time { return now },
+			}
+
+			err := h.process(context.Background(), []byte(tt.body), max(tt.attempt, 1))
+
+			switch {
+			case tt.wantDrop:
+				if !errors.Is(err, errDrop) {
+					t.Errorf("process() error = %v, want errDrop", err)
+				}
+			case tt.wantErr != nil:
+				if err == nil || errors.Is(err, errDrop) || err.Error() != tt.wantErr.Error() {
+					t.Errorf("process() error = %v, want %v", err, tt.wantErr)
+				}
+			case err != nil:
+				t.Errorf("process() unexpected error = %v", err)
+			}
+			if diff := cmp.Diff(tt.want, recorded, protocmp.Transform()); diff != "" {
+				t.Errorf("recorded delivery mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
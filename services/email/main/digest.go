@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// digestQueue collects the emails of users who asked for a digest and sends them as one summary per recipient.
+// The pending emails live in Postgres, a restart or a failed send keeps them for the next flush.
+type digestQueue struct {
+	store digestStore
+}
+
+func newDigestQueue(store digestStore) *digestQueue {
+	return &digestQueue{store: store}
+}
+
+func (q *digestQueue) add(ctx context.Context, msg EmailMessage) error {
+	return q.store.addDigestItem(ctx, msg)
+}
+
+// summarize builds the summary email of the pending emails of a recipient
+func summarize(to string, msgs []EmailMessage) EmailMessage {
+	var body strings.Builder
+	body.WriteString("Here is what happened on GoChat since our last email:\n")
+	for _, m := range msgs {
+		fmt.Fprintf(&body, "\n== %s ==\n%s\n", m.Subject, m.Body)
+	}
+	return EmailMessage{
+		To:       to,
+		Subject:  fmt.Sprintf("Your GoChat digest (%d notifications)", len(msgs)),
+		Body:     body.String(),
+		Template: templateDigest,
+	}
+}
+
+// flushes the queue every interval until ctx is cancelled; what is still pending waits in Postgres for the next start
+func (q *digestQueue) run(ctx context.Context, interval time.Duration, send func(EmailMessage) error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			// a digest that went out must also be deleted, so a flush in progress outlives shutdown
+			q.flush(context.WithoutCancel(ctx), send)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// sends one digest per recipient; the emails of a failed digest stay queued for the next interval
+func (q *digestQueue) flush(ctx context.Context, send func(EmailMessage) error) {
+	recipients, err := q.store.digestRecipients(ctx)
+	if err != nil {
+		log.Printf("Failed to list pending digests: %v", err)
+		return
+	}
+	for _, to := range recipients {
+		err := q.store.flushDigest(ctx, to, func(msgs []EmailMessage) error {
+			return send(summarize(to, msgs))
+		})
+		if err != nil {
+			log.Printf("Failed to queue the digest of %s, keeping it for the next interval: %v", to, err)
+		} else {
+			log.Printf("Queued the digest of %s", to)
+		}
+	}
+}
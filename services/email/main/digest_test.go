@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func Test_digestQueue_flush(t *testing.T) {
+	pending := map[string][]EmailMessage{
+		"ana@gochat.com": {{To: "ana@gochat.com", Subject: "New friend request", Body: "Bob wants to be friends"}},
+		"bob@gochat.com": {{To: "bob@gochat.com", Subject: "New message", Body: "Ana wrote to you"}},
+	}
+	q := newDigestQueue(newMockStorageAccess(StorageMockOptions{
+		DigestRecipientsFunc: func(ctx context.Context) ([]string, error) {
+			return []string{"ana@gochat.com", "bob@gochat.com"}, nil
+		},
+		// like Postgres, the items are only deleted when send succeeds
+		FlushDigestFunc: func(ctx context.Context, recipient string, send func([]EmailMessage) error) error {
+			if err := send(pending[recipient]); err != nil {
+				return err
+			}
+			delete(pending, recipient)
+			return nil
+		},
+	}))
+
+	var sent []EmailMessage
+	q.flush(context.Background(), func(msg EmailMessage) error {
+		if msg.To == "bob@gochat.com" {
+			return errors.New("smtp timeout")
+		}
+		sent = append(sent, msg)
+		return nil
+	})
+
+	want := []EmailMessage{{
+		To:       "ana@gochat.com",
+		Subject:  "Your GoChat digest (1 notifications)",
+		Body:     "Here is what happened on GoChat since our last email:\n\n== New friend request ==\nBob wants to be friends\n",
+		Template: templateDigest,
+	}}
+	if diff := cmp.Diff(want, sent); diff != "" {
+		t.Errorf("sent digests mismatch (-want +got):\n%s", diff)
+	}
+	if _, kept := pending["bob@gochat.com"]; !kept || len(pending) != 1 {
+		t.Errorf("the failed digest must stay queued and the sent one must not, pending: %v", pending)
+	}
+}
@@ -0,0 +1,17 @@
+package main
+
+import (
+	"context"
+
+	pb "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/email/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (svc *EmailService) ListEmailDeliveries(ctx context.Context, req *pb.ListEmailDeliveriesRequest) (*pb.ListEmailDeliveriesResponse, error) {
+	if req.GetPageSize() < 0 {
+		return nil, status.Error(codes.InvalidArgument, "pageSize cannot be negative")
+	}
+
+	return svc.storageAccess.listDeliveries(ctx, req)
+}
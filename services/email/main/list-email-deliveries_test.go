@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	errchecks "github.com/Costin2000/GoChat---Schwarz-Internship---2025/pkg"
+	pb "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/email/proto"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/testing/protocmp"
+)
+
+func fixtureListEmailDeliveriesResponse() *pb.ListEmailDeliveriesResponse {
+	return &pb.ListEmailDeliveriesResponse{
+		NextPageToken: "id:41",
+		Deliveries: []*pb.EmailDelivery{
+			{Id: 42, Recipient: "ana@gochat.com", UserId: 7, Template: "friend_request", Status: pb.DeliveryStatus_DELIVERY_STATUS_SENT, Attempt: 1},
+			{Id: 41, Recipient: "ana@gochat.com", UserId: 7, Template: "welcome", Status: pb.DeliveryStatus_DELIVERY_STATUS_BOUNCED, Error: "550 mailbox unavailable", Attempt: 1},
+		},
+	}
+}
+
+func Test_ListEmailDeliveries(t *testing.T) {
+	type want struct {
+		resp *pb.ListEmailDeliveriesResponse
+		err  errchecks.Check
+	}
+
+	tests := []struct {
+		name        string
+		req         *pb.ListEmailDeliveriesRequest
+		mockOptions StorageMockOptions
+		want        want
+	}{
+		{
+			name: "negative pageSize -> InvalidArgument",
+			req:  &pb.ListEmailDeliveriesRequest{PageSize: -1},
+			want: want{
+				err: errchecks.HasStatusCode(codes.InvalidArgument),
+			},
+		},
+		{
+			name: "happy path - filters are passed to storage",
+			req: &pb.ListEmailDeliveriesRequest{PageSize: 2, Filters: []*pb.ListEmailDeliveriesFiltersOneOf{
+				{Filter: &pb.ListEmailDeliveriesFiltersOneOf_Recipient{Recipient: "ana@gochat.com"}},
+			}},
+			mockOptions: StorageMockOptions{
+				ListDeliveriesFunc: func(ctx context.Context, req *pb.ListEmailDeliveriesRequest) (*pb.ListEmailDeliveriesResponse, error) {
+					if req.Filters[0].GetRecipient() != "ana@gochat.com" {
+						return nil, status.Error(codes.Internal, "filter not passed")
+					}
+					return fixtureListEmailDeliveriesResponse(), nil
+				},
+			},
+			want: want{
+				resp: fixtureListEmailDeliveriesResponse(),
+			},
+		},
+		{
+			name: "storage error is bubbled up",
+			req:  &pb.ListEmailDeliveriesRequest{},
+			mockOptions: StorageMockOptions{
+				ListDeliveriesFunc: func(ctx context.Context, req *pb.ListEmailDeliveriesRequest) (*pb.ListEmailDeliveriesResponse, error) {
+					return nil, status.Error(codes.Internal, "boom")
+				},
+			},
+			want: want{
+				err: errchecks.HasStatusCode(codes.Internal),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := NewMockService(tt.mockOptions)
+
+			rsp, err := svc.ListEmailDeliveries(context.Background(), tt.req)
+
+			errchecks.Assert(t, err, tt.want.err)
+			if diff := cmp.Diff(tt.want.resp, rsp, protocmp.Transform()); diff != "" {
+				t.Errorf("mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
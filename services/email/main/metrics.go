@@ -0,0 +1,24 @@
+package main
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	consumedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "rabbitmq_consumed_total",
+		Help: "Deliveries handled by the consumer, by queue and outcome (acked, retried, requeued or rejected).",
+	}, []string{"queue", "outcome"})
+
+	emailsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "email_deliveries_total",
+		Help: "Emails processed, by template and delivery status (sent, failed, bounced, dropped or digested).",
+	}, []string{"template", "status"})
+
+	smtpSendSeconds = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "email_smtp_send_seconds",
+		Help:    "Time spent sending a message over SMTP.",
+		Buckets: []float64{.1, .25, .5, 1, 2.5, 5, 10, 30},
+	}, []string{"template"})
+)
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	pb "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/email/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type StorageAccess interface {
+	recordDelivery(ctx context.Context, d *pb.EmailDelivery) error
+	listDeliveries(ctx context.Context, req *pb.ListEmailDeliveriesRequest) (*pb.ListEmailDeliveriesResponse, error)
+	digestStore
+}
+
+// digestStore keeps the notifications waiting for a digest, so they survive restarts
+type digestStore interface {
+	addDigestItem(ctx context.Context, msg EmailMessage) error
+	digestRecipients(ctx context.Context) ([]string, error)
+	// flushDigest passes the pending notifications of recipient to send and deletes them only if it succeeds
+	flushDigest(ctx context.Context, recipient string, send func([]EmailMessage) error) error
+}
+
+type PostgresAccess struct{ db *sql.DB }
+
+func newPostgresAccess(db *sql.DB) *PostgresAccess { return &PostgresAccess{db: db} }
+
+func (pa *PostgresAccess) recordDelivery(ctx context.Context, d *pb.EmailDelivery) error {
+	query := `
+		INSERT INTO email_deliveries (recipient, user_id, template, status, error, attempt, received_at, finished_at)
+		VALUES ($1, NULLIF($2::BIGINT, 0), $3, $4, $5, $6, $7, $8);
+	`
+	_, err := pa.db.ExecContext(ctx, query,
+		d.Recipient, d.UserId, d.Template, deliveryStatusToDB(d.Status), d.Error, d.Attempt,
+		d.ReceivedAt.AsTime(), d.FinishedAt.AsTime(),
+	)
+	if err != nil {
+		return fmt.Errorf("insert email delivery: %w", err)
+	}
+	return nil
+}
+
+func (pa *PostgresAccess) listDeliveries(ctx context.Context, req *pb.ListEmailDeliveriesRequest) (*pb.ListEmailDeliveriesResponse, error) {
+	const (
+		defaultPageSize = int64(50)
+		maxPageSize     = int64(1000)
+	)
+	ps := req.GetPageSize()
+	if ps <= 0 {
+		ps = defaultPageSize
+	}
+	if ps > maxPageSize {
+		ps = maxPageSize
+	}
+
+	var beforeID int64
+	if tok := strings.TrimSpace(req.GetNextPageToken()); tok != "" {
+		v, err := strconv.ParseInt(strings.TrimPrefix(tok, "id:"), 10, 64)
+		if err != nil || v <= 0 {
+			return nil, status.Error(codes.InvalidArgument, "invalid nextPageToken")
+		}
+		beforeID = v
+	}
+
+	where := []string{}
+	args := []any{}
+
+	for _, f := range req.GetFilters() {
+		switch x := f.Filter.(type) {
+		case *pb.ListEmailDeliveriesFiltersOneOf_Recipient:
+			if v := strings.TrimSpace(x.Recipient); v != "" {
+				where = append(where, fmt.Sprintf("LOWER(recipient) = LOWER($%d)", len(args)+1))
+				args = append(args, v)
+			}
+		case *pb.ListEmailDeliveriesFiltersOneOf_UserId:
+			if x.UserId > 0 {
+				where = append(where, fmt.Sprintf("user_id = $%d", len(args)+1))
+				args = append(args, x.UserId)
+			}
+		case *pb.ListEmailDeliveriesFiltersOneOf_Template:
+			if v := strings.TrimSpace(x.Template); v != "" {
+				where = append(where, fmt.Sprintf("template = $%d", len(args)+1))
+				args = append(args, v)
+			}
+		case *pb.ListEmailDeliveriesFiltersOneOf_Status:
+			if x.Status != pb.DeliveryStatus_DELIVERY_STATUS_UNKNOWN {
+				where = append(where, fmt.Sprintf("status = $%d", len(args)+1))
+				args = append(args, deliveryStatusToDB(x.Status))
+			}
+		}
+	}
+
+	// newest first, the token is the smallest id of the previous page
+	if beforeID > 0 {
+		where = append(where, fmt.Sprintf("id < $%d", len(args)+1))
+		args = append(args, beforeID)
+	}
+
+	query := `SELECT id, recipient, COALESCE(user_id, 0), template, status, error, attempt, received_at, finished_at FROM email_deliveries`
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += fmt.Sprintf(" ORDER BY id DESC LIMIT $%d", len(args)+1)
+	args = append(args, ps+1)
+
+	rows, err := pa.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "query error: %v", err)
+	}
+	defer rows.Close()
+
+	var deliveries []*pb.EmailDelivery
+	for rows.Next() {
+		var (
+			d                      pb.EmailDelivery
+			statusDB               string
+			receivedAt, finishedAt time.Time
+		)
+		if err := rows.Scan(&d.Id, &d.Recipient, &d.UserId, &d.Template, &statusDB, &d.Error, &d.Attempt, &receivedAt, &finishedAt); err != nil {
+			return nil, status.Errorf(codes.Internal, "scan error: %v", err)
+		}
+		d.Status = deliveryStatusFromDB(statusDB)
+		d.ReceivedAt = timestamppb.New(receivedAt)
+		d.FinishedAt = timestamppb.New(finishedAt)
+		deliveries = append(deliveries, &d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, status.Errorf(codes.Internal, "rows error: %v", err)
+	}
+
+	nextToken := ""
+	if int64(len(deliveries)) > ps {
+		nextToken = fmt.Sprintf("id:%d", deliveries[ps-1].Id)
+		deliveries = deliveries[:ps]
+	}
+
+	return &pb.ListEmailDeliveriesResponse{
+		NextPageToken: nextToken,
+		Deliveries:    deliveries,
+	}, nil
+}
+
+func (pa *PostgresAccess) addDigestItem(ctx context.Context, msg EmailMessage) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("encode digest item: %w", err)
+	}
+	if _, err := pa.db.ExecContext(ctx, `INSERT INTO email_digest_items (recipient, message) VALUES ($1, $2)`, msg.To, body); err != nil {
+		return fmt.Errorf("insert digest item: %w", err)
+	}
+	return nil
+}
+
+func (pa *PostgresAccess) digestRecipients(ctx context.Context) ([]string, error) {
+	rows, err := pa.db.QueryContext(ctx, `SELECT DISTINCT recipient FROM email_digest_items`)
+	if err != nil {
+		return nil, fmt.Errorf("query digest recipients: %w", err)
+	}
+	defer rows.Close()
+
+	var recipients []string
+	for rows.Next() {
+		var r string
+		if err := rows.Scan(&r); err != nil {
+			return nil, fmt.Errorf("scan digest recipient: %w", err)
+		}
+		recipients = append(recipients, r)
+	}
+	return recipients, rows.Err()
+}
+
+// the rows stay locked while send runs, so another replica flushing at the same time skips them
+func (pa *PostgresAccess) flushDigest(ctx context.Context, recipient string, send func([]EmailMessage) error) error {
+	tx, err := pa.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id, message FROM email_digest_items
+		WHERE recipient = $1
+		ORDER BY id
+		FOR UPDATE SKIP LOCKED;
+	`, recipient)
+	if err != nil {
+		return fmt.Errorf("query digest items: %w", err)
+	}
+	var (
+		ids  []int64
+		msgs []EmailMessage
+	)
+	for rows.Next() {
+		var (
+			id   int64
+			body []byte
+			msg  EmailMessage
+		)
+		if err := rows.Scan(&id, &body); err != nil {
+			rows.Close()
+			return fmt.Errorf("scan digest item: %w", err)
+		}
+		if err := json.Unmarshal(body, &msg); err != nil {
+			rows.Close()
+			return fmt.Errorf("decode digest item %d: %w", id, err)
+		}
+		ids = append(ids, id)
+		msgs = append(msgs, msg)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("read digest items: %w", err)
+	}
+	if len(msgs) == 0 {
+		return nil
+	}
+
+	if err := send(msgs); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM email_digest_items WHERE id = ANY($1)`, ids); err != nil {
+		return fmt.Errorf("delete digest items: %w", err)
+	}
+	return tx.Commit()
+}
+
+// maps DELIVERY_STATUS_SENT to "sent", the value of the EMAIL_DELIVERY_STATUS enum
+func deliveryStatusToDB(s pb.DeliveryStatus) string {
+	return strings.ToLower(strings.TrimPrefix(s.String(), "DELIVERY_STATUS_"))
+}
+
+func deliveryStatusFromDB(s string) pb.DeliveryStatus {
+	return pb.DeliveryStatus(pb.DeliveryStatus_value["DELIVERY_STATUS_"+strings.ToUpper(s)])
+}
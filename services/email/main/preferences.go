@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Costin2000/GoChat---Schwarz-Internship---2025/pkg/unsubscribe"
+	userpb "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/user-base/proto"
+	"google.golang.org/grpc"
+)
+
+type deliveryMode int
+
+const (
+	deliverNow deliveryMode = iota
+	deliverInDigest
+	deliverNever
+)
+
+type preferencesClient interface {
+	GetNotificationPreferences(ctx context.Context, req *userpb.GetNotificationPreferencesRequest, opts ...grpc.CallOption) (*userpb.GetNotificationPreferencesResponse, error)
+}
+
+type notificationFilter struct {
+	client  preferencesClient
+	timeout time.Duration
+}
+
+// decides how the message is delivered based on the preferences of the recipient.
+// Messages without user or event (e.g. the welcome email) cannot be opted out of.
+func (f *notificationFilter) modeFor(ctx context.Context, msg EmailMessage) deliveryMode {
+	if f.client == nil || msg.UserID <= 0 || msg.Event == "" {
+		return deliverNow
+	}
+
+	c, cancel := context.WithTimeout(ctx, f.timeout)
+	defer cancel()
+
+	resp, err := f.client.GetNotificationPreferences(c, &userpb.GetNotificationPreferencesRequest{UserId: msg.UserID})
+	if err != nil {
+		// prefer sending an unwanted email over losing a wanted one
+		log.Printf("WARN: could not fetch notification preferences of user %d, sending anyway: %v", msg.UserID, err)
+		return deliverNow
+	}
+
+	for _, pref := range resp.Preferences {
+		if eventName(pref.Event) != msg.Event {
+			continue
+		}
+		switch {
+		case !pref.EmailEnabled:
+			return deliverNever
+		case pref.Digest:
+			return deliverInDigest
+		}
+		return deliverNow
+	}
+	return deliverNow
+}
+
+// maps NOTIFICATION_EVENT_FRIEND_REQUEST to "friend_request", the value the publishers send
+func eventName(event userpb.NotificationEvent) string {
+	return strings.ToLower(strings.TrimPrefix(event.String(), "NOTIFICATION_EVENT_"))
+}
+
+// returns the one-click unsubscribe link for the message, or "" if it cannot be opted out of
+func unsubscribeURL(cfg Config, msg EmailMessage) string {
+	if cfg.UnsubscribeSecret == "" || msg.UserID <= 0 || msg.Event == "" {
+		return ""
+	}
+	token := unsubscribe.Sign([]byte(cfg.UnsubscribeSecret), msg.UserID, msg.Event)
+	return strings.TrimRight(cfg.PublicBaseURL, "/") + "/v1/unsubscribe?token=" + url.QueryEscape(token)
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	userpb "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/user-base/proto"
+	"google.golang.org/grpc"
+)
+
+type preferencesClientMock struct {
+	getPreferencesFunc func(ctx context.Context, req *userpb.GetNotificationPreferencesRequest) (*userpb.GetNotificationPreferencesResponse, error)
+}
+
+func (m *preferencesClientMock) GetNotificationPreferences(ctx context.Context, req *userpb.GetNotificationPreferencesRequest, opts ...grpc.CallOption) (*userpb.GetNotificationPreferencesResponse, error) {
+	return m.getPreferencesFunc(ctx, req)
+}
+
+func Test_modeFor(t *testing.T) {
+	prefs := func(emailEnabled, digest bool) func(ctx context.Context, req *userpb.GetNotificationPreferencesRequest) (*userpb.GetNotificationPreferencesResponse, error) {
+		return func(ctx context.Context, req *userpb.GetNotificationPreferencesRequest) (*userpb.GetNotificationPreferencesResponse, error) {
+			return &userpb.GetNotificationPreferencesResponse{Preferences: []*userpb.NotificationPreference{
+				{UserId: req.UserId, Event: userpb.NotificationEvent_NOTIFICATION_EVENT_FRIEND_REQUEST, EmailEnabled: emailEnabled, Digest: digest},
+				{UserId: req.UserId, Event: userpb.NotificationEvent_NOTIFICATION_EVENT_FRIEND_REQUEST_ACCEPTED, EmailEnabled: true},
+			}}, nil
+		}
+	}
+	friendRequest := EmailMessage{To: "a@b.com", UserID: 7, Event: "friend_request"}
+
+	tests := []struct {
+		name     string
+		msg      EmailMessage
+		getPrefs func(ctx context.Context, req *userpb.GetNotificationPreferencesRequest) (*userpb.GetNotificationPreferencesResponse, error)
+		want     deliveryMode
+	}{
+		{
+			name: "transactional email is always sent",
+			msg:  EmailMessage{To: "a@b.com"},
+			getPrefs: func(ctx context.Context, req *userpb.GetNotificationPreferencesRequest) (*userpb.GetNotificationPreferencesResponse, error) {
+				t.Fatal("preferences should not be fetched for transactional emails")
+				return nil, nil
+			},
+			want: deliverNow,
+		},
+		{
+			name:     "email enabled",
+			msg:      friendRequest,
+			getPrefs: prefs(true, false),
+			want:     deliverNow,
+		},
+		{
+			name:     "email disabled",
+			msg:      friendRequest,
+			getPrefs: prefs(false, true),
+			want:     deliverNever,
+		},
+		{
+			name:     "digest",
+			msg:      friendRequest,
+			getPrefs: prefs(true, true),
+			want:     deliverInDigest,
+		},
+		{
+			name: "user-base unavailable",
+			msg:  friendRequest,
+			getPrefs: func(ctx context.Context, req *userpb.GetNotificationPreferencesRequest) (*userpb.GetNotificationPreferencesResponse, error) {
+				return nil, errors.New("connection refused")
+			},
+			want: deliverNow,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &notificationFilter{client: &preferencesClientMock{getPreferencesFunc: tt.getPrefs}, timeout: time.Second}
+
+			if got := f.modeFor(context.Background(), tt.msg); got != tt.want {
+				t.Errorf("want mode %d, got %d", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+
+	pb "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/email/proto"
+)
+
+type mockStorage struct {
+	recordDeliveryFunc   func(ctx context.Context, d *pb.EmailDelivery) error
+	listDeliveriesFunc   func(ctx context.Context, req *pb.ListEmailDeliveriesRequest) (*pb.ListEmailDeliveriesResponse, error)
+	addDigestItemFunc    func(ctx context.Context, msg EmailMessage) error
+	digestRecipientsFunc func(ctx context.Context) ([]string, error)
+	flushDigestFunc      func(ctx context.Context, recipient string, send func([]EmailMessage) error) error
+}
+
+func (m *mockStorage) recordDelivery(ctx context.Context, d *pb.EmailDelivery) error {
+	if m.recordDeliveryFunc != nil {
+		return m.recordDeliveryFunc(ctx, d)
+	}
+	return nil
+}
+
+func (m *mockStorage) listDeliveries(ctx context.Context, req *pb.ListEmailDeliveriesRequest) (*pb.ListEmailDeliveriesResponse, error) {
+	if m.listDeliveriesFunc != nil {
+		return m.listDeliveriesFunc(ctx, req)
+	}
+	return nil, nil
+}
+
+func (m *mockStorage) addDigestItem(ctx context.Context, msg EmailMessage) error {
+	if m.addDigestItemFunc != nil {
+		return m.addDigestItemFunc(ctx, msg)
+	}
+	return nil
+}
+
+func (m *mockStorage) digestRecipients(ctx context.Context) ([]string, error) {
+	if m.digestRecipientsFunc != nil {
+		return m.digestRecipientsFunc(ctx)
+	}
+	return nil, nil
+}
+
+func (m *mockStorage) flushDigest(ctx context.Context, recipient string, send func([]EmailMessage) error) error {
+	if m.flushDigestFunc != nil {
+		return m.flushDigestFunc(ctx, recipient, send)
+	}
+	return nil
+}
+
+type StorageMockOptions struct {
+	RecordDeliveryFunc   func(ctx context.Context, d *pb.EmailDelivery) error
+	ListDeliveriesFunc   func(ctx context.Context, req *pb.ListEmailDeliveriesRequest) (*pb.ListEmailDeliveriesResponse, error)
+	AddDigestItemFunc    func(ctx context.Context, msg EmailMessage) error
+	DigestRecipientsFunc func(ctx context.Context) ([]string, error)
+	FlushDigestFunc      func(ctx context.Context, recipient string, send func([]EmailMessage) error) error
+}
+
+func newMockStorageAccess(opts StorageMockOptions) StorageAccess {
+
+	mock := &mockStorage{
+		recordDeliveryFunc:   opts.RecordDeliveryFunc,
+		listDeliveriesFunc:   opts.ListDeliveriesFunc,
+		addDigestItemFunc:    opts.AddDigestItemFunc,
+		digestRecipientsFunc: opts.DigestRecipientsFunc,
+		flushDigestFunc:      opts.FlushDigestFunc,
+	}
+	return mock
+}
+
+func NewMockService(opts StorageMockOptions) *EmailService {
+	return &EmailService{
+		storageAccess: newMockStorageAccess(opts),
+	}
+}
@@ -1,118 +1,188 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
 	"net/smtp"
-	"os"
 	"time"
 
+	"github.com/Costin2000/GoChat---Schwarz-Internship---2025/pkg/bootstrap"
+	pb "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/email/proto"
+	userpb "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/user-base/proto"
 	amqp "github.com/rabbitmq/amqp091-go"
 )
 
+type EmailService struct {
+	storageAccess StorageAccess
+	pb.UnimplementedEmailServiceServer
+}
+
 type EmailMessage struct {
-	To      string `json:"to"`
-	Subject string `json:"subject"`
-	Body    string `json:"body"`
+	To       string `json:"to"`
+	Subject  string `json:"subject"`
+	Body     string `json:"body"`
+	UserID   int64  `json:"user_id,omitempty"` // recipient, set for notifications the user can opt out of
+	Event    string `json:"event,omitempty"`
+	Template string `json:"template,omitempty"`
 }
 
 type Config struct {
-	RabbitMQAddr string
-	SmtpHost     string
-	SmtpPort     string
-	SmtpUser     string
-	SmtpPass     string
+	Addr              string        `env:"EMAIL_LISTEN_ADDR" default:":50057"`
+	MetricsAddr       string        `env:"EMAIL_METRICS_ADDR" default:":9107"`
+	RabbitMQAddr      string        `env:"RABBITMQ_ADDR" required:"true"`
+	SmtpHost          string        `env:"SMTP_HOST" required:"true"`
+	SmtpPort          string        `env:"SMTP_PORT" required:"true"`
+	SmtpUser          string        `env:"SMTP_USER" required:"true"`
+	SmtpPass          string        `env:"SMTP_PASS" required:"true"`
+	UserBaseAddr      string        `env:"USER_BASE_ADDR" default:"user-base:50051"`
+	UnsubscribeSecret string        `env:"UNSUBSCRIBE_SECRET"`
+	PublicBaseURL     string        `env:"PUBLIC_BASE_URL" default:"http://localhost:8080"`
+	DigestInterval    time.Duration `env:"DIGEST_INTERVAL" default:"1h"`
+	Workers           int           `env:"EMAIL_WORKERS" default:"4"`
+	Prefetch          int           `env:"EMAIL_PREFETCH"` // 0 means twice the workers
+	DrainTimeout      time.Duration `env:"EMAIL_DRAIN_TIMEOUT" default:"25s"`
+	MaxAttempts       int           `env:"EMAIL_MAX_ATTEMPTS" default:"5"`
+	RetryDelay        time.Duration `env:"EMAIL_RETRY_DELAY" default:"1m"`
+	DB                bootstrap.DBConfig
+	Shutdown          bootstrap.ShutdownConfig
+	Tracing           bootstrap.TracingConfig
+}
+
+func (c *Config) Validate() error {
+	if c.Workers <= 0 {
+		return fmt.Errorf("EMAIL_WORKERS must be positive")
+	}
+	if c.Prefetch < 0 {
+		return fmt.Errorf("EMAIL_PREFETCH must not be negative")
+	}
+	if c.MaxAttempts <= 0 {
+		return fmt.Errorf("EMAIL_MAX_ATTEMPTS must be positive")
+	}
+	if c.RetryDelay < time.Millisecond {
+		return fmt.Errorf("EMAIL_RETRY_DELAY must be at least 1ms")
+	}
+	return nil
 }
 
 func main() {
-	cfg := Config{
-		RabbitMQAddr: os.Getenv("RABBITMQ_ADDR"),
-		SmtpHost:     os.Getenv("SMTP_HOST"),
-		SmtpPort:     os.Getenv("SMTP_PORT"),
-		SmtpUser:     os.Getenv("SMTP_USER"),
-		SmtpPass:     os.Getenv("SMTP_PASS"),
+	bootstrap.Init("email")
+
+	var cfg Config
+	if err := bootstrap.LoadConfig(&cfg); err != nil {
+		bootstrap.Fail("load config", err)
 	}
-	if cfg.RabbitMQAddr == "" || cfg.SmtpHost == "" || cfg.SmtpPort == "" || cfg.SmtpUser == "" || cfg.SmtpPass == "" {
-		log.Fatal("Error: One or more environment variables (RABBITMQ_ADDR, SMTP_HOST, SMTP_PORT, SMTP_USER, SMTP_PASS) are not set.")
+	if cfg.Prefetch == 0 {
+		cfg.Prefetch = 2 * cfg.Workers
 	}
+	if cfg.UnsubscribeSecret == "" {
+		log.Println("WARN: UNSUBSCRIBE_SECRET not set; emails will be sent without unsubscribe links")
+	}
+
+	ctx, stop := bootstrap.SignalContext()
+	defer stop()
 
-	conn, err := connectToRabbitMQWithRetries(cfg.RabbitMQAddr)
+	shutdownTracing, err := bootstrap.InitTracing(ctx, cfg.Tracing)
 	if err != nil {
-		log.Fatalf("Failed to connect to RabbitMQ after several retries: %v", err)
+		bootstrap.Fail("init tracing", err)
 	}
-	defer conn.Close()
+	defer shutdownTracing(context.Background())
 
-	ch, err := conn.Channel()
+	userConn, err := bootstrap.Dial(cfg.UserBaseAddr)
 	if err != nil {
-		log.Fatalf("Failed to open a channel: %v", err)
+		bootstrap.Fail("dial user-base", err)
 	}
-	defer ch.Close()
+	defer userConn.Close()
 
-	q, err := ch.QueueDeclare("emails_queue", true, false, false, false, nil)
+	db, err := bootstrap.OpenDB(ctx, cfg.DB)
 	if err != nil {
-		log.Fatalf("Failed to declare a queue: %v", err)
+		bootstrap.Fail("open database", err)
+	}
+	defer db.Close()
+
+	storage := newPostgresAccess(db.DB)
+
+	handler := &deliveryHandler{
+		filter: &notificationFilter{
+			client:  userpb.NewUserServiceClient(userConn),
+			timeout: 5 * time.Second,
+		},
+		digests:       newDigestQueue(storage),
+		send:          func(msg EmailMessage) error { return sendEmail(cfg, msg) },
+		storageAccess: storage,
+		now:           time.Now,
 	}
 
-	// start consuming messages from the queue
-	msgs, err := ch.Consume(q.Name, "", true, false, false, false, nil)
-	if err != nil {
-		log.Fatalf("Failed to register a consumer: %v", err)
+	c := &consumer{
+		addr:         cfg.RabbitMQAddr,
+		queue:        "emails_queue",
+		workers:      cfg.Workers,
+		prefetch:     cfg.Prefetch,
+		drainTimeout: cfg.DrainTimeout,
+		maxAttempts:  cfg.MaxAttempts,
+		retryDelay:   cfg.RetryDelay,
+		handle: func(ctx context.Context, d amqp.Delivery) error {
+			return handler.process(ctx, d.Body, attemptOf(d))
+		},
 	}
 
-	log.Println(" [*] Waiting for email messages. To exit press CTRL+C")
+	// support looks up delivery attempts through gRPC
+	s := bootstrap.NewGRPCServer()
+	pb.RegisterEmailServiceServer(s, &EmailService{storageAccess: storage})
+	health := bootstrap.RegisterHealth(s, db.Check(), bootstrap.Check{Name: "rabbitmq", Probe: c.ping})
+	go health.Run(ctx)
 
-	var forever chan struct{}
+	if err := bootstrap.ServeMetrics(ctx, cfg.MetricsAddr); err != nil {
+		bootstrap.Fail("serve metrics", err)
+	}
 
-	// goroutine to process incoming messages concurrently
+	grpcDone := make(chan struct{})
 	go func() {
-		for d := range msgs {
-			log.Printf("Received a message: %s", d.Body)
-			var emailMsg EmailMessage
-			if err := json.Unmarshal(d.Body, &emailMsg); err != nil {
-				log.Printf("Error decoding JSON: %s", err)
-				continue
-			}
-			if err := sendEmail(cfg, emailMsg); err != nil {
-				log.Printf("Failed to send email to %s: %v", emailMsg.To, err)
-			} else {
-				log.Printf("Successfully sent email to %s", emailMsg.To)
-			}
+		defer close(grpcDone)
+		if err := bootstrap.ServeGRPC(ctx, s, cfg.Addr, health, cfg.Shutdown); err != nil {
+			bootstrap.Fail("serve", err)
 		}
 	}()
 
-	<-forever
+	digestsDone := make(chan struct{})
+	go func() {
+		defer close(digestsDone)
+		// digests go through the queue, so a failed one is retried like any other email
+		handler.digests.run(ctx, cfg.DigestInterval, func(msg EmailMessage) error {
+			body, err := json.Marshal(msg)
+			if err != nil {
+				return err
+			}
+			return c.enqueue(context.Background(), body)
+		})
+	}()
+
+	c.run(ctx)
+
+	<-digestsDone
+	<-grpcDone
+	log.Println("Email service stopped")
 }
 
 func sendEmail(cfg Config, msg EmailMessage) error {
 	auth := smtp.PlainAuth("", cfg.SmtpUser, cfg.SmtpPass, cfg.SmtpHost)
 	smtpAddr := fmt.Sprintf("%s:%s", cfg.SmtpHost, cfg.SmtpPort)
 
-	emailBody := "From: " + cfg.SmtpUser + "\r\n" +
+	headers := "From: " + cfg.SmtpUser + "\r\n" +
 		"To: " + msg.To + "\r\n" +
-		"Subject: " + msg.Subject + "\r\n" +
-		"\r\n" +
-		msg.Body
+		"Subject: " + msg.Subject + "\r\n"
+	body := msg.Body
+
+	// RFC 8058 one-click unsubscribe, mail clients show it next to the sender
+	if link := unsubscribeURL(cfg, msg); link != "" {
+		headers += "List-Unsubscribe: <" + link + ">\r\n" +
+			"List-Unsubscribe-Post: List-Unsubscribe=One-Click\r\n"
+		body += "\n\nDon't want these emails anymore? Unsubscribe: " + link
+	}
+
+	emailBody := headers + "\r\n" + body
 
 	err := smtp.SendMail(smtpAddr, auth, cfg.SmtpUser, []string{msg.To}, []byte(emailBody))
 	return err
 }
-
-// attempts to connect to RabbitMQ, retrying several times on failure
-func connectToRabbitMQWithRetries(addr string) (*amqp.Connection, error) {
-	var conn *amqp.Connection
-	var err error
-	maxRetries := 5
-	retryDelay := 5 * time.Second
-
-	for i := 0; i < maxRetries; i++ {
-		conn, err = amqp.Dial(addr)
-		if err == nil {
-			log.Println("Successfully connected to RabbitMQ")
-			return conn, nil
-		}
-		log.Printf("Could not connect to RabbitMQ, retrying in %v... (%d/%d)", retryDelay, i+1, maxRetries)
-		time.Sleep(retryDelay)
-	}
-	return nil, err
-}
@@ -27,6 +27,10 @@ func (svc *friendRequestService) CreateFriendRequest(ctx context.Context, req *p
 		return nil, status.Errorf(codes.InvalidArgument, "sender and receiver cannot be the same user")
 	}
 
+	if err := svc.checkFriendRequestPolicy(ctx, senderID, receiverID); err != nil {
+		return nil, err
+	}
+
 	friendRequestResp, err := svc.storageAccess.requestCreateFriendRequest(ctx, req)
 
 	if err != nil {
@@ -60,8 +64,10 @@ func (svc *friendRequestService) CreateFriendRequest(ctx context.Context, req *p
 				To:      receiver.Email,
 				Subject: subject,
 				Body:    body,
+				UserID:  receiver.Id,
+				Event:   eventFriendRequest,
 			}); pubErr != nil {
-				log.Printf("WARN: failed to publish email for friend request to %s: %v", receiver.Email, pubErr)
+				log.Printf("WARN: failed to publish email for friend request to user %s: %v", receiverID, pubErr)
 			}
 		}
 	}
@@ -69,6 +75,41 @@ func (svc *friendRequestService) CreateFriendRequest(ctx context.Context, req *p
 	return friendRequestResp, nil
 }
 
+// checkFriendRequestPolicy applies the privacy settings of the receiver, see UserSettings in user-base
+func (svc *friendRequestService) checkFriendRequestPolicy(ctx context.Context, senderIDStr, receiverIDStr string) error {
+	senderID, err := strconv.ParseInt(senderIDStr, 10, 64)
+	if err != nil {
+		return status.Errorf(codes.InvalidArgument, "invalid sender ID format: %v", err)
+	}
+	receiverID, err := strconv.ParseInt(receiverIDStr, 10, 64)
+	if err != nil {
+		return status.Errorf(codes.InvalidArgument, "invalid receiver ID format: %v", err)
+	}
+
+	settings, err := svc.userClient.GetUserSettings(ctx, &pbuser.GetUserSettingsRequest{UserId: receiverID})
+	if status.Code(err) == codes.NotFound {
+		return status.Error(codes.NotFound, "one or both users do not exist")
+	}
+	if err != nil {
+		log.Printf("could not get the settings of user %d: %v", receiverID, err)
+		return status.Error(codes.Unavailable, "could not check whether the user accepts friend requests, try again")
+	}
+
+	switch settings.GetFriendRequestPolicy() {
+	case pbuser.FriendRequestPolicy_FRIEND_REQUEST_POLICY_NOBODY:
+		return status.Error(codes.PermissionDenied, "the user does not accept friend requests")
+	case pbuser.FriendRequestPolicy_FRIEND_REQUEST_POLICY_FRIENDS_OF_FRIENDS:
+		mutual, err := svc.storageAccess.haveMutualFriend(ctx, senderID, receiverID)
+		if err != nil {
+			return err
+		}
+		if !mutual {
+			return status.Error(codes.PermissionDenied, "the user only accepts friend requests from friends of friends")
+		}
+	}
+	return nil
+}
+
 // helper: obtine user din user-base folosind ListUsers + filter by id
 func (svc *friendRequestService) getUserByID(ctx context.Context, idStr string) (*pbuser.User, error) {
 	id, err := strconv.ParseInt(idStr, 10, 64)
@@ -6,7 +6,10 @@ import (
 
 	errchecks "github.com/Costin2000/GoChat---Schwarz-Internship---2025/pkg"
 	pb "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/friend-request-base/proto"
+	pbuser "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/user-base/proto"
 	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/testing/protocmp"
 )
 
@@ -39,10 +42,33 @@ func Test_CreateFriendRequestRequest(t *testing.T) {
 
 	type given struct {
 		mockStorageAccess StorageAccess
+		userClient        pbuser.UserServiceClient
+	}
+
+	withPolicy := func(policy pbuser.FriendRequestPolicy) pbuser.UserServiceClient {
+		return &userClientMock{
+			getUserSettingsFunc: func(ctx context.Context, req *pbuser.GetUserSettingsRequest) (*pbuser.UserSettings, error) {
+				return &pbuser.UserSettings{UserId: req.UserId, FriendRequestPolicy: policy.Enum()}, nil
+			},
+		}
 	}
 
 	successfulResponse := fixtureCreateFriendResponse()
 
+	withMutualFriend := func(mutual bool) StorageAccess {
+		return newMockStorageAccess(StorageMockOptions{
+			createFriendRequestFunc: func(ctx context.Context, req *pb.CreateFriendRequestRequest) (*pb.CreateFriendRequestResponse, error) {
+				return successfulResponse, nil
+			},
+			haveMutualFriendFunc: func(ctx context.Context, userID, otherID int64) (bool, error) {
+				if userID != 111 || otherID != 222 {
+					t.Errorf("unexpected haveMutualFriend(%d, %d)", userID, otherID)
+				}
+				return mutual, nil
+			},
+		})
+	}
+
 	tests := []struct {
 		name         string
 		req          *pb.CreateFriendRequestRequest
@@ -86,12 +112,63 @@ func Test_CreateFriendRequestRequest(t *testing.T) {
 			expecterErr:  nil,
 			expectedResp: successfulResponse,
 		},
+		{
+			name:  "receiver accepts no friend requests",
+			req:   fixtureCreateFriendRequest(),
+			given: given{userClient: withPolicy(pbuser.FriendRequestPolicy_FRIEND_REQUEST_POLICY_NOBODY)},
+			expecterErr: errchecks.All(
+				errchecks.HasStatusCode(codes.PermissionDenied),
+				errchecks.MsgContains("does not accept friend requests"),
+			),
+		},
+		{
+			name: "receiver accepts friends of friends, no friend in common",
+			req:  fixtureCreateFriendRequest(),
+			given: given{
+				mockStorageAccess: withMutualFriend(false),
+				userClient:        withPolicy(pbuser.FriendRequestPolicy_FRIEND_REQUEST_POLICY_FRIENDS_OF_FRIENDS),
+			},
+			expecterErr: errchecks.All(
+				errchecks.HasStatusCode(codes.PermissionDenied),
+				errchecks.MsgContains("friends of friends"),
+			),
+		},
+		{
+			name: "receiver accepts friends of friends, a friend in common",
+			req:  fixtureCreateFriendRequest(),
+			given: given{
+				mockStorageAccess: withMutualFriend(true),
+				userClient:        withPolicy(pbuser.FriendRequestPolicy_FRIEND_REQUEST_POLICY_FRIENDS_OF_FRIENDS),
+			},
+			expectedResp: successfulResponse,
+		},
+		{
+			name: "unknown receiver",
+			req:  fixtureCreateFriendRequest(),
+			given: given{userClient: &userClientMock{
+				getUserSettingsFunc: func(ctx context.Context, req *pbuser.GetUserSettingsRequest) (*pbuser.UserSettings, error) {
+					return nil, status.Errorf(codes.NotFound, "user with id %d not found", req.UserId)
+				},
+			}},
+			expecterErr: errchecks.HasStatusCode(codes.NotFound),
+		},
+		{
+			name: "user-base unavailable",
+			req:  fixtureCreateFriendRequest(),
+			given: given{userClient: &userClientMock{
+				getUserSettingsFunc: func(ctx context.Context, req *pbuser.GetUserSettingsRequest) (*pbuser.UserSettings, error) {
+					return nil, status.Error(codes.Unavailable, "connection refused")
+				},
+			}},
+			expecterErr: errchecks.HasStatusCode(codes.Unavailable),
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			svc := NewMockService(ServiceMockOptions{
 				storageAccess: tt.given.mockStorageAccess,
+				userClient:    tt.given.userClient,
 			})
 
 			rsp, err := svc.CreateFriendRequest(context.Background(), tt.req)
@@ -3,17 +3,24 @@ package main
 import (
 	"context"
 	"encoding/json"
-	"time"
 
-	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/Costin2000/GoChat---Schwarz-Internship---2025/pkg/spool"
 )
 
 const emailsQueueName = "emails_queue"
 
+// notification events, the email service checks the recipient preferences for them
+const (
+	eventFriendRequest         = "friend_request"
+	eventFriendRequestAccepted = "friend_request_accepted"
+)
+
 type EmailMessage struct {
 	To      string `json:"to"`
 	Subject string `json:"subject"`
 	Body    string `json:"body"`
+	UserID  int64  `json:"user_id,omitempty"`
+	Event   string `json:"event,omitempty"`
 }
 
 type EmailPublisher interface {
@@ -22,25 +29,16 @@ type EmailPublisher interface {
 }
 
 type amqpEmailPublisher struct {
-	ch    *amqp.Channel
-	queue string
+	pub *spool.Publisher
 }
 
-// building AMQP publisher from connection
-func newAmqpEmailPublisher(conn *amqp.Connection) (EmailPublisher, error) {
-	ch, err := conn.Channel()
-	if err != nil {
-		return nil, err
-	}
-	_, err = ch.QueueDeclare(emailsQueueName, true, false, false, false, nil)
+// building AMQP publisher; it connects in the background and spools emails to spoolPath while RabbitMQ is down
+func newAmqpEmailPublisher(addr, spoolPath string) (*amqpEmailPublisher, error) {
+	pub, err := spool.NewPublisher(addr, emailsQueueName, spoolPath)
 	if err != nil {
-		_ = ch.Close()
 		return nil, err
 	}
-	return &amqpEmailPublisher{
-		ch:    ch,
-		queue: emailsQueueName,
-	}, nil
+	return &amqpEmailPublisher{pub: pub}, nil
 }
 
 // publish email message to queue
@@ -49,24 +47,14 @@ func (p *amqpEmailPublisher) Publish(ctx context.Context, msg EmailMessage) erro
 	if err != nil {
 		return err
 	}
-	return p.ch.PublishWithContext(
-		ctx,
-		"",
-		p.queue,
-		false,
-		false,
-		amqp.Publishing{
-			ContentType:  "application/json",
-			DeliveryMode: amqp.Persistent,
-			Timestamp:    time.Now(),
-			Body:         body,
-		},
-	)
+	return p.pub.Publish(ctx, body)
+}
+
+// reports whether RabbitMQ is reachable, used by the health check
+func (p *amqpEmailPublisher) Ping(ctx context.Context) error {
+	return p.pub.Ping(ctx)
 }
 
 func (p *amqpEmailPublisher) Close() error {
-	if p.ch != nil {
-		return p.ch.Close()
-	}
-	return nil
+	return p.pub.Close()
 }
@@ -11,6 +11,7 @@ import (
 	"time"
 
 	errchecks "github.com/Costin2000/GoChat---Schwarz-Internship---2025/pkg"
+	"github.com/Costin2000/GoChat---Schwarz-Internship---2025/pkg/bootstrap"
 	frproto "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/friend-request-base/proto"
 	"github.com/google/go-cmp/cmp"
 	"golang.org/x/crypto/bcrypt"
@@ -60,7 +61,7 @@ func TestListFriendRequests_Integration(t *testing.T) {
 
 	// load env
 	envPath := "./../../../devtest-db/.env"
-	if err := loadEnv(envPath); err != nil {
+	if err := bootstrap.LoadEnvFile(envPath); err != nil {
 		log.Fatalf("Error loading env: %v", err)
 	}
 
@@ -28,6 +28,7 @@ type StorageAccess interface {
 	requestCreateFriendRequest(ctx context.Context, req *proto.CreateFriendRequestRequest) (*proto.CreateFriendRequestResponse, error)
 	listFriendRequests(ctx context.Context, req *proto.ListFriendRequestsRequest) (*proto.ListFriendRequestsResponse, error)
 	requestUpdateFriendRequest(ctx context.Context, req *proto.UpdateFriendRequestRequest) (*proto.UpdateFriendRequestResponse, error)
+	haveMutualFriend(ctx context.Context, userID, otherID int64) (bool, error)
 }
 
 type PostgresAccess struct {
@@ -92,6 +93,28 @@ func (pa *PostgresAccess) requestCreateFriendRequest(ctx context.Context, req *p
 
 }
 
+// haveMutualFriend tells whether a user is friends with both users
+func (pa *PostgresAccess) haveMutualFriend(ctx context.Context, userID, otherID int64) (bool, error) {
+	query := `
+		WITH friends AS (
+			SELECT sender_id AS user_id, receiver_id AS friend_id FROM "Friend Requests" WHERE status = 'accepted'
+			UNION ALL
+			SELECT receiver_id, sender_id FROM "Friend Requests" WHERE status = 'accepted'
+		)
+		SELECT EXISTS (
+			SELECT 1 FROM friends a JOIN friends b ON a.friend_id = b.friend_id
+			WHERE a.user_id = $1 AND b.user_id = $2
+		);
+	`
+
+	var found bool
+	if err := pa.db.QueryRowContext(ctx, query, userID, otherID).Scan(&found); err != nil {
+		log.Printf("Database error on haveMutualFriend: %v", err)
+		return false, status.Errorf(codes.Internal, "failed to check mutual friends")
+	}
+	return found, nil
+}
+
 func (pa *PostgresAccess) listFriendRequests(ctx context.Context, req *proto.ListFriendRequestsRequest) (*proto.ListFriendRequestsResponse, error) {
 
 	// Creating the db query via formatted string - argument list pair to ensure protection against SQL Injection
@@ -142,10 +165,9 @@ func (pa *PostgresAccess) listFriendRequests(ctx context.Context, req *proto.Lis
 	query.WriteString(fmt.Sprintf(" ORDER BY id ASC LIMIT $%d", argCounter))
 	args = append(args, req.PageSize)
 
-	log.Printf("Executing query: %s with args: %v", query.String(), args)
 	rows, err := pa.db.QueryContext(ctx, query.String(), args...)
 	if err != nil {
-		log.Printf("DATABASE ERROR: Query '%s' with args %v failed: %v", query.String(), args, err)
+		log.Printf("Database error on listFriendRequests: %v", err)
 		return nil, status.Errorf(codes.Internal, "database query failed: %v", err)
 	}
 
@@ -4,6 +4,9 @@ import (
 	"context"
 
 	pb "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/friend-request-base/proto"
+	pbuser "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/user-base/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
@@ -11,6 +14,7 @@ type mockStorage struct {
 	createFriendRequestFunc func(ctx context.Context, req *pb.CreateFriendRequestRequest) (*pb.CreateFriendRequestResponse, error)
 	listFriendRequestsFunc  func(ctx context.Context, req *pb.ListFriendRequestsRequest) (*pb.ListFriendRequestsResponse, error)
 	updateFriendRequestFunc func(ctx context.Context, req *pb.UpdateFriendRequestRequest) (*pb.UpdateFriendRequestResponse, error)
+	haveMutualFriendFunc    func(ctx context.Context, userID, otherID int64) (bool, error)
 }
 
 func (m *mockStorage) requestCreateFriendRequest(ctx context.Context, req *pb.CreateFriendRequestRequest) (*pb.CreateFriendRequestResponse, error) {
@@ -25,10 +29,18 @@ func (m *mockStorage) requestUpdateFriendRequest(ctx context.Context, req *pb.Up
 	return m.updateFriendRequestFunc(ctx, req)
 }
 
+func (m *mockStorage) haveMutualFriend(ctx context.Context, userID, otherID int64) (bool, error) {
+	if m.haveMutualFriendFunc != nil {
+		return m.haveMutualFriendFunc(ctx, userID, otherID)
+	}
+	return false, nil
+}
+
 type StorageMockOptions struct {
 	createFriendRequestFunc func(ctx context.Context, req *pb.CreateFriendRequestRequest) (*pb.CreateFriendRequestResponse, error)
 	listFriendRequestsFunc  func(ctx context.Context, req *pb.ListFriendRequestsRequest) (*pb.ListFriendRequestsResponse, error)
 	updateFriendRequestFunc func(ctx context.Context, req *pb.UpdateFriendRequestRequest) (*pb.UpdateFriendRequestResponse, error)
+	haveMutualFriendFunc    func(ctx context.Context, userID, otherID int64) (bool, error)
 }
 
 func newMockStorageAccess(opts StorageMockOptions) StorageAccess {
@@ -55,11 +67,26 @@ func newMockStorageAccess(opts StorageMockOptions) StorageAccess {
 		createFriendRequestFunc: createFriendRequestFunc,
 		listFriendRequestsFunc:  listFriendRequestsFunc,
 		updateFriendRequestFunc: opts.updateFriendRequestFunc,
+		haveMutualFriendFunc:    opts.haveMutualFriendFunc,
 	}
 }
 
+// userClientMock implements the calls of user-base the service makes, the others panic
+type userClientMock struct {
+	pbuser.UserServiceClient
+	getUserSettingsFunc func(ctx context.Context, req *pbuser.GetUserSettingsRequest) (*pbuser.UserSettings, error)
+}
+
+func (m *userClientMock) GetUserSettings(ctx context.Context, req *pbuser.GetUserSettingsRequest, opts ...grpc.CallOption) (*pbuser.UserSettings, error) {
+	if m.getUserSettingsFunc != nil {
+		return m.getUserSettingsFunc(ctx, req)
+	}
+	return &pbuser.UserSettings{UserId: req.UserId, FriendRequestPolicy: pbuser.FriendRequestPolicy_FRIEND_REQUEST_POLICY_EVERYONE.Enum(), Discoverable: proto.Bool(true)}, nil
+}
+
 type ServiceMockOptions struct {
 	storageAccess StorageAccess
+	userClient    pbuser.UserServiceClient
 }
 
 func NewMockService(opts ServiceMockOptions) *friendRequestService {
@@ -67,9 +94,14 @@ func NewMockService(opts ServiceMockOptions) *friendRequestService {
 	if opts.storageAccess != nil {
 		storage = opts.storageAccess
 	}
+	var userClient pbuser.UserServiceClient = &userClientMock{}
+	if opts.userClient != nil {
+		userClient = opts.userClient
+	}
 
 	return &friendRequestService{
 		storageAccess: storage,
+		userClient:    userClient,
 	}
 }
 
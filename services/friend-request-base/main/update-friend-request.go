@@ -27,8 +27,6 @@ func (svc *friendRequestService) UpdateFriendRequest(ctx context.Context, req *p
 
 	// daca statusul este ACCEPTED, trimitem email catre SENDER
 	if resp != nil && resp.FriendRequest != nil && resp.FriendRequest.Status == proto.RequestStatus_STATUS_ACCEPTED {
-		log.Printf("A intrat in conditia care trebuia, nice")
-
 		// verificam ca avem publisher si clientul user-base
 		if svc.emailPub != nil && svc.userClient != nil {
 			senderID := resp.FriendRequest.SenderId
@@ -48,8 +46,10 @@ func (svc *friendRequestService) UpdateFriendRequest(ctx context.Context, req *p
 
 			// construim corpul email-ului; folosim fallback-uri daca nu avem date
 			senderEmail := ""
+			var senderUserID int64
 			if sender != nil {
 				senderEmail = sender.Email
+				senderUserID = sender.Id
 			}
 			receiverName := ""
 			receiverUsername := ""
@@ -77,10 +77,12 @@ func (svc *friendRequestService) UpdateFriendRequest(ctx context.Context, req *p
 					To:      senderEmail,
 					Subject: subject,
 					Body:    body,
+					UserID:  senderUserID,
+					Event:   eventFriendRequestAccepted,
 				}); pubErr != nil {
-					log.Printf("WARN: failed to publish accepted-friend-request email to %s: %v", senderEmail, pubErr)
+					log.Printf("WARN: failed to publish accepted-friend-request email to user %s: %v", senderID, pubErr)
 				} else {
-					log.Printf("Info: published accepted-friend-request email to %s", senderEmail)
+					log.Printf("Info: published accepted-friend-request email to user %s", senderID)
 				}
 			}
 		} else {
@@ -9,6 +9,7 @@ import (
 	"testing"
 
 	errchecks "github.com/Costin2000/GoChat---Schwarz-Internship---2025/pkg"
+	"github.com/Costin2000/GoChat---Schwarz-Internship---2025/pkg/bootstrap"
 	pb "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/friend-request-base/proto"
 	"github.com/google/go-cmp/cmp"
 	"google.golang.org/protobuf/testing/protocmp"
@@ -114,7 +115,7 @@ func TestUpdateFriendRequest_Integration(t *testing.T) {
 
 	// load env
 	envPath := "./../../../devtest-db/.env"
-	if err := loadEnv(envPath); err != nil {
+	if err := bootstrap.LoadEnvFile(envPath); err != nil {
 		t.Fatalf("Error loading env: %v", err)
 	}
 
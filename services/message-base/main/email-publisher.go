@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/Costin2000/GoChat---Schwarz-Internship---2025/pkg/spool"
+)
+
+const emailsQueueName = "emails_queue"
+
+// notification event, the email service checks the recipient preferences for it
+const eventUnreadMessages = "unread_messages"
+
+type EmailMessage struct {
+	To      string `json:"to"`
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+	UserID  int64  `json:"user_id,omitempty"`
+	Event   string `json:"event,omitempty"`
+}
+
+type EmailPublisher interface {
+	Publish(ctx context.Context, msg EmailMessage) error
+	Close() error
+}
+
+type amqpEmailPublisher struct {
+	pub *spool.Publisher
+}
+
+// building AMQP publisher; it connects in the background and spools emails to spoolPath while RabbitMQ is down
+func newAmqpEmailPublisher(addr, spoolPath string) (*amqpEmailPublisher, error) {
+	pub, err := spool.NewPublisher(addr, emailsQueueName, spoolPath)
+	if err != nil {
+		return nil, err
+	}
+	return &amqpEmailPublisher{pub: pub}, nil
+}
+
+// publish email message to queue
+func (p *amqpEmailPublisher) Publish(ctx context.Context, msg EmailMessage) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return p.pub.Publish(ctx, body)
+}
+
+// reports whether RabbitMQ is reachable, used by the health check
+func (p *amqpEmailPublisher) Ping(ctx context.Context) error {
+	return p.pub.Ping(ctx)
+}
+
+func (p *amqpEmailPublisher) Close() error {
+	return p.pub.Close()
+}
@@ -135,7 +135,7 @@ func Test_listMessages_Unit(t *testing.T) {
 
 // 	// load env
 // 	envPath := "./../../../devtest-db/.env"
-// 	if err := loadEnv(envPath); err != nil {
+// 	if err := bootstrap.LoadEnvFile(envPath); err != nil {
 // 		log.Fatalf("Error loading env: %v", err)
 // 	}
 
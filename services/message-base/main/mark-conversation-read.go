@@ -0,0 +1,24 @@
+package main
+
+import (
+	"context"
+
+	pb "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/message-base/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (svc *MessageService) MarkConversationRead(ctx context.Context, req *pb.MarkConversationReadRequest) (*pb.MarkConversationReadResponse, error) {
+	if req.ConversationId <= 0 || req.UserId <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "conversation_id, user_id must be positive")
+	}
+	if req.LastReadMessageId < 0 {
+		return nil, status.Error(codes.InvalidArgument, "last_read_message_id cannot be negative")
+	}
+
+	if err := svc.storageAccess.markConversationRead(ctx, req); err != nil {
+		return nil, err
+	}
+
+	return &pb.MarkConversationReadResponse{}, nil
+}
@@ -18,6 +18,18 @@ import (
 type StorageAccess interface {
 	createMessage(ctx context.Context, m *pb.Message) (*pb.Message, error)
 	listMessages(ctx context.Context, req *pb.ListMessagesRequest) (*pb.ListMessagesResponse, error)
+	markConversationRead(ctx context.Context, req *pb.MarkConversationReadRequest) error
+	listUnreadForDigest(ctx context.Context, olderThan time.Time) ([]unreadConversation, error)
+	claimUnreadDigest(ctx context.Context, userID, lastMessageID int64) (bool, error)
+}
+
+// unread messages of one recipient in one conversation, not yet included in a digest
+type unreadConversation struct {
+	userID         int64
+	conversationID int64
+	peerID         int64 // the other participant, who sent the messages
+	count          int64
+	lastMessageID  int64
 }
 
 type PostgresAccess struct{ db *sql.DB }
@@ -111,3 +123,86 @@ func (pa *PostgresAccess) listMessages(ctx context.Context, req *pb.ListMessages
 		Messages: messages,
 	}, nil
 }
+
+func (pa *PostgresAccess) markConversationRead(ctx context.Context, req *pb.MarkConversationReadRequest) error {
+	// the read marker never moves backwards; 0 means "up to the latest message"
+	query := `
+		INSERT INTO "Conversation Reads" (conversation_id, user_id, last_read_message_id)
+		SELECT c.id, $2, COALESCE(NULLIF($3::BIGINT, 0), (SELECT COALESCE(MAX(id), 0) FROM "Message" WHERE conversation_id = c.id))
+		FROM "Conversation" c
+		WHERE c.id = $1 AND $2 IN (c.user1_id, c.user2_id)
+		ON CONFLICT (conversation_id, user_id)
+		DO UPDATE SET last_read_message_id = GREATEST("Conversation Reads".last_read_message_id, EXCLUDED.last_read_message_id), updated_at = NOW();
+	`
+
+	res, err := pa.db.ExecContext(ctx, query, req.ConversationId, req.UserId, req.LastReadMessageId)
+	if err != nil {
+		log.Printf("Error marking conversation %d as read: %v", req.ConversationId, err)
+		return status.Errorf(codes.Internal, "failed to mark conversation as read")
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return status.Error(codes.NotFound, "conversation not found or user is not a participant")
+	}
+	return nil
+}
+
+func (pa *PostgresAccess) listUnreadForDigest(ctx context.Context, olderThan time.Time) ([]unreadConversation, error) {
+	// every participant of every conversation, with the messages of the other participant
+	// after both their read marker and the last digest they got
+	query := `
+		SELECT p.user_id, c.id,
+		       CASE WHEN c.user1_id = p.user_id THEN c.user2_id ELSE c.user1_id END,
+		       COUNT(m.id), MAX(m.id)
+		FROM "Conversation" c
+		CROSS JOIN LATERAL (VALUES (c.user1_id), (c.user2_id)) AS p(user_id)
+		JOIN "Message" m ON m.conversation_id = c.id AND m.sender_id <> p.user_id
+		LEFT JOIN "Conversation Reads" r ON r.conversation_id = c.id AND r.user_id = p.user_id
+		LEFT JOIN "Unread Digests" d ON d.user_id = p.user_id
+		WHERE m.id > COALESCE(r.last_read_message_id, 0)
+		  AND m.id > COALESCE(d.last_message_id, 0)
+		  AND m.created_at < $1
+		GROUP BY p.user_id, c.id, c.user1_id, c.user2_id
+		ORDER BY p.user_id, c.id;
+	`
+
+	rows, err := pa.db.QueryContext(ctx, query, olderThan)
+	if err != nil {
+		return nil, fmt.Errorf("query unread messages: %w", err)
+	}
+	defer rows.Close()
+
+	var unread []unreadConversation
+	for rows.Next() {
+		var u unreadConversation
+		if err := rows.Scan(&u.userID, &u.conversationID, &u.peerID, &u.count, &u.lastMessageID); err != nil {
+			return nil, fmt.Errorf("scan unread messages: %w", err)
+		}
+		unread = append(unread, u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("read unread messages: %w", err)
+	}
+	return unread, nil
+}
+
+// claimUnreadDigest moves the digest marker of the user to lastMessageID. It returns false if another run
+// already covered these messages. The caller publishes the digest only after a successful claim, so a failed
+// publish loses it instead of sending it twice: every digest is sent at most once.
+func (pa *PostgresAccess) claimUnreadDigest(ctx context.Context, userID, lastMessageID int64) (bool, error) {
+	query := `
+		INSERT INTO "Unread Digests" (user_id, last_message_id)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id)
+		DO UPDATE SET last_message_id = EXCLUDED.last_message_id, sent_at = NOW()
+		WHERE "Unread Digests".last_message_id < EXCLUDED.last_message_id;
+	`
+	res, err := pa.db.ExecContext(ctx, query, userID, lastMessageID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
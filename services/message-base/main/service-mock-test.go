@@ -2,13 +2,17 @@ package main
 
 import (
 	"context"
+	"time"
 
 	pb "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/message-base/proto"
 )
 
 type mockStorage struct {
-	createMessageFunc func(ctx context.Context, m *pb.Message) (*pb.Message, error)
-	listMessagesFunc  func(ctx context.Context, req *pb.ListMessagesRequest) (*pb.ListMessagesResponse, error)
+	createMessageFunc        func(ctx context.Context, m *pb.Message) (*pb.Message, error)
+	listMessagesFunc         func(ctx context.Context, req *pb.ListMessagesRequest) (*pb.ListMessagesResponse, error)
+	markConversationReadFunc func(ctx context.Context, req *pb.MarkConversationReadRequest) error
+	listUnreadForDigestFunc  func(ctx context.Context, olderThan time.Time) ([]unreadConversation, error)
+	claimUnreadDigestFunc    func(ctx context.Context, userID, lastMessageID int64) (bool, error)
 }
 
 func (m *mockStorage) createMessage(ctx context.Context, msg *pb.Message) (*pb.Message, error) {
@@ -25,16 +29,43 @@ func (m *mockStorage) listMessages(ctx context.Context, req *pb.ListMessagesRequ
 	return nil, nil
 }
 
+func (m *mockStorage) markConversationRead(ctx context.Context, req *pb.MarkConversationReadRequest) error {
+	if m.markConversationReadFunc != nil {
+		return m.markConversationReadFunc(ctx, req)
+	}
+	return nil
+}
+
+func (m *mockStorage) listUnreadForDigest(ctx context.Context, olderThan time.Time) ([]unreadConversation, error) {
+	if m.listUnreadForDigestFunc != nil {
+		return m.listUnreadForDigestFunc(ctx, olderThan)
+	}
+	return nil, nil
+}
+
+func (m *mockStorage) claimUnreadDigest(ctx context.Context, userID, lastMessageID int64) (bool, error) {
+	if m.claimUnreadDigestFunc != nil {
+		return m.claimUnreadDigestFunc(ctx, userID, lastMessageID)
+	}
+	return true, nil
+}
+
 type StorageMockOptions struct {
-	CreateMessageFunc func(ctx context.Context, m *pb.Message) (*pb.Message, error)
-	ListMessagesFunc  func(ctx context.Context, req *pb.ListMessagesRequest) (*pb.ListMessagesResponse, error)
+	CreateMessageFunc        func(ctx context.Context, m *pb.Message) (*pb.Message, error)
+	ListMessagesFunc         func(ctx context.Context, req *pb.ListMessagesRequest) (*pb.ListMessagesResponse, error)
+	MarkConversationReadFunc func(ctx context.Context, req *pb.MarkConversationReadRequest) error
+	ListUnreadForDigestFunc  func(ctx context.Context, olderThan time.Time) ([]unreadConversation, error)
+	ClaimUnreadDigestFunc    func(ctx context.Context, userID, lastMessageID int64) (bool, error)
 }
 
 func newMockStorageAccess(opts StorageMockOptions) StorageAccess {
 
 	mock := &mockStorage{
-		createMessageFunc: opts.CreateMessageFunc,
-		listMessagesFunc:  opts.ListMessagesFunc,
+		createMessageFunc:        opts.CreateMessageFunc,
+		listMessagesFunc:         opts.ListMessagesFunc,
+		markConversationReadFunc: opts.MarkConversationReadFunc,
+		listUnreadForDigestFunc:  opts.ListUnreadForDigestFunc,
+		claimUnreadDigestFunc:    opts.ClaimUnreadDigestFunc,
 	}
 	return mock
 }
@@ -1,100 +1,98 @@
 package main
 
 import (
-	"bufio"
-	"database/sql"
-	"fmt"
+	"context"
 	"log"
-	"net"
-	"os"
-	"path/filepath"
-	"strings"
+	"time"
 
+	"github.com/Costin2000/GoChat---Schwarz-Internship---2025/pkg/bootstrap"
 	pb "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/message-base/proto"
-	_ "github.com/jackc/pgx/v5/stdlib"
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/reflection"
+	userpb "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/user-base/proto"
 )
 
-const port = ":50055"
-
 type MessageService struct {
 	storageAccess StorageAccess
 	pb.UnimplementedMessageServiceServer
 }
 
-func loadEnv(filename string) error {
-	f, err := os.Open(filename)
-	if err != nil {
-		return err
-	}
-	defer f.Close()
-
-	sc := bufio.NewScanner(f)
-	for sc.Scan() {
-		line := sc.Text()
-		if len(line) == 0 || strings.HasPrefix(line, "#") {
-			continue
-		}
-		kv := strings.SplitN(line, "=", 2)
-		if len(kv) != 2 {
-			continue
-		}
-		if err := os.Setenv(strings.TrimSpace(kv[0]), strings.TrimSpace(kv[1])); err != nil {
-			log.Printf("warn: set env %s: %v", kv[0], err)
-		}
-	}
-	return sc.Err()
+type config struct {
+	Addr                 string        `env:"MESSAGE_BASE_LISTEN_ADDR" default:":50055"`
+	MetricsAddr          string        `env:"MESSAGE_BASE_METRICS_ADDR" default:":9105"`
+	UserBaseAddr         string        `env:"USER_BASE_ADDR" default:"user-base:50051"`
+	RabbitMQAddr         string        `env:"RABBITMQ_ADDR"`
+	SpoolPath            string        `env:"EMAIL_SPOOL_PATH" default:"./spool/emails.spool"`
+	UnreadDigestAfter    time.Duration `env:"UNREAD_DIGEST_AFTER" default:"30m"`
+	UnreadDigestInterval time.Duration `env:"UNREAD_DIGEST_INTERVAL" default:"10m"`
+	DB                   bootstrap.DBConfig
+	Shutdown             bootstrap.ShutdownConfig
+	Tracing              bootstrap.TracingConfig
 }
 
 func main() {
-	wd, err := os.Getwd()
-	if err != nil {
-		log.Fatalf("cwd: %v", err)
-	}
+	bootstrap.Init("message-base")
 
-	envPath := "./db/.env"
-	if filepath.Base(wd) == "message-base" {
-		envPath = "./../../db/.env"
-	}
-	if err := loadEnv(envPath); err != nil {
-		log.Fatalf("load env: %v", err)
+	var cfg config
+	if err := bootstrap.LoadConfig(&cfg); err != nil {
+		bootstrap.Fail("load config", err)
 	}
 
-	dbUser := os.Getenv("POSTGRES_USER")
-	dbPass := os.Getenv("POSTGRES_PASSWORD")
-	dbName := os.Getenv("POSTGRES_DB")
-	dbPort := os.Getenv("DB_PORT")
+	ctx, stop := bootstrap.SignalContext()
+	defer stop()
 
-	var dbHost string
-	if os.Getenv("ENV") == "docker" {
-		dbHost = "postgres-db"
-	} else {
-		dbHost = os.Getenv("DB_HOST")
+	shutdownTracing, err := bootstrap.InitTracing(ctx, cfg.Tracing)
+	if err != nil {
+		bootstrap.Fail("init tracing", err)
 	}
+	defer shutdownTracing(context.Background())
 
-	dsn := fmt.Sprintf("user=%s password=%s host=%s port=%s dbname=%s sslmode=disable", dbUser, dbPass, dbHost, dbPort, dbName)
-	db, err := sql.Open("pgx", dsn)
+	db, err := bootstrap.OpenDB(ctx, cfg.DB)
 	if err != nil {
-		log.Fatalf("open db: %v", err)
+		bootstrap.Fail("open database", err)
 	}
 	defer db.Close()
-	if err := db.Ping(); err != nil {
-		log.Fatalf("ping db: %v", err)
-	}
-	log.Println("MessageBase: connected to PostgreSQL")
+	checks := []bootstrap.Check{db.Check()}
 
-	lis, err := net.Listen("tcp", port)
-	if err != nil {
-		log.Fatalf("listen %s: %v", port, err)
+	storage := newPostgresAccess(db.DB)
+
+	// unread message digests; while RabbitMQ is down the digests are spooled to disk
+	if cfg.RabbitMQAddr != "" {
+		pub, err := newAmqpEmailPublisher(cfg.RabbitMQAddr, cfg.SpoolPath)
+		if err != nil {
+			bootstrap.Fail("create email publisher", err)
+		}
+		defer pub.Close()
+		// digests are spooled while RabbitMQ is down, so it does not make the service unready
+		checks = append(checks, bootstrap.Check{Name: "rabbitmq", Probe: pub.Ping, Optional: true})
+
+		userConn, err := bootstrap.Dial(cfg.UserBaseAddr)
+		if err != nil {
+			bootstrap.Fail("dial user-base", err)
+		}
+		defer userConn.Close()
+
+		job := &unreadDigestJob{
+			storageAccess: storage,
+			userClient:    userpb.NewUserServiceClient(userConn),
+			emailPub:      pub,
+			unreadAfter:   cfg.UnreadDigestAfter,
+			now:           time.Now,
+		}
+		go job.run(ctx, cfg.UnreadDigestInterval)
+		log.Println("MessageBase: unread digest job started")
+	} else {
+		log.Println("WARN: RABBITMQ_ADDR not set; unread digests disabled")
 	}
 
-	s := grpc.NewServer()
-	pb.RegisterMessageServiceServer(s, &MessageService{storageAccess: newPostgresAccess(db)})
-	reflection.Register(s)
+	s := bootstrap.NewGRPCServer()
+	pb.RegisterMessageServiceServer(s, &MessageService{storageAccess: storage})
+	health := bootstrap.RegisterHealth(s, checks...)
+	go health.Run(ctx)
+
+	if err := bootstrap.ServeMetrics(ctx, cfg.MetricsAddr); err != nil {
+		bootstrap.Fail("serve metrics", err)
+	}
 
-	log.Printf("MessageBase gRPC listening on %s", port)
-	if err := s.Serve(lis); err != nil {
-		log.Fatalf("serve: %v", err)
+	if err := bootstrap.ServeGRPC(ctx, s, cfg.Addr, health, cfg.Shutdown); err != nil {
+		bootstrap.Fail("serve", err)
 	}
 }
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	userpb "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/user-base/proto"
+	"google.golang.org/grpc"
+)
+
+type UserClient interface {
+	ListUsers(ctx context.Context, req *userpb.ListUsersRequest, opts ...grpc.CallOption) (*userpb.ListUsersResponse, error)
+}
+
+// unreadDigestJob emails every user one summary of the messages they haven't read for longer than unreadAfter
+type unreadDigestJob struct {
+	storageAccess StorageAccess
+	userClient    UserClient
+	emailPub      EmailPublisher
+	unreadAfter   time.Duration
+	now           func() time.Time
+}
+
+func (j *unreadDigestJob) run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := j.runOnce(ctx); err != nil {
+				log.Printf("unread digest run failed: %v", err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (j *unreadDigestJob) runOnce(ctx context.Context) error {
+	unread, err := j.storageAccess.listUnreadForDigest(ctx, j.now().Add(-j.unreadAfter))
+	if err != nil {
+		return err
+	}
+	if len(unread) == 0 {
+		return nil
+	}
+
+	byUser := make(map[int64][]unreadConversation)
+	userIDs := make(map[int64]struct{})
+	for _, u := range unread {
+		byUser[u.userID] = append(byUser[u.userID], u)
+		userIDs[u.userID] = struct{}{}
+		userIDs[u.peerID] = struct{}{}
+	}
+
+	users, err := j.fetchUsers(ctx, slices.Collect(maps.Keys(userIDs)))
+	if err != nil {
+		return err
+	}
+
+	sent := 0
+	for _, userID := range slices.Sorted(maps.Keys(byUser)) {
+		recipient, ok := users[userID]
+		if !ok || recipient.Email == "" {
+			log.Printf("WARN: unread digest: user %d not found, skipping", userID)
+			continue
+		}
+
+		conversations := byUser[userID]
+		var lastMessageID int64
+		for _, c := range conversations {
+			lastMessageID = max(lastMessageID, c.lastMessageID)
+		}
+
+		msg := composeUnreadDigest(recipient, conversations, users)
+		// claimed before publishing: a digest lost to a failed publish beats one sent twice
+		claimed, err := j.storageAccess.claimUnreadDigest(ctx, userID, lastMessageID)
+		if err != nil {
+			log.Printf("WARN: unread digest for user %d not claimed: %v", userID, err)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+		if err := j.emailPub.Publish(ctx, msg); err != nil {
+			log.Printf("WARN: unread digest for user %d not sent: %v", userID, err)
+			continue
+		}
+		sent++
+	}
+
+	log.Printf("unread digest: published %d emails", sent)
+	return nil
+}
+
+func (j *unreadDigestJob) fetchUsers(ctx context.Context, ids []int64) (map[int64]*userpb.User, error) {
+	users := make(map[int64]*userpb.User, len(ids))
+	filters := []*userpb.ListUsersFiltersOneOf{
+		{Filter: &userpb.ListUsersFiltersOneOf_UserIds{UserIds: &userpb.FilterByIdIn{UserId: ids}}},
+	}
+
+	nextPageToken := ""
+	for {
+		resp, err := j.userClient.ListUsers(ctx, &userpb.ListUsersRequest{
+			PageSize:      1000,
+			NextPageToken: nextPageToken,
+			Filters:       filters,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("list users: %w", err)
+		}
+		for _, u := range resp.Users {
+			users[u.Id] = u
+		}
+		nextPageToken = resp.NextPageToken
+		if nextPageToken == "" {
+			break
+		}
+	}
+	return users, nil
+}
+
+func composeUnreadDigest(recipient *userpb.User, conversations []unreadConversation, users map[int64]*userpb.User) EmailMessage {
+	var total int64
+	var lines strings.Builder
+	for _, c := range conversations {
+		total += c.count
+
+		from := "a friend"
+		if peer, ok := users[c.peerID]; ok {
+			from = fmt.Sprintf("%s %s (@%s)", peer.FirstName, peer.LastName, peer.UserName)
+		}
+		fmt.Fprintf(&lines, "- %d new %s from %s\n", c.count, pluralize(c.count, "message"), from)
+	}
+
+	return EmailMessage{
+		To:      recipient.Email,
+		Subject: fmt.Sprintf("You have %d unread %s on GoChat", total, pluralize(total, "message")),
+		Body:    fmt.Sprintf("Hi %s,\n\nThese conversations are waiting for you:\n\n%s\n- GoChat Team", recipient.FirstName, lines.String()),
+		UserID:  recipient.Id,
+		Event:   eventUnreadMessages,
+	}
+}
+
+func pluralize(n int64, noun string) string {
+	if n == 1 {
+		return noun
+	}
+	return noun + "s"
+}
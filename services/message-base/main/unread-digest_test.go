@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	userpb "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/user-base/proto"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc"
+)
+
+type userClientMock struct {
+	listUsersFunc func(ctx context.Context, req *userpb.ListUsersRequest) (*userpb.ListUsersResponse, error)
+}
+
+func (m *userClientMock) ListUsers(ctx context.Context, req *userpb.ListUsersRequest, opts ...grpc.CallOption) (*userpb.ListUsersResponse, error) {
+	return m.listUsersFunc(ctx, req)
+}
+
+type emailPublisherMock struct {
+	published  []EmailMessage
+	publishErr error
+}
+
+func (m *emailPublisherMock) Publish(ctx context.Context, msg EmailMessage) error {
+	if m.publishErr != nil {
+		return m.publishErr
+	}
+	m.published = append(m.published, msg)
+	return nil
+}
+
+func (m *emailPublisherMock) Close() error { return nil }
+
+func Test_unreadDigestJob_runOnce(t *testing.T) {
+	now := time.Date(2025, 8, 1, 12, 0, 0, 0, time.UTC)
+	users := &userClientMock{
+		listUsersFunc: func(ctx context.Context, req *userpb.ListUsersRequest) (*userpb.ListUsersResponse, error) {
+			return &userpb.ListUsersResponse{Users: []*userpb.User{
+				{Id: 1, FirstName: "Ana", LastName: "Ionescu", UserName: "ana", Email: "ana@example.com"},
+				{Id: 2, FirstName: "Ion", LastName: "Popescu", UserName: "ion", Email: "ion@example.com"},
+				{Id: 3, FirstName: "Maria", LastName: "Pop", UserName: "maria", Email: "maria@example.com"},
+			}}, nil
+		},
+	}
+	unread := []unreadConversation{
+		{userID: 1, conversationID: 10, peerID: 2, count: 3, lastMessageID: 105},
+		{userID: 1, conversationID: 11, peerID: 3, count: 1, lastMessageID: 98},
+		{userID: 2, conversationID: 10, peerID: 1, count: 1, lastMessageID: 101},
+	}
+
+	type claim struct {
+		userID        int64
+		lastMessageID int64
+	}
+
+	tests := []struct {
+		name          string
+		claimedBefore map[int64]bool
+		publishErr    error
+		wantClaims    []claim
+		wantPublished []EmailMessage
+	}{
+		{
+			name:       "one digest per user grouped by conversation",
+			wantClaims: []claim{{userID: 1, lastMessageID: 105}, {userID: 2, lastMessageID: 101}},
+			wantPublished: []EmailMessage{
+				{
+					To:      "ana@example.com",
+					Subject: "You have 4 unread messages on GoChat",
+					Body:    "Hi Ana,\n\nThese conversations are waiting for you:\n\n- 3 new messages from Ion Popescu (@ion)\n- 1 new message from Maria Pop (@maria)\n\n- GoChat Team",
+					UserID:  1,
+					Event:   eventUnreadMessages,
+				},
+				{
+					To:      "ion@example.com",
+					Subject: "You have 1 unread message on GoChat",
+					Body:    "Hi Ion,\n\nThese conversations are waiting for you:\n\n- 1 new message from Ana Ionescu (@ana)\n\n- GoChat Team",
+					UserID:  2,
+					Event:   eventUnreadMessages,
+				},
+			},
+		},
+		{
+			name:          "digest already claimed by another run is not published again",
+			claimedBefore: map[int64]bool{1: true},
+			wantClaims:    []claim{{userID: 1, lastMessageID: 105}, {userID: 2, lastMessageID: 101}},
+			wantPublished: []EmailMessage{
+				{
+					To:      "ion@example.com",
+					Subject: "You have 1 unread message on GoChat",
+					Body:    "Hi Ion,\n\nThese conversations are waiting for you:\n\n- 1 new message from Ana Ionescu (@ana)\n\n- GoChat Team",
+					UserID:  2,
+					Event:   eventUnreadMessages,
+				},
+			},
+		},
+		{
+			name:          "publish failure does not stop the run",
+			publishErr:    errors.New("channel closed"),
+			wantClaims:    []claim{{userID: 1, lastMessageID: 105}, {userID: 2, lastMessageID: 101}},
+			wantPublished: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var claims []claim
+			pub := &emailPublisherMock{publishErr: tt.publishErr}
+			job := &unreadDigestJob{
+				storageAccess: newMockStorageAccess(StorageMockOptions{
+					ListUnreadForDigestFunc: func(ctx context.Context, olderThan time.Time) ([]unreadConversation, error) {
+						if want := now.Add(-30 * time.Minute); !olderThan.Equal(want) {
+							t.Errorf("want olderThan %v, got %v", want, olderThan)
+						}
+						return unread, nil
+					},
+					ClaimUnreadDigestFunc: func(ctx context.Context, userID, lastMessageID int64) (bool, error) {
+						claims = append(claims, claim{userID: userID, lastMessageID: lastMessageID})
+						return !tt.claimedBefore[userID], nil
+					},
+				}),
+				userClient:  users,
+				emailPub:    pub,
+				unreadAfter: 30 * time.Minute,
+				now:         func() time.Time { return now },
+			}
+
+			if err := job.runOnce(context.Background()); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tt.wantClaims, claims, cmp.AllowUnexported(claim{})); diff != "" {
+				t.Errorf("claims mismatch (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tt.wantPublished, pub.published); diff != "" {
+				t.Errorf("published mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	pb "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/presence/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func (svc *presenceService) GetPresence(ctx context.Context, req *pb.GetPresenceRequest) (*pb.GetPresenceResponse, error) {
+	ids, err := svc.visibleUserIDs(ctx, req.GetViewerId(), req.GetUserIds())
+	if err != nil {
+		return nil, err
+	}
+	presences, err := svc.presenceOf(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	// in the order asked for
+	byID := make(map[int64]*pb.Presence, len(presences))
+	for _, p := range presences {
+		byID[p.UserId] = p
+	}
+	resp := &pb.GetPresenceResponse{Presences: []*pb.Presence{}}
+	for _, id := range req.UserIds {
+		if p, ok := byID[id]; ok {
+			resp.Presences = append(resp.Presences, p)
+			delete(byID, id)
+		}
+	}
+	return resp, nil
+}
+
+// presenceOf combines who is online with the last seen times saved for the others
+func (svc *presenceService) presenceOf(ctx context.Context, ids []int64) ([]*pb.Presence, error) {
+	online := svc.tracker.online(ids)
+	var offline []int64
+	for _, id := range ids {
+		if _, ok := online[id]; !ok {
+			offline = append(offline, id)
+		}
+	}
+	saved := map[int64]time.Time{}
+	if len(offline) > 0 {
+		var err error
+		if saved, err = svc.storageAccess.getLastSeen(ctx, offline); err != nil {
+			return nil, err
+		}
+	}
+
+	presences := make([]*pb.Presence, 0, len(ids))
+	for _, id := range ids {
+		p := &pb.Presence{UserId: id}
+		if at, ok := online[id]; ok {
+			p.Online, p.LastSeenAt = true, timestamppb.New(at)
+		} else if at, ok := saved[id]; ok {
+			p.LastSeenAt = timestamppb.New(at)
+		}
+		presences = append(presences, p)
+	}
+	return presences, nil
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	errchecks "github.com/Costin2000/GoChat---Schwarz-Internship---2025/pkg"
+	pb "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/presence/proto"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/testing/protocmp"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func Test_GetPresence(t *testing.T) {
+	lastWeek := fixtureTime.Add(-7 * 24 * time.Hour)
+	storage := func(mods ...func(*StorageMockOptions)) StorageAccess {
+		opts := StorageMockOptions{
+			getLastSeenFunc: func(ctx context.Context, userIDs []int64) (map[int64]time.Time, error) {
+				return map[int64]time.Time{2: lastWeek}, nil
+			},
+			// the viewer is friends with 1 and 2
+			visibleUsersFunc: func(ctx context.Context, viewerID int64, userIDs []int64) ([]int64, error) {
+				var visible []int64
+				for _, id := range userIDs {
+					if id == viewerID || id == 1 || id == 2 {
+						visible = append(visible, id)
+					}
+				}
+				return visible, nil
+			},
+		}
+		for _, mod := range mods {
+			mod(&opts)
+		}
+		return newMockStorageAccess(opts)
+	}
+
+	tests := []struct {
+		name         string
+		req          *pb.GetPresenceRequest
+		storage      StorageAccess
+		expectedErr  errchecks.Check
+		expectedResp *pb.GetPresenceResponse
+	}{
+		{
+			name:        "No user ids",
+			req:         &pb.GetPresenceRequest{ViewerId: 9},
+			storage:     storage(),
+			expectedErr: errchecks.All(errchecks.HasStatusCode(codes.InvalidArgument), errchecks.MsgContains("user_ids cannot be empty")),
+		},
+		{
+			name:        "Too many user ids",
+			req:         &pb.GetPresenceRequest{ViewerId: 9, UserIds: make([]int64, maxUserIDs+1)},
+			storage:     storage(),
+			expectedErr: errchecks.All(errchecks.HasStatusCode(codes.InvalidArgument), errchecks.MsgContains("at most")),
+		},
+		{
+			name:        "Invalid user id",
+			req:         &pb.GetPresenceRequest{ViewerId: 9, UserIds: []int64{1, -1}},
+			storage:     storage(),
+			expectedErr: errchecks.All(errchecks.HasStatusCode(codes.InvalidArgument), errchecks.MsgContains("must be positive")),
+		},
+		{
+			name: "Storage error",
+			req:  &pb.GetPresenceRequest{ViewerId: 9, UserIds: []int64{2}},
+			storage: storage(func(o *StorageMockOptions) {
+				o.getLastSeenFunc = func(ctx context.Context, userIDs []int64) (map[int64]time.Time, error) {
+					return nil, status.Errorf(codes.Internal, "failed to retrieve last seen")
+				}
+			}),
+			expectedErr: errchecks.HasStatusCode(codes.Internal),
+		},
+		{
+			name: "Happy path - online, last seen and never seen, in the order asked, strangers left out",
+			req:  &pb.GetPresenceRequest{ViewerId: 9, UserIds: []int64{2, 3, 9, 1, 2}},
+			storage: storage(func(o *StorageMockOptions) {
+				o.getLastSeenFunc = func(ctx context.Context, userIDs []int64) (map[int64]time.Time, error) {
+					if len(userIDs) != 2 {
+						return nil, errors.New("only the offline users should be looked up")
+					}
+					return map[int64]time.Time{2: lastWeek}, nil
+				}
+			}),
+			expectedErr: errchecks.IsNil,
+			expectedResp: &pb.GetPresenceResponse{Presences: []*pb.Presence{
+				{UserId: 2, LastSeenAt: timestamppb.New(lastWeek)},
+				{UserId: 9},
+				{UserId: 1, Online: true, LastSeenAt: timestamppb.New(fixtureTime)},
+			}},
+		},
+		{
+			name: "Services see everyone",
+			req:  &pb.GetPresenceRequest{UserIds: []int64{3}},
+			storage: storage(func(o *StorageMockOptions) {
+				o.visibleUsersFunc = func(ctx context.Context, viewerID int64, userIDs []int64) ([]int64, error) {
+					return nil, errors.New("services should not be checked")
+				}
+			}),
+			expectedErr:  errchecks.IsNil,
+			expectedResp: &pb.GetPresenceResponse{Presences: []*pb.Presence{{UserId: 3}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := NewMockService(ServiceMockOptions{storageAccess: tt.storage})
+			svc.tracker.heartbeat(1, "tab-1")
+
+			resp, err := svc.GetPresence(context.Background(), tt.req)
+
+			errchecks.Assert(t, err, tt.expectedErr)
+			if diff := cmp.Diff(tt.expectedResp, resp, protocmp.Transform()); diff != "" {
+				t.Errorf("unexpected response (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	pb "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/presence/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const maxConnectionIDLength = 64
+
+func (svc *presenceService) Heartbeat(ctx context.Context, req *pb.HeartbeatRequest) (*pb.HeartbeatResponse, error) {
+	if err := validateConnection(req.GetUserId(), req.GetConnectionId()); err != nil {
+		return nil, err
+	}
+
+	at, cameOnline := svc.tracker.heartbeat(req.UserId, req.ConnectionId)
+	// saved now as well as when going offline, a crash only loses the time spent online
+	if cameOnline {
+		svc.saveLastSeen(ctx, map[int64]time.Time{req.UserId: at})
+	}
+	return &pb.HeartbeatResponse{TtlSeconds: int64(svc.tracker.ttl.Seconds())}, nil
+}
+
+func (svc *presenceService) Disconnect(ctx context.Context, req *pb.DisconnectRequest) (*pb.DisconnectResponse, error) {
+	if err := validateConnection(req.GetUserId(), req.GetConnectionId()); err != nil {
+		return nil, err
+	}
+
+	if lastSeen, wentOffline := svc.tracker.disconnect(req.UserId, req.ConnectionId); wentOffline {
+		svc.saveLastSeen(ctx, map[int64]time.Time{req.UserId: lastSeen})
+	}
+	return &pb.DisconnectResponse{}, nil
+}
+
+func validateConnection(userID int64, connID string) error {
+	if userID <= 0 {
+		return status.Errorf(codes.InvalidArgument, "user_id must be positive")
+	}
+	if connID == "" || len(connID) > maxConnectionIDLength {
+		return status.Errorf(codes.InvalidArgument, "connection_id must be 1 to %d characters", maxConnectionIDLength)
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	errchecks "github.com/Costin2000/GoChat---Schwarz-Internship---2025/pkg"
+	pb "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/presence/proto"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/testing/protocmp"
+)
+
+func Test_Heartbeat(t *testing.T) {
+	tests := []struct {
+		name         string
+		req          *pb.HeartbeatRequest
+		expectedErr  errchecks.Check
+		expectedResp *pb.HeartbeatResponse
+	}{
+		{
+			name:        "Invalid user id",
+			req:         &pb.HeartbeatRequest{UserId: 0, ConnectionId: "tab-1"},
+			expectedErr: errchecks.All(errchecks.HasStatusCode(codes.InvalidArgument), errchecks.MsgContains("user_id must be positive")),
+		},
+		{
+			name:        "Empty connection id",
+			req:         &pb.HeartbeatRequest{UserId: 1},
+			expectedErr: errchecks.All(errchecks.HasStatusCode(codes.InvalidArgument), errchecks.MsgContains("connection_id")),
+		},
+		{
+			name:        "Connection id too long",
+			req:         &pb.HeartbeatRequest{UserId: 1, ConnectionId: strings.Repeat("a", maxConnectionIDLength+1)},
+			expectedErr: errchecks.All(errchecks.HasStatusCode(codes.InvalidArgument), errchecks.MsgContains("connection_id")),
+		},
+		{
+			name:         "Happy path - returns the TTL",
+			req:          &pb.HeartbeatRequest{UserId: 1, ConnectionId: "tab-1"},
+			expectedErr:  errchecks.IsNil,
+			expectedResp: &pb.HeartbeatResponse{TtlSeconds: 60},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := NewMockService(ServiceMockOptions{})
+
+			resp, err := svc.Heartbeat(context.Background(), tt.req)
+
+			errchecks.Assert(t, err, tt.expectedErr)
+			if diff := cmp.Diff(tt.expectedResp, resp, protocmp.Transform()); diff != "" {
+				t.Errorf("unexpected response (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func Test_HeartbeatSavesLastSeen(t *testing.T) {
+	saved := map[int64][]time.Time{}
+	svc := NewMockService(ServiceMockOptions{
+		storageAccess: newMockStorageAccess(StorageMockOptions{
+			saveLastSeenFunc: func(ctx context.Context, userID int64, at time.Time) error {
+				saved[userID] = append(saved[userID], at)
+				return nil
+			},
+		}),
+	})
+	ctx := context.Background()
+
+	// coming online and going offline are saved, the heartbeats in between are not
+	for _, conn := range []string{"tab-1", "tab-2", "tab-1"} {
+		_, err := svc.Heartbeat(ctx, &pb.HeartbeatRequest{UserId: 1, ConnectionId: conn})
+		errchecks.Assert(t, err, errchecks.IsNil)
+	}
+	for _, conn := range []string{"tab-1", "tab-2"} {
+		_, err := svc.Disconnect(ctx, &pb.DisconnectRequest{UserId: 1, ConnectionId: conn})
+		errchecks.Assert(t, err, errchecks.IsNil)
+	}
+
+	if len(saved[1]) != 2 {
+		t.Fatalf("got %d saves, want 2", len(saved[1]))
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type StorageAccess interface {
+	saveLastSeen(ctx context.Context, userID int64, at time.Time) error
+	getLastSeen(ctx context.Context, userIDs []int64) (map[int64]time.Time, error)
+	visibleUsers(ctx context.Context, viewerID int64, userIDs []int64) ([]int64, error)
+}
+
+type PostgresAccess struct {
+	db *sql.DB
+}
+
+func newPostgresAccess(db *sql.DB) *PostgresAccess {
+	return &PostgresAccess{db: db}
+}
+
+// saveLastSeen never moves the last seen time back, the saves of a user may finish out of order
+func (pa *PostgresAccess) saveLastSeen(ctx context.Context, userID int64, at time.Time) error {
+	query := `
+		INSERT INTO "Presence" (user_id, last_seen_at)
+		SELECT id, $2 FROM "User" WHERE id = $1
+		ON CONFLICT (user_id) DO UPDATE SET last_seen_at = GREATEST("Presence".last_seen_at, EXCLUDED.last_seen_at);
+	`
+	if _, err := pa.db.ExecContext(ctx, query, userID, at); err != nil {
+		log.Printf("Database error on saveLastSeen: %v", err)
+		return status.Errorf(codes.Internal, "failed to save last seen")
+	}
+	return nil
+}
+
+func (pa *PostgresAccess) getLastSeen(ctx context.Context, userIDs []int64) (map[int64]time.Time, error) {
+	rows, err := pa.db.QueryContext(ctx, `SELECT user_id, last_seen_at FROM "Presence" WHERE user_id = ANY($1)`, userIDs)
+	if err != nil {
+		log.Printf("Database error on getLastSeen: %v", err)
+		return nil, status.Errorf(codes.Internal, "failed to retrieve last seen")
+	}
+	defer rows.Close()
+
+	lastSeen := map[int64]time.Time{}
+	for rows.Next() {
+		var userID int64
+		var at time.Time
+		if err := rows.Scan(&userID, &at); err != nil {
+			return nil, status.Errorf(codes.Internal, "scan error: %v", err)
+		}
+		lastSeen[userID] = at
+	}
+	if err := rows.Err(); err != nil {
+		return nil, status.Errorf(codes.Internal, "rows error: %v", err)
+	}
+	return lastSeen, nil
+}
+
+// visibleUsers keeps the users whose presence the viewer may see: themselves and their friends
+func (pa *PostgresAccess) visibleUsers(ctx context.Context, viewerID int64, userIDs []int64) ([]int64, error) {
+	query := `
+		SELECT id FROM "User"
+		WHERE id = ANY($2) AND (id = $1 OR EXISTS (
+			SELECT 1 FROM "Friend Requests" fr
+			WHERE fr.status = 'accepted' AND (
+				(fr.sender_id = "User".id AND fr.receiver_id = $1) OR (fr.sender_id = $1 AND fr.receiver_id = "User".id))
+		));
+	`
+	rows, err := pa.db.QueryContext(ctx, query, viewerID, userIDs)
+	if err != nil {
+		log.Printf("Database error on visibleUsers: %v", err)
+		return nil, status.Errorf(codes.Internal, "failed to check friends")
+	}
+	defer rows.Close()
+
+	var visible []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, status.Errorf(codes.Internal, "scan error: %v", err)
+		}
+		visible = append(visible, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, status.Errorf(codes.Internal, "rows error: %v", err)
+	}
+	return visible, nil
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"time"
+)
+
+type mockStorage struct {
+	saveLastSeenFunc func(ctx context.Context, userID int64, at time.Time) error
+	getLastSeenFunc  func(ctx context.Context, userIDs []int64) (map[int64]time.Time, error)
+	visibleUsersFunc func(ctx context.Context, viewerID int64, userIDs []int64) ([]int64, error)
+}
+
+func (m *mockStorage) saveLastSeen(ctx context.Context, userID int64, at time.Time) error {
+	if m.saveLastSeenFunc != nil {
+		return m.saveLastSeenFunc(ctx, userID, at)
+	}
+	return nil
+}
+
+func (m *mockStorage) getLastSeen(ctx context.Context, userIDs []int64) (map[int64]time.Time, error) {
+	if m.getLastSeenFunc != nil {
+		return m.getLastSeenFunc(ctx, userIDs)
+	}
+	return map[int64]time.Time{}, nil
+}
+
+func (m *mockStorage) visibleUsers(ctx context.Context, viewerID int64, userIDs []int64) ([]int64, error) {
+	if m.visibleUsersFunc != nil {
+		return m.visibleUsersFunc(ctx, viewerID, userIDs)
+	}
+	return userIDs, nil
+}
+
+type StorageMockOptions struct {
+	saveLastSeenFunc func(ctx context.Context, userID int64, at time.Time) error
+	getLastSeenFunc  func(ctx context.Context, userIDs []int64) (map[int64]time.Time, error)
+	visibleUsersFunc func(ctx context.Context, viewerID int64, userIDs []int64) ([]int64, error)
+}
+
+func newMockStorageAccess(opts StorageMockOptions) StorageAccess {
+	return &mockStorage{
+		saveLastSeenFunc: opts.saveLastSeenFunc,
+		getLastSeenFunc:  opts.getLastSeenFunc,
+		visibleUsersFunc: opts.visibleUsersFunc,
+	}
+}
+
+type ServiceMockOptions struct {
+	storageAccess StorageAccess
+	tracker       *tracker
+}
+
+func NewMockService(opts ServiceMockOptions) *presenceService {
+	storage := newMockStorageAccess(StorageMockOptions{})
+	if opts.storageAccess != nil {
+		storage = opts.storageAccess
+	}
+	tr := opts.tracker
+	if tr == nil {
+		tr = newTracker(time.Minute, 3)
+		tr.now = fixtureClock().now
+	}
+
+	return &presenceService{
+		storageAccess: storage,
+		tracker:       tr,
+	}
+}
+
+// fakeClock is moved by the tests, the tracker reads it instead of the wall clock
+type fakeClock struct {
+	t time.Time
+}
+
+func (c *fakeClock) now() time.Time          { return c.t }
+func (c *fakeClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+func fixtureClock() *fakeClock               { return &fakeClock{t: fixtureTime} }
+
+var fixtureTime = time.Date(2025, 8, 1, 12, 0, 0, 0, time.UTC)
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"log"
+	"slices"
+	"time"
+
+	"github.com/Costin2000/GoChat---Schwarz-Internship---2025/pkg/bootstrap"
+	pb "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/presence/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// maxUserIDs bounds GetPresence and SubscribePresence, a friend list fits
+const maxUserIDs = 1000
+
+// presenceService keeps who is online in memory, so it runs as a single replica; the last seen
+// times are in Postgres and survive restarts, clients are back online with their next heartbeat
+type presenceService struct {
+	pb.UnimplementedPresenceServiceServer
+	storageAccess StorageAccess
+	tracker       *tracker
+	// closed on shutdown, ends the subscriptions
+	done <-chan struct{}
+}
+
+type config struct {
+	Addr        string `env:"PRESENCE_LISTEN_ADDR" default:":50058"`
+	MetricsAddr string `env:"PRESENCE_METRICS_ADDR" default:":9108"`
+	// a connection without a heartbeat for this long is gone
+	TTL time.Duration `env:"PRESENCE_TTL" default:"60s"`
+	// the connections (tabs, devices) tracked per user, the one heard from longest ago makes room
+	MaxConnections int `env:"PRESENCE_MAX_CONNECTIONS" default:"16"`
+	DB             bootstrap.DBConfig
+	Shutdown       bootstrap.ShutdownConfig
+	Tracing        bootstrap.TracingConfig
+}
+
+func main() {
+	bootstrap.Init("presence")
+
+	var cfg config
+	if err := bootstrap.LoadConfig(&cfg); err != nil {
+		bootstrap.Fail("load config", err)
+	}
+
+	ctx, stop := bootstrap.SignalContext()
+	defer stop()
+
+	shutdownTracing, err := bootstrap.InitTracing(ctx, cfg.Tracing)
+	if err != nil {
+		bootstrap.Fail("init tracing", err)
+	}
+	defer shutdownTracing(context.Background())
+
+	db, err := bootstrap.OpenDB(ctx, cfg.DB)
+	if err != nil {
+		bootstrap.Fail("open database", err)
+	}
+	defer db.Close()
+
+	PresenceServer := &presenceService{
+		storageAccess: newPostgresAccess(db.DB),
+		tracker:       newTracker(cfg.TTL, cfg.MaxConnections),
+		done:          ctx.Done(),
+	}
+	go PresenceServer.expireConnections(ctx, cfg.TTL/4)
+
+	grpcServer := bootstrap.NewGRPCServer()
+	pb.RegisterPresenceServiceServer(grpcServer, PresenceServer)
+	health := bootstrap.RegisterHealth(grpcServer, db.Check())
+	go health.Run(ctx)
+
+	if err := bootstrap.ServeMetrics(ctx, cfg.MetricsAddr); err != nil {
+		bootstrap.Fail("serve metrics", err)
+	}
+
+	if err := bootstrap.ServeGRPC(ctx, grpcServer, cfg.Addr, health, cfg.Shutdown); err != nil {
+		bootstrap.Fail("serve", err)
+	}
+
+	// the users still online were last seen now, as far as the next start knows
+	flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	PresenceServer.saveLastSeen(flushCtx, PresenceServer.tracker.everyoneOnline())
+}
+
+// expireConnections takes the users whose clients stopped sending heartbeats offline
+func (svc *presenceService) expireConnections(ctx context.Context, every time.Duration) {
+	ticker := time.NewTicker(max(every, time.Second))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			svc.saveLastSeen(ctx, svc.tracker.expire())
+		}
+	}
+}
+
+// saveLastSeen is best effort, a user who is not saved shows an older last seen time
+func (svc *presenceService) saveLastSeen(ctx context.Context, lastSeen map[int64]time.Time) {
+	for userID, at := range lastSeen {
+		if err := svc.storageAccess.saveLastSeen(ctx, userID, at); err != nil {
+			log.Printf("WARN: could not save the last seen time of user %d: %v", userID, err)
+		}
+	}
+}
+
+// visibleUserIDs checks and deduplicates the ids, and keeps those the viewer may see
+func (svc *presenceService) visibleUserIDs(ctx context.Context, viewerID int64, userIDs []int64) ([]int64, error) {
+	if len(userIDs) == 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "user_ids cannot be empty")
+	}
+	if len(userIDs) > maxUserIDs {
+		return nil, status.Errorf(codes.InvalidArgument, "at most %d user_ids", maxUserIDs)
+	}
+	if viewerID < 0 || slices.ContainsFunc(userIDs, func(id int64) bool { return id <= 0 }) {
+		return nil, status.Errorf(codes.InvalidArgument, "user ids must be positive")
+	}
+
+	ids := slices.Clone(userIDs)
+	slices.Sort(ids)
+	ids = slices.Compact(ids)
+	if viewerID == 0 {
+		return ids, nil
+	}
+	return svc.storageAccess.visibleUsers(ctx, viewerID, ids)
+}
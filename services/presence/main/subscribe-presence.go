@@ -0,0 +1,47 @@
+package main
+
+import (
+	pb "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/presence/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (svc *presenceService) SubscribePresence(req *pb.SubscribePresenceRequest, stream grpc.ServerStreamingServer[pb.Presence]) error {
+	ctx := stream.Context()
+	ids, err := svc.visibleUserIDs(ctx, req.GetViewerId(), req.GetUserIds())
+	if err != nil {
+		return err
+	}
+
+	// subscribed before reading the current presence, so no change falls in between; a change may
+	// arrive twice instead
+	sub := svc.tracker.subscribe(ids)
+	defer svc.tracker.unsubscribe(sub)
+
+	current, err := svc.presenceOf(ctx, ids)
+	if err != nil {
+		return err
+	}
+	for _, p := range current {
+		if err := stream.Send(p); err != nil {
+			return err
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		case <-svc.done:
+			return status.Error(codes.Unavailable, "presence is shutting down, subscribe again")
+		case p, ok := <-sub.events:
+			if !ok {
+				return status.Error(codes.ResourceExhausted, "the subscriber fell behind, subscribe again")
+			}
+			if err := stream.Send(p); err != nil {
+				return err
+			}
+		}
+	}
+}
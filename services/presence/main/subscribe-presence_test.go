@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	errchecks "github.com/Costin2000/GoChat---Schwarz-Internship---2025/pkg"
+	pb "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/presence/proto"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/testing/protocmp"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type fakePresenceStream struct {
+	grpc.ServerStreamingServer[pb.Presence]
+	ctx  context.Context
+	sent chan *pb.Presence
+}
+
+func (s *fakePresenceStream) Context() context.Context { return s.ctx }
+
+func (s *fakePresenceStream) Send(p *pb.Presence) error {
+	s.sent <- p
+	return nil
+}
+
+func (s *fakePresenceStream) next(t *testing.T) *pb.Presence {
+	t.Helper()
+	select {
+	case p := <-s.sent:
+		return p
+	case <-time.After(time.Second):
+		t.Fatal("no presence sent")
+		return nil
+	}
+}
+
+func Test_SubscribePresence(t *testing.T) {
+	svc := NewMockService(ServiceMockOptions{})
+	ctx, cancel := context.WithCancel(context.Background())
+	stream := &fakePresenceStream{ctx: ctx, sent: make(chan *pb.Presence, 8)}
+
+	errc := make(chan error, 1)
+	go func() {
+		errc <- svc.SubscribePresence(&pb.SubscribePresenceRequest{UserIds: []int64{1}}, stream)
+	}()
+
+	// the current presence first, then the changes
+	if diff := cmp.Diff(&pb.Presence{UserId: 1}, stream.next(t), protocmp.Transform()); diff != "" {
+		t.Errorf("unexpected snapshot (-want +got):\n%s", diff)
+	}
+	svc.tracker.heartbeat(1, "tab-1")
+	want := &pb.Presence{UserId: 1, Online: true, LastSeenAt: timestamppb.New(fixtureTime)}
+	if diff := cmp.Diff(want, stream.next(t), protocmp.Transform()); diff != "" {
+		t.Errorf("unexpected change (-want +got):\n%s", diff)
+	}
+
+	cancel()
+	errchecks.Assert(t, <-errc, errchecks.HasStatusCode(codes.Canceled))
+	if len(svc.tracker.subs) != 0 {
+		t.Error("the subscription should end with the stream")
+	}
+}
+
+func Test_SubscribePresence_Shutdown(t *testing.T) {
+	done := make(chan struct{})
+	svc := NewMockService(ServiceMockOptions{})
+	svc.done = done
+	stream := &fakePresenceStream{ctx: context.Background(), sent: make(chan *pb.Presence, 8)}
+
+	errc := make(chan error, 1)
+	go func() {
+		errc <- svc.SubscribePresence(&pb.SubscribePresenceRequest{UserIds: []int64{1}}, stream)
+	}()
+	stream.next(t)
+	close(done)
+
+	errchecks.Assert(t, <-errc, errchecks.HasStatusCode(codes.Unavailable))
+}
+
+func Test_SubscribePresence_InvalidRequest(t *testing.T) {
+	svc := NewMockService(ServiceMockOptions{})
+	stream := &fakePresenceStream{ctx: context.Background(), sent: make(chan *pb.Presence, 8)}
+
+	err := svc.SubscribePresence(&pb.SubscribePresenceRequest{}, stream)
+
+	errchecks.Assert(t, err, errchecks.HasStatusCode(codes.InvalidArgument))
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	pb "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/presence/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// subscriberBuffer is how many changes a subscriber may fall behind before it is dropped
+const subscriberBuffer = 64
+
+// tracker keeps the connections of the online users in memory and tells the subscribers when a
+// user comes online or goes offline
+type tracker struct {
+	ttl time.Duration
+	// the connections a user may hold, a new one beyond it replaces the one heard from longest ago
+	maxConns int
+	now      func() time.Time
+
+	mu sync.Mutex
+	// the last heartbeat of every connection, by user
+	conns map[int64]map[string]time.Time
+	subs  map[int64]map[*subscriber]struct{}
+}
+
+// subscriber receives the changes of the users it subscribed to. events is closed when it is
+// dropped for falling behind.
+type subscriber struct {
+	userIDs []int64
+	events  chan *pb.Presence
+}
+
+func newTracker(ttl time.Duration, maxConns int) *tracker {
+	return &tracker{
+		ttl:      ttl,
+		maxConns: max(maxConns, 1),
+		now:      time.Now,
+		conns:    map[int64]map[string]time.Time{},
+		subs:     map[int64]map[*subscriber]struct{}{},
+	}
+}
+
+// heartbeat keeps the connection alive and returns whether the user came online with it
+func (t *tracker) heartbeat(userID int64, connID string) (time.Time, bool) {
+	now := t.now()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	conns, online := t.conns[userID]
+	if !online {
+		conns = map[string]time.Time{}
+		t.conns[userID] = conns
+		t.publish(&pb.Presence{UserId: userID, Online: true, LastSeenAt: timestamppb.New(now)})
+	}
+	if _, known := conns[connID]; !known && len(conns) >= t.maxConns {
+		delete(conns, oldest(conns))
+	}
+	conns[connID] = now
+	return now, !online
+}
+
+// disconnect ends the connection and returns the last heartbeat of the user if it was their last one
+func (t *tracker) disconnect(userID int64, connID string) (time.Time, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	conns, ok := t.conns[userID]
+	if !ok {
+		return time.Time{}, false
+	}
+	if _, ok := conns[connID]; !ok {
+		return time.Time{}, false
+	}
+	lastSeen := latest(conns)
+	delete(conns, connID)
+	if len(conns) > 0 {
+		return time.Time{}, false
+	}
+	delete(t.conns, userID)
+	t.publish(&pb.Presence{UserId: userID, Online: false, LastSeenAt: timestamppb.New(lastSeen)})
+	return lastSeen, true
+}
+
+// expire ends the connections without a heartbeat within the TTL and returns the users who went
+// offline, with their last heartbeat
+func (t *tracker) expire() map[int64]time.Time {
+	deadline := t.now().Add(-t.ttl)
+	offline := map[int64]time.Time{}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for userID, conns := range t.conns {
+		lastSeen := latest(conns)
+		for connID, beat := range conns {
+			if beat.Before(deadline) {
+				delete(conns, connID)
+			}
+		}
+		if len(conns) == 0 {
+			delete(t.conns, userID)
+			offline[userID] = lastSeen
+			t.publish(&pb.Presence{UserId: userID, Online: false, LastSeenAt: timestamppb.New(lastSeen)})
+		}
+	}
+	return offline
+}
+
+// online returns the last heartbeat of the users who are online
+func (t *tracker) online(userIDs []int64) map[int64]time.Time {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	online := map[int64]time.Time{}
+	for _, id := range userIDs {
+		if conns, ok := t.conns[id]; ok {
+			online[id] = latest(conns)
+		}
+	}
+	return online
+}
+
+// everyoneOnline returns the last heartbeat of every online user, for the last seen times to be
+// saved on shutdown
+func (t *tracker) everyoneOnline() map[int64]time.Time {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	online := make(map[int64]time.Time, len(t.conns))
+	for id, conns := range t.conns {
+		online[id] = latest(conns)
+	}
+	return online
+}
+
+func (t *tracker) subscribe(userIDs []int64) *subscriber {
+	sub := &subscriber{userIDs: userIDs, events: make(chan *pb.Presence, subscriberBuffer)}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, id := range userIDs {
+		if t.subs[id] == nil {
+			t.subs[id] = map[*subscriber]struct{}{}
+		}
+		t.subs[id][sub] = struct{}{}
+	}
+	return sub
+}
+
+func (t *tracker) unsubscribe(sub *subscriber) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.remove(sub)
+}
+
+// publish must be called with mu held; it never blocks, subscribers who fall behind are dropped
+func (t *tracker) publish(p *pb.Presence) {
+	for sub := range t.subs[p.UserId] {
+		select {
+		case sub.events <- p:
+		default:
+			t.remove(sub)
+			close(sub.events)
+		}
+	}
+}
+
+func (t *tracker) remove(sub *subscriber) {
+	for _, id := range sub.userIDs {
+		delete(t.subs[id], sub)
+		if len(t.subs[id]) == 0 {
+			delete(t.subs, id)
+		}
+	}
+}
+
+// oldest returns the connection heard from longest ago
+func oldest(conns map[string]time.Time) string {
+	var (
+		id    string
+		first time.Time
+	)
+	for connID, beat := range conns {
+		if id == "" || beat.Before(first) {
+			id, first = connID, beat
+		}
+	}
+	return id
+}
+
+func latest(conns map[string]time.Time) time.Time {
+	var last time.Time
+	for _, beat := range conns {
+		if beat.After(last) {
+			last = beat
+		}
+	}
+	return last
+}
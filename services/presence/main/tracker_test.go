@@ -0,0 +1,116 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func newTestTracker() (*tracker, *fakeClock) {
+	clock := fixtureClock()
+	tr := newTracker(time.Minute, 3)
+	tr.now = clock.now
+	return tr, clock
+}
+
+func TestTracker_OnlineWhileAnyConnectionIsAlive(t *testing.T) {
+	tr, clock := newTestTracker()
+
+	if _, cameOnline := tr.heartbeat(1, "phone"); !cameOnline {
+		t.Fatal("first heartbeat should bring the user online")
+	}
+	clock.advance(30 * time.Second)
+	if _, cameOnline := tr.heartbeat(1, "laptop"); cameOnline {
+		t.Fatal("second connection should not bring the user online again")
+	}
+
+	if _, wentOffline := tr.disconnect(1, "phone"); wentOffline {
+		t.Fatal("user with another connection should stay online")
+	}
+	lastSeen, wentOffline := tr.disconnect(1, "laptop")
+	if !wentOffline || !lastSeen.Equal(clock.now()) {
+		t.Fatalf("got %v, %v; want offline, last seen at the laptop heartbeat", lastSeen, wentOffline)
+	}
+	if _, wentOffline := tr.disconnect(1, "laptop"); wentOffline {
+		t.Fatal("disconnecting twice should be a no-op")
+	}
+}
+
+func TestTracker_Expire(t *testing.T) {
+	tr, clock := newTestTracker()
+	tr.heartbeat(1, "a")
+	tr.heartbeat(2, "a")
+	clock.advance(45 * time.Second)
+	tr.heartbeat(2, "a")
+	clock.advance(30 * time.Second)
+
+	offline := tr.expire()
+
+	if len(offline) != 1 || !offline[1].Equal(fixtureTime) {
+		t.Fatalf("got %v, want only user 1 offline, last seen at %v", offline, fixtureTime)
+	}
+	if online := tr.online([]int64{1, 2}); len(online) != 1 || online[2].IsZero() {
+		t.Fatalf("got %v, want only user 2 online", online)
+	}
+}
+
+func TestTracker_EvictsOldestConnection(t *testing.T) {
+	tr, clock := newTestTracker()
+	for _, connID := range []string{"a", "b", "c"} {
+		tr.heartbeat(1, connID)
+		clock.advance(time.Second)
+	}
+	// "a" is refreshed, so "b" is the one heard from longest ago
+	tr.heartbeat(1, "a")
+	tr.heartbeat(1, "d")
+
+	if got := len(tr.conns[1]); got != 3 {
+		t.Fatalf("user holds %d connections, want at most 3", got)
+	}
+	if _, kept := tr.conns[1]["b"]; kept {
+		t.Error("the connection heard from longest ago should have been evicted")
+	}
+	if _, wentOffline := tr.disconnect(1, "b"); wentOffline {
+		t.Error("disconnecting an evicted connection should be a no-op")
+	}
+}
+
+func TestTracker_Subscribers(t *testing.T) {
+	tr, _ := newTestTracker()
+	sub := tr.subscribe([]int64{1})
+	other := tr.subscribe([]int64{2})
+
+	tr.heartbeat(1, "a")
+	tr.disconnect(1, "a")
+
+	if p := <-sub.events; p.UserId != 1 || !p.Online {
+		t.Fatalf("got %v, want user 1 online", p)
+	}
+	if p := <-sub.events; p.UserId != 1 || p.Online {
+		t.Fatalf("got %v, want user 1 offline", p)
+	}
+	if len(other.events) != 0 {
+		t.Fatal("a subscriber should only get the users it subscribed to")
+	}
+
+	tr.unsubscribe(sub)
+	tr.heartbeat(1, "a")
+	if len(sub.events) != 0 {
+		t.Fatal("an unsubscribed subscriber should get nothing")
+	}
+}
+
+func TestTracker_SlowSubscriberIsDropped(t *testing.T) {
+	tr, _ := newTestTracker()
+	sub := tr.subscribe([]int64{1})
+
+	for range subscriberBuffer/2 + 1 {
+		tr.heartbeat(1, "a")
+		tr.disconnect(1, "a")
+	}
+
+	for range sub.events {
+	}
+	if len(tr.subs) != 0 {
+		t.Fatalf("dropped subscriber is still subscribed: %v", tr.subs)
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+
+	"github.com/Costin2000/GoChat---Schwarz-Internship---2025/pkg/password"
+	pb "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/user-base/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (svc *UserService) ChangePassword(ctx context.Context, req *pb.ChangePasswordRequest) (*pb.ChangePasswordResponse, error) {
+	if req.UserId <= 0 || req.CurrentPassword == "" || req.NewPassword == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "user_id, current_password and new_password are required")
+	}
+	if err := svc.passwordPolicy.Check(req.NewPassword); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	current, err := svc.storageAccess.getPasswordHash(ctx, req.UserId)
+	if err != nil {
+		return nil, err
+	}
+	// bots and users of an identity provider have no password to change
+	if ok, _ := password.Verify(current, req.CurrentPassword, svc.passwordParams); !ok {
+		return nil, status.Errorf(codes.PermissionDenied, "current password is wrong")
+	}
+
+	hash, err := password.Hash(req.NewPassword, svc.passwordParams)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to hash password: %v", err)
+	}
+	updated, err := svc.storageAccess.updatePasswordHash(ctx, req.UserId, current, hash)
+	if err != nil {
+		return nil, err
+	}
+	if !updated {
+		return nil, status.Errorf(codes.Aborted, "password was changed meanwhile, try again")
+	}
+	return &pb.ChangePasswordResponse{}, nil
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	errchecks "github.com/Costin2000/GoChat---Schwarz-Internship---2025/pkg"
+	"github.com/Costin2000/GoChat---Schwarz-Internship---2025/pkg/password"
+	"golang.org/x/crypto/bcrypt"
+	"google.golang.org/grpc/codes"
+
+	pb "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/user-base/proto"
+)
+
+func Test_ChangePassword(t *testing.T) {
+	current, err := password.Hash("old passphrase", testPasswordParams)
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+	legacy, err := bcrypt.GenerateFromPassword([]byte("old passphrase"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("bcrypt: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		req         *pb.ChangePasswordRequest
+		storedHash  string
+		updated     bool
+		expectedErr errchecks.Check
+		wantStored  bool
+	}{
+		{
+			name:       "changes the password",
+			req:        &pb.ChangePasswordRequest{UserId: 1, CurrentPassword: "old passphrase", NewPassword: "new passphrase"},
+			storedHash: current,
+			updated:    true,
+			wantStored: true,
+		},
+		{
+			name:       "changes a bcrypt password",
+			req:        &pb.ChangePasswordRequest{UserId: 1, CurrentPassword: "old passphrase", NewPassword: "new passphrase"},
+			storedHash: string(legacy),
+			updated:    true,
+			wantStored: true,
+		},
+		{
+			name:        "wrong current password",
+			req:         &pb.ChangePasswordRequest{UserId: 1, CurrentPassword: "guess", NewPassword: "new passphrase"},
+			storedHash:  current,
+			expectedErr: errchecks.HasStatusCode(codes.PermissionDenied),
+		},
+		{
+			name:        "account without a password",
+			req:         &pb.ChangePasswordRequest{UserId: 1, CurrentPassword: "!", NewPassword: "new passphrase"},
+			storedHash:  noPassword,
+			expectedErr: errchecks.HasStatusCode(codes.PermissionDenied),
+		},
+		{
+			name:        "new password too short",
+			req:         &pb.ChangePasswordRequest{UserId: 1, CurrentPassword: "old passphrase", NewPassword: "short"},
+			storedHash:  current,
+			expectedErr: errchecks.All(errchecks.HasStatusCode(codes.InvalidArgument), errchecks.MsgContains("at least 10")),
+		},
+		{
+			name:        "new password breached",
+			req:         &pb.ChangePasswordRequest{UserId: 1, CurrentPassword: "old passphrase", NewPassword: "Password2024"},
+			storedHash:  current,
+			expectedErr: errchecks.HasStatusCode(codes.InvalidArgument),
+		},
+		{
+			name:        "changed meanwhile",
+			req:         &pb.ChangePasswordRequest{UserId: 1, CurrentPassword: "old passphrase", NewPassword: "new passphrase"},
+			storedHash:  current,
+			expectedErr: errchecks.HasStatusCode(codes.Aborted),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stored string
+			svc := NewMockService(ServiceMockOptions{storageAccess: newMockStorageAccess(StorageMockOptions{
+				getPasswordHashFunc: func(ctx context.Context, userID int64) (string, error) {
+					return tt.storedHash, nil
+				},
+				updatePasswordHashFunc: func(ctx context.Context, userID int64, oldHash, newHash string) (bool, error) {
+					if oldHash != tt.storedHash {
+						t.Errorf("old hash = %q, want the stored one", oldHash)
+					}
+					stored = newHash
+					return tt.updated, nil
+				},
+			})})
+
+			_, err := svc.ChangePassword(context.Background(), tt.req)
+
+			errchecks.Assert(t, err, tt.expectedErr)
+			if !tt.wantStored {
+				return
+			}
+			if ok, rehash := password.Verify(stored, tt.req.NewPassword, testPasswordParams); !ok || rehash {
+				t.Errorf("stored %q, want a current hash of the new password", stored)
+			}
+		})
+	}
+}
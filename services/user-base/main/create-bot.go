@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"strings"
+
+	pb "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/user-base/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// botEmailDomain is reserved (RFC 2606), bots need an email only because the column is unique and
+// required; nothing is ever sent there
+const botEmailDomain = "bots.gochat.invalid"
+
+func (svc *UserService) CreateBot(ctx context.Context, req *pb.CreateBotRequest) (*pb.CreateBotResponse, error) {
+	if req.OwnerId <= 0 ||
+		strings.TrimSpace(req.FirstName) == "" ||
+		strings.TrimSpace(req.UserName) == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "owner_id, first_name and user_name are required")
+	}
+
+	userName, err := normalizeUserName(req.UserName)
+	if err != nil {
+		return nil, err
+	}
+	bot, err := svc.storageAccess.createBot(ctx, &pb.User{
+		FirstName: strings.TrimSpace(req.FirstName),
+		LastName:  strings.TrimSpace(req.LastName),
+		UserName:  userName,
+		Email:     userName + "@" + botEmailDomain,
+		OwnerId:   req.OwnerId,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.CreateBotResponse{User: bot}, nil
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	errchecks "github.com/Costin2000/GoChat---Schwarz-Internship---2025/pkg"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/testing/protocmp"
+
+	pb "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/user-base/proto"
+)
+
+func Test_CreateBot(t *testing.T) {
+	tests := []struct {
+		name         string
+		req          *pb.CreateBotRequest
+		storage      StorageAccess
+		expectedErr  errchecks.Check
+		expectedResp *pb.CreateBotResponse
+	}{
+		{
+			name:        "missing owner",
+			req:         &pb.CreateBotRequest{FirstName: "Deploy", UserName: "deploy-bot"},
+			expectedErr: errchecks.HasStatusCode(codes.InvalidArgument),
+		},
+		{
+			name:        "missing user name",
+			req:         &pb.CreateBotRequest{OwnerId: 1, FirstName: "Deploy"},
+			expectedErr: errchecks.HasStatusCode(codes.InvalidArgument),
+		},
+		{
+			name: "owner not found or a bot",
+			req:  &pb.CreateBotRequest{OwnerId: 1, FirstName: "Deploy", UserName: "deploy-bot"},
+			storage: newMockStorageAccess(StorageMockOptions{
+				createBotFunc: func(ctx context.Context, bot *pb.User) (*pb.User, error) {
+					return nil, status.Errorf(codes.NotFound, "owner %d not found", bot.OwnerId)
+				},
+			}),
+			expectedErr: errchecks.HasStatusCode(codes.NotFound),
+		},
+		{
+			name: "creates the bot with a reserved email",
+			req:  &pb.CreateBotRequest{OwnerId: 1, FirstName: " Deploy ", LastName: "Bot", UserName: "Deploy-Bot"},
+			storage: newMockStorageAccess(StorageMockOptions{
+				createBotFunc: func(ctx context.Context, bot *pb.User) (*pb.User, error) {
+					bot.Id = 7
+					bot.IsBot = true
+					return bot, nil
+				},
+			}),
+			expectedResp: &pb.CreateBotResponse{User: &pb.User{
+				Id:        7,
+				FirstName: "Deploy",
+				LastName:  "Bot",
+				UserName:  "deploy-bot",
+				Email:     "deploy-bot@bots.gochat.invalid",
+				IsBot:     true,
+				OwnerId:   1,
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := NewMockService(ServiceMockOptions{storageAccess: tt.storage})
+
+			resp, err := svc.CreateBot(context.Background(), tt.req)
+
+			errchecks.Assert(t, err, tt.expectedErr)
+			if diff := cmp.Diff(tt.expectedResp, resp, protocmp.Transform()); diff != "" {
+				t.Errorf("mismatch (-expected +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -5,10 +5,12 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/Costin2000/GoChat---Schwarz-Internship---2025/pkg/password"
 	authpb "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/auth/proto"
 	pb "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/user-base/proto"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
 )
 
 func (svc *UserService) CreateUser(ctx context.Context, req *pb.CreateUserRequest) (*pb.CreateUserResponse, error) {
@@ -26,19 +28,35 @@ func (svc *UserService) CreateUser(ctx context.Context, req *pb.CreateUserReques
 		return nil, status.Errorf(codes.InvalidArgument, "all fields are required")
 	}
 
+	userName, err := normalizeUserName(user.UserName)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := svc.passwordPolicy.Check(user.Password); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
 	// Salvam parola originala pentru login ulterior
 	plainPassword := user.Password
+	hash, err := password.Hash(plainPassword, svc.passwordParams)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to hash password: %v", err)
+	}
 
 	// Cream utilizatorul in DB
-	createdUser, err := svc.storageAccess.createUser(ctx, req.User)
+	toCreate := proto.Clone(user).(*pb.User)
+	toCreate.UserName = userName
+	toCreate.Password = hash
+	createdUser, err := svc.storageAccess.createUser(ctx, toCreate)
 	if err != nil {
 		return nil, err
 	}
 
 	// Obtinem token apeland serviciul Auth
 	loginResp, err := svc.authClient.Login(ctx, &authpb.LoginRequest{
-		Email:    user.Email,
-		Password: plainPassword,
+		Identifier: &authpb.LoginRequest_Email{Email: user.Email},
+		Password:   plainPassword,
 	})
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "user created but failed to login: %v", err)
@@ -47,9 +65,11 @@ func (svc *UserService) CreateUser(ctx context.Context, req *pb.CreateUserReques
 	// Trimitem si tokenul in raspuns
 	if svc.emailPub != nil {
 		_ = svc.emailPub.Publish(ctx, EmailMessage{
-			To:      user.Email,
-			Subject: "Welcome to GoChat",
-			Body:    fmt.Sprintf("Hi %s, \n\nYour account was created successfully. Enjoy the experience!\n\n- GoChat Team", user.FirstName),
+			To:       user.Email,
+			Subject:  "Welcome to GoChat",
+			Body:     fmt.Sprintf("Hi %s, \n\nYour account was created successfully. Enjoy the experience!\n\n- GoChat Team", user.FirstName),
+			UserID:   createdUser.Id,
+			Template: templateWelcome,
 		})
 	}
 
@@ -58,3 +78,13 @@ func (svc *UserService) CreateUser(ctx context.Context, req *pb.CreateUserReques
 		Token: loginResp.Token,
 	}, nil
 }
+
+// normalizeUserName lower cases name, so "Alice" and "alice" are the same user. User names cannot
+// contain spaces or '@', which tells them apart from emails at login.
+func normalizeUserName(name string) (string, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if strings.ContainsAny(name, "@ \t\r\n") {
+		return "", status.Errorf(codes.InvalidArgument, "user_name cannot contain spaces or '@'")
+	}
+	return name, nil
+}
@@ -11,8 +11,11 @@ import (
 	"testing"
 
 	errchecks "github.com/Costin2000/GoChat---Schwarz-Internship---2025/pkg"
+	"github.com/Costin2000/GoChat---Schwarz-Internship---2025/pkg/bootstrap"
+	"github.com/Costin2000/GoChat---Schwarz-Internship---2025/pkg/password"
 	"github.com/google/go-cmp/cmp"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/testing/protocmp"
 
@@ -98,6 +101,66 @@ func Test_CreateUser(t *testing.T) {
 			},
 			expectedErr: errchecks.MsgContains("auth error"),
 		},
+		{
+			name: "password too short",
+			req: fixtureCreateUserRequest(func(req *pb.CreateUserRequest) {
+				req.User.Password = "secret"
+			}),
+			expectedErr: errchecks.All(errchecks.HasStatusCode(codes.InvalidArgument), errchecks.MsgContains("at least 10")),
+		},
+		{
+			name: "breached password",
+			req: fixtureCreateUserRequest(func(req *pb.CreateUserRequest) {
+				req.User.Password = "qwerty123456"
+			}),
+			expectedErr: errchecks.HasStatusCode(codes.InvalidArgument),
+		},
+		{
+			name: "stores an argon2id hash",
+			req:  fixtureCreateUserRequest(),
+			given: given{
+				mockStorageAccess: newMockStorageAccess(StorageMockOptions{
+					createUserFunc: func(ctx context.Context, user *pb.User) (*pb.User, error) {
+						if ok, rehash := password.Verify(user.Password, "secretpassword", testPasswordParams); !ok || rehash {
+							return nil, fmt.Errorf("stored %q, want the argon2id hash of the password", user.Password)
+						}
+						return fixtureUser(), nil
+					},
+				}),
+			},
+			expectedResp: fixtureCreateUserResponse(),
+		},
+		{
+			name: "user name with spaces",
+			req: fixtureCreateUserRequest(func(req *pb.CreateUserRequest) {
+				req.User.UserName = "john walter"
+			}),
+			expectedErr: errchecks.All(errchecks.HasStatusCode(codes.InvalidArgument), errchecks.MsgContains("user_name")),
+		},
+		{
+			name: "user name that looks like an email",
+			req: fixtureCreateUserRequest(func(req *pb.CreateUserRequest) {
+				req.User.UserName = "john@walter"
+			}),
+			expectedErr: errchecks.HasStatusCode(codes.InvalidArgument),
+		},
+		{
+			name: "stores the user name in lower case",
+			req: fixtureCreateUserRequest(func(req *pb.CreateUserRequest) {
+				req.User.UserName = " John.Walter "
+			}),
+			given: given{
+				mockStorageAccess: newMockStorageAccess(StorageMockOptions{
+					createUserFunc: func(ctx context.Context, user *pb.User) (*pb.User, error) {
+						if user.UserName != "john.walter" {
+							return nil, fmt.Errorf("stored user name %q", user.UserName)
+						}
+						return fixtureUser(), nil
+					},
+				}),
+			},
+			expectedResp: fixtureCreateUserResponse(),
+		},
 		{
 			name:         "successfully creates user",
 			req:          fixtureCreateUserRequest(),
@@ -128,7 +191,7 @@ func TestCreateUser_Integration(t *testing.T) {
 		LastName:  "Doe",
 		UserName:  "johndoe",
 		Email:     "johndoe@example.com",
-		Password:  "password123",
+		Password:  "n0t a common passphrase",
 	}
 
 	startDbCmd := exec.Command("bash", "./../scripts/db-start.sh")
@@ -140,7 +203,7 @@ func TestCreateUser_Integration(t *testing.T) {
 
 	// load env
 	envPath := "./../../../devtest-db/.env"
-	if err := loadEnv(envPath); err != nil {
+	if err := bootstrap.LoadEnvFile(envPath); err != nil {
 		log.Fatalf("Error loading env: %v", err)
 	}
 
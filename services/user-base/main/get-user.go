@@ -2,7 +2,7 @@ package main
 
 import (
 	"context"
-	"log"
+	"strings"
 
 	pb "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/user-base/proto"
 	"google.golang.org/grpc/codes"
@@ -10,16 +10,32 @@ import (
 )
 
 func (svc *UserService) GetUser(ctx context.Context, req *pb.GetUserRequest) (*pb.User, error) {
-	log.Printf("GetUser request for email: %s", req.Email)
-	email := req.Email
-	if email == "" {
-		return nil, status.Errorf(codes.InvalidArgument, "email cannot be empty")
-	}
-
-	user, err := svc.storageAccess.getUserByEmail(ctx, req.Email)
+	creds, err := svc.lookupUser(ctx, req)
 	if err != nil {
 		return nil, err
 	}
+	return creds.User, nil
+}
 
-	return user, nil
+func (svc *UserService) GetUserCredentials(ctx context.Context, req *pb.GetUserRequest) (*pb.UserCredentials, error) {
+	return svc.lookupUser(ctx, req)
+}
+
+func (svc *UserService) lookupUser(ctx context.Context, req *pb.GetUserRequest) (*pb.UserCredentials, error) {
+	switch id := req.Identifier.(type) {
+	case *pb.GetUserRequest_Email:
+		email := strings.TrimSpace(id.Email)
+		if email == "" {
+			return nil, status.Errorf(codes.InvalidArgument, "email cannot be empty")
+		}
+		return svc.storageAccess.getUserByEmail(ctx, email)
+	case *pb.GetUserRequest_UserName:
+		userName := strings.ToLower(strings.TrimSpace(id.UserName))
+		if userName == "" {
+			return nil, status.Errorf(codes.InvalidArgument, "user_name cannot be empty")
+		}
+		return svc.storageAccess.getUserByUserName(ctx, userName)
+	default:
+		return nil, status.Errorf(codes.InvalidArgument, "email or user_name is required")
+	}
 }
@@ -11,6 +11,7 @@ import (
 	"testing"
 
 	errchecks "github.com/Costin2000/GoChat---Schwarz-Internship---2025/pkg"
+	"github.com/Costin2000/GoChat---Schwarz-Internship---2025/pkg/bootstrap"
 	pb "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/user-base/proto"
 	"github.com/google/go-cmp/cmp"
 	"google.golang.org/grpc/codes"
@@ -20,7 +21,7 @@ import (
 
 func fixtureGetUserRequest(mods ...func(req *pb.GetUserRequest)) *pb.GetUserRequest {
 	user := &pb.GetUserRequest{
-		Email: "johnwalter@yahoo.com",
+		Identifier: &pb.GetUserRequest_Email{Email: "johnwalter@yahoo.com"},
 	}
 	for _, mod := range mods {
 		mod(user)
@@ -43,16 +44,41 @@ func Test_GetUser(t *testing.T) {
 		{
 			name: "email is empty",
 			req: fixtureGetUserRequest(func(req *pb.GetUserRequest) {
-				req.Email = ""
+				req.Identifier = &pb.GetUserRequest_Email{Email: ""}
 			}),
 			expectedErr: errchecks.All(errchecks.MsgContains("email cannot be empty")),
 		},
+		{
+			name:        "no identifier",
+			req:         &pb.GetUserRequest{},
+			expectedErr: errchecks.All(errchecks.HasStatusCode(codes.InvalidArgument), errchecks.MsgContains("email or user_name is required")),
+		},
+		{
+			name: "looks up the user name in lower case",
+			req: fixtureGetUserRequest(func(req *pb.GetUserRequest) {
+				req.Identifier = &pb.GetUserRequest_UserName{UserName: " John.Walter "}
+			}),
+			given: given{
+				mockStorageAccess: newMockStorageAccess(StorageMockOptions{
+					getUserByEmailFunc: func(ctx context.Context, email string) (*pb.UserCredentials, error) {
+						return nil, errors.New("looked up by email")
+					},
+					getUserByUserNameFunc: func(ctx context.Context, userName string) (*pb.UserCredentials, error) {
+						if userName != "john.walter" {
+							return nil, fmt.Errorf("looked up %q", userName)
+						}
+						return fixtureCredentials(), nil
+					},
+				}),
+			},
+			expectedResp: fixtureUser(),
+		},
 		{
 			name: "propagates error from getUserByEmail",
 			req:  fixtureGetUserRequest(),
 			given: given{
 				mockStorageAccess: newMockStorageAccess(StorageMockOptions{
-					getUserByEmailFunc: func(ctx context.Context, email string) (*pb.User, error) {
+					getUserByEmailFunc: func(ctx context.Context, email string) (*pb.UserCredentials, error) {
 						return nil, errors.New("getting user failed")
 					},
 				}),
@@ -60,7 +86,7 @@ func Test_GetUser(t *testing.T) {
 			expectedErr: errchecks.All(errchecks.MsgContains("getting user failed")),
 		},
 		{
-			name:         "successfully found user by email",
+			name:         "successfully found user by email, without the password hash",
 			req:          fixtureGetUserRequest(),
 			expectedResp: fixtureUser(),
 		},
@@ -82,6 +108,20 @@ func Test_GetUser(t *testing.T) {
 	}
 }
 
+func Test_GetUserCredentials(t *testing.T) {
+	svc := NewMockService(ServiceMockOptions{})
+
+	resp, err := svc.GetUserCredentials(context.Background(), fixtureGetUserRequest())
+
+	errchecks.Assert(t, err, errchecks.IsNil)
+	if diff := cmp.Diff(fixtureCredentials(), resp, protocmp.Transform()); diff != "" {
+		t.Errorf("mismatch (-expected +got):\n%s", diff)
+	}
+
+	_, err = svc.GetUserCredentials(context.Background(), &pb.GetUserRequest{})
+	errchecks.Assert(t, err, errchecks.HasStatusCode(codes.InvalidArgument))
+}
+
 func TestGetUser_Integration(t *testing.T) {
 
 	testGoodUser := &pb.User{
@@ -109,19 +149,31 @@ func TestGetUser_Integration(t *testing.T) {
 		{
 			name:         "Good request",
 			inputUser:    testGoodUser,
-			request:      &pb.GetUserRequest{Email: "test1@example.com"},
+			request:      &pb.GetUserRequest{Identifier: &pb.GetUserRequest_Email{Email: "test1@example.com"}},
+			expectedCode: codes.OK,
+		},
+		{
+			name:         "Good request - email in another case",
+			inputUser:    testGoodUser,
+			request:      &pb.GetUserRequest{Identifier: &pb.GetUserRequest_Email{Email: "Test1@Example.com"}},
+			expectedCode: codes.OK,
+		},
+		{
+			name:         "Good request - user name in another case",
+			inputUser:    testGoodUser,
+			request:      &pb.GetUserRequest{Identifier: &pb.GetUserRequest_UserName{UserName: "TestUser1"}},
 			expectedCode: codes.OK,
 		},
 		{
 			name:         "Bad request - no email provided",
 			inputUser:    testGoodUser,
-			request:      &pb.GetUserRequest{Email: ""},
+			request:      &pb.GetUserRequest{Identifier: &pb.GetUserRequest_Email{Email: ""}},
 			expectedCode: codes.InvalidArgument,
 		},
 		{
 			name:         "Bad request - invalid email",
 			inputUser:    testBadUser,
-			request:      &pb.GetUserRequest{Email: "test@badexample.com"},
+			request:      &pb.GetUserRequest{Identifier: &pb.GetUserRequest_Email{Email: "test@badexample.com"}},
 			expectedCode: codes.NotFound,
 		},
 	}
@@ -137,7 +189,7 @@ func TestGetUser_Integration(t *testing.T) {
 	// db connection
 	envPath := "./../../../devtest-db/.env"
 
-	if err := loadEnv(envPath); err != nil {
+	if err := bootstrap.LoadEnvFile(envPath); err != nil {
 		log.Fatalf("Error loading .env file: %v", err)
 	}
 
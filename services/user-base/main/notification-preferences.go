@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+
+	pb "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/user-base/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// events a user can opt out of; account related emails (e.g. welcome) are always sent
+var notificationEvents = []pb.NotificationEvent{
+	pb.NotificationEvent_NOTIFICATION_EVENT_FRIEND_REQUEST,
+	pb.NotificationEvent_NOTIFICATION_EVENT_FRIEND_REQUEST_ACCEPTED,
+	pb.NotificationEvent_NOTIFICATION_EVENT_UNREAD_MESSAGES,
+}
+
+func (svc *UserService) GetNotificationPreferences(ctx context.Context, req *pb.GetNotificationPreferencesRequest) (*pb.GetNotificationPreferencesResponse, error) {
+	if req.GetUserId() <= 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "user_id must be positive")
+	}
+
+	stored, err := svc.storageAccess.getNotificationPreferences(ctx, req.UserId)
+	if err != nil {
+		return nil, err
+	}
+
+	byEvent := make(map[pb.NotificationEvent]*pb.NotificationPreference, len(stored))
+	for _, pref := range stored {
+		byEvent[pref.Event] = pref
+	}
+
+	// events without a stored row fall back to the default: email on, no digest
+	prefs := make([]*pb.NotificationPreference, 0, len(notificationEvents))
+	for _, event := range notificationEvents {
+		if pref, ok := byEvent[event]; ok {
+			prefs = append(prefs, pref)
+			continue
+		}
+		prefs = append(prefs, &pb.NotificationPreference{
+			UserId:       req.UserId,
+			Event:        event,
+			EmailEnabled: true,
+		})
+	}
+
+	return &pb.GetNotificationPreferencesResponse{Preferences: prefs}, nil
+}
+
+func (svc *UserService) UpdateNotificationPreference(ctx context.Context, req *pb.UpdateNotificationPreferenceRequest) (*pb.UpdateNotificationPreferenceResponse, error) {
+	pref := req.GetPreference()
+	if pref == nil {
+		return nil, status.Errorf(codes.InvalidArgument, "preference object is required")
+	}
+	if pref.UserId <= 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "user_id must be positive")
+	}
+	if pref.Event == pb.NotificationEvent_NOTIFICATION_EVENT_UNKNOWN {
+		return nil, status.Errorf(codes.InvalidArgument, "event must be specified")
+	}
+	if _, ok := pb.NotificationEvent_name[int32(pref.Event)]; !ok {
+		return nil, status.Errorf(codes.InvalidArgument, "unsupported event value")
+	}
+
+	updated, err := svc.storageAccess.upsertNotificationPreference(ctx, pref)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.UpdateNotificationPreferenceResponse{Preference: updated}, nil
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	errchecks "github.com/Costin2000/GoChat---Schwarz-Internship---2025/pkg"
+	pb "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/user-base/proto"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/testing/protocmp"
+)
+
+func fixtureNotificationPreference(mods ...func(pref *pb.NotificationPreference)) *pb.NotificationPreference {
+	pref := &pb.NotificationPreference{
+		UserId:       1,
+		Event:        pb.NotificationEvent_NOTIFICATION_EVENT_FRIEND_REQUEST,
+		EmailEnabled: false,
+		Digest:       true,
+	}
+	for _, mod := range mods {
+		mod(pref)
+	}
+	return pref
+}
+
+func Test_GetNotificationPreferences(t *testing.T) {
+	type given struct {
+		mockStorageAccess StorageAccess
+	}
+
+	tests := []struct {
+		name         string
+		req          *pb.GetNotificationPreferencesRequest
+		given        given
+		expectedErr  errchecks.Check
+		expectedResp *pb.GetNotificationPreferencesResponse
+	}{
+		{
+			name:        "invalid user id",
+			req:         &pb.GetNotificationPreferencesRequest{UserId: 0},
+			expectedErr: errchecks.MsgContains("user_id must be positive"),
+		},
+		{
+			name: "propagates storage error",
+			req:  &pb.GetNotificationPreferencesRequest{UserId: 1},
+			given: given{
+				mockStorageAccess: newMockStorageAccess(StorageMockOptions{
+					getNotificationPreferencesFunc: func(ctx context.Context, userID int64) ([]*pb.NotificationPreference, error) {
+						return nil, errors.New("db down")
+					},
+				}),
+			},
+			expectedErr: errchecks.MsgContains("db down"),
+		},
+		{
+			name: "defaults for events without stored preference",
+			req:  &pb.GetNotificationPreferencesRequest{UserId: 1},
+			expectedResp: &pb.GetNotificationPreferencesResponse{
+				Preferences: []*pb.NotificationPreference{
+					{UserId: 1, Event: pb.NotificationEvent_NOTIFICATION_EVENT_FRIEND_REQUEST, EmailEnabled: true},
+					{UserId: 1, Event: pb.NotificationEvent_NOTIFICATION_EVENT_FRIEND_REQUEST_ACCEPTED, EmailEnabled: true},
+					{UserId: 1, Event: pb.NotificationEvent_NOTIFICATION_EVENT_UNREAD_MESSAGES, EmailEnabled: true},
+				},
+			},
+		},
+		{
+			name: "stored preference overrides the default",
+			req:  &pb.GetNotificationPreferencesRequest{UserId: 1},
+			given: given{
+				mockStorageAccess: newMockStorageAccess(StorageMockOptions{
+					getNotificationPreferencesFunc: func(ctx context.Context, userID int64) ([]*pb.NotificationPreference, error) {
+						return []*pb.NotificationPreference{fixtureNotificationPreference()}, nil
+					},
+				}),
+			},
+			expectedResp: &pb.GetNotificationPreferencesResponse{
+				Preferences: []*pb.NotificationPreference{
+					fixtureNotificationPreference(),
+					{UserId: 1, Event: pb.NotificationEvent_NOTIFICATION_EVENT_FRIEND_REQUEST_ACCEPTED, EmailEnabled: true},
+					{UserId: 1, Event: pb.NotificationEvent_NOTIFICATION_EVENT_UNREAD_MESSAGES, EmailEnabled: true},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := NewMockService(ServiceMockOptions{
+				storageAccess: tt.given.mockStorageAccess,
+			})
+
+			resp, err := svc.GetNotificationPreferences(context.Background(), tt.req)
+
+			errchecks.Assert(t, err, tt.expectedErr)
+			if diff := cmp.Diff(tt.expectedResp, resp, protocmp.Transform()); diff != "" {
+				t.Errorf("mismatch (-expected +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func Test_UpdateNotificationPreference(t *testing.T) {
+	type given struct {
+		mockStorageAccess StorageAccess
+	}
+
+	tests := []struct {
+		name         string
+		req          *pb.UpdateNotificationPreferenceRequest
+		given        given
+		expectedErr  errchecks.Check
+		expectedResp *pb.UpdateNotificationPreferenceResponse
+	}{
+		{
+			name:        "nil preference",
+			req:         &pb.UpdateNotificationPreferenceRequest{},
+			expectedErr: errchecks.MsgContains("preference object is required"),
+		},
+		{
+			name: "unknown event",
+			req: &pb.UpdateNotificationPreferenceRequest{Preference: fixtureNotificationPreference(func(pref *pb.NotificationPreference) {
+				pref.Event = pb.NotificationEvent_NOTIFICATION_EVENT_UNKNOWN
+			})},
+			expectedErr: errchecks.MsgContains("event must be specified"),
+		},
+		{
+			name: "propagates storage error",
+			req:  &pb.UpdateNotificationPreferenceRequest{Preference: fixtureNotificationPreference()},
+			given: given{
+				mockStorageAccess: newMockStorageAccess(StorageMockOptions{
+					upsertNotificationPreferenceFunc: func(ctx context.Context, pref *pb.NotificationPreference) (*pb.NotificationPreference, error) {
+						return nil, errors.New("upsert failed")
+					},
+				}),
+			},
+			expectedErr: errchecks.MsgContains("upsert failed"),
+		},
+		{
+			name:         "successfully updates preference",
+			req:          &pb.UpdateNotificationPreferenceRequest{Preference: fixtureNotificationPreference()},
+			expectedResp: &pb.UpdateNotificationPreferenceResponse{Preference: fixtureNotificationPreference()},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := NewMockService(ServiceMockOptions{
+				storageAccess: tt.given.mockStorageAccess,
+			})
+
+			resp, err := svc.UpdateNotificationPreference(context.Background(), tt.req)
+
+			errchecks.Assert(t, err, tt.expectedErr)
+			if diff := cmp.Diff(tt.expectedResp, resp, protocmp.Transform()); diff != "" {
+				t.Errorf("mismatch (-expected +got):\n%s", diff)
+			}
+		})
+	}
+}
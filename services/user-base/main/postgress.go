@@ -10,35 +10,43 @@ import (
 	"strings"
 	"time"
 
+	"github.com/Costin2000/GoChat---Schwarz-Internship---2025/pkg/avatar"
 	pb "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/user-base/proto"
-	"golang.org/x/crypto/bcrypt"
+	"github.com/jackc/pgx/v5/pgconn"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 type StorageAccess interface {
-	getUserByEmail(ctx context.Context, email string) (*pb.User, error)
+	getUserByEmail(ctx context.Context, email string) (*pb.UserCredentials, error)
+	getUserByUserName(ctx context.Context, userName string) (*pb.UserCredentials, error)
 	createUser(ctx context.Context, user *pb.User) (*pb.User, error)
+	createBot(ctx context.Context, bot *pb.User) (*pb.User, error)
+	createPasswordlessUser(ctx context.Context, user *pb.User) (*pb.User, error)
+	getPasswordHash(ctx context.Context, userID int64) (string, error)
+	updatePasswordHash(ctx context.Context, userID int64, oldHash, newHash string) (bool, error)
 	listUsers(ctx context.Context, req *pb.ListUsersRequest) (*pb.ListUsersResponse, error)
+	getNotificationPreferences(ctx context.Context, userID int64) ([]*pb.NotificationPreference, error)
+	upsertNotificationPreference(ctx context.Context, pref *pb.NotificationPreference) (*pb.NotificationPreference, error)
+	getUserSettings(ctx context.Context, userID int64) (*pb.UserSettings, error)
+	updateUserSettings(ctx context.Context, settings *pb.UserSettings) (*pb.UserSettings, error)
+	setAvatar(ctx context.Context, userID int64, key string) (*pb.User, string, error)
+	updateProfile(ctx context.Context, req *pb.UpdateProfileRequest) (*pb.User, error)
 }
 
 type PostgresAccess struct {
 	db *sql.DB
+	// signs the avatar_url of the users read
+	avatarURL avatar.URLConfig
 }
 
 func newPostgresAccess(db *sql.DB) *PostgresAccess {
 	return &PostgresAccess{db: db}
 }
 
+// createUser inserts the user; user.Password is the hash of the password
 func (pa *PostgresAccess) createUser(ctx context.Context, user *pb.User) (*pb.User, error) {
-	// Hash password
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
-	if err != nil {
-		log.Printf("Error hashing password: %v", err)
-		return nil, status.Errorf(codes.Internal, "failed to hash password")
-	}
-
 	// Insert into DB
 	query := `
 		INSERT INTO "User" (first_name, last_name, user_name, email, password)
@@ -48,8 +56,8 @@ func (pa *PostgresAccess) createUser(ctx context.Context, user *pb.User) (*pb.Us
 
 	var id int64
 	var createdAt time.Time
-	err = pa.db.QueryRowContext(ctx, query,
-		user.FirstName, user.LastName, user.UserName, user.Email, string(hashedPassword),
+	err := pa.db.QueryRowContext(ctx, query,
+		user.FirstName, user.LastName, user.UserName, user.Email, user.Password,
 	).Scan(&id, &createdAt)
 
 	if err != nil {
@@ -70,22 +78,141 @@ func (pa *PostgresAccess) createUser(ctx context.Context, user *pb.User) (*pb.Us
 		LastName:  user.LastName,
 		UserName:  user.UserName,
 		Email:     user.Email,
-		Password:  string(hashedPassword), // return hashed
 		CreatedAt: timestamppb.New(createdAt),
 	}, nil
 }
 
-func (pa *PostgresAccess) getUserByEmail(ctx context.Context, email string) (*pb.User, error) {
+// noPassword is stored for bots and users of an identity provider, it is no password hash so they can
+// never log in with a password
+const noPassword = "!"
+
+// createBot inserts a bot for bot.OwnerId; NotFound if the owner does not exist or is a bot itself
+func (pa *PostgresAccess) createBot(ctx context.Context, bot *pb.User) (*pb.User, error) {
+	query := `
+		INSERT INTO "User" (first_name, last_name, user_name, email, password, is_bot, owner_id)
+		SELECT $1, $2, $3, $4, $5, TRUE, id FROM "User" WHERE id = $6 AND NOT is_bot
+		RETURNING id, created_at;
+	`
+
+	var id int64
+	var createdAt time.Time
+	err := pa.db.QueryRowContext(ctx, query,
+		bot.FirstName, bot.LastName, bot.UserName, bot.Email, noPassword, bot.OwnerId,
+	).Scan(&id, &createdAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, status.Errorf(codes.NotFound, "owner %d not found", bot.OwnerId)
+		}
+		if strings.Contains(err.Error(), "duplicate key") {
+			return nil, status.Errorf(codes.AlreadyExists, "user with this username already exists")
+		}
+		log.Printf("Database error: %v", err)
+		return nil, status.Errorf(codes.Internal, "failed to create bot")
+	}
+
+	return &pb.User{
+		Id:        id,
+		FirstName: bot.FirstName,
+		LastName:  bot.LastName,
+		UserName:  bot.UserName,
+		Email:     bot.Email,
+		CreatedAt: timestamppb.New(createdAt),
+		IsBot:     true,
+		OwnerId:   bot.OwnerId,
+	}, nil
+}
+
+// createPasswordlessUser inserts a user who can only log in through an identity provider
+func (pa *PostgresAccess) createPasswordlessUser(ctx context.Context, user *pb.User) (*pb.User, error) {
+	query := `
+		INSERT INTO "User" (first_name, last_name, user_name, email, password)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at;
+	`
+
+	var id int64
+	var createdAt time.Time
+	err := pa.db.QueryRowContext(ctx, query,
+		user.FirstName, user.LastName, user.UserName, user.Email, noPassword,
+	).Scan(&id, &createdAt)
+	if err != nil {
+		if strings.Contains(err.Error(), "duplicate key") {
+			return nil, status.Errorf(codes.AlreadyExists, "user with this email or username already exists")
+		}
+		log.Printf("Database error: %v", err)
+		return nil, status.Errorf(codes.Internal, "failed to create user")
+	}
+
+	return &pb.User{
+		Id:        id,
+		FirstName: user.FirstName,
+		LastName:  user.LastName,
+		UserName:  user.UserName,
+		Email:     user.Email,
+		CreatedAt: timestamppb.New(createdAt),
+	}, nil
+}
+
+// getPasswordHash returns the stored password hash of the user, NotFound if there is no such user
+func (pa *PostgresAccess) getPasswordHash(ctx context.Context, userID int64) (string, error) {
+	var hash string
+	err := pa.db.QueryRowContext(ctx, `SELECT password FROM "User" WHERE id = $1;`, userID).Scan(&hash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", status.Errorf(codes.NotFound, "user with id %d not found", userID)
+	}
+	if err != nil {
+		log.Printf("Database error: %v", err)
+		return "", status.Errorf(codes.Internal, "failed to get user")
+	}
+	return hash, nil
+}
+
+// updatePasswordHash replaces the password hash of the user if it still is oldHash
+func (pa *PostgresAccess) updatePasswordHash(ctx context.Context, userID int64, oldHash, newHash string) (bool, error) {
+	res, err := pa.db.ExecContext(ctx, `UPDATE "User" SET password = $3 WHERE id = $1 AND password = $2;`, userID, oldHash, newHash)
+	if err != nil {
+		log.Printf("Database error: %v", err)
+		return false, status.Errorf(codes.Internal, "failed to update password")
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, status.Errorf(codes.Internal, "failed to update password")
+	}
+	return n == 1, nil
+}
+
+// getUserByEmail matches email case-insensitively, through the unique index on LOWER(email)
+func (pa *PostgresAccess) getUserByEmail(ctx context.Context, email string) (*pb.UserCredentials, error) {
+	user, err := pa.getUserWhere(ctx, `LOWER(email) = LOWER($1)`, email)
+	if err == sql.ErrNoRows {
+		return nil, status.Errorf(codes.NotFound, "user with email %s not found", email)
+	}
+	return user, err
+}
+
+// getUserByUserName expects a lower case name, user names are stored lower case
+func (pa *PostgresAccess) getUserByUserName(ctx context.Context, userName string) (*pb.UserCredentials, error) {
+	user, err := pa.getUserWhere(ctx, `user_name = $1`, userName)
+	if err == sql.ErrNoRows {
+		return nil, status.Errorf(codes.NotFound, "user with user_name %s not found", userName)
+	}
+	return user, err
+}
+
+// getUserWhere returns sql.ErrNoRows as is, for the caller to name what was not found
+func (pa *PostgresAccess) getUserWhere(ctx context.Context, cond string, arg any) (*pb.UserCredentials, error) {
 	var user pb.User
+	var hash string
 	var createdAt time.Time
+	var avatarKey sql.NullString
 
 	query := `
-        SELECT id, first_name, last_name, user_name, email, password, created_at
+        SELECT id, first_name, last_name, user_name, email, password, created_at, is_bot, COALESCE(owner_id, 0), avatar_key,
+            bio, status_text, timezone, locale
         FROM "User"
-        WHERE email = $1;
-    `
+        WHERE ` + cond
 
-	row := pa.db.QueryRowContext(ctx, query, email)
+	row := pa.db.QueryRowContext(ctx, query, arg)
 
 	err := row.Scan(
 		&user.Id,
@@ -93,13 +220,20 @@ func (pa *PostgresAccess) getUserByEmail(ctx context.Context, email string) (*pb
 		&user.LastName,
 		&user.UserName,
 		&user.Email,
-		&user.Password,
+		&hash,
 		&createdAt,
+		&user.IsBot,
+		&user.OwnerId,
+		&avatarKey,
+		&user.Bio,
+		&user.StatusText,
+		&user.Timezone,
+		&user.Locale,
 	)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, status.Errorf(codes.NotFound, "user with email %s not found", email)
+			return nil, err
 		}
 
 		log.Printf("Database error on GetUser: %v", err)
@@ -107,8 +241,9 @@ func (pa *PostgresAccess) getUserByEmail(ctx context.Context, email string) (*pb
 	}
 
 	user.CreatedAt = timestamppb.New(createdAt)
+	user.AvatarUrl = pa.avatarURL.URL(avatarKey.String, time.Now())
 
-	return &user, nil
+	return &pb.UserCredentials{User: &user, PasswordHash: hash}, nil
 }
 
 func (pa *PostgresAccess) listUsers(ctx context.Context, req *pb.ListUsersRequest) (*pb.ListUsersResponse, error) {
@@ -156,6 +291,9 @@ func (pa *PostgresAccess) listUsers(ctx context.Context, req *pb.ListUsersReques
 				where = append(where, fmt.Sprintf("id = ANY($%d)", len(args)+1))
 				args = append(args, ids)
 			}
+		case *pb.ListUsersFiltersOneOf_Discoverable:
+			where = append(where, fmt.Sprintf("discoverable = $%d", len(args)+1))
+			args = append(args, x.Discoverable.GetEquals())
 		}
 
 	}
@@ -165,7 +303,18 @@ func (pa *PostgresAccess) listUsers(ctx context.Context, req *pb.ListUsersReques
 		args = append(args, lastID)
 	}
 
-	baseQuery := `SELECT id, first_name, last_name, user_name, email, created_at FROM "User"`
+	// services list users without a viewer and see every email
+	email := "email"
+	if viewer := req.GetViewerId(); viewer > 0 {
+		email = fmt.Sprintf(`CASE WHEN "User".id = $%[1]d OR (share_email_with_friends AND EXISTS (
+			SELECT 1 FROM "Friend Requests" fr
+			WHERE fr.status = 'accepted' AND (
+				(fr.sender_id = "User".id AND fr.receiver_id = $%[1]d) OR (fr.sender_id = $%[1]d AND fr.receiver_id = "User".id))
+		)) THEN email ELSE '' END`, len(args)+1)
+		args = append(args, viewer)
+	}
+
+	baseQuery := `SELECT id, first_name, last_name, user_name, ` + email + `, created_at, is_bot, COALESCE(owner_id, 0), avatar_key, bio, status_text, timezone, locale FROM "User"`
 
 	if len(where) > 0 {
 		baseQuery += " WHERE " + strings.Join(where, " AND ")
@@ -183,11 +332,13 @@ func (pa *PostgresAccess) listUsers(ctx context.Context, req *pb.ListUsersReques
 	for rows.Next() {
 		var user pb.User
 		var createdAt time.Time
-		if err := rows.Scan(&user.Id, &user.FirstName, &user.LastName, &user.UserName, &user.Email, &createdAt); err != nil {
+		var avatarKey sql.NullString
+		if err := rows.Scan(&user.Id, &user.FirstName, &user.LastName, &user.UserName, &user.Email, &createdAt, &user.IsBot, &user.OwnerId, &avatarKey,
+			&user.Bio, &user.StatusText, &user.Timezone, &user.Locale); err != nil {
 			return nil, status.Errorf(codes.Internal, "scan error: %v", err)
 		}
 		user.CreatedAt = timestamppb.New(createdAt)
-		user.Password = ""
+		user.AvatarUrl = pa.avatarURL.URL(avatarKey.String, time.Now())
 		users = append(users, &user)
 	}
 	if err := rows.Err(); err != nil {
@@ -205,3 +356,200 @@ func (pa *PostgresAccess) listUsers(ctx context.Context, req *pb.ListUsersReques
 		Users:         users,
 	}, nil
 }
+
+func (pa *PostgresAccess) getNotificationPreferences(ctx context.Context, userID int64) ([]*pb.NotificationPreference, error) {
+	query := `
+		SELECT event_type, email_enabled, digest
+		FROM "Notification Preferences"
+		WHERE user_id = $1;
+	`
+
+	rows, err := pa.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		log.Printf("Database error on GetNotificationPreferences: %v", err)
+		return nil, status.Errorf(codes.Internal, "failed to retrieve notification preferences")
+	}
+	defer rows.Close()
+
+	var prefs []*pb.NotificationPreference
+	for rows.Next() {
+		var eventType string
+		pref := &pb.NotificationPreference{UserId: userID}
+		if err := rows.Scan(&eventType, &pref.EmailEnabled, &pref.Digest); err != nil {
+			return nil, status.Errorf(codes.Internal, "scan error: %v", err)
+		}
+		pref.Event = notificationEventFromDB(eventType)
+		if pref.Event == pb.NotificationEvent_NOTIFICATION_EVENT_UNKNOWN {
+			continue // event type no longer supported
+		}
+		prefs = append(prefs, pref)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, status.Errorf(codes.Internal, "rows error: %v", err)
+	}
+
+	return prefs, nil
+}
+
+func (pa *PostgresAccess) upsertNotificationPreference(ctx context.Context, pref *pb.NotificationPreference) (*pb.NotificationPreference, error) {
+	query := `
+		INSERT INTO "Notification Preferences" (user_id, event_type, email_enabled, digest)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id, event_type)
+		DO UPDATE SET email_enabled = EXCLUDED.email_enabled, digest = EXCLUDED.digest, updated_at = NOW();
+	`
+
+	_, err := pa.db.ExecContext(ctx, query, pref.UserId, notificationEventToDB(pref.Event), pref.EmailEnabled, pref.Digest)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+			return nil, status.Errorf(codes.NotFound, "user with id %d not found", pref.UserId)
+		}
+		log.Printf("Database error on UpdateNotificationPreference: %v", err)
+		return nil, status.Errorf(codes.Internal, "failed to update notification preference")
+	}
+
+	return &pb.NotificationPreference{
+		UserId:       pref.UserId,
+		Event:        pref.Event,
+		EmailEnabled: pref.EmailEnabled,
+		Digest:       pref.Digest,
+	}, nil
+}
+
+func (pa *PostgresAccess) getUserSettings(ctx context.Context, userID int64) (*pb.UserSettings, error) {
+	var (
+		shareEmail, discoverable bool
+		policy                   string
+	)
+	err := pa.db.QueryRowContext(ctx, `SELECT share_email_with_friends, friend_request_policy, discoverable FROM "User" WHERE id = $1`, userID).
+		Scan(&shareEmail, &policy, &discoverable)
+	if err == sql.ErrNoRows {
+		return nil, status.Errorf(codes.NotFound, "user with id %d not found", userID)
+	}
+	if err != nil {
+		log.Printf("Database error on GetUserSettings: %v", err)
+		return nil, status.Errorf(codes.Internal, "failed to retrieve user settings")
+	}
+	return &pb.UserSettings{
+		UserId:                userID,
+		ShareEmailWithFriends: &shareEmail,
+		FriendRequestPolicy:   friendRequestPolicyFromDB(policy).Enum(),
+		Discoverable:          &discoverable,
+	}, nil
+}
+
+// updateUserSettings writes the settings that are set and keeps the stored value of the others
+func (pa *PostgresAccess) updateUserSettings(ctx context.Context, settings *pb.UserSettings) (*pb.UserSettings, error) {
+	var policy *string
+	if settings.FriendRequestPolicy != nil {
+		p := friendRequestPolicyToDB(settings.GetFriendRequestPolicy())
+		policy = &p
+	}
+	query := `
+		UPDATE "User" SET
+			share_email_with_friends = COALESCE($2, share_email_with_friends),
+			friend_request_policy = COALESCE($3, friend_request_policy),
+			discoverable = COALESCE($4, discoverable)
+		WHERE id = $1
+		RETURNING share_email_with_friends, friend_request_policy, discoverable;
+	`
+	var (
+		shareEmail, discoverable bool
+		stored                   string
+	)
+	err := pa.db.QueryRowContext(ctx, query, settings.UserId, settings.ShareEmailWithFriends, policy, settings.Discoverable).
+		Scan(&shareEmail, &stored, &discoverable)
+	if err == sql.ErrNoRows {
+		return nil, status.Errorf(codes.NotFound, "user with id %d not found", settings.UserId)
+	}
+	if err != nil {
+		log.Printf("Database error on UpdateUserSettings: %v", err)
+		return nil, status.Errorf(codes.Internal, "failed to update user settings")
+	}
+	return &pb.UserSettings{
+		UserId:                settings.UserId,
+		ShareEmailWithFriends: &shareEmail,
+		FriendRequestPolicy:   friendRequestPolicyFromDB(stored).Enum(),
+		Discoverable:          &discoverable,
+	}, nil
+}
+
+func (pa *PostgresAccess) updateProfile(ctx context.Context, req *pb.UpdateProfileRequest) (*pb.User, error) {
+	query := `
+		UPDATE "User" SET bio = $2, status_text = $3, timezone = $4, locale = $5
+		WHERE id = $1
+		RETURNING id, first_name, last_name, user_name, email, created_at, is_bot, COALESCE(owner_id, 0), avatar_key,
+			bio, status_text, timezone, locale;
+	`
+
+	var user pb.User
+	var createdAt time.Time
+	var avatarKey sql.NullString
+	err := pa.db.QueryRowContext(ctx, query, req.UserId, req.Bio, req.StatusText, req.Timezone, req.Locale).Scan(
+		&user.Id, &user.FirstName, &user.LastName, &user.UserName, &user.Email, &createdAt, &user.IsBot, &user.OwnerId, &avatarKey,
+		&user.Bio, &user.StatusText, &user.Timezone, &user.Locale,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, status.Errorf(codes.NotFound, "user with id %d not found", req.UserId)
+	}
+	if err != nil {
+		log.Printf("Database error on UpdateProfile: %v", err)
+		return nil, status.Errorf(codes.Internal, "failed to update profile")
+	}
+	user.CreatedAt = timestamppb.New(createdAt)
+	user.AvatarUrl = pa.avatarURL.URL(avatarKey.String, time.Now())
+	return &user, nil
+}
+
+// setAvatar stores the avatar key of the user, NULL for "", and returns the key it replaced
+func (pa *PostgresAccess) setAvatar(ctx context.Context, userID int64, key string) (*pb.User, string, error) {
+	query := `
+		WITH old AS (SELECT avatar_key FROM "User" WHERE id = $1 FOR UPDATE)
+		UPDATE "User" u SET avatar_key = NULLIF($2, '')
+		FROM old
+		WHERE u.id = $1
+		RETURNING u.id, u.first_name, u.last_name, u.user_name, u.email, u.created_at, u.is_bot, COALESCE(u.owner_id, 0),
+			u.bio, u.status_text, u.timezone, u.locale, old.avatar_key;
+	`
+
+	var user pb.User
+	var createdAt time.Time
+	var previous sql.NullString
+	err := pa.db.QueryRowContext(ctx, query, userID, key).Scan(
+		&user.Id, &user.FirstName, &user.LastName, &user.UserName, &user.Email, &createdAt, &user.IsBot, &user.OwnerId,
+		&user.Bio, &user.StatusText, &user.Timezone, &user.Locale, &previous,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, "", status.Errorf(codes.NotFound, "user with id %d not found", userID)
+	}
+	if err != nil {
+		log.Printf("Database error on SetAvatar: %v", err)
+		return nil, "", status.Errorf(codes.Internal, "failed to set avatar")
+	}
+	user.CreatedAt = timestamppb.New(createdAt)
+	user.AvatarUrl = pa.avatarURL.URL(key, time.Now())
+	return &user, previous.String, nil
+}
+
+// maps NOTIFICATION_EVENT_FRIEND_REQUEST to "friend_request", the value stored in the db and sent with the email events
+func notificationEventToDB(event pb.NotificationEvent) string {
+	return strings.ToLower(strings.TrimPrefix(event.String(), "NOTIFICATION_EVENT_"))
+}
+
+// maps FRIEND_REQUEST_POLICY_FRIENDS_OF_FRIENDS to "friends_of_friends", the value stored in the db
+func friendRequestPolicyToDB(policy pb.FriendRequestPolicy) string {
+	return strings.ToLower(strings.TrimPrefix(policy.String(), "FRIEND_REQUEST_POLICY_"))
+}
+
+func friendRequestPolicyFromDB(policy string) pb.FriendRequestPolicy {
+	return pb.FriendRequestPolicy(pb.FriendRequestPolicy_value["FRIEND_REQUEST_POLICY_"+strings.ToUpper(policy)])
+}
+
+func notificationEventFromDB(eventType string) pb.NotificationEvent {
+	enumKey := "NOTIFICATION_EVENT_" + strings.ToUpper(eventType)
+	if val, ok := pb.NotificationEvent_value[enumKey]; ok {
+		return pb.NotificationEvent(val)
+	}
+	return pb.NotificationEvent_NOTIFICATION_EVENT_UNKNOWN
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	pb "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/user-base/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (svc *UserService) ProvisionUser(ctx context.Context, req *pb.ProvisionUserRequest) (*pb.ProvisionUserResponse, error) {
+	if strings.TrimSpace(req.FirstName) == "" ||
+		strings.TrimSpace(req.UserName) == "" ||
+		strings.TrimSpace(req.Email) == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "first_name, user_name and email are required")
+	}
+
+	userName, err := normalizeUserName(req.UserName)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := svc.storageAccess.createPasswordlessUser(ctx, &pb.User{
+		FirstName: strings.TrimSpace(req.FirstName),
+		LastName:  strings.TrimSpace(req.LastName),
+		UserName:  userName,
+		Email:     strings.TrimSpace(req.Email),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if svc.emailPub != nil {
+		_ = svc.emailPub.Publish(ctx, EmailMessage{
+			To:       user.Email,
+			Subject:  "Welcome to GoChat",
+			Body:     fmt.Sprintf("Hi %s, \n\nYour account was created successfully. Enjoy the experience!\n\n- GoChat Team", user.FirstName),
+			UserID:   user.Id,
+			Template: templateWelcome,
+		})
+	}
+
+	return &pb.ProvisionUserResponse{User: user}, nil
+}
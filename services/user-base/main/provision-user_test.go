@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	errchecks "github.com/Costin2000/GoChat---Schwarz-Internship---2025/pkg"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/testing/protocmp"
+
+	pb "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/user-base/proto"
+)
+
+func Test_ProvisionUser(t *testing.T) {
+	tests := []struct {
+		name         string
+		req          *pb.ProvisionUserRequest
+		storage      StorageAccess
+		expectedErr  errchecks.Check
+		expectedResp *pb.ProvisionUserResponse
+	}{
+		{
+			name:        "missing email",
+			req:         &pb.ProvisionUserRequest{FirstName: "Ana", UserName: "ana"},
+			expectedErr: errchecks.HasStatusCode(codes.InvalidArgument),
+		},
+		{
+			name: "username taken",
+			req:  &pb.ProvisionUserRequest{FirstName: "Ana", UserName: "ana", Email: "ana@example.com"},
+			storage: newMockStorageAccess(StorageMockOptions{
+				createPasswordlessUserFunc: func(ctx context.Context, user *pb.User) (*pb.User, error) {
+					return nil, status.Errorf(codes.AlreadyExists, "user with this email or username already exists")
+				},
+			}),
+			expectedErr: errchecks.HasStatusCode(codes.AlreadyExists),
+		},
+		{
+			name: "creates the user without a password",
+			req:  &pb.ProvisionUserRequest{FirstName: " Ana ", UserName: "ana", Email: "ana@example.com"},
+			storage: newMockStorageAccess(StorageMockOptions{
+				createPasswordlessUserFunc: func(ctx context.Context, user *pb.User) (*pb.User, error) {
+					if user.Password != "" {
+						t.Errorf("password %q passed to storage", user.Password)
+					}
+					user.Id = 9
+					return user, nil
+				},
+			}),
+			expectedResp: &pb.ProvisionUserResponse{User: &pb.User{
+				Id:        9,
+				FirstName: "Ana",
+				UserName:  "ana",
+				Email:     "ana@example.com",
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := NewMockService(ServiceMockOptions{storageAccess: tt.storage})
+
+			resp, err := svc.ProvisionUser(context.Background(), tt.req)
+
+			errchecks.Assert(t, err, tt.expectedErr)
+			if diff := cmp.Diff(tt.expectedResp, resp, protocmp.Transform()); diff != "" {
+				t.Errorf("mismatch (-expected +got):\n%s", diff)
+			}
+		})
+	}
+}
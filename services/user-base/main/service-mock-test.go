@@ -3,24 +3,70 @@ package main
 import (
 	"context"
 
+	"github.com/Costin2000/GoChat---Schwarz-Internship---2025/pkg/password"
 	pbauth "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/auth/proto"
 	pb "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/user-base/proto"
 	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
 )
 
 type mockStorage struct {
-	createUserFunc     func(ctx context.Context, user *pb.User) (*pb.User, error)
-	getUserByEmailFunc func(ctx context.Context, email string) (*pb.User, error)
-	listUsersFunc      func(ctx context.Context, req *pb.ListUsersRequest) (*pb.ListUsersResponse, error)
+	createUserFunc                   func(ctx context.Context, user *pb.User) (*pb.User, error)
+	createBotFunc                    func(ctx context.Context, bot *pb.User) (*pb.User, error)
+	createPasswordlessUserFunc       func(ctx context.Context, user *pb.User) (*pb.User, error)
+	getPasswordHashFunc              func(ctx context.Context, userID int64) (string, error)
+	updatePasswordHashFunc           func(ctx context.Context, userID int64, oldHash, newHash string) (bool, error)
+	getUserByEmailFunc               func(ctx context.Context, email string) (*pb.UserCredentials, error)
+	getUserByUserNameFunc            func(ctx context.Context, userName string) (*pb.UserCredentials, error)
+	listUsersFunc                    func(ctx context.Context, req *pb.ListUsersRequest) (*pb.ListUsersResponse, error)
+	getNotificationPreferencesFunc   func(ctx context.Context, userID int64) ([]*pb.NotificationPreference, error)
+	upsertNotificationPreferenceFunc func(ctx context.Context, pref *pb.NotificationPreference) (*pb.NotificationPreference, error)
+	getUserSettingsFunc              func(ctx context.Context, userID int64) (*pb.UserSettings, error)
+	updateUserSettingsFunc           func(ctx context.Context, settings *pb.UserSettings) (*pb.UserSettings, error)
+	setAvatarFunc                    func(ctx context.Context, userID int64, key string) (*pb.User, string, error)
+	updateProfileFunc                func(ctx context.Context, req *pb.UpdateProfileRequest) (*pb.User, error)
 }
 
 func (m *mockStorage) createUser(ctx context.Context, user *pb.User) (*pb.User, error) {
 	return m.createUserFunc(ctx, user)
 }
 
-func (m *mockStorage) getUserByEmail(ctx context.Context, email string) (*pb.User, error) {
+func (m *mockStorage) createBot(ctx context.Context, bot *pb.User) (*pb.User, error) {
+	if m.createBotFunc != nil {
+		return m.createBotFunc(ctx, bot)
+	}
+	return bot, nil
+}
+
+func (m *mockStorage) createPasswordlessUser(ctx context.Context, user *pb.User) (*pb.User, error) {
+	if m.createPasswordlessUserFunc != nil {
+		return m.createPasswordlessUserFunc(ctx, user)
+	}
+	return user, nil
+}
+
+func (m *mockStorage) getPasswordHash(ctx context.Context, userID int64) (string, error) {
+	if m.getPasswordHashFunc != nil {
+		return m.getPasswordHashFunc(ctx, userID)
+	}
+	return "", nil
+}
+
+func (m *mockStorage) updatePasswordHash(ctx context.Context, userID int64, oldHash, newHash string) (bool, error) {
+	if m.updatePasswordHashFunc != nil {
+		return m.updatePasswordHashFunc(ctx, userID, oldHash, newHash)
+	}
+	return true, nil
+}
+
+func (m *mockStorage) getUserByEmail(ctx context.Context, email string) (*pb.UserCredentials, error) {
 	return m.getUserByEmailFunc(ctx, email)
 }
+
+func (m *mockStorage) getUserByUserName(ctx context.Context, userName string) (*pb.UserCredentials, error) {
+	return m.getUserByUserNameFunc(ctx, userName)
+}
+
 func (m *mockStorage) listUsers(ctx context.Context, req *pb.ListUsersRequest) (*pb.ListUsersResponse, error) {
 	if m.listUsersFunc != nil {
 		return m.listUsersFunc(ctx, req)
@@ -28,6 +74,50 @@ func (m *mockStorage) listUsers(ctx context.Context, req *pb.ListUsersRequest) (
 	return nil, nil
 }
 
+func (m *mockStorage) getNotificationPreferences(ctx context.Context, userID int64) ([]*pb.NotificationPreference, error) {
+	if m.getNotificationPreferencesFunc != nil {
+		return m.getNotificationPreferencesFunc(ctx, userID)
+	}
+	return nil, nil
+}
+
+func (m *mockStorage) upsertNotificationPreference(ctx context.Context, pref *pb.NotificationPreference) (*pb.NotificationPreference, error) {
+	if m.upsertNotificationPreferenceFunc != nil {
+		return m.upsertNotificationPreferenceFunc(ctx, pref)
+	}
+	return pref, nil
+}
+
+func (m *mockStorage) getUserSettings(ctx context.Context, userID int64) (*pb.UserSettings, error) {
+	if m.getUserSettingsFunc != nil {
+		return m.getUserSettingsFunc(ctx, userID)
+	}
+	return &pb.UserSettings{UserId: userID, FriendRequestPolicy: pb.FriendRequestPolicy_FRIEND_REQUEST_POLICY_EVERYONE.Enum(), Discoverable: proto.Bool(true)}, nil
+}
+
+func (m *mockStorage) updateUserSettings(ctx context.Context, settings *pb.UserSettings) (*pb.UserSettings, error) {
+	if m.updateUserSettingsFunc != nil {
+		return m.updateUserSettingsFunc(ctx, settings)
+	}
+	return settings, nil
+}
+
+func (m *mockStorage) setAvatar(ctx context.Context, userID int64, key string) (*pb.User, string, error) {
+	if m.setAvatarFunc != nil {
+		return m.setAvatarFunc(ctx, userID, key)
+	}
+	return fixtureUser(), "", nil
+}
+
+func (m *mockStorage) updateProfile(ctx context.Context, req *pb.UpdateProfileRequest) (*pb.User, error) {
+	if m.updateProfileFunc != nil {
+		return m.updateProfileFunc(ctx, req)
+	}
+	return fixtureUser(func(user *pb.User) {
+		user.Id, user.Bio, user.StatusText, user.Timezone, user.Locale = req.UserId, req.Bio, req.StatusText, req.Timezone, req.Locale
+	}), nil
+}
+
 type authMock struct {
 	loginFunc func(ctx context.Context, req *pbauth.LoginRequest, opts ...grpc.CallOption) (*pbauth.LoginResponse, error)
 }
@@ -37,9 +127,20 @@ func (m *authMock) Login(ctx context.Context, req *pbauth.LoginRequest, opts ...
 }
 
 type StorageMockOptions struct {
-	createUserFunc     func(ctx context.Context, user *pb.User) (*pb.User, error)
-	getUserByEmailFunc func(ctx context.Context, email string) (*pb.User, error)
-	listUsersFunc      func(ctx context.Context, req *pb.ListUsersRequest) (*pb.ListUsersResponse, error)
+	createUserFunc                   func(ctx context.Context, user *pb.User) (*pb.User, error)
+	createBotFunc                    func(ctx context.Context, bot *pb.User) (*pb.User, error)
+	createPasswordlessUserFunc       func(ctx context.Context, user *pb.User) (*pb.User, error)
+	getPasswordHashFunc              func(ctx context.Context, userID int64) (string, error)
+	updatePasswordHashFunc           func(ctx context.Context, userID int64, oldHash, newHash string) (bool, error)
+	getUserByEmailFunc               func(ctx context.Context, email string) (*pb.UserCredentials, error)
+	getUserByUserNameFunc            func(ctx context.Context, userName string) (*pb.UserCredentials, error)
+	listUsersFunc                    func(ctx context.Context, req *pb.ListUsersRequest) (*pb.ListUsersResponse, error)
+	getNotificationPreferencesFunc   func(ctx context.Context, userID int64) ([]*pb.NotificationPreference, error)
+	upsertNotificationPreferenceFunc func(ctx context.Context, pref *pb.NotificationPreference) (*pb.NotificationPreference, error)
+	getUserSettingsFunc              func(ctx context.Context, userID int64) (*pb.UserSettings, error)
+	updateUserSettingsFunc           func(ctx context.Context, settings *pb.UserSettings) (*pb.UserSettings, error)
+	setAvatarFunc                    func(ctx context.Context, userID int64, key string) (*pb.User, string, error)
+	updateProfileFunc                func(ctx context.Context, req *pb.UpdateProfileRequest) (*pb.User, error)
 }
 
 func newMockStorageAccess(
@@ -52,16 +153,34 @@ func newMockStorageAccess(
 		createUserFunc = opts.createUserFunc
 	}
 
-	getUserByEmailFunc := func(ctx context.Context, email string) (*pb.User, error) {
-		return fixtureUser(), nil
+	getUserByEmailFunc := func(ctx context.Context, email string) (*pb.UserCredentials, error) {
+		return fixtureCredentials(), nil
 	}
 	if opts.getUserByEmailFunc != nil {
 		getUserByEmailFunc = opts.getUserByEmailFunc
 	}
 
+	getUserByUserNameFunc := func(ctx context.Context, userName string) (*pb.UserCredentials, error) {
+		return fixtureCredentials(), nil
+	}
+	if opts.getUserByUserNameFunc != nil {
+		getUserByUserNameFunc = opts.getUserByUserNameFunc
+	}
+
 	return &mockStorage{
-		createUserFunc:     createUserFunc,
-		getUserByEmailFunc: getUserByEmailFunc,
+		createUserFunc:                   createUserFunc,
+		createBotFunc:                    opts.createBotFunc,
+		createPasswordlessUserFunc:       opts.createPasswordlessUserFunc,
+		getPasswordHashFunc:              opts.getPasswordHashFunc,
+		updatePasswordHashFunc:           opts.updatePasswordHashFunc,
+		getUserByEmailFunc:               getUserByEmailFunc,
+		getUserByUserNameFunc:            getUserByUserNameFunc,
+		getNotificationPreferencesFunc:   opts.getNotificationPreferencesFunc,
+		upsertNotificationPreferenceFunc: opts.upsertNotificationPreferenceFunc,
+		getUserSettingsFunc:              opts.getUserSettingsFunc,
+		updateUserSettingsFunc:           opts.updateUserSettingsFunc,
+		setAvatarFunc:                    opts.setAvatarFunc,
+		updateProfileFunc:                opts.updateProfileFunc,
 	}
 }
 
@@ -88,21 +207,31 @@ func NewMockService(opts ServiceMockOptions) *UserService {
 	}
 
 	return &UserService{
-		storageAccess: storage,
-		authClient:    authCl,
+		storageAccess:  storage,
+		authClient:     authCl,
+		passwordParams: testPasswordParams,
+		passwordPolicy: testPasswordPolicy,
 	}
 }
 
+// cheap argon2id parameters, the tests do not need the memory of production hashes
+var testPasswordParams = password.Params{MemoryKiB: 64, Iterations: 1, Parallelism: 1}
+
+var testPasswordPolicy = password.Policy{MinLength: 10, MaxLength: 128, RejectBreached: true}
+
 func fixtureUser(mods ...func(user *pb.User)) *pb.User {
 	user := &pb.User{
 		Id:        1,
 		FirstName: "John",
 		LastName:  "Walter",
 		Email:     "johnwalter@yahoo.com",
-		Password:  "secretpassword",
 	}
 	for _, mod := range mods {
 		mod(user)
 	}
 	return user
 }
+
+func fixtureCredentials() *pb.UserCredentials {
+	return &pb.UserCredentials{User: fixtureUser(), PasswordHash: "$argon2id$v=19$m=64,t=1,p=1$c2FsdA$a2V5"}
+}
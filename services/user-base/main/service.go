@@ -1,29 +1,18 @@
 package main
 
 import (
-	"bufio"
 	"context"
-	"database/sql"
-	"fmt"
 	"log"
-	"net"
-	"os"
-	"path/filepath"
-	"strings"
-	"time"
+	_ "time/tzdata" // UpdateProfile accepts the same timezones whatever zoneinfo the host has
 
+	"github.com/Costin2000/GoChat---Schwarz-Internship---2025/pkg/avatar"
+	"github.com/Costin2000/GoChat---Schwarz-Internship---2025/pkg/bootstrap"
+	"github.com/Costin2000/GoChat---Schwarz-Internship---2025/pkg/password"
 	pbauth "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/auth/proto"
 	pb "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/user-base/proto"
-	_ "github.com/jackc/pgx/v5/stdlib"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
-	"google.golang.org/grpc/reflection"
-
-	amqp "github.com/rabbitmq/amqp091-go"
 )
 
-const port = ":50051"
-
 type authClient interface {
 	Login(ctx context.Context, req *pbauth.LoginRequest, opts ...grpc.CallOption) (*pbauth.LoginResponse, error)
 }
@@ -31,160 +20,97 @@ type authClient interface {
 type UserService struct {
 	storageAccess StorageAccess
 	pb.UnimplementedUserServiceServer
-	emailPub   EmailPublisher
-	authClient authClient
+	emailPub       EmailPublisher
+	authClient     authClient
+	passwordParams password.Params
+	passwordPolicy password.Policy
 }
 
-// retrieve db setup from the .env file
-func loadEnv(filename string) error {
-
-	file, err := os.Open(filename)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		line := scanner.Text()
-
-		// ignore empty lines and comments
-		if len(line) == 0 || strings.HasPrefix(line, "#") {
-			continue
-		}
-
-		envs := strings.SplitN(line, "=", 2)
-		if len(envs) != 2 {
-			continue // skip bad lines
-		}
-
-		key := strings.TrimSpace(envs[0])
-		value := strings.TrimSpace(envs[1])
-
-		// Set the environment variable
-		if err := os.Setenv(key, value); err != nil {
-			log.Printf("Warning: could not set env var %s: %v", key, err)
-		}
-	}
-
-	return scanner.Err()
+type config struct {
+	Addr           string `env:"USER_BASE_LISTEN_ADDR" default:":50051"`
+	MetricsAddr    string `env:"USER_BASE_METRICS_ADDR" default:":9101"`
+	AuthAddr       string `env:"AUTH_ADDR" default:"auth:50053"`
+	RabbitMQAddr   string `env:"RABBITMQ_ADDR"`
+	SpoolPath      string `env:"EMAIL_SPOOL_PATH" default:"./spool/emails.spool"`
+	Password       password.Params
+	PasswordPolicy password.Policy
+	AvatarURL      avatar.URLConfig
+	DB             bootstrap.DBConfig
+	Shutdown       bootstrap.ShutdownConfig
+	Tracing        bootstrap.TracingConfig
 }
 
 func main() {
+	bootstrap.Init("user-base")
 
-	wd, err := os.Getwd()
-	if err != nil {
-		log.Fatalf("Could not get current working directory: %v", err)
+	var cfg config
+	if err := bootstrap.LoadConfig(&cfg); err != nil {
+		bootstrap.Fail("load config", err)
 	}
 
-	envPath := "./db/.env" // db env path from root
-	if filepath.Base(wd) == "user-base" {
-		envPath = "./../../db/.env" // db env path from user-base service directory
-	}
-
-	// db connection
-	if err := loadEnv(envPath); err != nil {
-		log.Fatalf("Error loading .env file: %v", err)
-	}
-
-	dbUser := os.Getenv("POSTGRES_USER")
-	dbPassword := os.Getenv("POSTGRES_PASSWORD")
-	dbName := os.Getenv("POSTGRES_DB")
-	dbPort := os.Getenv("DB_PORT")
+	ctx, stop := bootstrap.SignalContext()
+	defer stop()
 
-	var dbHost string
-	if os.Getenv("ENV") == "docker" {
-		dbHost = "postgres-db"
-	} else {
-		dbHost = os.Getenv("DB_HOST")
+	shutdownTracing, err := bootstrap.InitTracing(ctx, cfg.Tracing)
+	if err != nil {
+		bootstrap.Fail("init tracing", err)
 	}
+	defer shutdownTracing(context.Background())
 
-	connStr := fmt.Sprintf("user=%s password=%s host=%s port=%s dbname=%s sslmode=disable", dbUser, dbPassword, dbHost, dbPort, dbName)
-	db, err := sql.Open("pgx", connStr)
+	db, err := bootstrap.OpenDB(ctx, cfg.DB)
 	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
+		bootstrap.Fail("open database", err)
 	}
 	defer db.Close()
+	checks := []bootstrap.Check{db.Check()}
 
-	if err := db.Ping(); err != nil {
-		log.Fatalf("Failed to ping database: %v", err)
-	}
-	log.Println("Successfully connected to PostgreSQL database.")
-
-	// Initialze RabbitMQ publisher
-	rmqAddr := os.Getenv("RABBITMQ_ADDR")
+	// Initialze RabbitMQ publisher, it keeps reconnecting and spools emails to disk while RabbitMQ is down
 	var emailPub EmailPublisher
-	if rmqAddr != "" {
-		conn, err := connectToRabbitMQWithRetries(rmqAddr)
+	if cfg.RabbitMQAddr != "" {
+		pub, err := newAmqpEmailPublisher(cfg.RabbitMQAddr, cfg.SpoolPath)
 		if err != nil {
-			log.Printf("WARN: cannot connect to RabbitMQ (%s): %v (CreatUser will work, but no emails published)", rmqAddr, err)
-		} else {
-			defer conn.Close()
-			pub, err := newAmqpEmailPublisher(conn)
-			if err != nil {
-				log.Printf("WARN: cannot create email publisher: %v", err)
-			} else {
-				defer pub.Close()
-				emailPub = pub
-				log.Println("Connected to RabbitMQ and email publisher ready!")
-			}
+			bootstrap.Fail("create email publisher", err)
 		}
+		defer pub.Close()
+		emailPub = pub
+		// emails are spooled while RabbitMQ is down, so it does not make the service unready
+		checks = append(checks, bootstrap.Check{Name: "rabbitmq", Probe: pub.Ping, Optional: true})
+		log.Printf("Email publisher ready, spooling to %s while RabbitMQ is unavailable", cfg.SpoolPath)
 	} else {
 		log.Println("WARN: RABBITMQ_ADDR not set; emails will not be published")
 	}
 
-	// network connection
-	lis, err := net.Listen("tcp", port)
-	if err != nil {
-		log.Fatalf("Error polling port %s %v", port, err)
-	}
-
-	authAddr := os.Getenv("AUTH_ADDR")
-	if authAddr == "" {
-		authAddr = "auth:50053"
+	if !cfg.AvatarURL.Enabled() {
+		log.Println("WARN: AVATAR_URL_SECRET not set; users will have no avatar_url")
 	}
+	storage := newPostgresAccess(db.DB)
+	storage.avatarURL = cfg.AvatarURL
 
-	conn, err := grpc.Dial(authAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	conn, err := bootstrap.Dial(cfg.AuthAddr)
 	if err != nil {
-		panic("failed to connect to auth service")
+		bootstrap.Fail("dial auth", err)
 	}
 	defer conn.Close()
 
-	authClient := pbauth.NewAuthServiceClient(conn)
-
 	// server connections
-	storage := newPostgresAccess(db)
 	UserBaseServer := &UserService{
-		storageAccess: storage,
-		emailPub:      emailPub,
-		authClient:    authClient,
+		storageAccess:  storage,
+		emailPub:       emailPub,
+		authClient:     pbauth.NewAuthServiceClient(conn),
+		passwordParams: cfg.Password,
+		passwordPolicy: cfg.PasswordPolicy,
 	}
 
-	grpcServer := grpc.NewServer()
+	grpcServer := bootstrap.NewGRPCServer()
 	pb.RegisterUserServiceServer(grpcServer, UserBaseServer)
-	reflection.Register(grpcServer)
+	health := bootstrap.RegisterHealth(grpcServer, checks...)
+	go health.Run(ctx)
 
-	log.Printf("gRPC polling on port %s...", port)
-
-	if err := grpcServer.Serve(lis); err != nil {
-		log.Fatalf("Error starting server: %v", err)
+	if err := bootstrap.ServeMetrics(ctx, cfg.MetricsAddr); err != nil {
+		bootstrap.Fail("serve metrics", err)
 	}
-}
 
-func connectToRabbitMQWithRetries(addr string) (*amqp.Connection, error) {
-	var conn *amqp.Connection
-	var err error
-	maxRetries := 10
-	backoff := 3 * time.Second
-
-	for i := 1; i <= maxRetries; i++ {
-		conn, err = amqp.Dial(addr)
-		if err == nil {
-			log.Println("user-base: connected to RabbitMQ")
-			return conn, nil
-		}
-		log.Printf("user-base: cannot connect to RabbitMQ, retrying in %v... (%d/%d)", backoff, i, maxRetries)
-		time.Sleep(backoff)
+	if err := bootstrap.ServeGRPC(ctx, grpcServer, cfg.Addr, health, cfg.Shutdown); err != nil {
+		bootstrap.Fail("serve", err)
 	}
-	return nil, err
 }
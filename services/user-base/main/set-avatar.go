@@ -0,0 +1,29 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Costin2000/GoChat---Schwarz-Internship---2025/pkg/avatar"
+	pb "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/user-base/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (svc *UserService) SetAvatar(ctx context.Context, req *pb.SetAvatarRequest) (*pb.SetAvatarResponse, error) {
+	if req.GetUserId() <= 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "user_id must be positive")
+	}
+	// keys start with the id of their user, so one user can never point at the avatar of another
+	key := req.GetAvatarKey()
+	if key != "" && (!avatar.ValidKey(key) || !strings.HasPrefix(key, fmt.Sprintf("%d-", req.UserId))) {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid avatar_key %q", key)
+	}
+
+	user, previous, err := svc.storageAccess.setAvatar(ctx, req.UserId, key)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.SetAvatarResponse{User: user, PreviousAvatarKey: previous}, nil
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	errchecks "github.com/Costin2000/GoChat---Schwarz-Internship---2025/pkg"
+	pb "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/user-base/proto"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/testing/protocmp"
+)
+
+func Test_SetAvatar(t *testing.T) {
+	withAvatar := func(user *pb.User) { user.AvatarUrl = "http://localhost:8080/v1/avatars/1-ab12.png?exp=1&sig=x" }
+
+	tests := []struct {
+		name         string
+		req          *pb.SetAvatarRequest
+		storage      StorageAccess
+		expectedErr  errchecks.Check
+		expectedResp *pb.SetAvatarResponse
+	}{
+		{
+			name:        "invalid user id",
+			req:         &pb.SetAvatarRequest{AvatarKey: "1-ab12.png"},
+			expectedErr: errchecks.HasStatusCode(codes.InvalidArgument),
+		},
+		{
+			name: "key of another user",
+			req:  &pb.SetAvatarRequest{UserId: 1, AvatarKey: "12-ab12.png"},
+			expectedErr: errchecks.All(
+				errchecks.HasStatusCode(codes.InvalidArgument),
+				errchecks.MsgContains("invalid avatar_key"),
+			),
+		},
+		{
+			name:        "key that is a path",
+			req:         &pb.SetAvatarRequest{UserId: 1, AvatarKey: "1-../../etc.png"},
+			expectedErr: errchecks.HasStatusCode(codes.InvalidArgument),
+		},
+		{
+			name: "unknown user",
+			req:  &pb.SetAvatarRequest{UserId: 9, AvatarKey: "9-ab12.png"},
+			storage: newMockStorageAccess(StorageMockOptions{
+				setAvatarFunc: func(ctx context.Context, userID int64, key string) (*pb.User, string, error) {
+					return nil, "", status.Errorf(codes.NotFound, "user with id %d not found", userID)
+				},
+			}),
+			expectedErr: errchecks.HasStatusCode(codes.NotFound),
+		},
+		{
+			name: "replaces the avatar",
+			req:  &pb.SetAvatarRequest{UserId: 1, AvatarKey: "1-ab12.png"},
+			storage: newMockStorageAccess(StorageMockOptions{
+				setAvatarFunc: func(ctx context.Context, userID int64, key string) (*pb.User, string, error) {
+					if userID != 1 || key != "1-ab12.png" {
+						t.Errorf("unexpected setAvatar(%d, %q)", userID, key)
+					}
+					return fixtureUser(withAvatar), "1-0000.jpg", nil
+				},
+			}),
+			expectedResp: &pb.SetAvatarResponse{User: fixtureUser(withAvatar), PreviousAvatarKey: "1-0000.jpg"},
+		},
+		{
+			name: "removes the avatar",
+			req:  &pb.SetAvatarRequest{UserId: 1},
+			storage: newMockStorageAccess(StorageMockOptions{
+				setAvatarFunc: func(ctx context.Context, userID int64, key string) (*pb.User, string, error) {
+					return fixtureUser(), "1-ab12.png", nil
+				},
+			}),
+			expectedResp: &pb.SetAvatarResponse{User: fixtureUser(), PreviousAvatarKey: "1-ab12.png"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := NewMockService(ServiceMockOptions{storageAccess: tt.storage})
+
+			resp, err := svc.SetAvatar(context.Background(), tt.req)
+
+			errchecks.Assert(t, err, tt.expectedErr)
+			if diff := cmp.Diff(tt.expectedResp, resp, protocmp.Transform()); diff != "" {
+				t.Errorf("mismatch (-expected +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"context"
+
+	pb "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/user-base/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (svc *UserService) UpdatePasswordHash(ctx context.Context, req *pb.UpdatePasswordHashRequest) (*pb.UpdatePasswordHashResponse, error) {
+	if req.UserId <= 0 || req.OldHash == "" || req.NewHash == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "user_id, old_hash and new_hash are required")
+	}
+	// accounts without a password must not get one this way
+	if req.OldHash == noPassword {
+		return nil, status.Errorf(codes.FailedPrecondition, "user has no password")
+	}
+
+	updated, err := svc.storageAccess.updatePasswordHash(ctx, req.UserId, req.OldHash, req.NewHash)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.UpdatePasswordHashResponse{Updated: updated}, nil
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	errchecks "github.com/Costin2000/GoChat---Schwarz-Internship---2025/pkg"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/testing/protocmp"
+
+	pb "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/user-base/proto"
+)
+
+func Test_UpdatePasswordHash(t *testing.T) {
+	tests := []struct {
+		name         string
+		req          *pb.UpdatePasswordHashRequest
+		updated      bool
+		expectedErr  errchecks.Check
+		expectedResp *pb.UpdatePasswordHashResponse
+	}{
+		{
+			name:         "replaces the hash",
+			req:          &pb.UpdatePasswordHashRequest{UserId: 1, OldHash: "$2a$10$old", NewHash: "$argon2id$new"},
+			updated:      true,
+			expectedResp: &pb.UpdatePasswordHashResponse{Updated: true},
+		},
+		{
+			name:         "password changed meanwhile",
+			req:          &pb.UpdatePasswordHashRequest{UserId: 1, OldHash: "$2a$10$old", NewHash: "$argon2id$new"},
+			expectedResp: &pb.UpdatePasswordHashResponse{},
+		},
+		{
+			name:        "account without a password",
+			req:         &pb.UpdatePasswordHashRequest{UserId: 1, OldHash: noPassword, NewHash: "$argon2id$new"},
+			expectedErr: errchecks.HasStatusCode(codes.FailedPrecondition),
+		},
+		{
+			name:        "missing new hash",
+			req:         &pb.UpdatePasswordHashRequest{UserId: 1, OldHash: "$2a$10$old"},
+			expectedErr: errchecks.HasStatusCode(codes.InvalidArgument),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := NewMockService(ServiceMockOptions{storageAccess: newMockStorageAccess(StorageMockOptions{
+				updatePasswordHashFunc: func(ctx context.Context, userID int64, oldHash, newHash string) (bool, error) {
+					return tt.updated, nil
+				},
+			})})
+
+			resp, err := svc.UpdatePasswordHash(context.Background(), tt.req)
+
+			errchecks.Assert(t, err, tt.expectedErr)
+			if diff := cmp.Diff(tt.expectedResp, resp, protocmp.Transform()); diff != "" {
+				t.Errorf("mismatch (-expected +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	pb "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/user-base/proto"
+	"golang.org/x/text/language"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	maxBioLength        = 500
+	maxStatusTextLength = 100
+)
+
+func (svc *UserService) UpdateProfile(ctx context.Context, req *pb.UpdateProfileRequest) (*pb.User, error) {
+	if req.GetUserId() <= 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "user_id must be positive")
+	}
+
+	profile := &pb.UpdateProfileRequest{
+		UserId:     req.UserId,
+		Bio:        strings.TrimSpace(req.Bio),
+		StatusText: strings.TrimSpace(req.StatusText),
+		Timezone:   strings.TrimSpace(req.Timezone),
+	}
+	if utf8.RuneCountInString(profile.Bio) > maxBioLength {
+		return nil, status.Errorf(codes.InvalidArgument, "bio must be at most %d characters", maxBioLength)
+	}
+	if utf8.RuneCountInString(profile.StatusText) > maxStatusTextLength || strings.ContainsAny(profile.StatusText, "\r\n") {
+		return nil, status.Errorf(codes.InvalidArgument, "status_text must be a single line of at most %d characters", maxStatusTextLength)
+	}
+	// "Local" is the zone of the server, not one a user can live in
+	if profile.Timezone != "" {
+		if _, err := time.LoadLocation(profile.Timezone); err != nil || profile.Timezone == "Local" {
+			return nil, status.Errorf(codes.InvalidArgument, "unknown timezone %q", profile.Timezone)
+		}
+	}
+	if locale := strings.TrimSpace(req.Locale); locale != "" {
+		tag, err := language.Parse(locale)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid locale %q", locale)
+		}
+		profile.Locale = tag.String()
+	}
+
+	return svc.storageAccess.updateProfile(ctx, profile)
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	errchecks "github.com/Costin2000/GoChat---Schwarz-Internship---2025/pkg"
+	pb "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/user-base/proto"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/testing/protocmp"
+)
+
+func Test_UpdateProfile(t *testing.T) {
+	tests := []struct {
+		name         string
+		req          *pb.UpdateProfileRequest
+		storage      StorageAccess
+		expectedErr  errchecks.Check
+		expectedResp *pb.User
+	}{
+		{
+			name:        "invalid user id",
+			req:         &pb.UpdateProfileRequest{Bio: "hi"},
+			expectedErr: errchecks.HasStatusCode(codes.InvalidArgument),
+		},
+		{
+			name:        "bio too long",
+			req:         &pb.UpdateProfileRequest{UserId: 1, Bio: strings.Repeat("ă", 501)},
+			expectedErr: errchecks.All(errchecks.HasStatusCode(codes.InvalidArgument), errchecks.MsgContains("bio")),
+		},
+		{
+			name:        "status text on two lines",
+			req:         &pb.UpdateProfileRequest{UserId: 1, StatusText: "busy\nreally"},
+			expectedErr: errchecks.All(errchecks.HasStatusCode(codes.InvalidArgument), errchecks.MsgContains("status_text")),
+		},
+		{
+			name:        "unknown timezone",
+			req:         &pb.UpdateProfileRequest{UserId: 1, Timezone: "Europe/Atlantis"},
+			expectedErr: errchecks.All(errchecks.HasStatusCode(codes.InvalidArgument), errchecks.MsgContains("timezone")),
+		},
+		{
+			name:        "server timezone",
+			req:         &pb.UpdateProfileRequest{UserId: 1, Timezone: "Local"},
+			expectedErr: errchecks.HasStatusCode(codes.InvalidArgument),
+		},
+		{
+			name:        "invalid locale",
+			req:         &pb.UpdateProfileRequest{UserId: 1, Locale: "not a locale"},
+			expectedErr: errchecks.All(errchecks.HasStatusCode(codes.InvalidArgument), errchecks.MsgContains("locale")),
+		},
+		{
+			name: "unknown user",
+			req:  &pb.UpdateProfileRequest{UserId: 9},
+			storage: newMockStorageAccess(StorageMockOptions{
+				updateProfileFunc: func(ctx context.Context, req *pb.UpdateProfileRequest) (*pb.User, error) {
+					return nil, status.Errorf(codes.NotFound, "user with id %d not found", req.UserId)
+				},
+			}),
+			expectedErr: errchecks.HasStatusCode(codes.NotFound),
+		},
+		{
+			name: "stores the trimmed profile with a canonical locale",
+			req: &pb.UpdateProfileRequest{
+				UserId: 1, Bio: " Gopher. ", StatusText: "on holiday ", Timezone: "Europe/Bucharest", Locale: "ro_ro",
+			},
+			expectedResp: fixtureUser(func(user *pb.User) {
+				user.Bio, user.StatusText, user.Timezone, user.Locale = "Gopher.", "on holiday", "Europe/Bucharest", "ro-RO"
+			}),
+		},
+		{
+			name:         "clears the profile",
+			req:          &pb.UpdateProfileRequest{UserId: 1},
+			expectedResp: fixtureUser(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := NewMockService(ServiceMockOptions{storageAccess: tt.storage})
+
+			resp, err := svc.UpdateProfile(context.Background(), tt.req)
+
+			errchecks.Assert(t, err, tt.expectedErr)
+			if diff := cmp.Diff(tt.expectedResp, resp, protocmp.Transform()); diff != "" {
+				t.Errorf("mismatch (-expected +got):\n%s", diff)
+			}
+		})
+	}
+}
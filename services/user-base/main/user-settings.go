@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+
+	pb "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/user-base/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (svc *UserService) GetUserSettings(ctx context.Context, req *pb.GetUserSettingsRequest) (*pb.UserSettings, error) {
+	if req.GetUserId() <= 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "user_id must be positive")
+	}
+
+	return svc.storageAccess.getUserSettings(ctx, req.UserId)
+}
+
+func (svc *UserService) UpdateUserSettings(ctx context.Context, req *pb.UpdateUserSettingsRequest) (*pb.UserSettings, error) {
+	settings := req.GetSettings()
+	if settings == nil {
+		return nil, status.Errorf(codes.InvalidArgument, "settings object is required")
+	}
+	if settings.UserId <= 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "user_id must be positive")
+	}
+	// the settings left out keep their value, a policy that is set must be a real one
+	if settings.FriendRequestPolicy != nil {
+		if _, known := pb.FriendRequestPolicy_name[int32(settings.GetFriendRequestPolicy())]; !known ||
+			settings.GetFriendRequestPolicy() == pb.FriendRequestPolicy_FRIEND_REQUEST_POLICY_UNSPECIFIED {
+			return nil, status.Errorf(codes.InvalidArgument, "friend_request_policy must be EVERYONE, FRIENDS_OF_FRIENDS or NOBODY")
+		}
+	}
+
+	return svc.storageAccess.updateUserSettings(ctx, settings)
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	errchecks "github.com/Costin2000/GoChat---Schwarz-Internship---2025/pkg"
+	pb "github.com/Costin2000/GoChat---Schwarz-Internship---2025/services/user-base/proto"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/testing/protocmp"
+)
+
+func Test_GetUserSettings(t *testing.T) {
+	tests := []struct {
+		name         string
+		req          *pb.GetUserSettingsRequest
+		storage      StorageAccess
+		expectedErr  errchecks.Check
+		expectedResp *pb.UserSettings
+	}{
+		{
+			name:        "invalid user id",
+			req:         &pb.GetUserSettingsRequest{},
+			expectedErr: errchecks.HasStatusCode(codes.InvalidArgument),
+		},
+		{
+			name: "unknown user",
+			req:  &pb.GetUserSettingsRequest{UserId: 9},
+			storage: newMockStorageAccess(StorageMockOptions{
+				getUserSettingsFunc: func(ctx context.Context, userID int64) (*pb.UserSettings, error) {
+					return nil, status.Errorf(codes.NotFound, "user with id %d not found", userID)
+				},
+			}),
+			expectedErr: errchecks.HasStatusCode(codes.NotFound),
+		},
+		{
+			name: "returns the stored settings",
+			req:  &pb.GetUserSettingsRequest{UserId: 1},
+			storage: newMockStorageAccess(StorageMockOptions{
+				getUserSettingsFunc: func(ctx context.Context, userID int64) (*pb.UserSettings, error) {
+					return &pb.UserSettings{UserId: userID, ShareEmailWithFriends: proto.Bool(true), FriendRequestPolicy: pb.FriendRequestPolicy_FRIEND_REQUEST_POLICY_NOBODY.Enum()}, nil
+				},
+			}),
+			expectedResp: &pb.UserSettings{UserId: 1, ShareEmailWithFriends: proto.Bool(true), FriendRequestPolicy: pb.FriendRequestPolicy_FRIEND_REQUEST_POLICY_NOBODY.Enum()},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := NewMockService(ServiceMockOptions{storageAccess: tt.storage})
+
+			resp, err := svc.GetUserSettings(context.Background(), tt.req)
+
+			errchecks.Assert(t, err, tt.expectedErr)
+			if diff := cmp.Diff(tt.expectedResp, resp, protocmp.Transform()); diff != "" {
+				t.Errorf("mismatch (-expected +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func Test_UpdateUserSettings(t *testing.T) {
+	tests := []struct {
+		name         string
+		req          *pb.UpdateUserSettingsRequest
+		expectedErr  errchecks.Check
+		expectedResp *pb.UserSettings
+	}{
+		{
+			name:        "missing settings",
+			req:         &pb.UpdateUserSettingsRequest{},
+			expectedErr: errchecks.All(errchecks.HasStatusCode(codes.InvalidArgument), errchecks.MsgContains("settings object is required")),
+		},
+		{
+			name:        "invalid user id",
+			req:         &pb.UpdateUserSettingsRequest{Settings: &pb.UserSettings{ShareEmailWithFriends: proto.Bool(true)}},
+			expectedErr: errchecks.HasStatusCode(codes.InvalidArgument),
+		},
+		{
+			name:         "settings left out are not written",
+			req:          &pb.UpdateUserSettingsRequest{Settings: &pb.UserSettings{UserId: 1, ShareEmailWithFriends: proto.Bool(true)}},
+			expectedResp: &pb.UserSettings{UserId: 1, ShareEmailWithFriends: proto.Bool(true)},
+		},
+		{
+			name:        "unknown friend request policy",
+			req:         &pb.UpdateUserSettingsRequest{Settings: &pb.UserSettings{UserId: 1, FriendRequestPolicy: pb.FriendRequestPolicy(9).Enum()}},
+			expectedErr: errchecks.All(errchecks.HasStatusCode(codes.InvalidArgument), errchecks.MsgContains("friend_request_policy")),
+		},
+		{
+			name:        "unspecified friend request policy",
+			req:         &pb.UpdateUserSettingsRequest{Settings: &pb.UserSettings{UserId: 1, FriendRequestPolicy: pb.FriendRequestPolicy_FRIEND_REQUEST_POLICY_UNSPECIFIED.Enum()}},
+			expectedErr: errchecks.HasStatusCode(codes.InvalidArgument),
+		},
+		{
+			name: "stores the settings",
+			req: &pb.UpdateUserSettingsRequest{Settings: &pb.UserSettings{
+				UserId: 1, ShareEmailWithFriends: proto.Bool(false), FriendRequestPolicy: pb.FriendRequestPolicy_FRIEND_REQUEST_POLICY_FRIENDS_OF_FRIENDS.Enum(), Discoverable: proto.Bool(false),
+			}},
+			expectedResp: &pb.UserSettings{
+				UserId: 1, ShareEmailWithFriends: proto.Bool(false), FriendRequestPolicy: pb.FriendRequestPolicy_FRIEND_REQUEST_POLICY_FRIENDS_OF_FRIENDS.Enum(), Discoverable: proto.Bool(false),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := NewMockService(ServiceMockOptions{})
+
+			resp, err := svc.UpdateUserSettings(context.Background(), tt.req)
+
+			errchecks.Assert(t, err, tt.expectedErr)
+			if diff := cmp.Diff(tt.expectedResp, resp, protocmp.Transform()); diff != "" {
+				t.Errorf("mismatch (-expected +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -13,12 +13,6 @@ else
   exit 1
 fi
 
-# check for the init.sql file
-if [ ! -f ./../../../devtest-db/init.sql ]; then
-    echo "Error: init.sql file not found in the current directory."
-    exit 1
-fi
-
 # start PostgreSQL server
 # ------------------------------------
 echo "Checking PostgreSQL server status..."
@@ -51,22 +45,16 @@ else
 fi
 
 
-# recreate the Database and Run Init Script
+# Drop + recreate the Database and apply the migrations
 # ------------------------------------
-echo "Checking for database '$POSTGRES_DB' existence..."
-DB_EXISTS=$(psql -h "$POSTGRES_HOST" -U "$POSTGRES_USER" -d postgres -tAc "SELECT 1 FROM pg_database WHERE datname='$POSTGRES_DB'")
-
-if [ "$DB_EXISTS" != "1" ]; then
-    echo "-> Database '$POSTGRES_DB' not found. Creating it..."
-    createdb -h "$POSTGRES_HOST" -U "$POSTGRES_USER" "$POSTGRES_DB"
-    echo "-> New empty database created."
-else
-    echo "-> Database '$POSTGRES_DB' already exists."
-fi
-
-# execute the init.sql script against the new database
-psql -h "$POSTGRES_HOST" -U "$POSTGRES_USER" -d "$POSTGRES_DB" -f ./../../../devtest-db/init.sql
-echo "Successfully executed init.sql to set up schema and data."
+echo "Dropping and recreating database '$POSTGRES_DB'..."
+psql -h "$POSTGRES_HOST" -U "$POSTGRES_USER" -d postgres -c "DROP DATABASE IF EXISTS \"$POSTGRES_DB\" WITH (FORCE);"
+createdb -h "$POSTGRES_HOST" -U "$POSTGRES_USER" "$POSTGRES_DB"
+echo "-> New empty database created."
+
+echo "Applying migrations from db/migrations..."
+(cd ./../../.. && DB_HOST="$POSTGRES_HOST" go run ./cmd/migrate -env ./devtest-db/.env up)
+echo "Successfully applied the migrations."
 
 
 echo "DB setup complete!"